@@ -0,0 +1,82 @@
+// abstract_test.go
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestAbstractModels checks that abstract bases get no table and that their
+// fields, Meta and constraint names are flattened into concrete subclasses.
+func TestAbstractModels(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"core/models.py": `from django.db import models
+
+class Timestamped(models.Model):
+    created = models.DateTimeField()
+    note = models.TextField()
+    parent = models.ForeignKey("self", null=True, on_delete=models.CASCADE)
+
+    class Meta:
+        abstract = True
+        ordering = ["created"]
+        constraints = [
+            models.UniqueConstraint(fields=["created"], name="%(app_label)s_%(class)s_created"),
+        ]
+`,
+		"blog/models.py": `from django.db import models
+from core.models import Timestamped
+
+class Post(Timestamped):
+    title = models.CharField(max_length=100)
+    note = None
+
+class Page(Timestamped):
+    note = models.CharField(max_length=20)
+
+    class Meta(Timestamped.Meta):
+        db_table = "pages"
+`,
+	})
+	var names []string
+	for _, m := range out.Models {
+		names = append(names, m.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"Page", "Post"}) {
+		t.Fatalf("models = %v", names)
+	}
+	for _, m := range out.Models {
+		var fields []string
+		for _, f := range m.Fields {
+			fields = append(fields, f.Name)
+		}
+		want := map[string][]string{
+			"Post": {"created", "parent", "title"},
+			"Page": {"created", "parent", "note"},
+		}[m.Name]
+		if !slices.Equal(fields, want) {
+			t.Errorf("%s fields = %v, want %v", m.Name, fields, want)
+		}
+		if !slices.Equal(m.Ordering, []string{"created"}) {
+			t.Errorf("%s ordering = %v", m.Name, m.Ordering)
+		}
+		if len(m.Constraints) != 1 || m.Constraints[0].Name != "blog_"+strings.ToLower(m.Name)+"_created" {
+			t.Errorf("%s constraints = %+v", m.Name, m.Constraints)
+		}
+	}
+
+	if diags := normalize(&Config{}, out.Models, Options{Dialect: "postgres", NoAppPrefix: true}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, Options{Dialect: "postgres"})
+	for _, want := range []string{"CREATE TABLE post (", "CREATE TABLE pages (", "REFERENCES post(id)", "REFERENCES pages(id)"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "timestamped") {
+		t.Errorf("abstract base got a table:\n%s", sql)
+	}
+}
@@ -0,0 +1,165 @@
+// admin.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Admin is a ModelAdmin registered for a model in an admin.py file.
+type Admin struct {
+	Model        string   `json:"model"`
+	Admin        string   `json:"admin"`
+	File         string   `json:"file"`
+	Line         int      `json:"line"`
+	ListDisplay  []string `json:"list_display"`
+	ListFilter   []string `json:"list_filter"`
+	SearchFields []string `json:"search_fields"`
+	Ordering     []string `json:"ordering"`
+}
+
+// searchLookups maps the search_fields prefixes to the lookup Django uses.
+var searchLookups = map[byte]string{'^': "istartswith", '=': "iexact", '@': "search"}
+
+// adminQueries generates a paginated List<Model>Admin query and a matching
+// Count<Model>Admin query for every registered ModelAdmin, with an optional
+// parameter per list_filter field and a single search parameter matched
+// against the search_fields. Options that are not plain columns are reported
+// and left out.
+func adminQueries(admins []Admin, models []Model, dialect string) ([]TranslatedQuery, []Diagnostic) {
+	byName := modelsByName(models)
+	var result []TranslatedQuery
+	var diags []Diagnostic
+	for _, a := range admins {
+		m, ok := byName[a.Model]
+		if !ok {
+			continue
+		}
+		skip := func(option, name string, err error) {
+			diags = append(diags, Diagnostic{
+				Code: codeAdminOption, Severity: severity(codeAdminOption), File: a.File, Line: a.Line,
+				App: m.App, Model: m.Name, Message: fmt.Sprintf("%s.%s %q skipped: %v", a.Admin, option, name, err),
+			})
+		}
+		params := map[string]int{}
+		table := m.table()
+
+		var cols []string
+		for _, name := range a.ListDisplay {
+			fc, err := resolveColumns(name, m)
+			if err != nil {
+				if name != "__str__" {
+					skip("list_display", name, err)
+				}
+				continue
+			}
+			cols = append(cols, fc...)
+		}
+		selected := "*"
+		if len(cols) > 0 {
+			selected = columnList(m.pkColumns())
+			for _, c := range cols {
+				if !strings.Contains(", "+selected+", ", ", "+c+", ") {
+					selected += ", " + c
+				}
+			}
+		}
+
+		var conds []string
+		for _, name := range a.ListFilter {
+			fc, err := resolveColumns(name, m)
+			if err != nil {
+				skip("list_filter", name, err)
+				continue
+			}
+			for _, c := range fc {
+				p := "sqlc.narg(" + param(c, params) + ")"
+				check := p + " IS NULL"
+				if dialect != "mysql" {
+					check = p + "::" + sqlType(fieldType(c, m), dialect) + " IS NULL"
+				}
+				conds = append(conds, "("+check+" OR "+c+" = "+p+")")
+			}
+		}
+
+		search := "sqlc.narg(" + param("search", params) + ")"
+		var matches []string
+		for _, name := range a.SearchFields {
+			lookup := "icontains"
+			if name == "" {
+				continue
+			}
+			if l, ok := searchLookups[name[0]]; ok {
+				lookup, name = l, name[1:]
+			}
+			col, err := resolveColumn(name, m)
+			if err != nil {
+				skip("search_fields", name, err)
+				continue
+			}
+			matches = append(matches, searchMatch(col, lookup, search, dialect))
+		}
+		if len(matches) > 0 {
+			check := search + " IS NULL"
+			if dialect != "mysql" {
+				check = search + "::text IS NULL"
+			}
+			conds = append(conds, "("+check+" OR "+strings.Join(matches, " OR ")+")")
+		}
+
+		order := m.defaultOrder()
+		if len(a.Ordering) > 0 {
+			order = nil
+			for _, name := range a.Ordering {
+				dir := ""
+				if strings.HasPrefix(name, "-") {
+					name, dir = name[1:], " DESC"
+				}
+				col, err := resolveColumn(name, m)
+				if err != nil {
+					skip("ordering", name, err)
+					continue
+				}
+				order = append(order, col+dir)
+			}
+		}
+		orderBy := ""
+		if len(order) > 0 {
+			orderBy = " ORDER BY " + strings.Join(order, ", ")
+		}
+
+		source := fmt.Sprintf("%s (%s:%d)", a.Admin, a.File, a.Line)
+		where := whereClause(conds)
+		result = append(result,
+			TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: source},
+				Name:  "List" + m.Name + "Admin",
+				Kind:  ":many",
+				SQL: "SELECT " + selected + " FROM " + table + where + orderBy +
+					" LIMIT sqlc.arg(" + param("limit", params) + ") OFFSET sqlc.arg(" + param("offset", params) + ");",
+			},
+			TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: source},
+				Name:  "Count" + m.Name + "Admin",
+				Kind:  ":one",
+				SQL:   "SELECT COUNT(*) FROM " + table + where + ";",
+			})
+	}
+	return result, diags
+}
+
+// searchMatch renders the condition matching a search_fields column against
+// the search parameter. Full-text (@) fields fall back to icontains.
+func searchMatch(col, lookup, search, dialect string) string {
+	if lookup == "iexact" {
+		return "LOWER(" + col + ") = LOWER(" + search + ")"
+	}
+	parts := []string{search, "'%'"}
+	if lookup != "istartswith" {
+		parts = append([]string{"'%'"}, parts...)
+	}
+	if dialect == "mysql" {
+		return col + " LIKE CONCAT(" + strings.Join(parts, ", ") + ")"
+	}
+	return col + " ILIKE " + strings.Join(parts, " || ")
+}
@@ -0,0 +1,70 @@
+// admin_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestAdminQueries parses a registered ModelAdmin and checks the generated
+// list and count queries and the skipped options.
+func TestAdminQueries(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+    status = models.CharField(max_length=10)
+    author = models.ForeignKey("Author", on_delete=models.CASCADE)
+`,
+		"blog/admin.py": `from django.contrib import admin
+from .models import Post
+
+@admin.register(Post)
+class PostAdmin(admin.ModelAdmin):
+    list_display = ("title", "status", "word_count")
+    list_filter = ("status", ("author__name", admin.RelatedOnlyFieldListFilter))
+    search_fields = ["^title", "=status"]
+    ordering = ["-title"]
+`,
+	})
+	if len(out.Admins) != 1 {
+		t.Fatalf("admins = %+v", out.Admins)
+	}
+	queries, diags := adminQueries(out.Admins, out.Models, "postgres")
+	if len(queries) != 2 {
+		t.Fatalf("got %d admin queries, want list and count", len(queries))
+	}
+	list, count := queries[0], queries[1]
+	if list.Name != "ListPostAdmin" || !strings.HasPrefix(list.SQL, "SELECT id, title, status FROM post WHERE") {
+		t.Errorf("list query = %s %q", list.Name, list.SQL)
+	}
+	for _, want := range []string{
+		"(sqlc.narg(status)::TEXT IS NULL OR status = sqlc.narg(status))",
+		"(sqlc.narg(search)::text IS NULL OR title ILIKE sqlc.narg(search) || '%' OR LOWER(status) = LOWER(sqlc.narg(search)))",
+		"ORDER BY title DESC LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+	} {
+		if !strings.Contains(list.SQL, want) {
+			t.Errorf("list query missing %q:\n%s", want, list.SQL)
+		}
+	}
+	if count.Name != "CountPostAdmin" || !strings.HasPrefix(count.SQL, "SELECT COUNT(*) FROM post WHERE") {
+		t.Errorf("count query = %s %q", count.Name, count.SQL)
+	}
+
+	var skipped []string
+	for _, d := range diags {
+		if d.Code != codeAdminOption || d.File != "blog/admin.py" {
+			t.Errorf("unexpected diagnostic %v", d)
+		}
+		skipped = append(skipped, d.Message)
+	}
+	if len(skipped) != 2 || !strings.Contains(skipped[0], `list_display "word_count"`) || !strings.Contains(skipped[1], `list_filter "author__name"`) {
+		t.Errorf("skipped = %q", skipped)
+	}
+
+	mysql, _ := adminQueries(out.Admins, out.Models, "mysql")
+	if !strings.Contains(mysql[0].SQL, "(sqlc.narg(search) IS NULL OR title LIKE CONCAT(sqlc.narg(search), '%')") {
+		t.Errorf("mysql list query = %q", mysql[0].SQL)
+	}
+}
@@ -0,0 +1,131 @@
+// alias.go
+package main
+
+import "strings"
+
+// Column alias strategies of --column-aliases: sqlc names the fields of the
+// result structs after the selected columns, which lose the case of the
+// Django attributes they come from.
+const (
+	// aliasNone selects the columns under their own names.
+	aliasNone = "none"
+	// aliasAttribute aliases the columns whose name differs from the
+	// attribute, so that isActive becomes IsActive rather than Isactive.
+	aliasAttribute = "attribute"
+)
+
+// attributeAliases maps the columns of the model, and the SQL names of its
+// translatable properties, to the attribute names they lost the case of.
+func attributeAliases(m Model) map[string]string {
+	aliases := map[string]string{}
+	for _, f := range m.Fields {
+		if f.Relation == "many2many" {
+			continue
+		}
+		cols := fieldColumns(f)
+		if len(cols) != 1 {
+			// Composite foreign keys have no single attribute.
+			continue
+		}
+		attr := f.Name
+		if f.Relation != "" {
+			attr += "_id"
+		}
+		if cols[0].Name != attr {
+			aliases[cols[0].Name] = attr
+		}
+	}
+	for _, p := range m.Properties {
+		if name := toSnake(p.Name); name != p.Name {
+			aliases[name] = p.Name
+		}
+	}
+	return aliases
+}
+
+// aliasColumns rewrites the select lists of the queries reading a model's
+// table to alias its columns to the attribute names, expanding * when the
+// table has any column to alias. Queries on views are left alone, as their
+// columns are not known.
+func aliasColumns(queries []TranslatedQuery, models []Model, opts Options) {
+	if opts.ColumnAliases != aliasAttribute {
+		return
+	}
+	byTable := map[string]Model{}
+	for _, m := range models {
+		if !m.isView() {
+			byTable[m.table()] = m
+		}
+	}
+	for i, q := range queries {
+		list, rest, ok := strings.Cut(q.SQL, " FROM ")
+		if !ok || !strings.HasPrefix(list, "SELECT ") {
+			continue
+		}
+		table, _, _ := strings.Cut(strings.TrimSuffix(rest, ";"), " ")
+		m, ok := byTable[table]
+		if !ok {
+			continue
+		}
+		aliases := attributeAliases(m)
+		if len(aliases) == 0 {
+			continue
+		}
+		alias := func(col string) string {
+			if attr, ok := aliases[col]; ok {
+				return col + " AS " + quoteAlias(attr, opts.Dialect)
+			}
+			return col
+		}
+		var items []string
+		for _, item := range splitSelect(strings.TrimPrefix(list, "SELECT ")) {
+			switch expr, name, isAlias := strings.Cut(item, " AS "); {
+			case item == "*":
+				cols, _ := tableColumns(m)
+				if opts.ComputedColumns == "generated" {
+					for _, c := range propertyColumns(m, opts.Dialect) {
+						cols = append(cols, c.Name)
+					}
+				}
+				for _, col := range cols {
+					items = append(items, alias(col))
+				}
+			case isAlias && aliases[name] != "":
+				items = append(items, expr+" AS "+quoteAlias(aliases[name], opts.Dialect))
+			default:
+				items = append(items, alias(item))
+			}
+		}
+		queries[i].SQL = "SELECT " + strings.Join(items, ", ") + " FROM " + rest
+	}
+}
+
+// quoteAlias quotes an alias so that the database keeps its case.
+func quoteAlias(name, dialect string) string {
+	if dialect == "mysql" {
+		return "`" + name + "`"
+	}
+	return `"` + name + `"`
+}
+
+// splitSelect splits a select list on the commas outside parentheses and
+// string literals.
+func splitSelect(list string) []string {
+	var items []string
+	depth, start, quoted := 0, 0, false
+	for i, r := range list {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case quoted:
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(list[start:]))
+}
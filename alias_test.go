@@ -0,0 +1,44 @@
+// alias_test.go
+package main
+
+import "testing"
+
+// TestAliasColumns checks that * is expanded with the columns whose name
+// lost the case of their attribute aliased, and that explicit lists and
+// aliases are rewritten in place.
+func TestAliasColumns(t *testing.T) {
+	models := []Model{
+		{Name: "Author", Fields: []Field{{Name: "name", Type: "CharField"}}},
+		{Name: "Post", Fields: []Field{
+			{Name: "isActive", Type: "BooleanField"},
+			{Name: "title", Type: "CharField"},
+			{Name: "mainAuthor", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+		}},
+	}
+	resolveRelations(models)
+	queries := []TranslatedQuery{
+		{Name: "GetPost", SQL: "SELECT * FROM post WHERE id = $1;"},
+		{Name: "ListActive", SQL: "SELECT isactive, COUNT(*) AS isactive FROM post GROUP BY isactive;"},
+		{Name: "GetAuthor", SQL: "SELECT * FROM author WHERE id = $1;"},
+	}
+	aliasColumns(queries, models, Options{Dialect: "postgres", ColumnAliases: aliasAttribute})
+	want := []string{
+		`SELECT id, isactive AS "isActive", title, mainauthor_id AS "mainAuthor_id" FROM post WHERE id = $1;`,
+		`SELECT isactive AS "isActive", COUNT(*) AS "isActive" FROM post GROUP BY isactive;`,
+		"SELECT * FROM author WHERE id = $1;",
+	}
+	for i, q := range queries {
+		if q.SQL != want[i] {
+			t.Errorf("%s:\n got %s\nwant %s", q.Name, q.SQL, want[i])
+		}
+	}
+
+	queries = []TranslatedQuery{{SQL: "SELECT * FROM post;"}}
+	aliasColumns(queries, models, Options{Dialect: "mysql", ColumnAliases: aliasNone})
+	if queries[0].SQL != "SELECT * FROM post;" {
+		t.Errorf("none rewrote %s", queries[0].SQL)
+	}
+	if got := quoteAlias("isActive", "mysql"); got != "`isActive`" {
+		t.Errorf("mysql alias = %s", got)
+	}
+}
@@ -0,0 +1,38 @@
+// apps.go
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// skipUninstalledApps drops the models of the apps missing from
+// INSTALLED_APPS, such as abandoned or example apps kept in the repository,
+// with their call sites and management commands, and returns the labels of
+// those apps. Nothing is dropped when no settings were found or when
+// INSTALLED_APPS cannot be read without running them.
+func skipUninstalledApps(out *Output) (skipped []string) {
+	s := out.Settings
+	if len(s.InstalledApps) == 0 || s.InstalledAppsUnresolved {
+		return nil
+	}
+	dropped := map[string]bool{}
+	var models []Model
+	for _, m := range out.Models {
+		if listsApp(s.InstalledApps, m.App) {
+			models = append(models, m)
+			continue
+		}
+		if !dropped[m.App] {
+			dropped[m.App] = true
+			skipped = append(skipped, m.App)
+		}
+	}
+	// Call sites outside apps, as in the project package, are kept: only
+	// those of the dropped apps would query missing models.
+	out.Models = models
+	out.Queries = slices.DeleteFunc(out.Queries, func(q Query) bool { return dropped[q.App] })
+	out.Commands = slices.DeleteFunc(out.Commands, func(c Command) bool { return dropped[c.App] })
+	sort.Strings(skipped)
+	return skipped
+}
@@ -0,0 +1,59 @@
+// apps_test.go
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSkipUninstalledApps checks that the apps missing from INSTALLED_APPS,
+// followed through list concatenations, are dropped with their call sites.
+func TestSkipUninstalledApps(t *testing.T) {
+	files := map[string]string{
+		"project/settings.py": `DJANGO_APPS = ["django.contrib.auth"]
+LOCAL_APPS = ("blog.apps.BlogConfig", "project.apps.shop")
+INSTALLED_APPS = DJANGO_APPS + list(LOCAL_APPS)
+`,
+		"blog/models.py":    "from django.db import models\n\nclass Post(models.Model):\n    title = models.CharField(max_length=10)\n",
+		"shop/models.py":    "from django.db import models\n\nclass Item(models.Model):\n    name = models.CharField(max_length=10)\n",
+		"example/models.py": "from django.db import models\n\nclass Demo(models.Model):\n    name = models.CharField(max_length=10)\n",
+		"example/views.py":  "from .models import Demo\n\ndef index():\n    return Demo.objects.all()\n",
+	}
+	// list(...) cannot be followed: nothing is dropped.
+	out := parseProject(t, files)
+	if !out.Settings.InstalledAppsUnresolved || skipUninstalledApps(out) != nil || len(out.Models) != 3 {
+		t.Errorf("unresolved INSTALLED_APPS: settings = %+v, models = %d", out.Settings, len(out.Models))
+	}
+
+	files["project/settings.py"] = `DJANGO_APPS = ["django.contrib.auth"]
+LOCAL_APPS = ("blog.apps.BlogConfig", "project.apps.shop")
+INSTALLED_APPS = DJANGO_APPS + ["blog.apps.BlogConfig"]
+INSTALLED_APPS += ["project.apps.shop"]
+`
+	out = parseProject(t, files)
+	if skipped := skipUninstalledApps(out); !slices.Equal(skipped, []string{"example"}) {
+		t.Errorf("skipped = %v", skipped)
+	}
+	var names []string
+	for _, m := range out.Models {
+		names = append(names, m.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"Item", "Post"}) || len(out.Queries) != 0 {
+		t.Errorf("models = %v, queries = %+v", names, out.Queries)
+	}
+}
+
+// TestAppLabel checks the labels of INSTALLED_APPS entries.
+func TestAppLabel(t *testing.T) {
+	for entry, want := range map[string]string{
+		"blog":                 "blog",
+		"project.blog":         "blog",
+		"blog.apps.BlogConfig": "blog",
+		"project.apps.blog":    "blog",
+	} {
+		if got := appLabel(entry); got != want {
+			t.Errorf("appLabel(%q) = %q, want %q", entry, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// autonow.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// onUpdate returns the ON UPDATE clause of an auto_now column MySQL updates
+// itself, which it only does for timestamps; "" for the others.
+func onUpdate(f Field, dialect string) string {
+	if !f.OnUpdate || dialect != "mysql" || f.Type != "DateTimeField" {
+		return ""
+	}
+	return "ON UPDATE " + nowSQL(f, dialect)
+}
+
+// autoNowTrigger returns the statements creating the trigger that sets the
+// model's auto_now columns on every UPDATE of table, and dropping it, or ""
+// when there is none to create. MySQL only needs one for dates and times.
+func autoNowTrigger(m Model, table, dialect string) (create, drop string) {
+	name := m.table() + "_auto_now"
+	var sets []string
+	for _, f := range m.Fields {
+		if f.OnUpdate && onUpdate(f, dialect) == "" {
+			sets = append(sets, "NEW."+columnName(f)+" = "+nowSQL(f, dialect))
+		}
+	}
+	if len(sets) == 0 {
+		return "", ""
+	}
+	if dialect == "mysql" {
+		return fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW SET %s;", name, table, strings.Join(sets, ", ")),
+			fmt.Sprintf("DROP TRIGGER IF EXISTS %s;", name)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CREATE OR REPLACE FUNCTION %s() RETURNS TRIGGER AS $$\nBEGIN\n", name)
+	for _, s := range sets {
+		sb.WriteString("    " + strings.Replace(s, " = ", " := ", 1) + ";\n")
+	}
+	fmt.Fprintf(&sb, "    RETURN NEW;\nEND;\n$$ LANGUAGE plpgsql;\n\nCREATE TRIGGER %s BEFORE UPDATE ON %s\n    FOR EACH ROW EXECUTE FUNCTION %s();", name, table, name)
+	return sb.String(), fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s;\nDROP FUNCTION IF EXISTS %s();", name, table, name)
+}
+
+// diffTriggers returns the step replacing the auto_now trigger of a kept or
+// renamed table when its columns change.
+func diffTriggers(o, n Model, dialect string) []migrationStep {
+	table := n.table()
+	oldCreate, oldDrop := autoNowTrigger(o, table, dialect)
+	newCreate, newDrop := autoNowTrigger(n, table, dialect)
+	if oldCreate == newCreate {
+		return nil
+	}
+	join := func(stmts ...string) string {
+		var nonEmpty []string
+		for _, s := range stmts {
+			if s != "" {
+				nonEmpty = append(nonEmpty, s)
+			}
+		}
+		return strings.Join(nonEmpty, "\n")
+	}
+	return []migrationStep{{up: join(oldDrop, newCreate), down: join(newDrop, oldCreate)}}
+}
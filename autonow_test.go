@@ -0,0 +1,71 @@
+// autonow_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// autoNowModels returns a Post with auto_now_add and auto_now fields,
+// normalized with opts and tables named after the model alone.
+func autoNowModels(t *testing.T, opts Options) []Model {
+	t.Helper()
+	models := []Model{{Name: "Post", App: "blog", Fields: []Field{
+		{Name: "created", Type: "DateTimeField", AutoNowAdd: true},
+		{Name: "updated", Type: "DateTimeField", AutoNow: true},
+		{Name: "day", Type: "DateField", AutoNow: true},
+	}}}
+	opts.NoAppPrefix = true
+	if diags := normalize(&Config{}, models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	return models
+}
+
+// TestAutoNow checks the defaults of auto_now fields, and the trigger or
+// ON UPDATE clause keeping them current with --auto-now-triggers.
+func TestAutoNow(t *testing.T) {
+	opts := Options{Dialect: "postgres"}
+	sql := generateSQL(autoNowModels(t, opts), opts)
+	if !strings.Contains(sql, "created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP") || strings.Contains(sql, "TRIGGER") {
+		t.Errorf("postgres without triggers:\n%s", sql)
+	}
+
+	opts.AutoNowTriggers = true
+	sql = generateSQL(autoNowModels(t, opts), opts)
+	for _, want := range []string{
+		"NEW.updated := CURRENT_TIMESTAMP;\n    NEW.day := CURRENT_DATE;",
+		"CREATE TRIGGER post_auto_now BEFORE UPDATE ON post\n    FOR EACH ROW EXECUTE FUNCTION post_auto_now();",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("postgres schema lacks %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "NEW.created") {
+		t.Errorf("auto_now_add field updated:\n%s", sql)
+	}
+
+	opts.Dialect = "mysql"
+	sql = generateSQL(autoNowModels(t, opts), opts)
+	for _, want := range []string{
+		"updated DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6)",
+		"day DATE NOT NULL DEFAULT (CURRENT_DATE)",
+		"CREATE TRIGGER post_auto_now BEFORE UPDATE ON post FOR EACH ROW SET NEW.day = CURRENT_DATE;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("mysql schema lacks %q:\n%s", want, sql)
+		}
+	}
+}
+
+// TestAutoNowDiff checks that turning on the triggers creates them in a
+// migration that drops them on the way down.
+func TestAutoNowDiff(t *testing.T) {
+	prev := autoNowModels(t, Options{Dialect: "postgres"})
+	opts := Options{Dialect: "postgres", AutoNowTriggers: true}
+	steps := diffModels(prev, autoNowModels(t, opts), nil, opts)
+	if len(steps) != 1 || !strings.Contains(steps[0].up, "CREATE TRIGGER post_auto_now") ||
+		steps[0].down != "DROP TRIGGER IF EXISTS post_auto_now ON post;\nDROP FUNCTION IF EXISTS post_auto_now();" {
+		t.Errorf("steps = %+v", steps)
+	}
+}
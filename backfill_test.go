@@ -0,0 +1,51 @@
+// backfill_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBackfill checks that a NOT NULL column added to an existing table is
+// added nullable, backfilled and only then set NOT NULL.
+func TestBackfill(t *testing.T) {
+	prev := []Model{{Name: "Post", Fields: []Field{{Name: "title", Type: "CharField"}}}}
+	cur := []Model{{Name: "Post", Fields: []Field{
+		{Name: "title", Type: "CharField"},
+		{Name: "status", Type: "CharField", Default: "draft"},
+		{Name: "note", Type: "TextField", Nullable: true},
+	}}}
+	safe, notNull, destructive := groupSteps(diffModels(prev, cur, nil, Options{Dialect: "postgres"}))
+	if len(safe) != 2 || len(notNull) != 1 || len(destructive) != 0 {
+		t.Fatalf("steps: %d safe, %d not null, %d destructive", len(safe), len(notNull), len(destructive))
+	}
+	if safe[0].up != "ALTER TABLE post ADD COLUMN status TEXT DEFAULT 'draft';" {
+		t.Errorf("added column: %s", safe[0].up)
+	}
+	if !strings.Contains(notNull[0].up, "SET NOT NULL") || !strings.Contains(notNull[0].down, "DROP NOT NULL") {
+		t.Errorf("not null step: %s / %s", notNull[0].up, notNull[0].down)
+	}
+
+	notNull[0].backfill.tenant = true
+	files, err := generateBackfill(notNull, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["backfill/backfill.go"]
+	for _, want := range []string{
+		"func PostStatus(ctx context.Context, db DB, value any) error {",
+		"// Django's default is \"draft\".\n// Run it for every tenant, on a connection from tenant.Open.",
+		"UPDATE post SET status = $1 WHERE ctid IN (SELECT ctid FROM post WHERE status IS NULL LIMIT $2)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("backfill.go lacks %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestBackfillQueryMySQL(t *testing.T) {
+	got := backfillQuery(backfill{table: "post", column: "status"}, "mysql")
+	if got != "UPDATE post SET status = ? WHERE status IS NULL LIMIT ?" {
+		t.Errorf("query = %s", got)
+	}
+}
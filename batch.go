@@ -0,0 +1,37 @@
+// batch.go
+package main
+
+// relationQueries generates a List<Model>By<Relation>IDs query for every
+// foreign key and one-to-one field, returning the rows pointing at any of a
+// set of related rows: the building block for replacing prefetch_related
+// with one query per relation instead of one per parent. PostgreSQL takes
+// the ids as an array; MySQL expands them into an IN list. Foreign keys to
+// composite primary keys have no single id and get no query.
+func relationQueries(models []Model, dialect string) []TranslatedQuery {
+	var result []TranslatedQuery
+	for _, m := range models {
+		if m.isView() {
+			continue
+		}
+		for _, f := range m.Fields {
+			if f.Relation != "foreignkey" && f.Relation != "one2one" {
+				continue
+			}
+			cols := fieldColumns(f)
+			if len(cols) != 1 {
+				continue
+			}
+			cond := cols[0].Name + " = ANY(sqlc.arg(ids)::" + cols[0].sqlType(dialect) + "[])"
+			if dialect == "mysql" {
+				cond = cols[0].Name + " IN (sqlc.slice(ids))"
+			}
+			result = append(result, TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: f.Name + " = " + f.Type + "(" + f.RelatedTo + ")"},
+				Name:  "List" + m.Name + "By" + camel(f.Name) + "IDs",
+				Kind:  ":many",
+				SQL:   "SELECT * FROM " + m.table() + " WHERE " + cond + ";",
+			})
+		}
+	}
+	return result
+}
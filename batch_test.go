@@ -0,0 +1,29 @@
+// batch_test.go
+package main
+
+import "testing"
+
+func TestRelationQueries(t *testing.T) {
+	models := []Model{
+		{Name: "Author", App: "blog"},
+		{Name: "Post", App: "blog", Fields: []Field{
+			{Name: "title", Type: "CharField"},
+			{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+			{Name: "tags", Type: "ManyToManyField", Relation: "many2many", RelatedTo: "Author"},
+		}},
+	}
+	resolveRelations(models)
+	for dialect, want := range map[string]string{
+		"postgres": "SELECT * FROM post WHERE author_id = ANY(sqlc.arg(ids)::INTEGER[]);",
+		"mysql":    "SELECT * FROM post WHERE author_id IN (sqlc.slice(ids));",
+	} {
+		queries := relationQueries(models, dialect)
+		if len(queries) != 1 {
+			t.Fatalf("%s queries = %+v", dialect, queries)
+		}
+		q := queries[0]
+		if q.Name != "ListPostByAuthorIDs" || q.Kind != ":many" || q.SQL != want || !q.Generated() {
+			t.Errorf("%s query = %+v", dialect, q)
+		}
+	}
+}
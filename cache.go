@@ -0,0 +1,294 @@
+// cache.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultCacheTTL is how long the generated cache keeps query results when
+// the config sets no TTL.
+const defaultCacheTTL = 5 * time.Minute
+
+// generateCache renders the cache package: a read-through cache for the hot
+// queries of the report, invalidated by the write queries changing the
+// tables they read, like the per-view caches of a Django project.
+func generateCache(hot []HotQuery, queries []TranslatedQuery, ttl time.Duration) (map[string]string, error) {
+	if ttl == 0 {
+		ttl = defaultCacheTTL
+	}
+	var reads []string
+	for _, h := range hot {
+		for _, name := range h.Names {
+			reads = append(reads, fmt.Sprintf("%q: {key: %q, tables: %s},", name, h.Names[0], goStrings(h.Tables)))
+		}
+	}
+	var writes []string
+	for _, q := range queries {
+		if !q.Translated() || isRead(q.SQL) {
+			continue
+		}
+		if tables := queryTables(q.SQL); len(tables) > 0 {
+			// The first table is the one written; the others are read.
+			writes = append(writes, fmt.Sprintf("%q: %q,", q.Name, tables[0]))
+		}
+	}
+	sort.Strings(writes)
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package cache caches the results of the hot read queries, those run from
+// many call sites, and drops them when a write query changes a table they
+// read.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TTL is how long a cached result is kept.
+var TTL = %[1]s
+
+// reads maps the hot read queries to their cache key and the tables they
+// read. Queries with the same SQL share their cached results.
+var reads = map[string]struct {
+	key    string
+	tables []string
+}{
+	%[2]s
+}
+
+// writes maps the write queries to the table they change.
+var writes = map[string]string{
+	%[3]s
+}
+
+// Store holds the cached values. Memory is an in-process store; a shared
+// store, such as Redis with GET and SET PX, implements the same methods so
+// that every instance of the service sees the invalidations.
+type Store interface {
+	// Get returns the value of key, reporting whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value of key for ttl, or forever when ttl is zero.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Cache is a read-through cache of query results held in a store.
+type Cache struct {
+	store Store
+}
+
+// New returns a cache holding its values in store.
+func New(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Wrap returns fn, a sqlc query method taking one argument such as
+// queries.GetPostBySlug, reading through the cache:
+//
+//	getPost := cache.Wrap(c, "GetPostBySlug", queries.GetPostBySlug)
+//
+// Results are stored as JSON, keyed by the query, its argument and the
+// generation of the tables it reads. Errors are returned uncached, and a
+// failing store falls back to running the query.
+func Wrap[A, R any](c *Cache, name string, fn func(context.Context, A) (R, error)) func(context.Context, A) (R, error) {
+	q, ok := reads[name]
+	if !ok {
+		panic(fmt.Sprintf("cache: %%s is not a hot read query", name))
+	}
+	return func(ctx context.Context, arg A) (R, error) {
+		return read(ctx, c, q.key, q.tables, arg, func() (R, error) { return fn(ctx, arg) })
+	}
+}
+
+// Wrap0 is Wrap for the query methods taking no argument.
+func Wrap0[R any](c *Cache, name string, fn func(context.Context) (R, error)) func(context.Context) (R, error) {
+	q, ok := reads[name]
+	if !ok {
+		panic(fmt.Sprintf("cache: %%s is not a hot read query", name))
+	}
+	return func(ctx context.Context) (R, error) {
+		return read(ctx, c, q.key, q.tables, nil, func() (R, error) { return fn(ctx) })
+	}
+}
+
+// read returns the cached result of a query, running load on a miss.
+func read[R any](ctx context.Context, c *Cache, name string, tables []string, arg any, load func() (R, error)) (R, error) {
+	key, err := c.key(ctx, name, tables, arg)
+	if err != nil {
+		return load()
+	}
+	if b, ok, err := c.store.Get(ctx, key); err == nil && ok {
+		var r R
+		if json.Unmarshal(b, &r) == nil {
+			return r, nil
+		}
+	}
+	r, err := load()
+	if err != nil {
+		return r, err
+	}
+	if b, err := json.Marshal(r); err == nil {
+		c.store.Set(ctx, key, b, TTL)
+	}
+	return r, nil
+}
+
+// key returns the cache key of a query run with arg. Invalidating a table
+// changes its generation, so the keys of the results read from it before
+// are never looked up again and expire with their TTL.
+func (c *Cache) key(ctx context.Context, name string, tables []string, arg any) (string, error) {
+	b, err := json.Marshal(arg)
+	if err != nil {
+		return "", err
+	}
+	key := "query:" + name
+	for _, table := range tables {
+		gen, _, err := c.store.Get(ctx, "table:"+table)
+		if err != nil {
+			return "", err
+		}
+		key += ":" + string(gen)
+	}
+	return key + ":" + string(b), nil
+}
+
+// Invalidate drops the cached results read from the tables, for writes made
+// outside the sqlc queries, such as by another service.
+func (c *Cache) Invalidate(ctx context.Context, tables ...string) error {
+	gen := strconv.FormatInt(time.Now().UnixNano(), 36)
+	for _, table := range tables {
+		if err := c.store.Set(ctx, "table:"+table, []byte(gen), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DBTX is the database handle of the sqlc queries.
+type DBTX interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// DB wraps the handle given to the sqlc queries, as in
+// db.New(c.DB(conn)), so that the write queries invalidate the results
+// read from the table they change. Writes run in a transaction invalidate
+// before it commits; a read in between may cache the old rows until the
+// TTL expires.
+func (c *Cache) DB(db DBTX) DBTX {
+	return invalidatingDB{db: db, cache: c}
+}
+
+type invalidatingDB struct {
+	db    DBTX
+	cache *Cache
+}
+
+func (i invalidatingDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := i.db.ExecContext(ctx, query, args...)
+	if err == nil {
+		i.written(ctx, query)
+	}
+	return res, err
+}
+
+func (i invalidatingDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return i.db.PrepareContext(ctx, query)
+}
+
+func (i invalidatingDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	rows, err := i.db.QueryContext(ctx, query, args...)
+	if err == nil {
+		i.written(ctx, query)
+	}
+	return rows, err
+}
+
+func (i invalidatingDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	row := i.db.QueryRowContext(ctx, query, args...)
+	if row.Err() == nil {
+		i.written(ctx, query)
+	}
+	return row
+}
+
+var queryName = regexp.MustCompile(%[4]s)
+
+// written invalidates the table changed by a write query.
+func (i invalidatingDB) written(ctx context.Context, query string) {
+	m := queryName.FindStringSubmatch(query)
+	if m == nil {
+		return
+	}
+	if table, ok := writes[m[1]]; ok {
+		i.cache.Invalidate(ctx, table)
+	}
+}
+
+// Memory is a Store holding the values in process. Expired values are
+// dropped when they are read.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemory returns an empty in-process store.
+func NewMemory() *Memory {
+	return &Memory{entries: map[string]entry{}}
+}
+
+// Get implements Store.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if ok && !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return e.value, ok, nil
+}
+
+// Set implements Store.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := entry{value: value}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	m.entries[key] = e
+	return nil
+}
+`, goDuration(ttl), strings.Join(reads, "\n"), strings.Join(writes, "\n"), "`^-- name: (\\w+)`")
+	files := map[string]string{}
+	if err := addGoFile(files, "cache/cache.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// goStrings renders a string slice as a Go literal.
+func goStrings(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
@@ -0,0 +1,47 @@
+// cache_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestHotQueriesCache checks that a read run from enough call sites is
+// reported as hot and cached, and that writes invalidate its table.
+func TestHotQueriesCache(t *testing.T) {
+	read := "SELECT id, title FROM post WHERE author_id = $1;"
+	site := func(name, file string, line int, sql string) TranslatedQuery {
+		return TranslatedQuery{Query: Query{File: file, Line: line, App: "blog", Model: "Post"}, Name: name, SQL: sql}
+	}
+	queries := []TranslatedQuery{
+		site("ListPostByAuthor", "blog/views.py", 10, read),
+		site("ListPostByAuthor2", "blog/views.py", 20, read),
+		site("ListPostByAuthor3", "blog/api.py", 5, read),
+		site("GetPost", "blog/views.py", 30, "SELECT id FROM post WHERE id = $1;"),
+		site("GetPost2", "blog/views.py", 31, "SELECT id FROM post WHERE id = $1;"),
+		site("UpdatePostTitle", "blog/views.py", 40, "UPDATE post SET title = $1 WHERE id = $2;"),
+	}
+	hot := hotQueries(queries)
+	if len(hot) != 1 || strings.Join(hot[0].Names, ",") != "ListPostByAuthor,ListPostByAuthor2,ListPostByAuthor3" ||
+		strings.Join(hot[0].Tables, ",") != "post" || strings.Join(hot[0].CallSites, ",") != "blog/views.py:10,blog/views.py:20,blog/api.py:5" {
+		t.Fatalf("hot queries = %+v", hot)
+	}
+
+	files, err := generateCache(hot, queries, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["cache/cache.go"]
+	for _, want := range []string{
+		"var TTL = 5 * time.Minute",
+		`"ListPostByAuthor2": {key: "ListPostByAuthor", tables: []string{"post"}},`,
+		`"UpdatePostTitle": "post",`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("cache.go lacks %q", want)
+		}
+	}
+	if strings.Contains(src, `"GetPost"`) {
+		t.Error("cache.go caches a query from two call sites")
+	}
+}
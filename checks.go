@@ -0,0 +1,69 @@
+// checks.go
+package main
+
+import "fmt"
+
+// varcharTypes maps the CharField subclasses validating their content, and
+// the file fields storing a path, to Django's default max_length. They
+// become VARCHAR columns of their max_length rather than TEXT.
+var varcharTypes = map[string]int{
+	"EmailField": 254,
+	"URLField":   200,
+	"SlugField":  defaultSlugLength,
+	"FileField":  100,
+	"ImageField": 100,
+}
+
+// positiveTypes maps the positive integer field types to the integer field
+// type of their range. Their columns reject negative values like Django's:
+// unsigned on MySQL, and with a CHECK constraint on the other dialects.
+var positiveTypes = map[string]string{
+	"PositiveIntegerField":      "IntegerField",
+	"PositiveBigIntegerField":   "BigIntegerField",
+	"PositiveSmallIntegerField": "SmallIntegerField",
+}
+
+// autoTypes maps the auto-incrementing field types of primary keys to the
+// integer field type of their range, which the foreign keys pointing at them
+// share.
+var autoTypes = map[string]string{
+	"AutoField":      "IntegerField",
+	"BigAutoField":   "BigIntegerField",
+	"SmallAutoField": "SmallIntegerField",
+}
+
+// ipFamilies maps the GenericIPAddressField protocols restricting addresses
+// to one family, lower-cased as Django compares them, to the family the
+// INET column is checked for.
+var ipFamilies = map[string]int{"ipv4": 4, "ipv6": 6}
+
+// fieldPatterns are the regular expressions --strict-checks enforces on the
+// columns of the validating field types, simplified from Django's
+// validators. They avoid backslashes, which MySQL string literals escape.
+var fieldPatterns = map[string]string{
+	"EmailField": "^[^@ ]+@[^@ ]+$",
+	"URLField":   "^(https?|ftps?)://[^ ]+$",
+	// Letters in any script, so that slugs with allow_unicode=True pass.
+	"SlugField": "^[-_[:alnum:]]+$",
+}
+
+// fieldChecks returns the CHECK constraints validating the model's
+// EmailField, URLField and SlugField columns like their Django validators.
+// Empty strings pass, as Django stores them for blank=True fields.
+func fieldChecks(m Model, dialect string) []string {
+	op := "~"
+	if dialect == "mysql" {
+		op = "REGEXP"
+	}
+	var defs []string
+	for _, f := range m.Fields {
+		pattern, ok := fieldPatterns[f.Type]
+		if !ok {
+			continue
+		}
+		col := columnName(f)
+		defs = append(defs, fmt.Sprintf("CONSTRAINT %s_%s_check CHECK (%s = '' OR %s %s '%s')",
+			m.table(), col, col, col, op, pattern))
+	}
+	return defs
+}
@@ -0,0 +1,40 @@
+// checks_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidatingFields checks the VARCHAR lengths of the validating field
+// types and the CHECK constraints --strict-checks adds for them.
+func TestValidatingFields(t *testing.T) {
+	models := []Model{{Name: "Profile", Fields: []Field{
+		{Name: "email", Type: "EmailField"},
+		{Name: "website", Type: "URLField", MaxLength: 500},
+		{Name: "handle", Type: "SlugField"},
+		{Name: "bio", Type: "TextField"},
+	}}}
+	if diags := applyDialectTypes(&Config{}, models, Options{Dialect: "postgres"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	var types []string
+	for _, f := range models[0].Fields {
+		types = append(types, f.DBType)
+	}
+	if got := strings.Join(types, ","); got != "VARCHAR(254),VARCHAR(500),VARCHAR(50)," {
+		t.Errorf("types = %s", got)
+	}
+
+	if sql := generateSQL(models, Options{Dialect: "postgres"}); strings.Contains(sql, "CHECK") {
+		t.Errorf("checks without --strict-checks:\n%s", sql)
+	}
+	sql := generateSQL(models, Options{Dialect: "postgres", StrictChecks: true})
+	if want := "CONSTRAINT profile_email_check CHECK (email = '' OR email ~ '^[^@ ]+@[^@ ]+$')"; !strings.Contains(sql, want) {
+		t.Errorf("schema lacks %q:\n%s", want, sql)
+	}
+	checks := fieldChecks(models[0], "mysql")
+	if len(checks) != 3 || !strings.Contains(checks[2], "handle REGEXP '^[-_[:alnum:]]+$'") {
+		t.Errorf("mysql checks = %v", checks)
+	}
+}
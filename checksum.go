@@ -0,0 +1,157 @@
+// checksum.go
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaChecksum returns the SHA-256 of a schema's DDL, headers aside, so
+// that regenerating an unchanged schema keeps it.
+func schemaChecksum(ddl string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(ddl)))
+}
+
+// checksumTable is the one-row table the migrations record the checksum of
+// the schema they reach in, for Verify to compare.
+const checksumTable = "django2go_schema"
+
+// dropChecksumSQL drops the checksum table, in the down migration of
+// create_tables.
+const dropChecksumSQL = "DROP TABLE IF EXISTS " + checksumTable + ";\n"
+
+// checksumSQL renders the statements recording the checksum, creating the
+// table first for databases migrated before it existed.
+func checksumSQL(checksum string) string {
+	return fmt.Sprintf(`-- The checksum of the schema, compared by migrations.Verify.
+CREATE TABLE IF NOT EXISTS %[1]s (checksum VARCHAR(71) NOT NULL);
+DELETE FROM %[1]s;
+INSERT INTO %[1]s (checksum) VALUES ('%[2]s');
+`, checksumTable, checksum)
+}
+
+// recordDiffChecksum records the checksum of schema, the one the --diff
+// migrations reach, in the migrations files and returns it. Steps
+// migrating nothing keep the previous checksum, the one in the database.
+func recordDiffChecksum(files map[string]string, schema string, steps []migrationStep, previous string) string {
+	checksum := schemaChecksum(schema)
+	if len(steps) == 0 && previous != "" {
+		checksum = previous
+	}
+	recordChecksum(files, checksum, previous)
+	return checksum
+}
+
+// recordChecksum appends the recording of the checksum to the last of the
+// --diff migrations, and of the previous one, if known, to its down
+// migration. Tenant migrations run once per schema and record nothing.
+func recordChecksum(files map[string]string, checksum, previous string) {
+	var ups []string
+	for name := range files {
+		if strings.HasSuffix(name, ".up.sql") && !strings.HasPrefix(name, "tenant/") {
+			ups = append(ups, name)
+		}
+	}
+	if len(ups) == 0 {
+		return
+	}
+	sort.Strings(ups)
+	up := ups[len(ups)-1]
+	down := strings.TrimSuffix(up, ".up.sql") + ".down.sql"
+	files[up] += "\n" + checksumSQL(checksum)
+	if previous != "" {
+		files[down] += "\n" + checksumSQL(previous)
+	} else {
+		files[down] += "\nDELETE FROM " + checksumTable + ";\n"
+	}
+}
+
+// latestMigration returns the version of the last up migration in dir, the
+// number its file name starts with, as golang-migrate reads it.
+func latestMigration(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".up.sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(e.Name(), "_")
+		if version, err := strconv.ParseInt(prefix, 10, 64); err == nil {
+			latest = max(latest, version)
+		}
+	}
+	return latest, nil
+}
+
+// generateSchemaCheck renders migrations/schema.go, identifying the schema
+// the sqlc code is generated for by the version of the migration reaching
+// it and its checksum. Verify, called at startup, refuses a database
+// behind that migration, left dirty by a failed one, or at that version
+// with a different checksum recorded, so that a service deployed against
+// the wrong schema fails before serving rather than on its first query.
+func generateSchemaCheck(layout Layout, version int64, checksum string) (map[string]string, error) {
+	src := fmt.Sprintf(`// Code generated by django2go. DO NOT EDIT.
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// The schema the sqlc code was generated for: the version of the migration
+// creating it and the SHA-256 of its DDL.
+const (
+	Version  = %[1]d
+	Checksum = %[2]q
+)
+
+// Verify checks that the database has the schema the code was generated
+// for: at Version or later, as during a rolling deploy, according to
+// golang-migrate's schema_migrations table, not left dirty by a failed
+// migration, and, at Version, with Checksum recorded by the migration in
+// %[3]s, which migrations edited since generation would not match.
+// Call it at startup.
+func Verify(ctx context.Context, db *sql.DB) error {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("schema %%s: no migration applied, want version %%d", Checksum, Version)
+	case err != nil:
+		return fmt.Errorf("schema %%s: %%w", Checksum, err)
+	case dirty:
+		return fmt.Errorf("schema %%s: migration %%d is dirty", Checksum, version)
+	case version < Version:
+		return fmt.Errorf("schema %%s: database at version %%d, want %%d", Checksum, version, Version)
+	case version > Version:
+		return nil
+	}
+	var recorded string
+	if err := db.QueryRowContext(ctx, "SELECT checksum FROM %[3]s").Scan(&recorded); err != nil {
+		return fmt.Errorf("schema %%s: recorded checksum: %%w", Checksum, err)
+	}
+	if recorded != Checksum {
+		return fmt.Errorf("schema %%s: database at version %%d has schema %%s", Checksum, version, recorded)
+	}
+	return nil
+}
+`, version, checksum, checksumTable)
+	files := map[string]string{}
+	if err := addGoFile(files, path.Join(filepath.ToSlash(layout.Migrations), "schema.go"), src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
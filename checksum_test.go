@@ -0,0 +1,155 @@
+// checksum_test.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSchemaCheck checks that the schema check embeds the version of the
+// last up migration and the checksum of the schema.
+func TestSchemaCheck(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20240101000000_init.up.sql", "20240102030405_post.up.sql", "20240102030405_post.down.sql", "99999999999999_notes.txt", "README.md"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := latestMigration(dir)
+	if err != nil || version != 20240102030405 {
+		t.Fatalf("latestMigration = %d, %v", version, err)
+	}
+	if schemaChecksum("CREATE TABLE post ();") == schemaChecksum("CREATE TABLE tag ();") {
+		t.Error("different schemas share a checksum")
+	}
+	checksum := schemaChecksum("CREATE TABLE post ();")
+	files, err := generateSchemaCheck(defaultLayout, version, checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files[filepath.Join(defaultLayout.Migrations, "schema.go")]
+	for _, want := range []string{"Version  = 20240102030405", `Checksum = "` + checksum + `"`, "func Verify(ctx context.Context, db *sql.DB) error"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("schema.go lacks %q:\n%s", want, src)
+		}
+	}
+}
+
+// verifyTest runs the generated Verify against a fake driver answering the
+// schema_migrations and checksum queries.
+const verifyTest = `package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+)
+
+// answers holds the row of each query, by its table.
+type answers map[string][]driver.Value
+
+func (a answers) Open(string) (driver.Conn, error) { return conn{a}, nil }
+
+type conn struct{ a answers }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.a, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type stmt struct {
+	a     answers
+	query string
+}
+
+func (s stmt) Close() error                               { return nil }
+func (s stmt) NumInput() int                              { return 0 }
+func (s stmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s stmt) Query([]driver.Value) (driver.Rows, error) {
+	for table, row := range s.a {
+		if strings.Contains(s.query, " FROM "+table) {
+			return &rows{row: row}, nil
+		}
+	}
+	return &rows{}, nil
+}
+
+type rows struct {
+	row  []driver.Value
+	done bool
+}
+
+func (r *rows) Columns() []string { return make([]string, len(r.row)) }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if r.done || r.row == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+func TestVerify(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		version  int64
+		checksum string
+		ok       bool
+	}{
+		{"same", Version, Checksum, true},
+		{"drifted", Version, "sha256:other", false},
+		{"ahead", Version + 1, "sha256:other", true},
+		{"behind", Version - 1, Checksum, false},
+	} {
+		sql.Register(c.name, answers{
+			"schema_migrations": {c.version, false},
+			"django2go_schema":  {c.checksum},
+		})
+		db, err := sql.Open(c.name, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Verify(context.Background(), db); (err == nil) != c.ok {
+			t.Errorf("%s: Verify = %v", c.name, err)
+		}
+	}
+}
+`
+
+// TestVerifyChecksum checks that the generated Verify fails on a database
+// whose recorded checksum is not the one the code was generated for, by
+// running it as a test of the generated package.
+func TestVerifyChecksum(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	files, err := generateSchemaCheck(defaultLayout, 20240102030405, schemaChecksum("CREATE TABLE post (id SERIAL);"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.22\n"
+	files[filepath.Join(defaultLayout.Migrations, "schema_test.go")] = verifyTest
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(gobin, "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go test: %v\n%s", err, strings.TrimSpace(string(out)))
+	}
+}
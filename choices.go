@@ -0,0 +1,229 @@
+// choices.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Choice is one of the values a field's choices allow, with its label.
+type Choice struct {
+	Value any    `json:"value"`
+	Label string `json:"label"`
+}
+
+// Values of --choices.
+const (
+	choicesCheck = "check"
+	choicesEnum  = "enum"
+)
+
+// choiceField reports whether the field's column is restricted to its
+// choices. Relations and arrays keep Django's validation only.
+func choiceField(f Field) bool {
+	return len(f.Choices) > 0 && f.Relation == "" && f.Type != "ArrayField"
+}
+
+// enumChoices reports whether the field's choices can be stored as an enum
+// type: they must all be strings.
+func enumChoices(f Field) bool {
+	if !choiceField(f) {
+		return false
+	}
+	for _, c := range f.Choices {
+		if _, ok := c.Value.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// choiceList renders the allowed values of the field, separated by commas.
+func choiceList(f Field) string {
+	values := make([]string, len(f.Choices))
+	for i, c := range f.Choices {
+		values[i] = value(Arg{Literal: true, Value: c.Value}, "", nil)
+	}
+	return strings.Join(values, ", ")
+}
+
+// choiceCheck returns the CHECK constraint of a column restricted to the
+// field's choices, or "" when the column is an enum type or unrestricted.
+func choiceCheck(f Field, col string) string {
+	if !choiceField(f) || f.Enum {
+		return ""
+	}
+	return " CHECK (" + col + " IN (" + choiceList(f) + "))"
+}
+
+// choicesNote returns the note listing the labels of the field's choices,
+// so the Go port can display the stored values as Django does.
+func choicesNote(f Field) string {
+	if !choiceField(f) {
+		return ""
+	}
+	labels := make([]string, len(f.Choices))
+	for i, c := range f.Choices {
+		labels[i] = fmt.Sprintf("%v = %s", c.Value, c.Label)
+	}
+	return "choices: " + strings.Join(labels, ", ")
+}
+
+// enumType returns the name of the PostgreSQL enum type of a field.
+func enumType(m Model, f Field) string {
+	return m.table() + "_" + columnName(f)
+}
+
+// enumTypes returns the CREATE TYPE statements of the enum types of the
+// model's fields, and the DROP TYPE statements removing them.
+func enumTypes(m Model, dialect string) (create, drop []string) {
+	if dialect != "postgres" {
+		return nil, nil
+	}
+	for _, f := range m.Fields {
+		if f.Enum {
+			c, d := enumStatements(m, f)
+			create, drop = append(create, c), append(drop, d)
+		}
+	}
+	return create, drop
+}
+
+// enumStatements returns the CREATE TYPE and DROP TYPE statements of a
+// field's enum type.
+func enumStatements(m Model, f Field) (create, drop string) {
+	name := enumType(m, f)
+	return "CREATE TYPE " + name + " AS ENUM (" + choiceList(f) + ");", "DROP TYPE IF EXISTS " + name + ";"
+}
+
+// choicesPackage is the directory of the Go package of the choice types.
+const choicesPackage = "choices"
+
+// choiceGoType returns the underlying Go type of a field's choice type, the
+// one sqlc reads its column as, or "" when the choices are neither all
+// strings nor all integers.
+func choiceGoType(f Field) string {
+	if !choiceField(f) {
+		return ""
+	}
+	if enumChoices(f) {
+		return "string"
+	}
+	for _, c := range f.Choices {
+		if v, ok := c.Value.(float64); !ok || v != math.Trunc(v) {
+			return ""
+		}
+	}
+	switch f.Type {
+	case "BigIntegerField", "PositiveBigIntegerField":
+		return "int64"
+	case "SmallIntegerField", "PositiveSmallIntegerField":
+		return "int16"
+	}
+	return "int32"
+}
+
+// choiceTypeName returns the name of the Go type of a field's choices, the
+// one sqlc gives its enum type with --choices enum.
+func choiceTypeName(m Model, f Field) string {
+	return sqlcName(enumType(m, f))
+}
+
+// choiceConstNames names the constants of a field's choices after the
+// type and the choice's value, or its label for integer choices, keeping
+// the names unique.
+func choiceConstNames(typeName string, f Field) []string {
+	names := make([]string, len(f.Choices))
+	seen := map[string]bool{}
+	for i, c := range f.Choices {
+		word := fmt.Sprint(c.Value)
+		if _, ok := c.Value.(string); !ok && c.Label != "" {
+			word = c.Label
+		}
+		word = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, word)
+		name := typeName + sqlcName(word)
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s%s%d", typeName, sqlcName(word), n)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// generateChoices renders the choices package: a Go type per field with
+// choices, mirroring Django's TextChoices and IntegerChoices with a
+// constant per choice, the values in order and their labels. The sqlc
+// overrides bind the fields' columns to these types.
+func generateChoices(models []Model) (map[string]string, error) {
+	var sb strings.Builder
+	ints := false
+	for _, m := range models {
+		for _, f := range m.Fields {
+			goType := choiceGoType(f)
+			if goType == "" {
+				continue
+			}
+			name := choiceTypeName(m, f)
+			consts := choiceConstNames(name, f)
+			fmt.Fprintf(&sb, "\n// %s is the choices of %s.%s.\ntype %s %s\n\n// The %s choices.\nconst (\n", name, m.Name, f.Name, name, goType, name)
+			for i, c := range f.Choices {
+				fmt.Fprintf(&sb, "\t%s %s = %s\n", consts[i], name, choiceGoLiteral(c.Value))
+			}
+			fmt.Fprintf(&sb, ")\n\n// %sValues lists the %s choices in declaration order.\nvar %sValues = []%s{%s}\n",
+				name, name, name, name, strings.Join(consts, ", "))
+			fmt.Fprintf(&sb, "\n// Label returns the label of the choice, as get_%s_display does, or the\n// value itself when it is not a choice.\nfunc (v %s) Label() string {\n\tswitch v {\n", f.Name, name)
+			for i, c := range f.Choices {
+				if c.Label != "" {
+					fmt.Fprintf(&sb, "\tcase %s:\n\t\treturn %q\n", consts[i], c.Label)
+				}
+			}
+			value := "string(v)"
+			if goType != "string" {
+				value, ints = "strconv.FormatInt(int64(v), 10)", true
+			}
+			fmt.Fprintf(&sb, "\t}\n\treturn %s\n}\n", value)
+			fmt.Fprintf(&sb, "\n// Valid reports whether v is one of the choices.\nfunc (v %s) Valid() bool {\n\treturn slices.Contains(%sValues, v)\n}\n", name, name)
+		}
+	}
+	if sb.Len() == 0 {
+		return nil, nil
+	}
+	src := `// Code generated by django2go. DO NOT EDIT.
+
+// Package choices mirrors the choices of the Django fields as Go types, one
+// per field, which the sqlc code reads and writes their columns as.
+package choices
+
+import (
+	"slices"%s
+)
+`
+	imports := ""
+	if ints {
+		imports = "\n\t\"strconv\""
+	}
+	src = fmt.Sprintf(src, imports) + sb.String()
+	files := map[string]string{}
+	if err := addGoFile(files, path.Join(choicesPackage, "choices.go"), src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// choiceGoLiteral renders a choice value as a Go constant.
+func choiceGoLiteral(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprint(v)
+}
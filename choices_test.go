@@ -0,0 +1,112 @@
+// choices_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// choiceModels parses a model with literal, grouped and TextChoices
+// choices, normalized for the dialect and --choices strategy.
+func choiceModels(t *testing.T, dialect, choices string) []Model {
+	t.Helper()
+	out := parseProject(t, map[string]string{
+		"shop/models.py": `from django.db import models
+from django.utils.translation import gettext_lazy as _
+
+class Status(models.TextChoices):
+    DRAFT = "d", _("Draft")
+    PAID = "p", _("Paid")
+
+class Ticket(models.Model):
+    status = models.CharField(max_length=1, choices=Status.choices)
+    size = models.IntegerField(choices=[(1, "Small"), ("Large", [(2, "Big"), (3, "Huge")])])
+`,
+	})
+	opts := Options{Dialect: dialect, Choices: choices, NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	return out.Models
+}
+
+// TestChoices checks the CHECK constraints, the enum types of string
+// choices and the labels recorded on the columns.
+func TestChoices(t *testing.T) {
+	models := choiceModels(t, "postgres", choicesCheck)
+	sql := generateSQL(models, Options{Dialect: "postgres", Choices: choicesCheck})
+	for _, want := range []string{
+		"status VARCHAR(1) NOT NULL CHECK (status IN ('d', 'p'))",
+		"size INTEGER NOT NULL CHECK (size IN (1, 2, 3))",
+		"COMMENT ON COLUMN ticket.status IS 'choices: d = Draft, p = Paid';",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("check schema lacks %q:\n%s", want, sql)
+		}
+	}
+
+	opts := Options{Dialect: "postgres", Choices: choicesEnum, NoAppPrefix: true}
+	models = choiceModels(t, "postgres", choicesEnum)
+	sql = generateSQL(models, opts)
+	for _, want := range []string{"CREATE TYPE ticket_status AS ENUM ('d', 'p');", "status ticket_status NOT NULL,", "size INTEGER NOT NULL CHECK"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("enum schema lacks %q:\n%s", want, sql)
+		}
+	}
+	if down := generateDownSQL(models, opts); !strings.Contains(down, "DROP TYPE IF EXISTS ticket_status;") {
+		t.Errorf("down migration keeps the enum type:\n%s", down)
+	}
+
+	models = choiceModels(t, "mysql", choicesEnum)
+	sql = generateSQL(models, Options{Dialect: "mysql", Choices: choicesEnum})
+	if !strings.Contains(sql, "status ENUM('d', 'p') NOT NULL COMMENT") || strings.Contains(sql, "CREATE TYPE") {
+		t.Errorf("mysql enum:\n%s", sql)
+	}
+}
+
+// TestChoicesEnumDiff checks that switching a column to an enum type creates
+// the type first and converts the values explicitly.
+func TestChoicesEnumDiff(t *testing.T) {
+	old := choiceModels(t, "postgres", choicesCheck)
+	models := choiceModels(t, "postgres", choicesEnum)
+	steps := diffModels(old, models, nil, Options{Dialect: "postgres", Choices: choicesEnum})
+	var up []string
+	for _, s := range steps {
+		up = append(up, s.up)
+	}
+	got := strings.Join(up, "\n")
+	create := strings.Index(got, "CREATE TYPE ticket_status")
+	alter := strings.Index(got, "ALTER COLUMN status TYPE ticket_status USING status::ticket_status;")
+	if create < 0 || alter < create {
+		t.Errorf("up migration:\n%s", got)
+	}
+}
+
+// TestGenerateChoices checks the Go types of the choices and the sqlc
+// overrides binding the columns to them.
+func TestGenerateChoices(t *testing.T) {
+	models := choiceModels(t, "postgres", choicesCheck)
+	files, err := generateChoices(models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["choices/choices.go"]
+	for _, want := range []string{
+		"type TicketStatus string",
+		`TicketStatusD TicketStatus = "d"`,
+		"type TicketSize int32",
+		"TicketSizeBig   TicketSize = 2",
+		"var TicketSizeValues = []TicketSize{TicketSizeSmall, TicketSizeBig, TicketSizeHuge}",
+		"case TicketStatusP:\n\t\treturn \"Paid\"",
+		"return strconv.FormatInt(int64(v), 10)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("choices.go lacks %q:\n%s", want, src)
+		}
+	}
+	cfg := generateSQLCConfig(models, "postgres", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "example.com/app/choices")
+	want := "- column: \"ticket.status\"\n            go_type:\n              import: \"example.com/app/choices\"\n              type: \"TicketStatus\"\n"
+	if !strings.Contains(cfg, want) {
+		t.Errorf("sqlc.yaml lacks %q:\n%s", want, cfg)
+	}
+}
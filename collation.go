@@ -0,0 +1,41 @@
+// collation.go
+package main
+
+import "regexp"
+
+// mysqlCollation matches MySQL collation names, which start with their
+// character set.
+var mysqlCollation = regexp.MustCompile(`^(utf8mb4|utf8mb3|utf8|latin1|ascii|binary|ucs2|utf16|utf16le|utf32)(_\w+)?$`)
+
+// applyCollation resolves the field's db_collation for the dialect: the
+// collation the config maps it to, or the name itself when it is one of the
+// dialect's, such as "und-x-icu" on PostgreSQL. Other names are dropped,
+// leaving the column the database's default collation.
+func applyCollation(cfg *Config, m Model, f *Field, dialect string) []Diagnostic {
+	if f.Collation == "" {
+		return nil
+	}
+	if c := cfg.Collations[dialect][f.Collation]; c != "" {
+		f.Collation = c
+		return nil
+	}
+	if mysqlCollation.MatchString(f.Collation) == (dialect == "mysql") {
+		return nil
+	}
+	d := diagnose(codeCollation, m, f.Name, "db_collation %q is not a %s collation; map it under collations.%s in the config", f.Collation, dialect, dialect)
+	f.Collation = ""
+	return []Diagnostic{d}
+}
+
+// collateSQL returns the COLLATE clause of the field's column, or "".
+// PostgreSQL collation names are identifiers, quoted for their dashes; its
+// enum types take no collation.
+func collateSQL(f Field, dialect string) string {
+	switch {
+	case f.Collation == "" || f.Enum && dialect == "postgres":
+		return ""
+	case dialect == "mysql":
+		return " COLLATE " + f.Collation
+	}
+	return ` COLLATE "` + f.Collation + `"`
+}
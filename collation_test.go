@@ -0,0 +1,53 @@
+// collation_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCollation checks the COLLATE clauses per dialect, the config mapping
+// another backend's collation and the warning for unmapped ones.
+func TestCollation(t *testing.T) {
+	models := func() []Model {
+		return []Model{{Name: "Tag", App: "blog", Fields: []Field{
+			{Name: "name", Type: "CharField", MaxLength: 50, Collation: "utf8mb4_bin"},
+			{Name: "label", Type: "TextField", Collation: "und-x-icu"},
+		}}}
+	}
+	cfg := &Config{Collations: map[string]map[string]string{"postgres": {"utf8mb4_bin": "C"}}}
+	ms := models()
+	if diags := normalize(cfg, ms, Options{Dialect: "postgres"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(ms, Options{Dialect: "postgres"})
+	for _, want := range []string{`name VARCHAR(50) COLLATE "C" NOT NULL`, `label TEXT COLLATE "und-x-icu" NOT NULL`} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("postgres schema lacks %q:\n%s", want, sql)
+		}
+	}
+
+	ms = models()
+	diags := normalize(&Config{}, ms, Options{Dialect: "mysql"})
+	if len(diags) != 1 || diags[0].Code != codeCollation || !strings.Contains(diags[0].Message, "und-x-icu") {
+		t.Errorf("diagnostics = %+v", diags)
+	}
+	sql = generateSQL(ms, Options{Dialect: "mysql"})
+	if !strings.Contains(sql, "name VARCHAR(50) COLLATE utf8mb4_bin NOT NULL") || !strings.Contains(sql, "label TEXT NOT NULL") {
+		t.Errorf("mysql schema:\n%s", sql)
+	}
+}
+
+// TestCollationDiff checks that a collation change alters the column type
+// without being linted as a table rewrite on PostgreSQL.
+func TestCollationDiff(t *testing.T) {
+	prev := []Model{{Name: "Tag", Fields: []Field{{Name: "name", Type: "TextField"}}}}
+	cur := []Model{{Name: "Tag", Fields: []Field{{Name: "name", Type: "TextField", Collation: "C"}}}}
+	steps := diffModels(prev, cur, nil, Options{Dialect: "postgres"})
+	if len(steps) != 1 || steps[0].up != `ALTER TABLE tag ALTER COLUMN name TYPE TEXT COLLATE "C";` || steps[0].down != "ALTER TABLE tag ALTER COLUMN name TYPE TEXT;" {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if rewrites(`TEXT COLLATE "C"`, steps[0].down, "tag", "name", "postgres") {
+		t.Error("collation change linted as a rewrite")
+	}
+}
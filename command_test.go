@@ -0,0 +1,65 @@
+// command_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateCommands parses a management command and checks the cobra
+// stub generated for its arguments.
+func TestGenerateCommands(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/management/__init__.py":          "",
+		"blog/management/commands/__init__.py": "",
+		"blog/management/commands/import_posts.py": `from django.core.management.base import BaseCommand
+
+class Command(BaseCommand):
+    help = "Import posts from a feed"
+
+    def add_arguments(self, parser):
+        parser.add_argument("url")
+        parser.add_argument("limit", type=int, nargs="?")
+        parser.add_argument("--dry-run", action="store_true", help="Do not save")
+        parser.add_argument("-v", "--verbose", action="count")
+        parser.add_argument("--tag", action="append", dest="tags")
+        parser.add_argument("--format", default="rss", choices=["rss", "atom"])
+
+    def handle(self, *args, **options):
+        pass
+`,
+	})
+	if len(out.Commands) != 1 || out.Commands[0].Name != "import_posts" {
+		t.Fatalf("commands = %+v", out.Commands)
+	}
+	files, err := generateCommands(out.Commands, "example.com/blog", defaultLayout, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"commands/root.go", "commands/import_posts.go", "cmd/manage/main.go"} {
+		if files[path] == "" {
+			t.Errorf("missing %s", path)
+		}
+	}
+	if root := files["commands/root.go"]; !strings.Contains(root, `_ "github.com/jackc/pgx/v5/stdlib"`) || !strings.Contains(root, "newImportPostsCmd(queries),") {
+		t.Errorf("root.go:\n%s", root)
+	}
+	stub := files["commands/import_posts.go"]
+	for _, want := range []string{
+		`"example.com/blog/db"`,
+		"Url     string\n\tLimit   int\n\tDryRun  bool\n\tVerbose int\n\tTags    []string\n\tFormat  string",
+		`Use:   "import_posts <url> [limit]"`,
+		`Short: "Import posts from a feed"`,
+		"Args:  cobra.RangeArgs(1, 2)",
+		"v, err := strconv.Atoi(args[1])",
+		`cmd.Flags().BoolVarP(&opts.DryRun, "dry-run", "", false, "Do not save")`,
+		`cmd.Flags().CountVarP(&opts.Verbose, "verbose", "v", "")`,
+		`cmd.Flags().StringSliceVarP(&opts.Tags, "tag", "", nil, "")`,
+		`cmd.Flags().StringVarP(&opts.Format, "format", "", "rss", "(one of rss, atom)")`,
+		`return errors.New("import_posts is not implemented yet")`,
+	} {
+		if !strings.Contains(stub, want) {
+			t.Errorf("stub missing %q:\n%s", want, stub)
+		}
+	}
+}
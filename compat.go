@@ -0,0 +1,111 @@
+// compat.go
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// djangoVersion is a Django release, the zero value standing for an unknown
+// one, which is read as the latest.
+type djangoVersion struct {
+	major, minor int
+}
+
+// parseDjangoVersion parses a "major.minor" version, such as "4.2". An
+// empty string is the unknown version.
+func parseDjangoVersion(s string) (djangoVersion, error) {
+	if s == "" {
+		return djangoVersion{}, nil
+	}
+	major, minor, ok := strings.Cut(s, ".")
+	v := djangoVersion{}
+	var err error
+	if v.major, err = strconv.Atoi(major); err == nil && ok {
+		v.minor, err = strconv.Atoi(minor)
+	}
+	if err != nil || !ok || v.major < 1 {
+		return djangoVersion{}, fmt.Errorf("invalid Django version %q, want major.minor", s)
+	}
+	return v, nil
+}
+
+// before reports whether the version is known and older than major.minor.
+func (v djangoVersion) before(major, minor int) bool {
+	if v == (djangoVersion{}) {
+		return false
+	}
+	return v.major < major || v.major == major && v.minor < minor
+}
+
+// applyDjangoVersion reads the models the way the project's Django version
+// does:
+//
+//   - DEFAULT_AUTO_FIELD and AppConfig.default_auto_field only exist since
+//     Django 3.2; older projects get AutoField keys.
+//   - GeneratedField exists since Django 5.0. It becomes a generated column
+//     of its output_field's type; before, it can only be a custom field.
+//   - Meta.index_together was removed in Django 5.1 and is ignored there.
+//     Other projects, including those of an unknown version, which cannot
+//     run 5.1 while declaring it, get a B-tree index per entry.
+func applyDjangoVersion(models []Model, opts Options) []Diagnostic {
+	v := opts.Django
+	var diags []Diagnostic
+	for i := range models {
+		m := &models[i]
+		if v.before(3, 2) {
+			m.AutoField = ""
+		}
+		for j := range m.Fields {
+			f := &m.Fields[j]
+			if f.Type != "GeneratedField" || f.OutputField == "" || v.before(5, 0) {
+				continue
+			}
+			f.Type, f.Generated = f.OutputField, true
+		}
+		for j := range m.Fields {
+			f := &m.Fields[j]
+			if !f.Generated {
+				continue
+			}
+			sql, err := generatedSQL(*f, *m, opts.Dialect)
+			if err != nil {
+				f.Generated = false
+				diags = append(diags, diagnose(codeGeneratedField, *m, f.Name, "GeneratedField stored as a plain column: %v", err))
+				continue
+			}
+			f.GeneratedSQL = sql
+		}
+		if len(m.IndexTogether) == 0 {
+			continue
+		}
+		if v != (djangoVersion{}) && !v.before(5, 1) {
+			diags = append(diags, diagnose(codeRemovedOption, *m, "", "Meta.index_together was removed in Django 5.1 and is ignored"))
+			continue
+		}
+		for _, fields := range m.IndexTogether {
+			m.Indexes = append(m.Indexes, Index{Fields: fields})
+		}
+	}
+	return diags
+}
+
+// generatedSQL translates the expression of a GeneratedField.
+func generatedSQL(f Field, m Model, dialect string) (string, error) {
+	if f.Expression == nil {
+		return "", fmt.Errorf("expression not recognized")
+	}
+	return f.Expression.sql(m, dialect)
+}
+
+// generatedClause returns the GENERATED clause of a generated column:
+// STORED when the field is declared with db_persist=True, and always on
+// PostgreSQL, which Django only supports stored ones on.
+func generatedClause(f Field, dialect string) string {
+	storage := "VIRTUAL"
+	if f.DBPersist || dialect == "postgres" {
+		storage = "STORED"
+	}
+	return fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", f.GeneratedSQL, storage)
+}
@@ -0,0 +1,95 @@
+// compat_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// compatProject is a project declaring options whose reading depends on
+// the Django version.
+var compatProject = map[string]string{
+	"people/models.py": `from django.db import models
+from django.db.models import F, Q, Value
+from django.db.models.functions import Concat
+
+class Person(models.Model):
+    first = models.CharField(max_length=50)
+    last = models.CharField(max_length=50)
+    age = models.IntegerField()
+    full = models.GeneratedField(expression=Concat("first", Value(" "), "last"), output_field=models.CharField(max_length=101), db_persist=True)
+
+    class Meta:
+        index_together = [["first", "last"]]
+        constraints = [models.CheckConstraint(check=Q(age__gte=18), name="adult")]
+`,
+}
+
+// TestDjangoVersion checks that the models are read according to the
+// Django version the project's requirements pin.
+func TestDjangoVersion(t *testing.T) {
+	for _, c := range []struct {
+		requirements string
+		want, reject []string
+		codes        []string
+	}{
+		{
+			requirements: "Django==5.0.4",
+			want: []string{
+				"full VARCHAR(101) GENERATED ALWAYS AS (first || ' ' || last) STORED NOT NULL",
+				"CONSTRAINT adult CHECK (age >= 18)",
+				"CREATE INDEX person_first_last_idx ON person (first, last);",
+			},
+		},
+		{
+			requirements: "django>=5.1,<6",
+			reject:       []string{"person_first_last_idx"},
+			codes:        []string{codeRemovedOption},
+		},
+		{
+			requirements: "Django~=4.2",
+			want:         []string{"full TEXT NOT NULL", "CREATE INDEX person_first_last_idx"},
+			codes:        []string{codeUnknownFieldType},
+		},
+	} {
+		files := map[string]string{"requirements.txt": c.requirements + "\n"}
+		for name, src := range compatProject {
+			files[name] = src
+		}
+		out := parseProject(t, files)
+		opts := Options{Dialect: "postgres", Django: out.Settings.django(), NoAppPrefix: true}
+		var codes []string
+		for _, d := range normalize(&Config{}, out.Models, opts) {
+			codes = append(codes, d.Code)
+		}
+		if strings.Join(codes, ",") != strings.Join(c.codes, ",") {
+			t.Errorf("%s: codes = %v, want %v", c.requirements, codes, c.codes)
+		}
+		sql := generateSQL(out.Models, opts)
+		for _, want := range c.want {
+			if !strings.Contains(sql, want) {
+				t.Errorf("%s: schema lacks %q:\n%s", c.requirements, want, sql)
+			}
+		}
+		for _, reject := range c.reject {
+			if strings.Contains(sql, reject) {
+				t.Errorf("%s: schema has %q:\n%s", c.requirements, reject, sql)
+			}
+		}
+	}
+}
+
+// TestParseDjangoVersion checks the versions accepted by django_version.
+func TestParseDjangoVersion(t *testing.T) {
+	if v, err := parseDjangoVersion("4.2"); err != nil || v != (djangoVersion{4, 2}) || !v.before(5, 0) || v.before(4, 2) {
+		t.Errorf("4.2 = %+v, %v", v, err)
+	}
+	if v, err := parseDjangoVersion(""); err != nil || v.before(99, 0) {
+		t.Errorf("unknown version = %+v, %v", v, err)
+	}
+	for _, bad := range []string{"4", "four.two", "0.9", "4.x"} {
+		if _, err := parseDjangoVersion(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
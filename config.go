@@ -0,0 +1,154 @@
+// config.go
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the optional YAML configuration file passed with --config. It
+// holds overrides for things that cannot be inferred from the Django code.
+type Config struct {
+	Models map[string]ModelConfig `yaml:"models"`
+	// DialectTypes maps, per dialect, field types the dialect cannot store
+	// natively to the column type to use instead.
+	DialectTypes map[string]map[string]string `yaml:"dialect_types"`
+	// TypeOverrides maps field classes to their column and Go types.
+	TypeOverrides map[string]TypeOverride `yaml:"type_overrides"`
+	// Collations maps, per dialect, db_collation names of another backend
+	// to a collation of the dialect.
+	Collations map[string]map[string]string `yaml:"collations"`
+	// Anonymize maps "Model.field" or "*.field" to the rule applied to
+	// sampled values by the seed subcommand.
+	Anonymize map[string]string `yaml:"anonymize"`
+	// Renames maps an old model name to its new one, or "Model.field" to the
+	// field's new name, for migrations generated with --diff.
+	Renames map[string]string `yaml:"renames"`
+	// SQLStyle is the layout of the generated SQL.
+	SQLStyle SQLStyle `yaml:"sql_style"`
+	// Databases routes apps and models to databases other than the
+	// default one, each generated in its own tree.
+	Databases map[string]DatabaseConfig `yaml:"databases"`
+	// DjangoVersion is the project's Django version, such as "4.2", when
+	// its requirements do not pin it.
+	DjangoVersion string `yaml:"django_version"`
+	// Workspace converts the project app by app.
+	Workspace WorkspaceConfig `yaml:"workspace"`
+	// Emit selects optional generated code.
+	Emit EmitConfig `yaml:"emit"`
+	// Pool tunes the connection pool of the generated database package.
+	Pool PoolConfig `yaml:"pool"`
+	// Header is the template of the provenance comment at the top of the
+	// generated files.
+	Header string `yaml:"header"`
+	// Profiles bundles emit targets and flags under names selected with
+	// --profile.
+	Profiles map[string]Profile `yaml:"profiles"`
+	// Layout places the generated files.
+	Layout Layout `yaml:"layout"`
+	// GoNames spells the generated Go identifiers.
+	GoNames GoNames `yaml:"go_names"`
+	// PII marks the fields holding personal data.
+	PII PIIConfig `yaml:"pii"`
+
+	// path is the config file, and dir its directory used to resolve paths.
+	path string
+	dir  string
+}
+
+// ModelConfig holds the overrides for a single model, keyed by model name.
+type ModelConfig struct {
+	// PrimaryKey lists the fields forming a composite primary key, for
+	// legacy tables that Django fakes with unique_together.
+	PrimaryKey []string `yaml:"primary_key"`
+	// View turns the model into a database view defined by this SELECT.
+	View string `yaml:"view"`
+	// ViewFile is like View but reads the SELECT from a file relative to
+	// the config file.
+	ViewFile string `yaml:"view_file"`
+}
+
+// loadConfig reads the configuration file at path. An empty path yields an
+// empty configuration.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Layout: defaultLayout}
+	if path == "" {
+		return cfg, nil
+	}
+	cfg.path, cfg.dir = path, filepath.Dir(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := parseDjangoVersion(cfg.DjangoVersion); err != nil {
+		return nil, fmt.Errorf("%s: django_version: %w", path, err)
+	}
+	if err := cfg.SQLStyle.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Emit.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Pool.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Layout.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.GoNames.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if p.Emit == nil {
+			continue
+		}
+		if err := p.Emit.validate(); err != nil {
+			return nil, fmt.Errorf("%s: profiles: %s: %w", path, name, err)
+		}
+	}
+	if _, err := parseHeader(cfg.Header); err != nil {
+		return nil, fmt.Errorf("%s: header: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// applyConfig copies the per-model overrides onto the parsed models and
+// marks their personal data.
+func applyConfig(cfg *Config, models []Model) error {
+	byName := map[string]*Model{}
+	for i := range models {
+		byName[models[i].Name] = &models[i]
+	}
+	for name, mc := range cfg.Models {
+		m, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("config: unknown model %q", name)
+		}
+		for _, pk := range mc.PrimaryKey {
+			if _, ok := m.field(pk); !ok {
+				return fmt.Errorf("config: primary key field %q not found on %s", pk, name)
+			}
+		}
+		m.PrimaryKey = mc.PrimaryKey
+		if mc.View != "" {
+			m.View = mc.View
+		} else if mc.ViewFile != "" {
+			m.ViewFile = filepath.Join(cfg.dir, mc.ViewFile)
+		}
+	}
+	if err := applyPII(cfg, models); err != nil {
+		return err
+	}
+	return loadViews(models)
+}
@@ -0,0 +1,83 @@
+// config_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func compositeModels() []Model {
+	return []Model{
+		{Name: "Person", App: "club", Managed: true, Fields: []Field{
+			{Name: "name", Type: "CharField"},
+		}},
+		{Name: "Group", App: "club", Managed: true, Fields: []Field{
+			{Name: "title", Type: "CharField"},
+		}},
+		{Name: "Membership", App: "club", Managed: true, Fields: []Field{
+			{Name: "person", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Person"},
+			{Name: "group", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Group"},
+		}},
+		{Name: "Attendance", App: "club", Managed: true, Fields: []Field{
+			{Name: "membership", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Membership"},
+		}},
+	}
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "django2go.yaml")
+	config := "models:\n  Membership:\n    primary_key: [person, group]\n"
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := compositeModels()
+	if err := applyConfig(cfg, models); err != nil {
+		t.Fatal(err)
+	}
+	resolveRelations(models)
+	sql := generateSQL(models, Options{Dialect: "postgres"})
+
+	membership := sql[strings.Index(sql, "CREATE TABLE membership"):strings.Index(sql, "CREATE TABLE attendance")]
+	if strings.Contains(membership, "id SERIAL") {
+		t.Errorf("composite key table has a surrogate id:\n%s", membership)
+	}
+	if !strings.Contains(membership, "PRIMARY KEY (person_id, group_id)") {
+		t.Errorf("missing composite primary key:\n%s", membership)
+	}
+	for _, want := range []string{
+		"membership_person_id",
+		"membership_group_id",
+		"FOREIGN KEY (membership_person_id, membership_group_id) REFERENCES membership(person_id, group_id)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	for name, cfg := range map[string]*Config{
+		"unknown model": {Models: map[string]ModelConfig{"Missing": {PrimaryKey: []string{"person"}}}},
+		"unknown field": {Models: map[string]ModelConfig{"Membership": {PrimaryKey: []string{"person", "club"}}}},
+	} {
+		if err := applyConfig(cfg, compositeModels()); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "django2go.yaml")
+	if err := os.WriteFile(path, []byte("modles: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Error("expected an error for a misspelt key")
+	}
+}
@@ -0,0 +1,350 @@
+// constraint.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is an entry of a model's Meta.constraints.
+type Constraint struct {
+	Kind   string   `json:"kind"` // unique, check or exclusion
+	Name   string   `json:"name,omitempty"`
+	Fields []string `json:"fields"`
+	// Functional lists the expressions of a unique constraint declared
+	// with positional ones, such as Lower("name"), nil for unsupported ones.
+	Functional []*Expr `json:"functional,omitempty"`
+	// Expressions and IndexType describe an ExclusionConstraint.
+	Expressions []ExclusionExpr `json:"expressions,omitempty"`
+	IndexType   string          `json:"index_type,omitempty"`
+	// Deferrable is "deferred" or "immediate" for Deferrable.DEFERRED and
+	// Deferrable.IMMEDIATE, or empty.
+	Deferrable string `json:"deferrable,omitempty"`
+	// Condition is the Q object limiting a unique constraint to some rows,
+	// or the one a CheckConstraint checks, passed as check= before Django
+	// 5.1 and as condition= since.
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// ExclusionExpr is a (field, operator) pair of an ExclusionConstraint, with
+// RangeOperators already mapped to their SQL operators.
+type ExclusionExpr struct {
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+}
+
+// Index is an entry of a model's Meta.indexes or Meta.index_together.
+type Index struct {
+	Name   string   `json:"name,omitempty"`
+	Fields []string `json:"fields"`
+	Method string   `json:"method,omitempty"` // gist, gin, or "" for B-tree
+	// Functional lists the expressions of an index declared with
+	// positional ones, nil for unsupported ones.
+	Functional []*Expr `json:"functional,omitempty"`
+	// Condition is the Q object limiting a partial index to some rows.
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// Condition is a Q object: a boolean tree of field lookups.
+type Condition struct {
+	Op       string       `json:"op"` // and, or, not, lookup or unsupported
+	Children []*Condition `json:"children,omitempty"`
+	Lookup   *Arg         `json:"lookup,omitempty"`
+}
+
+// constraintDefs returns the table constraints for the model's
+// Meta.constraints, the statements to run after CREATE TABLE, and warnings
+// for semantics the dialect cannot express.
+func constraintDefs(m Model, dialect string) (defs, stmts []string, warnings []Diagnostic) {
+	table := m.table()
+	for _, c := range m.Constraints {
+		name := c.constraintName(m)
+		if c.Kind == "exclusion" {
+			def, err := c.exclusionDef(m, dialect)
+			if err != nil {
+				warnings = append(warnings, diagnose(codeConstraintSkipped, m, "", "constraint %s skipped: %v", name, err))
+			} else {
+				defs = append(defs, def)
+			}
+			continue
+		}
+		if c.Kind == "check" {
+			def, err := c.checkDef(m, dialect)
+			if err != nil {
+				warnings = append(warnings, diagnose(codeConstraintSkipped, m, "", "constraint %s skipped: %v", name, err))
+			} else {
+				defs = append(defs, def)
+			}
+			continue
+		}
+		cols, err := c.uniqueColumns(m, dialect)
+		if err != nil {
+			warnings = append(warnings, diagnose(codeConstraintSkipped, m, "", "constraint %s skipped: %v", name, err))
+			continue
+		}
+		if len(cols) == 0 {
+			continue
+		}
+
+		if c.Condition != nil {
+			where, err := c.Condition.sql(m, dialect)
+			if err != nil {
+				warnings = append(warnings, diagnose(codeConstraintSkipped, m, "", "constraint %s skipped: %v", name, err))
+				continue
+			}
+			if dialect != "mysql" {
+				stmts = append(stmts, fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s) WHERE %s;",
+					name, table, strings.Join(cols, ", "), where))
+				continue
+			}
+			// MySQL has no partial indexes. Unique functional key parts that
+			// are NULL outside the condition emulate one (MySQL 8.0.13+).
+			parts := make([]string, len(cols))
+			for i, col := range cols {
+				parts[i] = fmt.Sprintf("(CASE WHEN %s THEN %s END)", where, col)
+			}
+			warnings = append(warnings, diagnose(codeConstraintEmulated, m, "", "constraint %s is emulated with functional key parts (requires MySQL 8.0.13+)", name))
+			stmts = append(stmts, fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s);", name, table, strings.Join(parts, ", ")))
+			continue
+		}
+
+		if len(c.Functional) > 0 {
+			// Expressions can only be indexed, as Django does too.
+			stmts = append(stmts, fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s);", name, table, strings.Join(cols, ", ")))
+			continue
+		}
+		def := fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", name, strings.Join(cols, ", "))
+		if c.Deferrable != "" {
+			if dialect == "mysql" {
+				warnings = append(warnings, diagnose(codeNotDeferrable, m, "", "constraint %s is deferrable in Django but mysql checks it immediately", name))
+			} else {
+				def += " DEFERRABLE INITIALLY " + strings.ToUpper(c.Deferrable)
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, stmts, warnings
+}
+
+// uniqueColumns returns the key parts of a unique constraint: the columns
+// of its fields, or its parenthesized expressions.
+func (c Constraint) uniqueColumns(m Model, dialect string) ([]string, error) {
+	var cols []string
+	for _, f := range c.Fields {
+		fc, err := resolveColumns(f, m)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, fc...)
+	}
+	for _, e := range c.Functional {
+		if e == nil {
+			return nil, fmt.Errorf("expression not recognized")
+		}
+		s, err := e.sql(m, dialect)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, "("+s+")")
+	}
+	return cols, nil
+}
+
+// exclusionDef renders an ExclusionConstraint as an EXCLUDE table
+// constraint, which only PostgreSQL supports.
+func (c Constraint) exclusionDef(m Model, dialect string) (string, error) {
+	if dialect != "postgres" {
+		return "", fmt.Errorf("exclusion constraints are not supported by %s", dialect)
+	}
+	var parts []string
+	for _, e := range c.Expressions {
+		if e.Field == "" || e.Operator == "" {
+			return "", fmt.Errorf("only (field, operator) expressions are supported")
+		}
+		col, err := resolveColumn(e.Field, m)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, col+" WITH "+e.Operator)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("no expressions")
+	}
+	def := fmt.Sprintf("CONSTRAINT %s EXCLUDE USING %s (%s)", c.constraintName(m), c.IndexType, strings.Join(parts, ", "))
+	if c.Condition != nil {
+		where, err := c.Condition.sql(m, dialect)
+		if err != nil {
+			return "", err
+		}
+		def += " WHERE (" + where + ")"
+	}
+	return def, nil
+}
+
+// checkDef renders a CheckConstraint as a CHECK table constraint.
+func (c Constraint) checkDef(m Model, dialect string) (string, error) {
+	if c.Condition == nil {
+		return "", fmt.Errorf("no condition")
+	}
+	where, err := c.Condition.sql(m, dialect)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", c.constraintName(m), where), nil
+}
+
+// constraintName returns the constraint name, deriving one from the table and
+// fields when Django's name is missing.
+func (c Constraint) constraintName(m Model) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	if c.Kind == "exclusion" {
+		var fields []string
+		for _, e := range c.Expressions {
+			fields = append(fields, e.Field)
+		}
+		return m.table() + "_" + strings.Join(fields, "_") + "_excl"
+	}
+	if c.Kind == "check" {
+		return m.table() + "_check"
+	}
+	return m.table() + "_" + strings.Join(c.Fields, "_") + "_uniq"
+}
+
+// schemaDiagnostics returns the warnings for Meta.constraints and
+// Meta.indexes entries the dialect cannot express as written.
+func schemaDiagnostics(models []Model, dialect string) []Diagnostic {
+	var diags []Diagnostic
+	for _, m := range models {
+		if m.isView() {
+			continue
+		}
+		_, _, warnings := constraintDefs(m, dialect)
+		_, indexWarnings := indexStatements(m, dialect)
+		diags = append(diags, warnings...)
+		diags = append(diags, indexWarnings...)
+		diags = append(diags, defaultDiagnostics(m, dialect)...)
+	}
+	return diags
+}
+
+// indexStatements returns the CREATE INDEX statements for the model's
+// Meta.indexes, spatial indexes and range indexes.
+func indexStatements(m Model, dialect string) (stmts []string, warnings []Diagnostic) {
+	for _, idx := range m.Indexes {
+		_, stmt, err := idx.statement(m, dialect)
+		if err != nil {
+			warnings = append(warnings, diagnose(codeIndexSkipped, m, "", "%v", err))
+		} else if stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	spatial, spatialWarnings := spatialIndexes(m, dialect)
+	ranges, rangeWarnings := rangeIndexes(m, dialect)
+	stmts = append(append(stmts, spatial...), ranges...)
+	return stmts, append(append(warnings, spatialWarnings...), rangeWarnings...)
+}
+
+// statement returns the name and CREATE INDEX statement of a Meta.indexes
+// or Meta.index_together entry, or no statement for an index without
+// fields or expressions. Fields prefixed with "-" are indexed in descending
+// order.
+func (idx Index) statement(m Model, dialect string) (name, stmt string, err error) {
+	table := m.table()
+	var cols, parts []string
+	for _, f := range idx.Fields {
+		col, err := resolveColumn(strings.TrimPrefix(f, "-"), m)
+		if err != nil {
+			return "", "", fmt.Errorf("index on %s skipped: %v", table, err)
+		}
+		cols = append(cols, col)
+		if strings.HasPrefix(f, "-") {
+			col += " DESC"
+		}
+		parts = append(parts, col)
+	}
+	for _, e := range idx.Functional {
+		if e == nil {
+			return "", "", fmt.Errorf("index on %s skipped: expression not recognized", table)
+		}
+		s, err := e.sql(m, dialect)
+		if err != nil {
+			return "", "", fmt.Errorf("index on %s skipped: %v", table, err)
+		}
+		parts = append(parts, "("+s+")")
+	}
+	if len(parts) == 0 {
+		return "", "", nil
+	}
+	name, using := idx.Name, ""
+	if idx.Method == "" {
+		// A B-tree index, as from models.Index or Meta.index_together,
+		// which every dialect supports.
+		if name == "" {
+			name = table + "_" + strings.Join(cols, "_") + "_idx"
+		}
+	} else {
+		if name == "" {
+			name = table + "_" + strings.Join(cols, "_") + "_" + idx.Method
+		}
+		if dialect != "postgres" {
+			return "", "", fmt.Errorf("%s index %s is not supported by %s", idx.Method, name, dialect)
+		}
+		using = " USING " + idx.Method
+	}
+	stmt = fmt.Sprintf("CREATE INDEX %s ON %s%s (%s)", name, table, using, strings.Join(parts, ", "))
+	if idx.Condition != nil {
+		if dialect == "mysql" {
+			return "", "", fmt.Errorf("partial index %s is not supported by mysql", name)
+		}
+		where, err := idx.Condition.sql(m, dialect)
+		if err != nil {
+			return "", "", fmt.Errorf("index %s skipped: %v", name, err)
+		}
+		stmt += " WHERE " + where
+	}
+	return name, stmt + ";", nil
+}
+
+// dropIndex returns the statement dropping an index of the table.
+func dropIndex(name, table, dialect string) string {
+	if dialect == "mysql" {
+		return fmt.Sprintf("DROP INDEX %s ON %s;", name, table)
+	}
+	return fmt.Sprintf("DROP INDEX IF EXISTS %s;", name)
+}
+
+// sql renders the condition as a boolean SQL expression. Lookups must
+// compare against literals or F() expressions since DDL cannot take
+// parameters.
+func (c *Condition) sql(m Model, dialect string) (string, error) {
+	switch c.Op {
+	case "lookup":
+		if !c.Lookup.Literal && c.Lookup.Field == "" {
+			return "", fmt.Errorf("condition on %s compares against a non-literal value", c.Lookup.Key)
+		}
+		cond, _, err := lookupCondition(*c.Lookup, m, dialect, map[string]int{})
+		return cond, err
+	case "and", "or":
+		parts := make([]string, len(c.Children))
+		for i, child := range c.Children {
+			s, err := child.sql(m, dialect)
+			if err != nil {
+				return "", err
+			}
+			if child.Op == "and" || child.Op == "or" {
+				s = "(" + s + ")"
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, " "+strings.ToUpper(c.Op)+" "), nil
+	case "not":
+		s, err := c.Children[0].sql(m, dialect)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + s + ")", nil
+	}
+	return "", fmt.Errorf("unsupported condition expression")
+}
@@ -0,0 +1,190 @@
+// constraint_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUniqueConstraints parses a deferrable UniqueConstraint and checks the
+// generated constraint for each dialect.
+func TestUniqueConstraints(t *testing.T) {
+	out := parseProject(t, map[string]string{"shop/models.py": `from django.db import models
+from django.db.models import Deferrable, UniqueConstraint
+
+class Slot(models.Model):
+    day = models.IntegerField()
+    position = models.IntegerField()
+
+    class Meta:
+        constraints = [
+            UniqueConstraint(fields=["day", "position"], name="unique_slot", deferrable=Deferrable.DEFERRED),
+            models.UniqueConstraint(fields=["position"]),
+        ]
+`})
+	postgres := generateSQL(out.Models, Options{Dialect: "postgres"})
+	for _, want := range []string{
+		"CONSTRAINT unique_slot UNIQUE (day, position) DEFERRABLE INITIALLY DEFERRED",
+		"CONSTRAINT slot_position_uniq UNIQUE (position)",
+	} {
+		if !strings.Contains(postgres, want) {
+			t.Errorf("missing %q in:\n%s", want, postgres)
+		}
+	}
+
+	mysql := generateSQL(out.Models, Options{Dialect: "mysql"})
+	if strings.Contains(mysql, "DEFERRABLE") || !strings.Contains(mysql, "CONSTRAINT unique_slot UNIQUE (day, position)") {
+		t.Errorf("mysql constraint:\n%s", mysql)
+	}
+	if !strings.HasPrefix(mysql, "-- warning: constraint unique_slot is deferrable") {
+		t.Errorf("missing mysql warning:\n%s", mysql)
+	}
+}
+
+func TestConstraintUnknownField(t *testing.T) {
+	m := Model{Name: "Slot", Fields: []Field{{Name: "day", Type: "IntegerField"}},
+		Constraints: []Constraint{{Kind: "unique", Fields: []string{"day", "hour"}}}}
+	defs, stmts, warnings := constraintDefs(m, "postgres")
+	if len(defs) != 0 || len(stmts) != 0 || len(warnings) != 1 ||
+		warnings[0].Code != codeConstraintSkipped || !strings.Contains(warnings[0].Message, "slot_day_hour_uniq skipped") {
+		t.Errorf("defs %v, warnings %v", defs, warnings)
+	}
+}
+
+// TestConditionalUniqueConstraint checks that a UniqueConstraint with a Q
+// condition becomes a partial index, emulated on mysql.
+func TestConditionalUniqueConstraint(t *testing.T) {
+	out := parseProject(t, map[string]string{"shop/models.py": `from django.db import models
+from django.db.models import Q
+
+class Coupon(models.Model):
+    code = models.CharField(max_length=20)
+    active = models.BooleanField()
+    kind = models.CharField(max_length=10)
+
+    class Meta:
+        constraints = [
+            models.UniqueConstraint(fields=["code"], name="active_code", condition=Q(active=True) & ~Q(kind__in=["gift", "promo"])),
+            models.UniqueConstraint(fields=["kind"], name="bad", condition=Q(code=DEFAULT_CODE)),
+        ]
+`})
+	postgres := generateSQL(out.Models, Options{Dialect: "postgres"})
+	if !strings.Contains(postgres, "CREATE UNIQUE INDEX active_code ON coupon (code) WHERE active = TRUE AND NOT (kind IN ('gift', 'promo'));") {
+		t.Errorf("missing partial index in:\n%s", postgres)
+	}
+	if !strings.Contains(postgres, "-- warning: constraint bad skipped") {
+		t.Errorf("non-literal condition not skipped:\n%s", postgres)
+	}
+
+	mysql := generateSQL(out.Models, Options{Dialect: "mysql"})
+	if !strings.Contains(mysql, "CREATE UNIQUE INDEX active_code ON coupon ((CASE WHEN active = TRUE AND NOT (kind IN ('gift', 'promo')) THEN code END));") ||
+		!strings.Contains(mysql, "-- warning: constraint active_code is emulated") {
+		t.Errorf("mysql emulation:\n%s", mysql)
+	}
+}
+
+// TestExclusionConstraint checks range columns, an ExclusionConstraint over
+// a range and a scalar column, and a GiST index.
+func TestExclusionConstraint(t *testing.T) {
+	out := parseProject(t, map[string]string{"rooms/models.py": `from django.contrib.postgres.constraints import ExclusionConstraint
+from django.contrib.postgres.fields import DateTimeRangeField, RangeOperators
+from django.contrib.postgres.indexes import GistIndex
+from django.db import models
+from django.db.models import Q
+
+class Booking(models.Model):
+    room = models.IntegerField()
+    span = DateTimeRangeField()
+    cancelled = models.BooleanField()
+
+    class Meta:
+        constraints = [
+            ExclusionConstraint(
+                name="no_overlap",
+                expressions=[("span", RangeOperators.OVERLAPS), ("room", RangeOperators.EQUAL)],
+                condition=Q(cancelled=False),
+            ),
+        ]
+        indexes = [GistIndex(fields=["span"])]
+`})
+	sql := generateSQL(out.Models, Options{Dialect: "postgres"})
+	for _, want := range []string{
+		"CREATE EXTENSION IF NOT EXISTS btree_gist;",
+		"span TSTZRANGE NOT NULL",
+		"CONSTRAINT no_overlap EXCLUDE USING gist (span WITH &&, room WITH =) WHERE (cancelled = FALSE)",
+		"CREATE INDEX booking_span_gist ON booking USING gist (span);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+
+	mysql := generateSQL(out.Models, Options{Dialect: "mysql"})
+	if strings.Contains(mysql, "EXCLUDE") || strings.Contains(mysql, "EXTENSION") ||
+		!strings.Contains(mysql, "-- warning: constraint no_overlap skipped") ||
+		!strings.Contains(mysql, "-- warning: gist index booking_span_gist is not supported by mysql") {
+		t.Errorf("mysql:\n%s", mysql)
+	}
+}
+
+// indexModels parses an Article model with the Meta.indexes given.
+func indexModels(t *testing.T, indexes string) []Model {
+	t.Helper()
+	out := parseProject(t, map[string]string{"blog/models.py": `from django.db import models
+from django.db.models import Q
+from django.db.models.functions import Lower
+
+class Article(models.Model):
+    title = models.CharField(max_length=100)
+    published = models.DateTimeField(null=True)
+    draft = models.BooleanField()
+
+    class Meta:
+        indexes = [` + indexes + `]
+`})
+	if diags := normalize(&Config{}, out.Models, Options{Dialect: "postgres", NoAppPrefix: true}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	return out.Models
+}
+
+// TestIndexes checks the B-tree, descending, functional and partial indexes
+// of models.Index entries, and the migration of a changed one.
+func TestIndexes(t *testing.T) {
+	models := indexModels(t, `
+            models.Index(fields=["-published", "title"]),
+            models.Index(Lower("title"), name="article_title_ci"),
+            models.Index(fields=["published"], name="article_live", condition=Q(draft=False)),
+        `)
+	sql := generateSQL(models, Options{Dialect: "postgres"})
+	for _, want := range []string{
+		"CREATE INDEX article_published_title_idx ON article (published DESC, title);",
+		"CREATE INDEX article_title_ci ON article ((LOWER(title)));",
+		"CREATE INDEX article_live ON article (published) WHERE draft = FALSE;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	_, warnings := indexStatements(models[0], "mysql")
+	if len(warnings) != 1 || !strings.Contains(warnings[0].Message, "partial index article_live") {
+		t.Errorf("mysql warnings = %+v", warnings)
+	}
+
+	changed := indexModels(t, `
+            models.Index(fields=["-published", "title"]),
+            models.Index(fields=["published"], name="article_live", condition=Q(draft=True)),
+        `)
+	var up []string
+	for _, s := range diffModels(models, changed, nil, Options{Dialect: "postgres"}) {
+		up = append(up, s.up)
+	}
+	want := []string{
+		"DROP INDEX IF EXISTS article_title_ci;",
+		"DROP INDEX IF EXISTS article_live;",
+		"CREATE INDEX article_live ON article (published) WHERE draft = TRUE;",
+	}
+	if strings.Join(up, "\n") != strings.Join(want, "\n") {
+		t.Errorf("up = %q, want %q", up, want)
+	}
+}
@@ -0,0 +1,81 @@
+// coverage.go
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Coverage counts the ORM call sites of a Python module, or of the whole
+// project, by how far they are ported.
+type Coverage struct {
+	Module    string `json:"module,omitempty"`
+	CallSites int    `json:"call_sites"`
+	// Translated call sites have a sqlc query of their own. Generated ones
+	// run the SQL of a query generated from the models, such as a slug
+	// lookup or a relation loader, which they can call instead.
+	Translated   int `json:"translated"`
+	Generated    int `json:"generated"`
+	Untranslated int `json:"untranslated"`
+	// Percent is the share of call sites translated or generated.
+	Percent float64 `json:"percent"`
+}
+
+// add counts a call site.
+func (c *Coverage) add(q TranslatedQuery, generated map[string]bool) {
+	c.CallSites++
+	switch {
+	case !q.Translated():
+		c.Untranslated++
+	case generated[normalizeSQL(q.SQL)]:
+		c.Generated++
+	default:
+		c.Translated++
+	}
+	c.Percent = math.Round(float64(c.Translated+c.Generated)*1000/float64(c.CallSites)) / 10
+}
+
+// normalizeSQL collapses the whitespace of a statement, so that the same
+// query compares equal however it was laid out.
+func normalizeSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// pythonModule returns the dotted module name of a Python file.
+func pythonModule(file string) string {
+	module := strings.TrimSuffix(filepath.ToSlash(file), ".py")
+	module = strings.TrimSuffix(module, "/__init__")
+	return strings.ReplaceAll(module, "/", ".")
+}
+
+// queryCoverage returns the coverage of the call sites, in total and per
+// module in module order.
+func queryCoverage(queries []TranslatedQuery) (Coverage, []Coverage) {
+	generated := map[string]bool{}
+	for _, q := range queries {
+		if q.Generated() && q.Translated() {
+			generated[normalizeSQL(q.SQL)] = true
+		}
+	}
+	var total Coverage
+	modules := map[string]*Coverage{}
+	for _, q := range queries {
+		if q.Generated() {
+			continue
+		}
+		name := pythonModule(q.File)
+		if modules[name] == nil {
+			modules[name] = &Coverage{Module: name}
+		}
+		modules[name].add(q, generated)
+		total.add(q, generated)
+	}
+	var byModule []Coverage
+	for _, c := range modules {
+		byModule = append(byModule, *c)
+	}
+	sort.Slice(byModule, func(i, j int) bool { return byModule[i].Module < byModule[j].Module })
+	return total, byModule
+}
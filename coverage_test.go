@@ -0,0 +1,32 @@
+// coverage_test.go
+package main
+
+import "testing"
+
+// TestQueryCoverage checks the per-module coverage counts, with call sites
+// running a generated query's SQL counted as generated.
+func TestQueryCoverage(t *testing.T) {
+	queries := []TranslatedQuery{
+		{Query: Query{Source: "slug lookup"}, Name: "GetPostBySlug", SQL: "SELECT * FROM post WHERE slug = $1;"},
+		{Query: Query{File: "blog/views.py"}, Name: "ListPosts", SQL: "SELECT * FROM post;"},
+		{Query: Query{File: "blog/views.py"}, Name: "GetPost", SQL: "SELECT *\n  FROM post\n WHERE slug = $1;"},
+		{Query: Query{File: "blog/views.py"}, Reason: "unsupported"},
+		{Query: Query{File: "shop/__init__.py"}, Name: "ListItems", SQL: "SELECT * FROM item;"},
+	}
+	total, byModule := queryCoverage(queries)
+	if want := (Coverage{CallSites: 4, Translated: 2, Generated: 1, Untranslated: 1, Percent: 75}); total != want {
+		t.Errorf("total = %+v, want %+v", total, want)
+	}
+	want := []Coverage{
+		{Module: "blog.views", CallSites: 3, Translated: 1, Generated: 1, Untranslated: 1, Percent: 66.7},
+		{Module: "shop", CallSites: 1, Translated: 1, Percent: 100},
+	}
+	if len(byModule) != len(want) {
+		t.Fatalf("byModule = %+v", byModule)
+	}
+	for i := range want {
+		if byModule[i] != want[i] {
+			t.Errorf("byModule[%d] = %+v, want %+v", i, byModule[i], want[i])
+		}
+	}
+}
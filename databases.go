@@ -0,0 +1,159 @@
+// databases.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// defaultDatabase is the database of the models no router sends elsewhere,
+// generated at the root of the output directory.
+const defaultDatabase = "default"
+
+// DatabaseConfig routes apps and models to a database other than the
+// default one, like a Django database router.
+type DatabaseConfig struct {
+	// Apps lists the app labels whose models live in the database.
+	Apps []string `yaml:"apps"`
+	// Models lists single models living in the database, taking precedence
+	// over Apps.
+	Models []string `yaml:"models"`
+}
+
+// databaseOf returns the database a model is routed to.
+func (cfg *Config) databaseOf(m Model) string {
+	names := databaseNames(cfg)
+	for _, name := range names {
+		if slices.Contains(cfg.Databases[name].Models, m.Name) {
+			return name
+		}
+	}
+	for _, name := range names {
+		if slices.Contains(cfg.Databases[name].Apps, m.App) {
+			return name
+		}
+	}
+	return defaultDatabase
+}
+
+// checkDatabases reports databases routing unknown apps or models, apps
+// or models routed to two databases, and relations between models of
+// different databases, which Django does not support either.
+func checkDatabases(cfg *Config, models []Model) []Diagnostic {
+	if len(cfg.Databases) == 0 {
+		return nil
+	}
+	var diags []Diagnostic
+	configError := func(format string, args ...any) {
+		diags = append(diags, Diagnostic{Code: codeConfig, Severity: severity(codeConfig), File: cfg.path, Message: fmt.Sprintf(format, args...)})
+	}
+	apps, byName := map[string]bool{}, map[string]Model{}
+	for _, m := range models {
+		apps[m.App] = true
+		byName[m.Name] = m
+	}
+	claimedApps, claimedModels := map[string]string{}, map[string]string{}
+	for _, name := range databaseNames(cfg) {
+		db := cfg.Databases[name]
+		for _, app := range db.Apps {
+			if !apps[app] {
+				configError("databases: %s: unknown app %q", name, app)
+			} else if prev, ok := claimedApps[app]; ok {
+				configError("databases: %s: app %s is already routed to %s", name, app, prev)
+			}
+			claimedApps[app] = name
+		}
+		for _, model := range db.Models {
+			if _, ok := byName[model]; !ok {
+				configError("databases: %s: unknown model %q", name, model)
+			} else if prev, ok := claimedModels[model]; ok {
+				configError("databases: %s: model %s is already routed to %s", name, model, prev)
+			}
+			claimedModels[model] = name
+		}
+	}
+	for _, m := range models {
+		for _, f := range m.Fields {
+			target, ok := byName[f.RelatedTo]
+			if f.Relation == "" || !ok {
+				continue
+			}
+			if from, to := cfg.databaseOf(m), cfg.databaseOf(target); from != to {
+				diags = append(diags, diagnose(codeCrossDatabase, m, f.Name,
+					"relation to %s crosses from database %s to %s", f.RelatedTo, from, to))
+			}
+		}
+	}
+	return diags
+}
+
+// databaseNames returns the configured databases in order.
+func databaseNames(cfg *Config) []string {
+	var names []string
+	for name := range cfg.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// target is the generated tree of one database: its models and queries,
+// and the incremental migration computed for it with --diff.
+type target struct {
+	name string
+	// dir is the output directory of the tree.
+	dir    string
+	models []Model
+	// managed and unmanaged split models as splitManaged does.
+	managed, unmanaged []Model
+	// tenant holds, with django-tenants, the managed models living in the
+	// schema of every tenant rather than in the public schema.
+	tenant  []Model
+	queries []TranslatedQuery
+	// snapshot is the snapshot passed to --diff for this database, and
+	// checksum the schema checksum it recorded.
+	snapshot    string
+	checksum    string
+	steps       []migrationStep
+	tenantSteps []migrationStep
+	diffFiles   map[string]string
+}
+
+// splitDatabases splits the models and queries into one target per
+// database, the default one first. The other databases get their own tree
+// in a subdirectory of output named after them; their --diff snapshot is
+// found in the same place relative to the default one's migrations.
+func splitDatabases(cfg *Config, models []Model, queries []TranslatedQuery, output, diff string) []target {
+	targets := []target{{name: defaultDatabase, dir: output, snapshot: diff}}
+	index := map[string]int{defaultDatabase: 0}
+	for _, name := range databaseNames(cfg) {
+		if name == defaultDatabase {
+			continue
+		}
+		t := target{name: name, dir: filepath.Join(output, name)}
+		if diff != "" {
+			t.snapshot = filepath.Join(strings.TrimSuffix(diff, filepath.Join(cfg.Layout.Migrations, snapshotFile)), name, cfg.Layout.Migrations, snapshotFile)
+		}
+		index[name] = len(targets)
+		targets = append(targets, t)
+	}
+	byModel := map[string]int{}
+	perTarget := make([][]Model, len(targets))
+	for _, m := range models {
+		i := index[cfg.databaseOf(m)]
+		byModel[m.App+"."+m.Name] = i
+		perTarget[i] = append(perTarget[i], m)
+	}
+	for i := range targets {
+		targets[i].models = perTarget[i]
+		targets[i].managed, targets[i].unmanaged = splitManaged(perTarget[i])
+	}
+	for _, q := range queries {
+		i := byModel[q.App+"."+q.Model]
+		targets[i].queries = append(targets[i].queries, q)
+	}
+	return targets
+}
@@ -0,0 +1,67 @@
+// databases_test.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// databaseModels returns an analytics app routed to its own database, and
+// an Event model of the blog app routed there too.
+func databaseModels() ([]Model, *Config) {
+	models := []Model{
+		{Name: "Post", App: "blog", File: "blog/models.py"},
+		{Name: "Event", App: "blog", File: "blog/models.py"},
+		{Name: "PageView", App: "analytics", File: "analytics/models.py", Fields: []Field{
+			{Name: "event", Relation: "foreignkey", RelatedTo: "Event"},
+		}},
+	}
+	cfg := &Config{path: "django2go.yaml", Layout: defaultLayout, Databases: map[string]DatabaseConfig{
+		"analytics": {Apps: []string{"analytics"}, Models: []string{"Event"}},
+	}}
+	return models, cfg
+}
+
+func TestSplitDatabases(t *testing.T) {
+	models, cfg := databaseModels()
+	queries := []TranslatedQuery{
+		{Query: Query{App: "blog", Model: "Post"}},
+		{Query: Query{App: "analytics", Model: "PageView"}},
+	}
+	targets := splitDatabases(cfg, models, queries, "out", "out/migrations/snapshot.json")
+	if len(targets) != 2 {
+		t.Fatalf("targets = %+v", targets)
+	}
+	def, an := targets[0], targets[1]
+	if def.name != defaultDatabase || def.dir != "out" || len(def.models) != 1 || def.models[0].Name != "Post" || len(def.queries) != 1 {
+		t.Errorf("default target = %+v", def)
+	}
+	if an.dir != filepath.Join("out", "analytics") || an.snapshot != filepath.Join("out", "analytics", "migrations", snapshotFile) {
+		t.Errorf("analytics target in %s with snapshot %s", an.dir, an.snapshot)
+	}
+	if len(an.models) != 2 || len(an.queries) != 1 || an.queries[0].Model != "PageView" {
+		t.Errorf("analytics target = %+v", an)
+	}
+}
+
+func TestCheckDatabases(t *testing.T) {
+	models, cfg := databaseModels()
+	if diags := checkDatabases(cfg, models); len(diags) != 0 {
+		t.Errorf("diagnostics = %v", diags)
+	}
+	models[0].Fields = []Field{{Name: "event", Relation: "foreignkey", RelatedTo: "Event"}}
+	cfg.Databases["archive"] = DatabaseConfig{Apps: []string{"analytics", "shop"}}
+	var got []string
+	for _, d := range checkDatabases(cfg, models) {
+		got = append(got, d.Code+" "+d.Message)
+	}
+	want := []string{
+		"E101 databases: archive: app analytics is already routed to analytics",
+		"E101 databases: archive: unknown app \"shop\"",
+		"E108 relation to Event crosses from database default to analytics",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
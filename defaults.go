@@ -0,0 +1,120 @@
+// defaults.go
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// callableDefaults maps the callables commonly passed as default= to the SQL
+// expressions computing the same value, per dialect.
+var callableDefaults = map[string]map[string]string{
+	"uuid.uuid4":          {"postgres": "gen_random_uuid()", "mysql": "(UUID())"},
+	"uuid4":               {"postgres": "gen_random_uuid()", "mysql": "(UUID())"},
+	"date.today":          {"postgres": "CURRENT_DATE", "mysql": "(CURRENT_DATE)"},
+	"datetime.date.today": {"postgres": "CURRENT_DATE", "mysql": "(CURRENT_DATE)"},
+}
+
+// nowCallables are the callables returning the current time, whose default
+// is the current date, time or timestamp depending on the field.
+var nowCallables = map[string]bool{
+	"timezone.now": true, "django.utils.timezone.now": true, "now": true,
+	"datetime.now": true, "datetime.datetime.now": true,
+}
+
+// emptyDefaults maps the container types passed as default= to the empty
+// value of the field types storing them.
+var emptyDefaults = map[string]map[string]string{
+	"JSONField":   {"dict": "{}", "list": "[]"},
+	"ArrayField":  {"list": "{}"},
+	"HStoreField": {"dict": ""},
+}
+
+// mysqlExpressionTypes are the MySQL column types whose defaults must be
+// written as expressions, in parentheses.
+var mysqlExpressionTypes = []string{"TEXT", "JSON", "LONGBLOB"}
+
+// defaultSQL returns the SQL default of a column, or "" when the field has
+// none or Django computes it with an unknown callable.
+func defaultSQL(f Field, c Column, dialect string) string {
+	if _, ok := autoTypes[c.Type]; ok || f.PrimaryKey {
+		return ""
+	}
+	if f.AutoNow || f.AutoNowAdd {
+		return nowDefault(f, dialect)
+	}
+	if f.DefaultCallable != "" {
+		return callableDefault(f, c, dialect)
+	}
+	switch f.Default.(type) {
+	case string, float64, bool:
+		return literalDefault(f.Default, c, dialect)
+	}
+	return ""
+}
+
+// literalDefault renders a literal default of a column.
+func literalDefault(v any, c Column, dialect string) string {
+	sql := value(Arg{Literal: true, Value: v}, "", nil)
+	if dialect == "mysql" && slices.Contains(mysqlExpressionTypes, c.sqlType(dialect)) {
+		sql = "(" + sql + ")"
+	}
+	return sql
+}
+
+// callableDefault returns the SQL computing the value of the field's
+// default callable, or "" when it has no known equivalent.
+func callableDefault(f Field, c Column, dialect string) string {
+	if empty, ok := emptyDefaults[f.Type][f.DefaultCallable]; ok {
+		if f.Type == "ArrayField" && dialect != "postgres" {
+			return ""
+		}
+		return literalDefault(empty, c, dialect)
+	}
+	if nowCallables[f.DefaultCallable] {
+		return nowDefault(f, dialect)
+	}
+	return callableDefaults[f.DefaultCallable][dialect]
+}
+
+// nowSQL returns the SQL expression of the current date, time or timestamp,
+// as the field stores it.
+func nowSQL(f Field, dialect string) string {
+	precision := ""
+	if dialect == "mysql" {
+		precision = "(6)"
+	}
+	switch f.Type {
+	case "DateField":
+		return "CURRENT_DATE"
+	case "TimeField":
+		return "CURRENT_TIME" + precision
+	}
+	return "CURRENT_TIMESTAMP" + precision
+}
+
+// nowDefault returns the default of a field set to the current time. MySQL
+// takes expressions other than CURRENT_TIMESTAMP in parentheses.
+func nowDefault(f Field, dialect string) string {
+	sql := nowSQL(f, dialect)
+	if dialect == "mysql" && !strings.HasPrefix(sql, "CURRENT_TIMESTAMP") {
+		sql = "(" + sql + ")"
+	}
+	return sql
+}
+
+// defaultDiagnostics warns about the model's fields whose default is
+// computed by a callable without an SQL equivalent: rows inserted outside
+// Django get no value for them.
+func defaultDiagnostics(m Model, dialect string) []Diagnostic {
+	var diags []Diagnostic
+	for _, f := range m.Fields {
+		if f.DefaultCallable == "" || f.Relation == "many2many" {
+			continue
+		}
+		if cols := fieldColumns(f); len(cols) != 1 || callableDefault(f, cols[0], dialect) == "" {
+			diags = append(diags, diagnose(codeCallableDefault, m, f.Name, "default %s has no SQL equivalent; the column gets no DEFAULT", f.DefaultCallable))
+		}
+	}
+	return diags
+}
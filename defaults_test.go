@@ -0,0 +1,73 @@
+// defaults_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDefaults checks the DEFAULT clauses of literal and known callable
+// defaults, and the warning for unknown callables.
+func TestDefaults(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `import uuid
+from django.db import models
+from django.utils import timezone
+
+def next_slug():
+    return "x"
+
+class Post(models.Model):
+    key = models.UUIDField(default=uuid.uuid4)
+    created = models.DateTimeField(default=timezone.now)
+    day = models.DateField(default=timezone.now)
+    status = models.TextField(default="draft")
+    meta = models.JSONField(default=dict)
+    slug = models.TextField(default=next_slug)
+`,
+	})
+	opts := Options{Dialect: "postgres"}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{
+		"key TEXT NOT NULL DEFAULT gen_random_uuid()",
+		"created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP",
+		"day DATE NOT NULL DEFAULT CURRENT_DATE",
+		"status TEXT NOT NULL DEFAULT 'draft'",
+		"meta JSONB NOT NULL DEFAULT '{}'",
+		"slug TEXT NOT NULL -- Post.slug",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	diags := schemaDiagnostics(out.Models, "postgres")
+	if len(diags) != 1 || diags[0].Code != codeCallableDefault || !strings.Contains(diags[0].Message, "next_slug") {
+		t.Errorf("diagnostics = %+v", diags)
+	}
+
+	mysql := Options{Dialect: "mysql"}
+	out = parseProject(t, map[string]string{"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    status = models.TextField(default="draft")
+`})
+	if diags := normalize(&Config{}, out.Models, mysql); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	if sql := generateSQL(out.Models, mysql); !strings.Contains(sql, "status TEXT NOT NULL DEFAULT ('draft')") {
+		t.Errorf("mysql TEXT default is not an expression:\n%s", sql)
+	}
+}
+
+// TestDefaultDiff checks that a changed default is altered in place.
+func TestDefaultDiff(t *testing.T) {
+	prev := []Model{{Name: "Post", Fields: []Field{{Name: "status", Type: "TextField", Default: "draft"}}}}
+	cur := []Model{{Name: "Post", Fields: []Field{{Name: "status", Type: "TextField", Default: "new"}}}}
+	steps := diffModels(prev, cur, nil, Options{Dialect: "postgres"})
+	if len(steps) != 1 || steps[0].up != "ALTER TABLE post ALTER COLUMN status SET DEFAULT 'new';" || steps[0].down != "ALTER TABLE post ALTER COLUMN status SET DEFAULT 'draft';" {
+		t.Errorf("steps = %+v", steps)
+	}
+}
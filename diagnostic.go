@@ -0,0 +1,196 @@
+// diagnostic.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diagnostic codes. The letter gives the default severity (E for errors, W
+// for warnings) and the hundreds the stage reporting it: 0 for the parser,
+// 1 for the normalizer, 2 for the schema generator, 3 for the query
+// generator, 4 for the Go code generators and 5 for triage.
+const (
+	codeFileSkipped        = "W001"
+	codeSessionTable       = "W002"
+	codeCacheTable         = "W003"
+	codeTenantSchemas      = "W004"
+	codePython2            = "W005"
+	codeLinkOutside        = "W006"
+	codeSettingsModule     = "W007"
+	codeStaleParse         = "W008"
+	codeConfig             = "E101"
+	codeRelationTarget     = "E102"
+	codeRelationNotFound   = "W103"
+	codeTableCollision     = "E104"
+	codeColumnCollision    = "E105"
+	codeDialectType        = "E106"
+	codeArrayBase          = "E107"
+	codeCrossDatabase      = "E108"
+	codeCollation          = "W109"
+	codeUnknownFieldType   = "W110"
+	codeGeneratedField     = "W111"
+	codeRemovedOption      = "W112"
+	codeOrdering           = "W113"
+	codeConstraintSkipped  = "W201"
+	codeConstraintEmulated = "W202"
+	codeNotDeferrable      = "W203"
+	codeIndexSkipped       = "W204"
+	codeDestructive        = "E205"
+	codeAddDefault         = "W206"
+	codeTableRewrite       = "W207"
+	codeSetNotNull         = "W208"
+	codeForeignKeyLock     = "W209"
+	codeDropInUse          = "W210"
+	codeCallableDefault    = "W211"
+	codeRenameGuessed      = "W212"
+	codeAdminOption        = "W301"
+	codeKeysetSkipped      = "W302"
+	codeScheduleSkipped    = "W401"
+	codeModelsMigrations   = "W501"
+	codeMigrationUnapplied = "W502"
+	codeSchemaDrift        = "W503"
+)
+
+// Diagnostic is a problem found while parsing, normalizing or generating,
+// located on a file and line and, when known, a model and field.
+type Diagnostic struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"` // error or warning
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	App      string `json:"app,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Field    string `json:"field,omitempty"`
+	// Class is the field class a diagnostic on a field's type is about.
+	Class   string `json:"class,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as
+// "file:line: severity CODE: app.Model.field: message".
+func (d Diagnostic) String() string {
+	var sb strings.Builder
+	if d.File != "" {
+		sb.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", d.Line)
+		}
+		sb.WriteString(": ")
+	}
+	sb.WriteString(d.Severity + " " + d.Code + ": ")
+	sb.WriteString(d.located())
+	return sb.String()
+}
+
+// located returns the message prefixed with the app, model and field it is
+// about, as "app.Model.field: message".
+func (d Diagnostic) located() string {
+	var loc []string
+	for _, part := range []string{d.App, d.Model, d.Field} {
+		if part != "" {
+			loc = append(loc, part)
+		}
+	}
+	if len(loc) == 0 {
+		return d.Message
+	}
+	return strings.Join(loc, ".") + ": " + d.Message
+}
+
+// severity returns the default severity of a diagnostic code.
+func severity(code string) string {
+	if strings.HasPrefix(code, "E") {
+		return "error"
+	}
+	return "warning"
+}
+
+// diagnose builds a diagnostic located on a model, or on one of its fields
+// when field is set.
+func diagnose(code string, m Model, field, format string, args ...any) Diagnostic {
+	d := Diagnostic{
+		Code:     code,
+		Severity: severity(code),
+		File:     m.File,
+		Line:     m.Line,
+		App:      m.App,
+		Model:    m.Name,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if f, ok := m.field(field); ok && f.Line > 0 {
+		d.Line = f.Line
+	}
+	return d
+}
+
+// hasErrors reports whether any diagnostic is an error.
+func hasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortDiagnostics orders diagnostics by file and line.
+func sortDiagnostics(diags []Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		a, b := diags[i], diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// DiagnosticPolicy decides which diagnostics fail a run.
+type DiagnosticPolicy struct {
+	// MaxWarnings fails the run when more warnings are reported; negative
+	// means no limit.
+	MaxWarnings int
+	// ErrorOn lists warning codes treated as errors.
+	ErrorOn []string
+}
+
+// apply promotes the warnings listed in ErrorOn to errors and returns an
+// error when the run must fail.
+func (p DiagnosticPolicy) apply(diags []Diagnostic) error {
+	warnings := 0
+	for i := range diags {
+		for _, code := range p.ErrorOn {
+			if diags[i].Code == code {
+				diags[i].Severity = "error"
+			}
+		}
+		if diags[i].Severity == "warning" {
+			warnings++
+		}
+	}
+	if hasErrors(diags) {
+		return fmt.Errorf("errors reported")
+	}
+	if p.MaxWarnings >= 0 && warnings > p.MaxWarnings {
+		return fmt.Errorf("%d warnings exceed --max-warnings %d", warnings, p.MaxWarnings)
+	}
+	return nil
+}
+
+// parseCodes splits a comma-separated list of diagnostic codes.
+func parseCodes(s string) ([]string, error) {
+	var codes []string
+	for _, code := range strings.Split(s, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" {
+			continue
+		}
+		if len(code) != 4 || code[0] != 'W' {
+			return nil, fmt.Errorf("invalid warning code %q", code)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
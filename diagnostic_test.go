@@ -0,0 +1,56 @@
+// diagnostic_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDiagnosticPolicy checks --error-on promotion and the --max-warnings
+// limit.
+func TestDiagnosticPolicy(t *testing.T) {
+	warnings := func() []Diagnostic {
+		return []Diagnostic{
+			{Code: codeConstraintSkipped, Severity: "warning"},
+			{Code: codeIndexSkipped, Severity: "warning"},
+		}
+	}
+	if err := (DiagnosticPolicy{MaxWarnings: -1}).apply(warnings()); err != nil {
+		t.Errorf("no limit: %v", err)
+	}
+	if err := (DiagnosticPolicy{MaxWarnings: 1}).apply(warnings()); err == nil || !strings.Contains(err.Error(), "2 warnings exceed --max-warnings 1") {
+		t.Errorf("limit 1: %v", err)
+	}
+	diags := warnings()
+	if err := (DiagnosticPolicy{MaxWarnings: -1, ErrorOn: []string{"W204"}}).apply(diags); err == nil {
+		t.Error("W204 not treated as an error")
+	}
+	if diags[0].Severity != "warning" || diags[1].Severity != "error" {
+		t.Errorf("severities = %s, %s", diags[0].Severity, diags[1].Severity)
+	}
+
+	if codes, err := parseCodes(" w201, W204 ,"); err != nil || strings.Join(codes, ",") != "W201,W204" {
+		t.Errorf("parseCodes = %v, %v", codes, err)
+	}
+	if _, err := parseCodes("E101"); err == nil {
+		t.Error("error codes accepted by --error-on")
+	}
+}
+
+// TestSyntaxErrorDiagnostic checks that a file the parser cannot read is
+// skipped with W001 instead of failing the run.
+func TestSyntaxErrorDiagnostic(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": "from django.db import models\n\nclass Post(models.Model):\n    title = models.CharField(max_length=100)\n",
+		"blog/broken.py": "def broken(:\n",
+	})
+	if len(out.Models) != 1 {
+		t.Errorf("got %d models, want the valid file parsed", len(out.Models))
+	}
+	if len(out.Diagnostics) != 1 {
+		t.Fatalf("diagnostics = %v", out.Diagnostics)
+	}
+	if d := out.Diagnostics[0].String(); !strings.HasPrefix(d, "blog/broken.py:1: warning W001: file skipped: syntax error") {
+		t.Errorf("diagnostic = %q", d)
+	}
+}
@@ -0,0 +1,100 @@
+// dialect.go
+package main
+
+import "fmt"
+
+// dialects lists the supported values of --dialect.
+var dialects = []string{"postgres", "mysql"}
+
+// postgresOnlyTypes lists the django.contrib.postgres field types that have
+// no equivalent column type on other dialects.
+var postgresOnlyTypes = map[string]bool{
+	"ArrayField":           true,
+	"HStoreField":          true,
+	"IntegerRangeField":    true,
+	"BigIntegerRangeField": true,
+	"DecimalRangeField":    true,
+	"DateRangeField":       true,
+	"DateTimeRangeField":   true,
+	"SearchVectorField":    true,
+}
+
+// applyDialectTypes sets the column type of fields the dialect cannot store
+// natively from the dialect_types section of the config. Unmapped fields are
+// reported as errors instead of producing broken DDL. DecimalFields get
+// their precision and scale, and CharFields and their validating subclasses
+// their length, on every dialect. DateTimeFields lose their time zone when
+// USE_TZ is off, and string choices become enum types with --choices enum.
+func applyDialectTypes(cfg *Config, models []Model, opts Options) []Diagnostic {
+	dialect := opts.Dialect
+	var diags []Diagnostic
+	for i := range models {
+		m := &models[i]
+		for j := range m.Fields {
+			f := &m.Fields[j]
+			f.OnUpdate = f.AutoNow && opts.AutoNowTriggers
+			diags = append(diags, applyCollation(cfg, *m, f, dialect)...)
+			if o, ok := cfg.TypeOverrides[f.Type]; ok {
+				if t := o.sqlType(dialect); t != "" {
+					f.DBType = t
+					continue
+				}
+			} else if f.Relation == "" && !knownFieldType(f.Type) {
+				d := diagnose(codeUnknownFieldType, *m, f.Name, "%s is not a known field type and is stored as TEXT; map it under type_overrides in the config", f.Type)
+				d.Class = f.Type
+				diags = append(diags, d)
+			}
+			if geoTypes[f.Type] != "" {
+				f.DBType = geoType(*f, dialect)
+				continue
+			}
+			if f.Type == "ArrayField" && dialect == "postgres" {
+				if f.BaseType == "" {
+					diags = append(diags, diagnose(codeArrayBase, *m, f.Name, "ArrayField base field is not a field call"))
+					continue
+				}
+				f.DBType = sqlType(f.BaseType, dialect) + "[]"
+				continue
+			}
+			if opts.Choices == choicesEnum && enumChoices(*f) {
+				f.Enum = true
+				f.DBType = enumType(*m, *f)
+				if dialect == "mysql" {
+					f.DBType = "ENUM(" + choiceList(*f) + ")"
+				}
+				continue
+			}
+			if f.Type == "CharField" && f.MaxLength > 0 && !opts.CharAsText {
+				f.DBType = fmt.Sprintf("VARCHAR(%d)", f.MaxLength)
+				continue
+			}
+			if n, ok := varcharTypes[f.Type]; ok {
+				// Bounded columns also let MySQL index the whole value, where
+				// TEXT needs a prefix length.
+				if f.MaxLength > 0 {
+					n = f.MaxLength
+				}
+				f.DBType = fmt.Sprintf("VARCHAR(%d)", n)
+				continue
+			}
+			if f.Type == "DateTimeField" && opts.NaiveDateTimes && dialect == "postgres" {
+				f.DBType = "TIMESTAMP"
+				continue
+			}
+			if f.Type == "DecimalField" && f.MaxDigits > 0 {
+				f.DBType = fmt.Sprintf("NUMERIC(%d,%d)", f.MaxDigits, f.DecimalPlaces)
+				continue
+			}
+			if dialect == "postgres" || !postgresOnlyTypes[f.Type] {
+				continue
+			}
+			if t := cfg.DialectTypes[dialect][f.Type]; t != "" {
+				f.DBType = t
+				continue
+			}
+			diags = append(diags, diagnose(codeDialectType, *m, f.Name, "%s is not supported by %s; map it under dialect_types.%s in the config (e.g. %s: JSON)",
+				f.Type, dialect, dialect, f.Type))
+		}
+	}
+	return diags
+}
@@ -0,0 +1,219 @@
+// dialect_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// dialectModels returns a model using postgres-only field types.
+func dialectModels() []Model {
+	return []Model{{Name: "Event", App: "events", Managed: true, Fields: []Field{
+		{Name: "tags", Type: "ArrayField", BaseType: "CharField"},
+		{Name: "meta", Type: "HStoreField"},
+		{Name: "span", Type: "DateRangeField"},
+	}}}
+}
+
+// TestApplyDialectTypes checks ArrayField columns on postgres and the
+// dialect_types mappings required on mysql.
+func TestApplyDialectTypes(t *testing.T) {
+	models := dialectModels()
+	if diags := applyDialectTypes(&Config{}, models, Options{Dialect: "postgres"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	if sql := generateSQL(models, Options{Dialect: "postgres"}); !strings.Contains(sql, "tags TEXT[] NOT NULL") {
+		t.Errorf("array column missing in:\n%s", sql)
+	}
+
+	diags := applyDialectTypes(&Config{}, dialectModels(), Options{Dialect: "mysql"})
+	if len(diags) != 3 || !hasErrors(diags) || !strings.HasPrefix(diags[1].String(), "error E106: events.Event.meta: HStoreField is not supported by mysql") {
+		t.Errorf("diagnostics = %v, want an error per field", diags)
+	}
+
+	cfg := &Config{DialectTypes: map[string]map[string]string{"mysql": {
+		"ArrayField": "JSON", "HStoreField": "JSON", "DateRangeField": "VARCHAR(64)",
+	}}}
+	models = dialectModels()
+	if diags := applyDialectTypes(cfg, models, Options{Dialect: "mysql"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(models, Options{Dialect: "mysql"})
+	for _, want := range []string{"tags JSON NOT NULL", "meta JSON NOT NULL", "span VARCHAR(64) NOT NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+}
+
+// TestDecimalField checks that DecimalFields keep their precision and
+// scale, and are read by sqlc as decimals on both dialects.
+func TestDecimalField(t *testing.T) {
+	out := parseProject(t, map[string]string{"shop/models.py": `from django.db import models
+
+class Product(models.Model):
+    price = models.DecimalField(max_digits=10, decimal_places=2)
+    weight = models.DecimalField()
+`})
+	models := out.Models
+	if diags := applyDialectTypes(&Config{}, models, Options{Dialect: "mysql"}); len(diags) != 0 {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+	price, weight := models[0].Fields[0], models[0].Fields[1]
+	if price.DBType != "NUMERIC(10,2)" || weight.DBType != "" || sqlType(weight.Type, "mysql") != "NUMERIC" {
+		t.Errorf("price %q, weight %q", price.DBType, weight.DBType)
+	}
+	for dialect, want := range map[string]string{"postgres": `db_type: "numeric"`, "mysql": `db_type: "decimal"`} {
+		if cfg := generateSQLCConfig(models, dialect, defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, ""); !strings.Contains(cfg, want) || !strings.Contains(cfg, "shopspring/decimal.Decimal") {
+			t.Errorf("%s sqlc.yaml lacks %q:\n%s", dialect, want, cfg)
+		}
+	}
+}
+
+func TestNarrowsNumeric(t *testing.T) {
+	for _, c := range []struct {
+		from, to string
+		want     bool
+	}{
+		{"NUMERIC(10,2)", "NUMERIC(12,2)", false},
+		{"NUMERIC(10,2)", "NUMERIC(12,4)", false},
+		{"NUMERIC(10,2)", "NUMERIC(10,4)", true},
+		{"NUMERIC(10,2)", "NUMERIC(10,1)", true},
+	} {
+		if got := narrows(c.from, c.to); got != c.want {
+			t.Errorf("narrows(%s, %s) = %v", c.from, c.to, got)
+		}
+	}
+}
+
+// TestCharFieldVarchar checks that CharFields get their max_length, that
+// foreign keys to a composite primary key copy its column types, and that
+// --char-as-text keeps TEXT.
+func TestCharFieldVarchar(t *testing.T) {
+	models := func() []Model {
+		return []Model{
+			{Name: "Country", App: "geo", File: "geo/models.py", PrimaryKey: []string{"code", "region"}, Fields: []Field{
+				{Name: "code", Type: "CharField", MaxLength: 2},
+				{Name: "region", Type: "CharField", MaxLength: 10},
+				{Name: "name", Type: "CharField", MaxLength: 100},
+			}},
+			{Name: "City", App: "geo", File: "geo/models.py", Fields: []Field{
+				{Name: "country", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Country"},
+			}},
+		}
+	}
+	ms := models()
+	if diags := normalize(&Config{}, ms, Options{Dialect: "postgres"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(ms, Options{Dialect: "postgres"})
+	for _, want := range []string{"code VARCHAR(2) NOT NULL", "name VARCHAR(100) NOT NULL", "country_code VARCHAR(2) NOT NULL", "country_region VARCHAR(10) NOT NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+
+	ms = models()
+	opts := Options{Dialect: "postgres", CharAsText: true}
+	if diags := normalize(&Config{}, ms, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	if sql := generateSQL(ms, opts); strings.Contains(sql, "VARCHAR") {
+		t.Errorf("--char-as-text schema:\n%s", sql)
+	}
+}
+
+// TestIntegerFieldTypes checks the column types of the integer fields and
+// that positive ones reject negative values on every dialect.
+func TestIntegerFieldTypes(t *testing.T) {
+	for _, c := range []struct {
+		ftype, postgres, mysql string
+	}{
+		{"BigIntegerField", "BIGINT", "BIGINT"},
+		{"SmallIntegerField", "SMALLINT", "SMALLINT"},
+		{"PositiveIntegerField", "INTEGER", "INTEGER UNSIGNED"},
+		{"PositiveBigIntegerField", "BIGINT", "BIGINT UNSIGNED"},
+		{"PositiveSmallIntegerField", "SMALLINT", "SMALLINT UNSIGNED"},
+	} {
+		if got := sqlType(c.ftype, "postgres"); got != c.postgres {
+			t.Errorf("%s on postgres: %s", c.ftype, got)
+		}
+		if got := sqlType(c.ftype, "mysql"); got != c.mysql {
+			t.Errorf("%s on mysql: %s", c.ftype, got)
+		}
+	}
+	f := Field{Name: "stock", Type: "PositiveIntegerField"}
+	c := fieldColumns(f)[0]
+	if got := columnDef(f, c, "postgres"); got != "stock INTEGER NOT NULL CHECK (stock >= 0)" {
+		t.Errorf("postgres column = %s", got)
+	}
+	if got := columnDef(f, c, "mysql"); got != "stock INTEGER UNSIGNED NOT NULL" {
+		t.Errorf("mysql column = %s", got)
+	}
+	if narrows("SMALLINT UNSIGNED", "INTEGER") || !narrows("INTEGER UNSIGNED", "INTEGER") {
+		t.Error("unsigned widenings")
+	}
+	if f := dumpFieldType("int(10) unsigned"); f.Type != "PositiveIntegerField" {
+		t.Errorf("dumped unsigned int = %s", f.Type)
+	}
+}
+
+// TestGenericIPAddressField checks the INET column, the family check its
+// protocol adds on PostgreSQL and the MySQL fallback.
+func TestGenericIPAddressField(t *testing.T) {
+	f := Field{Name: "ip", Type: "GenericIPAddressField", Protocol: "IPv4"}
+	c := fieldColumns(f)[0]
+	if got := columnDef(f, c, "postgres"); got != "ip INET NOT NULL CHECK (family(ip) = 4)" {
+		t.Errorf("postgres column = %s", got)
+	}
+	if got := columnDef(f, c, "mysql"); got != "ip VARCHAR(39) NOT NULL" {
+		t.Errorf("mysql column = %s", got)
+	}
+	f.Protocol = "both"
+	if got := columnDef(f, c, "postgres"); got != "ip INET NOT NULL" {
+		t.Errorf("postgres column for both = %s", got)
+	}
+	if f := dumpFieldType("inet"); f.Type != "GenericIPAddressField" {
+		t.Errorf("dumped inet = %s", f.Type)
+	}
+}
+
+// TestDateTimeTypes checks the column types of the date and time fields, and
+// the naive timestamps of projects with USE_TZ = False.
+func TestDateTimeTypes(t *testing.T) {
+	for _, c := range []struct {
+		ftype, postgres, mysql string
+	}{
+		{"DateField", "DATE", "DATE"},
+		{"TimeField", "TIME", "TIME(6)"},
+		{"DateTimeField", "TIMESTAMP WITH TIME ZONE", "DATETIME(6)"},
+	} {
+		if got := sqlType(c.ftype, "postgres"); got != c.postgres {
+			t.Errorf("%s on postgres: %s", c.ftype, got)
+		}
+		if got := sqlType(c.ftype, "mysql"); got != c.mysql {
+			t.Errorf("%s on mysql: %s", c.ftype, got)
+		}
+	}
+	out := parseProject(t, map[string]string{
+		"site/settings.py": "USE_TZ = False\n",
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    published = models.DateTimeField()
+`,
+	})
+	if out.Settings.useTZ() {
+		t.Fatal("USE_TZ = False not parsed")
+	}
+	opts := Options{Dialect: "postgres", NaiveDateTimes: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	if sql := generateSQL(out.Models, opts); !strings.Contains(sql, "published TIMESTAMP NOT NULL") {
+		t.Errorf("naive timestamp:\n%s", sql)
+	}
+	if !narrows("TIMESTAMP WITH TIME ZONE", "TIMESTAMP") || narrows("TIMESTAMP", "TIMESTAMP WITH TIME ZONE") {
+		t.Error("time zone widening")
+	}
+}
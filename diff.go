@@ -0,0 +1,711 @@
+// diff.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// snapshotFile is the name of the snapshot written next to the migrations.
+const snapshotFile = "snapshot.json"
+
+// Snapshot is the normalized IR of the managed models a run generated its
+// schema from. It is saved with the migrations so that a later run can
+// generate an incremental migration against it with --diff.
+type Snapshot struct {
+	Dialect string  `json:"dialect"`
+	Models  []Model `json:"models"`
+	// Checksum is the checksum of the schema the migrations reached, as
+	// recorded in the database by the last of them.
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// JSON returns the snapshot as indented JSON.
+func (s *Snapshot) JSON() string {
+	b, _ := json.MarshalIndent(s, "", "  ")
+	return string(b) + "\n"
+}
+
+// loadSnapshot reads a snapshot written by an earlier run.
+func loadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// migrationStep is one change of an incremental migration, with the SQL
+// applying and reverting it.
+type migrationStep struct {
+	up, down string
+	// destructive is set, to the diagnostic reported without
+	// --allow-destructive, for steps that can lose data.
+	destructive *Diagnostic
+	// guessed is set, to the warning reported, for renames guessed from
+	// similarity rather than stated in the config or by Meta.db_table.
+	guessed *Diagnostic
+	// backfill is set for steps making a new column NOT NULL, which must
+	// wait until the column has been backfilled.
+	backfill *backfill
+}
+
+// destructiveHeader opens the migrations holding the destructive steps.
+const destructiveHeader = `-- DESTRUCTIVE: these statements drop tables or columns or narrow column
+-- types, losing data. Back up and review before applying.
+`
+
+// destructive returns the diagnostic flagging a destructive step.
+func destructive(m Model, field, format string, args ...any) *Diagnostic {
+	d := diagnose(codeDestructive, m, field, format+"; pass --allow-destructive to generate it", args...)
+	return &d
+}
+
+// guessedRename returns the warning of a rename guessed from similarity.
+func guessedRename(m Model, field, format string, args ...any) *Diagnostic {
+	d := diagnose(codeRenameGuessed, m, field, format+"; review the migration, or state renames in the config", args...)
+	return &d
+}
+
+// destructiveDiagnostics returns the diagnostics of the destructive steps.
+func destructiveDiagnostics(steps []migrationStep) []Diagnostic {
+	var diags []Diagnostic
+	for _, s := range steps {
+		if s.destructive != nil {
+			diags = append(diags, *s.destructive)
+		}
+	}
+	return diags
+}
+
+// renameDiagnostics returns the warnings of the guessed renames.
+func renameDiagnostics(steps []migrationStep) []Diagnostic {
+	var diags []Diagnostic
+	for _, s := range steps {
+		if s.guessed != nil {
+			diags = append(diags, *s.guessed)
+		}
+	}
+	return diags
+}
+
+// groupSteps separates the steps making backfilled columns NOT NULL and the
+// destructive steps, which are written to their own migrations applied
+// after the safe one.
+func groupSteps(steps []migrationStep) (safe, notNull, destructive []migrationStep) {
+	for _, s := range steps {
+		switch {
+		case s.destructive != nil:
+			destructive = append(destructive, s)
+		case s.backfill != nil:
+			notNull = append(notNull, s)
+		default:
+			safe = append(safe, s)
+		}
+	}
+	return safe, notNull, destructive
+}
+
+// widenings lists, per column type, the types it can be changed to without
+// losing data, besides longer VARCHARs and wider NUMERICs.
+var widenings = map[string][]string{
+	"SMALLINT":                {"INTEGER", "BIGINT"},
+	"INTEGER":                 {"BIGINT"},
+	"SMALLINT UNSIGNED":       {"INTEGER", "INTEGER UNSIGNED", "BIGINT", "BIGINT UNSIGNED"},
+	"INTEGER UNSIGNED":        {"BIGINT", "BIGINT UNSIGNED"},
+	"SERIAL":                  {"INTEGER", "BIGINT"},
+	"SMALLINT AUTO_INCREMENT": {"INTEGER AUTO_INCREMENT", "BIGINT AUTO_INCREMENT"},
+	"INTEGER AUTO_INCREMENT":  {"BIGINT AUTO_INCREMENT"},
+	"REAL":                    {"DOUBLE PRECISION"},
+	"FLOAT":                   {"DOUBLE"},
+	"DATE":                    {"TIMESTAMP", "TIMESTAMP WITH TIME ZONE", "DATETIME", "DATETIME(6)"},
+	"TIMESTAMP":               {"TIMESTAMP WITH TIME ZONE"},
+	"TIME":                    {"TIME(6)"},
+	"DATETIME":                {"DATETIME(6)"},
+}
+
+// autoColumnType returns the type an auto-incrementing column is compared
+// and altered with: its integer type on PostgreSQL, where the serial types
+// only exist in CREATE TABLE, and the full type on MySQL, whose MODIFY
+// COLUMN would otherwise drop AUTO_INCREMENT.
+func autoColumnType(ftype, dialect string) string {
+	if dialect == "mysql" {
+		return sqlType(ftype, dialect)
+	}
+	return sqlType(autoTypes[ftype], dialect)
+}
+
+// narrows reports whether changing a column from one type to another can
+// lose data. Changes not known to widen the type are assumed to narrow it.
+func narrows(from, to string) bool {
+	if from == to || slices.Contains(widenings[from], to) {
+		return false
+	}
+	fromLen, fromVarchar := varcharLength(from)
+	toLen, toVarchar := varcharLength(to)
+	switch {
+	case fromVarchar && to == "TEXT":
+		return false
+	case fromVarchar && toVarchar:
+		return toLen < fromLen
+	}
+	fromDigits, fromPlaces, fromNumeric := numericPrecision(from)
+	toDigits, toPlaces, toNumeric := numericPrecision(to)
+	if fromNumeric && toNumeric {
+		// Both the integer digits and the decimal places must fit.
+		return toPlaces < fromPlaces || toDigits-toPlaces < fromDigits-fromPlaces
+	}
+	return true
+}
+
+// numericPrecision returns the precision and scale of a NUMERIC(p,s) type.
+func numericPrecision(t string) (int, int, bool) {
+	var p, s int
+	if _, err := fmt.Sscanf(t, "NUMERIC(%d,%d)", &p, &s); err != nil {
+		return 0, 0, false
+	}
+	return p, s, true
+}
+
+// varcharLength returns the length of a VARCHAR(n) type.
+func varcharLength(t string) (int, bool) {
+	var n int
+	if _, err := fmt.Sscanf(t, "VARCHAR(%d)", &n); err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// diffColumn is a column of a table as the diff compares it.
+type diffColumn struct {
+	name     string
+	field    string
+	sqlType  string
+	nullable bool
+	def      string // definition for CREATE TABLE and ADD COLUMN
+	fk       string // FOREIGN KEY clause of a relation's first column
+	dflt     any    // the field's literal default
+	dfltSQL  string // the column's SQL default
+	onUpdate string // MySQL's ON UPDATE clause
+	collate  string // the column's COLLATE clause
+	// createType and dropType create and drop the column's enum type.
+	createType, dropType string
+}
+
+// diffColumns returns the stored columns of a model's table.
+func diffColumns(m Model, dialect string) []diffColumn {
+	var cols []diffColumn
+	if len(m.PrimaryKey) == 0 {
+		cols = append(cols, diffColumn{name: "id", sqlType: autoColumnType(m.autoField(), dialect), def: m.idDef(dialect)})
+	}
+	for _, f := range m.Fields {
+		if f.Relation == "many2many" {
+			continue
+		}
+		fcols := fieldColumns(f)
+		for i, c := range fcols {
+			col := diffColumn{name: c.Name, field: f.Name, sqlType: c.sqlType(dialect), nullable: f.Nullable, def: columnDef(f, c, dialect), dflt: f.Default, dfltSQL: defaultSQL(f, c, dialect), onUpdate: onUpdate(f, dialect), collate: collateSQL(f, dialect)}
+			if _, ok := autoTypes[c.Type]; ok {
+				col.sqlType = autoColumnType(c.Type, dialect)
+			}
+			if f.Enum && dialect == "postgres" {
+				col.createType, col.dropType = enumStatements(m, f)
+			}
+			if (f.Relation == "foreignkey" || f.Relation == "one2one") && i == 0 {
+				col.fk = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)", columnList(fcols), f.relatedTable(), columnList(f.RelatedPK))
+			}
+			cols = append(cols, col)
+		}
+	}
+	if m.OrderWithRespectTo != "" {
+		cols = append(cols, diffColumn{name: "_order", sqlType: "INTEGER", def: "_order INTEGER NOT NULL DEFAULT 0"})
+	}
+	return cols
+}
+
+// similarity returns how alike two names are, from 0 to 1, as one minus
+// their edit distance over the longer length.
+func similarity(a, b string) float64 {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	if n := max(len(a), len(b)); n > 0 {
+		return 1 - float64(prev[len(b)])/float64(n)
+	}
+	return 1
+}
+
+// renameThreshold is the similarity above which a dropped and an added
+// table or column are taken for a rename.
+const renameThreshold = 0.5
+
+// tableSimilarity compares two tables by the share of column names and
+// types they have in common, leaving out the primary keys, which every
+// table has.
+func tableSimilarity(a, b Model, dialect string) float64 {
+	columns := func(m Model) []diffColumn {
+		pk := map[string]bool{}
+		for _, c := range m.pkColumns() {
+			pk[c.Name] = true
+		}
+		return slices.DeleteFunc(diffColumns(m, dialect), func(c diffColumn) bool { return pk[c.name] })
+	}
+	cols := map[string]bool{}
+	for _, c := range columns(a) {
+		cols[c.name+" "+c.sqlType] = true
+	}
+	common, total := 0, len(cols)
+	for _, c := range columns(b) {
+		if cols[c.name+" "+c.sqlType] {
+			common++
+		} else {
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(common) / float64(total)
+}
+
+// diffModels returns the steps migrating the schema generated for the old
+// models to the one of the new models. A dropped table or column is taken
+// for a renamed one when the config's renames say so, when a model's
+// Meta.db_table changed or, failing that, when an added one is similar
+// enough: tables sharing most columns, columns of the same type with a
+// similar or contained name, or the only dropped and added columns of a
+// table when their types match. Renames guessed from similarity are
+// reported.
+func diffModels(prev, cur []Model, renames map[string]string, opts Options) []migrationStep {
+	tables := func(models []Model) []Model {
+		return slices.DeleteFunc(slices.Clone(models), func(m Model) bool { return m.isView() })
+	}
+	prev, cur = tables(prev), tables(cur)
+	has := func(models []Model, name string) bool {
+		return slices.ContainsFunc(models, func(m Model) bool { return m.table() == name })
+	}
+	var dropped, added []Model
+	for _, m := range prev {
+		if !has(cur, m.table()) {
+			dropped = append(dropped, m)
+		}
+	}
+	for _, m := range cur {
+		if !has(prev, m.table()) {
+			added = append(added, m)
+		}
+	}
+
+	var steps []migrationStep
+	renamed := map[string]Model{} // old table to new model
+	for _, d := range dropped {
+		best, score, reason := -1, renameThreshold, "similar columns"
+		for i, a := range added {
+			if renames[d.Name] == a.Name {
+				best, reason = i, "config"
+				break
+			}
+			if d.Name == a.Name && d.App == a.App {
+				best, reason = i, "db_table"
+				break
+			}
+			if s := tableSimilarity(d, a, opts.Dialect); s >= score {
+				best, score = i, s
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		a := added[best]
+		added = slices.Delete(added, best, best+1)
+		from, to := d.table(), a.table()
+		renamed[from] = a
+		step := migrationStep{
+			up:   fmt.Sprintf("-- %s renamed to %s (%s)\nALTER TABLE %s RENAME TO %s;", from, to, reason, from, to),
+			down: fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", to, from),
+		}
+		if reason == "similar columns" {
+			step.guessed = guessedRename(a, "", "table %s renamed to %s, guessed from %s", from, to, reason)
+		}
+		steps = append(steps, step)
+	}
+
+	for _, o := range prev {
+		n, ok := renamed[o.table()]
+		if !ok {
+			i := slices.IndexFunc(cur, func(m Model) bool { return m.table() == o.table() })
+			if i < 0 {
+				continue
+			}
+			n = cur[i]
+		}
+		steps = append(steps, diffTable(o, n, renames, opts.Dialect)...)
+		steps = append(steps, diffTriggers(o, n, opts.Dialect)...)
+		steps = append(steps, diffIndexes(o, n, opts.Dialect)...)
+	}
+	// The added tables are created together, in the order of their foreign
+	// keys.
+	if len(added) > 0 {
+		steps = append(steps, migrationStep{
+			up:   strings.TrimSpace(generateSQL(added, opts)),
+			down: strings.TrimSpace(generateDownSQL(added, opts)),
+		})
+	}
+	for i := len(dropped) - 1; i >= 0; i-- {
+		d := dropped[i]
+		if _, ok := renamed[d.table()]; ok {
+			continue
+		}
+		steps = append(steps, migrationStep{
+			up:          strings.TrimSpace(generateDownSQL([]Model{d}, opts)),
+			down:        strings.TrimSpace(generateSQL([]Model{d}, opts)),
+			destructive: destructive(d, "", "table %s would be dropped", d.table()),
+		})
+	}
+	return steps
+}
+
+// diffIndexes returns the steps dropping the Meta.indexes entries of a kept
+// or renamed table that were removed or changed, and creating the new and
+// changed ones.
+func diffIndexes(o, n Model, dialect string) []migrationStep {
+	table := n.table()
+	statements := func(m Model) (names []string, stmts map[string]string) {
+		m.Table = table
+		stmts = map[string]string{}
+		for _, idx := range m.Indexes {
+			if name, stmt, err := idx.statement(m, dialect); err == nil && stmt != "" {
+				names = append(names, name)
+				stmts[name] = stmt
+			}
+		}
+		return names, stmts
+	}
+	oldNames, old := statements(o)
+	newNames, cur := statements(n)
+	var steps []migrationStep
+	for _, name := range oldNames {
+		if cur[name] != old[name] {
+			steps = append(steps, migrationStep{up: dropIndex(name, table, dialect), down: old[name]})
+		}
+	}
+	for _, name := range newNames {
+		if old[name] != cur[name] {
+			steps = append(steps, migrationStep{up: cur[name], down: dropIndex(name, table, dialect)})
+		}
+	}
+	return steps
+}
+
+// diffTable returns the steps migrating the columns and join tables of a
+// kept or renamed table, named after the new model.
+func diffTable(o, n Model, renames map[string]string, dialect string) []migrationStep {
+	table := n.table()
+	oldCols, newCols := diffColumns(o, dialect), diffColumns(n, dialect)
+	find := func(cols []diffColumn, name string) int {
+		return slices.IndexFunc(cols, func(c diffColumn) bool { return c.name == name })
+	}
+	var dropped, added []diffColumn
+	for _, c := range oldCols {
+		if find(newCols, c.name) < 0 {
+			dropped = append(dropped, c)
+		}
+	}
+	for _, c := range newCols {
+		if find(oldCols, c.name) < 0 {
+			added = append(added, c)
+		}
+	}
+
+	var steps []migrationStep
+	single := len(dropped) == 1 && len(added) == 1
+	pairs := map[string]string{}  // old column to new column
+	fields := map[string]string{} // old field to new field
+	for _, d := range dropped {
+		hint := renames[o.Name+"."+d.field]
+		best, score, reason := -1, renameThreshold, "similar name"
+		for i, a := range added {
+			if hint != "" && a.field == hint && strings.TrimPrefix(d.name, d.field) == strings.TrimPrefix(a.name, a.field) {
+				best, reason = i, "config"
+				break
+			}
+			if a.sqlType != d.sqlType || a.nullable != d.nullable {
+				continue
+			}
+			s := similarity(d.name, a.name)
+			if strings.Contains(d.name, a.name) || strings.Contains(a.name, d.name) {
+				s = max(s, renameThreshold)
+			}
+			if s >= score || single {
+				best, score = i, s
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		a := added[best]
+		added = slices.Delete(added, best, best+1)
+		pairs[d.name], fields[d.field] = a.name, a.field
+		step := migrationStep{
+			up:   fmt.Sprintf("-- %s.%s renamed to %s (%s)\nALTER TABLE %s RENAME COLUMN %s TO %s;", table, d.name, a.name, reason, table, d.name, a.name),
+			down: fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", table, a.name, d.name),
+		}
+		if reason == "similar name" {
+			step.guessed = guessedRename(n, a.field, "column %s.%s renamed to %s, guessed from %s", table, d.name, a.name, reason)
+		}
+		steps = append(steps, step)
+	}
+
+	for _, oc := range oldCols {
+		name := oc.name
+		if to, ok := pairs[name]; ok {
+			name = to
+		}
+		i := find(newCols, name)
+		if i < 0 {
+			continue
+		}
+		if nc := newCols[i]; nc.sqlType != oc.sqlType || nc.nullable != oc.nullable || nc.dfltSQL != oc.dfltSQL || nc.onUpdate != oc.onUpdate || nc.collate != oc.collate {
+			step := migrationStep{
+				up:   alterColumn(table, oc, nc, dialect),
+				down: alterColumn(table, nc, diffColumn{name: nc.name, sqlType: oc.sqlType, nullable: oc.nullable, dfltSQL: oc.dfltSQL, onUpdate: oc.onUpdate, collate: oc.collate, createType: oc.createType}, dialect),
+			}
+			if nc.sqlType != oc.sqlType {
+				step.up = strings.TrimPrefix(nc.createType+"\n", "\n") + step.up + strings.TrimSuffix("\n"+oc.dropType, "\n")
+				step.down = strings.TrimPrefix(oc.createType+"\n", "\n") + step.down + strings.TrimSuffix("\n"+nc.dropType, "\n")
+			}
+			if narrows(oc.sqlType, nc.sqlType) {
+				step.destructive = destructive(n, nc.field, "column %s.%s would change from %s to %s", table, nc.name, oc.sqlType, nc.sqlType)
+			}
+			steps = append(steps, step)
+		}
+	}
+	for _, a := range added {
+		// A NOT NULL column cannot be added to a table with rows in one
+		// locking statement: it is added nullable, backfilled in batches and
+		// only then set NOT NULL.
+		backfilled := !a.nullable && a.field != ""
+		def := a.def
+		if backfilled {
+			def = strings.Replace(def, " NOT NULL", "", 1)
+		}
+		up := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, def)
+		if a.fk != "" {
+			up += fmt.Sprintf("\nALTER TABLE %s ADD %s;", table, a.fk)
+		}
+		down := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, a.name)
+		if a.createType != "" {
+			up, down = a.createType+"\n"+up, down+"\n"+a.dropType
+		}
+		steps = append(steps, migrationStep{up: up, down: down})
+		if backfilled {
+			nullable := a
+			nullable.nullable = true
+			steps = append(steps, migrationStep{
+				up:       alterColumn(table, nullable, a, dialect),
+				down:     alterColumn(table, a, nullable, dialect),
+				backfill: &backfill{table: table, column: a.name, value: a.dflt},
+			})
+		}
+	}
+	for _, d := range dropped {
+		if _, ok := pairs[d.name]; ok {
+			continue
+		}
+		up := fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, d.name)
+		down := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, d.def)
+		if d.fk != "" {
+			down += fmt.Sprintf("\nALTER TABLE %s ADD %s;", table, d.fk)
+		}
+		if d.createType != "" {
+			up, down = up+"\n"+d.dropType, d.createType+"\n"+down
+		}
+		steps = append(steps, migrationStep{
+			up:          up,
+			down:        down,
+			destructive: destructive(o, d.field, "column %s.%s would be dropped", table, d.name),
+		})
+	}
+	return append(steps, diffJoinTables(o, n, fields, dialect)...)
+}
+
+// diffJoinTables returns the steps migrating the join tables of a kept or
+// renamed table's many-to-many fields. Join tables of renamed models or
+// fields are renamed along with their columns.
+func diffJoinTables(o, n Model, fields map[string]string, dialect string) []migrationStep {
+	var steps []migrationStep
+	kept := map[string]bool{}
+	for _, f := range n.Fields {
+		if f.Relation != "many2many" {
+			continue
+		}
+		i := slices.IndexFunc(o.Fields, func(of Field) bool {
+			return of.Relation == "many2many" && (of.Name == f.Name || fields[of.Name] == f.Name)
+		})
+		if i < 0 {
+			steps = append(steps, migrationStep{
+				up:   strings.TrimSpace(joinTableSQL(n, f, dialect)),
+				down: fmt.Sprintf("DROP TABLE IF EXISTS %s;", joinTableName(n, f)),
+			})
+			continue
+		}
+		of := o.Fields[i]
+		kept[of.Name] = true
+		from, to := joinTableName(o, of), joinTableName(n, f)
+		if from != to {
+			steps = append(steps, migrationStep{
+				up:   fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", from, to),
+				down: fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", to, from),
+			})
+		}
+		oldSides, newSides := joinSides(o, of), joinSides(n, f)
+		for s := range newSides {
+			for c, name := range newSides[s].names {
+				if c < len(oldSides[s].names) && oldSides[s].names[c] != name {
+					steps = append(steps, migrationStep{
+						up:   fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", to, oldSides[s].names[c], name),
+						down: fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", to, name, oldSides[s].names[c]),
+					})
+				}
+			}
+		}
+	}
+	for _, of := range o.Fields {
+		if of.Relation == "many2many" && !kept[of.Name] {
+			steps = append(steps, migrationStep{
+				up:          fmt.Sprintf("DROP TABLE IF EXISTS %s;", joinTableName(o, of)),
+				down:        strings.TrimSpace(joinTableSQL(o, of, dialect)),
+				destructive: destructive(o, of.Name, "join table %s would be dropped", joinTableName(o, of)),
+			})
+		}
+	}
+	return steps
+}
+
+// alterColumn changes the type, nullability and default of a column from
+// those of from to those of to.
+func alterColumn(table string, from, to diffColumn, dialect string) string {
+	if dialect == "mysql" && (from.sqlType != to.sqlType || from.nullable != to.nullable || from.onUpdate != to.onUpdate || from.collate != to.collate) {
+		// MODIFY COLUMN rebuilds the table; a new default alone is set in
+		// place below.
+		def := to.name + " " + to.sqlType + to.collate
+		if !to.nullable {
+			def += " NOT NULL"
+		}
+		if to.dfltSQL != "" {
+			def += " DEFAULT " + to.dfltSQL
+		}
+		if to.onUpdate != "" {
+			def += " " + to.onUpdate
+		}
+		return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s;", table, def)
+	}
+	var stmts []string
+	if from.sqlType != to.sqlType && from.dfltSQL != "" {
+		// The old default may not convert to the new type: it is dropped
+		// first and set again below.
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, to.name))
+		from.dfltSQL = ""
+	}
+	if from.sqlType != to.sqlType || from.collate != to.collate {
+		using := ""
+		if from.createType != "" || to.createType != "" {
+			// Enum types only convert from and to strings explicitly.
+			using = " USING " + to.name + "::" + to.sqlType
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s%s%s;", table, to.name, to.sqlType, to.collate, using))
+	}
+	if from.nullable != to.nullable {
+		change := "SET NOT NULL"
+		if to.nullable {
+			change = "DROP NOT NULL"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, to.name, change))
+	}
+	if from.dfltSQL != to.dfltSQL {
+		change := "SET DEFAULT " + to.dfltSQL
+		if to.dfltSQL == "" {
+			change = "DROP DEFAULT"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, to.name, change))
+	}
+	return strings.Join(stmts, "\n")
+}
+
+// diffMigrations renders the steps as migration files keyed by name: the
+// safe changes, then the backfilled NOT NULL constraints, then the
+// destructive changes. Each migration gets its own version, the timestamp
+// of at and of the seconds after it, so that they apply in that order.
+func diffMigrations(steps []migrationStep, snapshot string, at time.Time) map[string]string {
+	files := map[string]string{}
+	safe, notNull, destructive := groupSteps(steps)
+	for _, group := range []struct {
+		name   string
+		header string
+		steps  []migrationStep
+	}{
+		{"alter_tables", "", safe},
+		{"backfilled_not_null", notNullHeader, notNull},
+		{"destructive", destructiveHeader, destructive},
+	} {
+		if len(group.steps) == 0 {
+			continue
+		}
+		up, down := migrationSQL(group.steps, snapshot, group.header)
+		name := at.Format(versionLayout) + "_" + group.name
+		files[name+".up.sql"] = up
+		files[name+".down.sql"] = down
+		at = at.Add(time.Second)
+	}
+	return files
+}
+
+// versionAfter returns at, or the second after the last migration in dir
+// when that one is not older, so that a run within a second of the previous
+// one does not reuse its version.
+func versionAfter(dir string, at time.Time) time.Time {
+	latest, err := latestMigration(dir)
+	if err != nil {
+		return at
+	}
+	last, err := time.ParseInLocation(versionLayout, strconv.FormatInt(latest, 10), at.Location())
+	if err != nil || last.Before(at.Truncate(time.Second)) {
+		return at
+	}
+	return last.Add(time.Second)
+}
+
+// migrationSQL renders the steps as up and down migrations, the down one
+// reverting the steps in reverse order.
+func migrationSQL(steps []migrationStep, snapshot, header string) (up, down string) {
+	header = "-- Generated by django2go from the changes since " + snapshot + ".\n" + header + "\n"
+	ups := make([]string, len(steps))
+	downs := make([]string, len(steps))
+	for i, s := range steps {
+		ups[i] = s.up
+		downs[len(steps)-1-i] = s.down
+	}
+	return header + strings.Join(ups, "\n\n") + "\n", header + strings.Join(downs, "\n\n") + "\n"
+}
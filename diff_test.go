@@ -0,0 +1,222 @@
+// diff_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDiffModelsRenames checks that a renamed table and column are renamed
+// in place rather than dropped and added again.
+func TestDiffModelsRenames(t *testing.T) {
+	prev := []Model{{Name: "Post", App: "blog", Fields: []Field{
+		{Name: "title", Type: "CharField", MaxLength: 100},
+		{Name: "body", Type: "TextField"},
+		{Name: "views", Type: "IntegerField"},
+	}}}
+	cur := []Model{{Name: "Article", App: "blog", Fields: []Field{
+		{Name: "title", Type: "CharField", MaxLength: 100},
+		{Name: "content", Type: "TextField"},
+		{Name: "views", Type: "IntegerField"},
+	}}}
+	steps := diffModels(prev, cur, nil, Options{Dialect: "postgres"})
+	var got []string
+	for _, d := range renameDiagnostics(steps) {
+		got = append(got, d.Code+" "+d.Message)
+	}
+	want := []string{
+		"W212 table post renamed to article, guessed from similar columns; review the migration, or state renames in the config",
+		"W212 column article.body renamed to content, guessed from similar name; review the migration, or state renames in the config",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	up, down := migrationSQL(steps, "snapshot.json", "")
+	for _, want := range []string{
+		"-- post renamed to article (similar columns)\nALTER TABLE post RENAME TO article;",
+		"-- article.body renamed to content (similar name)\nALTER TABLE article RENAME COLUMN body TO content;",
+	} {
+		if !strings.Contains(up, want) {
+			t.Errorf("up migration lacks %q:\n%s", want, up)
+		}
+	}
+	if strings.Contains(up, "DROP") || strings.Contains(up, "ADD") || strings.Contains(up, "CREATE TABLE") {
+		t.Errorf("up migration drops or recreates:\n%s", up)
+	}
+	if !strings.Contains(down, "ALTER TABLE article RENAME TO post;") || !strings.Contains(down, "ALTER TABLE article RENAME COLUMN content TO body;") {
+		t.Errorf("down migration:\n%s", down)
+	}
+}
+
+// TestDiffModelsConfigRenames checks that the config's renames win over
+// the similarity of names.
+func TestDiffModelsConfigRenames(t *testing.T) {
+	prev := []Model{{Name: "Post", Fields: []Field{
+		{Name: "a", Type: "IntegerField"},
+		{Name: "b", Type: "IntegerField"},
+	}}}
+	cur := []Model{{Name: "Post", Fields: []Field{
+		{Name: "x", Type: "IntegerField"},
+		{Name: "y", Type: "IntegerField"},
+	}}}
+	steps := diffModels(prev, cur, map[string]string{"Post.a": "y"}, Options{Dialect: "postgres"})
+	up, _ := migrationSQL(steps, "snapshot.json", "")
+	if !strings.Contains(up, "-- post.a renamed to y (config)") {
+		t.Errorf("up migration:\n%s", up)
+	}
+	if diags := renameDiagnostics(steps); len(diags) != 0 {
+		t.Errorf("config rename reported: %v", diags)
+	}
+	if !strings.Contains(up, "ADD COLUMN x") || !strings.Contains(up, "DROP COLUMN b") {
+		t.Errorf("unrenamed columns not added and dropped:\n%s", up)
+	}
+}
+
+// TestTableSimilarity checks that the primary keys every table has do not
+// make unrelated tables look alike.
+func TestTableSimilarity(t *testing.T) {
+	post := Model{Name: "Post", Fields: []Field{
+		{Name: "title", Type: "CharField", MaxLength: 100},
+		{Name: "body", Type: "TextField"},
+	}}
+	tag := Model{Name: "Tag", Fields: []Field{
+		{Name: "title", Type: "CharField", MaxLength: 100},
+		{Name: "slug", Type: "SlugField"},
+	}}
+	if s := tableSimilarity(post, tag, "postgres"); s != 1.0/3 {
+		t.Errorf("similarity = %v, want 1/3", s)
+	}
+	steps := diffModels([]Model{post}, []Model{tag}, nil, Options{Dialect: "postgres"})
+	if up, _ := migrationSQL(steps, "snapshot.json", ""); strings.Contains(up, "RENAME") {
+		t.Errorf("up migration renames:\n%s", up)
+	}
+}
+
+// TestDestructiveSteps checks which changes are flagged as losing data
+// and that they are split from the safe ones.
+func TestDestructiveSteps(t *testing.T) {
+	prev := []Model{
+		{Name: "Post", File: "blog/models.py", Fields: []Field{
+			{Name: "title", Type: "CharField", DBType: "VARCHAR(100)"},
+			{Name: "summary", Type: "CharField", DBType: "VARCHAR(200)"},
+			{Name: "views", Type: "IntegerField", DBType: "INTEGER"},
+			{Name: "body", Type: "TextField"},
+			{Name: "legacy", Type: "BooleanField"},
+		}},
+		{Name: "Tag", File: "blog/models.py", Fields: []Field{{Name: "name", Type: "CharField", MaxLength: 50}}},
+	}
+	cur := []Model{{Name: "Post", File: "blog/models.py", Fields: []Field{
+		{Name: "title", Type: "CharField", DBType: "VARCHAR(200)"},
+		{Name: "summary", Type: "CharField", DBType: "VARCHAR(50)"},
+		{Name: "views", Type: "IntegerField", DBType: "BIGINT"},
+		{Name: "body", Type: "TextField"},
+	}}}
+	steps := diffModels(prev, cur, nil, Options{Dialect: "postgres"})
+	var got []string
+	for _, d := range destructiveDiagnostics(steps) {
+		got = append(got, d.Code+" "+d.Message)
+	}
+	want := []string{
+		"E205 column post.summary would change from VARCHAR(200) to VARCHAR(50); pass --allow-destructive to generate it",
+		"E205 column post.legacy would be dropped; pass --allow-destructive to generate it",
+		"E205 table tag would be dropped; pass --allow-destructive to generate it",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	safe, _, destructive := groupSteps(steps)
+	if len(safe) != 2 || len(destructive) != 3 {
+		t.Errorf("split into %d safe and %d destructive steps", len(safe), len(destructive))
+	}
+	up, _ := migrationSQL(destructive, "snapshot.json", destructiveHeader)
+	if !strings.Contains(up, "-- DESTRUCTIVE") {
+		t.Errorf("destructive migration lacks its header:\n%s", up)
+	}
+}
+
+// TestDiffMigrationsVersions checks that the migrations of a run get
+// distinct versions, in the order they must be applied in.
+func TestDiffMigrationsVersions(t *testing.T) {
+	steps := []migrationStep{
+		{up: "ALTER TABLE post ADD COLUMN slug TEXT;", down: "ALTER TABLE post DROP COLUMN slug;"},
+		{up: "ALTER TABLE post ALTER COLUMN slug SET NOT NULL;", down: "ALTER TABLE post ALTER COLUMN slug DROP NOT NULL;", backfill: &backfill{}},
+		{up: "ALTER TABLE post DROP COLUMN views;", down: "ALTER TABLE post ADD COLUMN views INTEGER;", destructive: &Diagnostic{}},
+	}
+	// The last second of a minute, so that the versions roll over.
+	at := time.Date(2024, 1, 2, 3, 4, 59, 0, time.UTC)
+	files := diffMigrations(steps, "snapshot.json", at)
+
+	var ups []string
+	for name := range files {
+		if strings.HasSuffix(name, ".up.sql") {
+			ups = append(ups, name)
+		}
+		if _, ok := files[strings.Replace(name, ".up.sql", ".down.sql", 1)]; !ok {
+			t.Errorf("%s has no down migration", name)
+		}
+	}
+	sort.Strings(ups)
+	want := []string{
+		"20240102030459_alter_tables.up.sql",
+		"20240102030500_backfilled_not_null.up.sql",
+		"20240102030501_destructive.up.sql",
+	}
+	if strings.Join(ups, " ") != strings.Join(want, " ") {
+		t.Fatalf("up migrations = %v, want %v", ups, want)
+	}
+	seen := map[string]bool{}
+	for _, name := range ups {
+		version, _, _ := strings.Cut(name, "_")
+		if seen[version] {
+			t.Errorf("version %s used twice", version)
+		}
+		seen[version] = true
+	}
+}
+
+// TestDiffMigrationsSingleGroup checks that a run with only safe changes
+// keeps the version of its own time.
+func TestDiffMigrationsSingleGroup(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	files := diffMigrations([]migrationStep{{up: "SELECT 1;", down: "SELECT 1;"}}, "snapshot.json", at)
+	if _, ok := files["20240102030405_alter_tables.up.sql"]; !ok || len(files) != 2 {
+		t.Fatalf("files = %v", sortedNames(files))
+	}
+}
+
+// sortedNames returns the sorted names of a file map.
+func sortedNames(files map[string]string) []string {
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TestVersionAfter checks that a run within a second of the previous one
+// versions its migrations after the previous ones.
+func TestVersionAfter(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "20240102030405_create_tables.up.sql"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		at, want time.Time
+	}{
+		{time.Date(2024, 1, 2, 3, 4, 5, 500, time.UTC), time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)},
+		{time.Date(2024, 1, 2, 3, 4, 1, 0, time.UTC), time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC)},
+		{time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC), time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC)},
+	} {
+		if got := versionAfter(dir, c.at); !got.Equal(c.want) {
+			t.Errorf("versionAfter(%s) = %s, want %s", c.at, got, c.want)
+		}
+	}
+	if at := time.Now(); !versionAfter(filepath.Join(dir, "missing"), at).Equal(at) {
+		t.Error("versionAfter moved the time of an empty directory")
+	}
+}
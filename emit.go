@@ -0,0 +1,100 @@
+// emit.go
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EmitConfig selects optional generated code, set in the config's emit
+// section.
+type EmitConfig struct {
+	// OTel generates the telemetry package tracing the sqlc queries and
+	// HTTP handlers with OpenTelemetry.
+	OTel bool `yaml:"otel"`
+	// QueryLog generates the querylog package logging the sqlc queries with
+	// slog.
+	QueryLog bool `yaml:"query_log"`
+	// SlowQuery is the duration from which the logged queries are reported
+	// as slow, defaulting to defaultSlowQuery.
+	SlowQuery time.Duration `yaml:"slow_query"`
+	// Cache generates the cache package caching the hot read queries of
+	// the report.
+	Cache bool `yaml:"cache"`
+	// CacheTTL is how long cached results are kept, defaulting to
+	// defaultCacheTTL.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// Health generates the health package serving the /healthz and /readyz
+	// probes.
+	Health bool `yaml:"health"`
+	// Shadow generates the shadow package, replaying requests served by the
+	// Django app against the Go service and comparing the responses.
+	Shadow bool `yaml:"shadow"`
+	// Choices generates the choices package, a Go type per field with
+	// choices, and binds the fields' columns to them in sqlc.yaml.
+	Choices bool `yaml:"choices"`
+	// QueryTests generates dbtest/queries_test.go, running every translated
+	// query against fixture rows in a test database.
+	QueryTests bool `yaml:"query_tests"`
+	// TypeTests generates dbtest/types_test.go, reading boundary values of
+	// every column type back through the sqlc structs.
+	TypeTests bool `yaml:"type_tests"`
+	// Workflow generates a Makefile (make) or Taskfile.yaml (task) in the
+	// output directory, running the steps of the port.
+	Workflow string `yaml:"workflow"`
+}
+
+// validate checks the emit settings.
+func (e EmitConfig) validate() error {
+	if e.SlowQuery < 0 {
+		return fmt.Errorf("emit: slow_query must not be negative")
+	}
+	if e.SlowQuery != 0 && !e.QueryLog {
+		return fmt.Errorf("emit: slow_query needs query_log")
+	}
+	if e.CacheTTL < 0 {
+		return fmt.Errorf("emit: cache_ttl must not be negative")
+	}
+	if e.CacheTTL != 0 && !e.Cache {
+		return fmt.Errorf("emit: cache_ttl needs cache")
+	}
+	if e.Workflow != "" && e.Workflow != workflowMake && e.Workflow != workflowTask {
+		return fmt.Errorf("emit: workflow must be %s or %s", workflowMake, workflowTask)
+	}
+	return nil
+}
+
+// emitter generates one set of files from the models and queries once they
+// are final, independently of the other emitters.
+type emitter struct {
+	name string
+	// done is printed once its files are written; empty prints nothing.
+	done string
+	gen  func() (map[string]string, error)
+}
+
+// runEmitters runs the emitters concurrently and returns the files of each,
+// in emitter order. The emitters only read the shared models and queries.
+// The errors of every failing emitter are joined, prefixed with its name,
+// so a run reports all of them at once.
+func runEmitters(emitters []emitter) ([]map[string]string, error) {
+	files := make([]map[string]string, len(emitters))
+	errs := make([]error, len(emitters))
+	var wg sync.WaitGroup
+	for i, e := range emitters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := e.gen()
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", e.name, err)
+				return
+			}
+			files[i] = f
+		}()
+	}
+	wg.Wait()
+	return files, errors.Join(errs...)
+}
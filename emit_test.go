@@ -0,0 +1,35 @@
+// emit_test.go
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestRunEmitters checks that the files come back in emitter order and that
+// the errors of every failing emitter are reported together.
+func TestRunEmitters(t *testing.T) {
+	gen := func(name string, err error) emitter {
+		return emitter{name: name, gen: func() (map[string]string, error) {
+			if err != nil {
+				return nil, err
+			}
+			return map[string]string{name + "/" + name + ".go": name}, nil
+		}}
+	}
+	files, err := runEmitters([]emitter{gen("a", nil), gen("b", nil), gen("c", nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b", "c"} {
+		if files[i][name+"/"+name+".go"] != name {
+			t.Errorf("files[%d] = %v", i, files[i])
+		}
+	}
+
+	errB, errC := errors.New("bad template"), errors.New("no module")
+	_, err = runEmitters([]emitter{gen("a", nil), gen("b", errB), gen("c", errC)})
+	if !errors.Is(err, errB) || !errors.Is(err, errC) || err.Error() != "b: bad template\nc: no module" {
+		t.Errorf("error = %v", err)
+	}
+}
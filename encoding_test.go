@@ -0,0 +1,31 @@
+// encoding_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSourceEncodings checks that Python files are decoded by their BOM or
+// encoding declaration, and that files invalid in theirs are skipped.
+func TestSourceEncodings(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"shop/models.py": "# -*- coding: latin-1 -*-\nfrom django.db import models\n\nclass Caf\xe9(models.Model):\n    name = models.CharField(max_length=10, default='cr\xe8me')\n",
+		"blog/models.py": "\ufefffrom django.db import models\n\nclass Post(models.Model):\n    title = models.CharField(max_length=10)\n",
+		"bad/models.py":  "from django.db import models\n\n# caf\xe9\n",
+	})
+	var names []string
+	for _, m := range out.Models {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); !strings.Contains(got, "Café") || !strings.Contains(got, "Post") {
+		t.Errorf("models = %s", got)
+	}
+	if len(out.Diagnostics) != 1 {
+		t.Fatalf("diagnostics = %+v", out.Diagnostics)
+	}
+	d := out.Diagnostics[0]
+	if d.Code != codeFileSkipped || d.File != "bad/models.py" || d.Line != 3 || !strings.Contains(d.Message, "not valid utf-8") {
+		t.Errorf("diagnostic = %+v", d)
+	}
+}
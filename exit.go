@@ -0,0 +1,40 @@
+// exit.go
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Exit codes, so that scripts around django2go can tell failures apart.
+const (
+	// exitError is an unexpected failure, such as generated Go code that
+	// does not format.
+	exitError = 1
+	// exitUsage is an invalid flag or config; the flag package exits with
+	// it too.
+	exitUsage = 2
+	// exitParse is a failure to parse the Django code or schema dump.
+	exitParse = 3
+	// exitDiagnostics is a run failing its diagnostics policy: errors such
+	// as unsupported features, --error-on codes or too many warnings.
+	exitDiagnostics = 4
+	// exitDrift is triage finding that the models, migrations and database
+	// disagree beyond its diagnostics policy.
+	exitDrift = 5
+	// exitIO is a failure to read or write a file or the database.
+	exitIO = 6
+)
+
+// atExit, when set, is run by fail before exiting, such as to write the
+// profiles of the run so far.
+var atExit func()
+
+// fail prints the message and exits with code.
+func fail(code int, format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+	if atExit != nil {
+		atExit()
+	}
+	os.Exit(code)
+}
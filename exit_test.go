@@ -0,0 +1,58 @@
+// exit_test.go
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestExitCodes runs the command in a subprocess and checks the code it
+// exits with per kind of failure.
+func TestExitCodes(t *testing.T) {
+	if args := os.Getenv("DJANGO2GO_ARGS"); args != "" {
+		os.Args = append([]string{"django2go"}, strings.Fields(args)...)
+		main()
+		os.Exit(0)
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "broken.py"), []byte("def (:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dump := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(dump, []byte("CREATE TABLE blog_post (id integer, CONSTRAINT broken);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	for _, c := range []struct {
+		args string
+		want int
+	}{
+		{"--output " + out, exitUsage},
+		{"--input " + filepath.Join(project, "missing") + " --output " + out, exitUsage},
+		{"--input " + project + " --output " + out + " --dialect oracle", exitUsage},
+		{"--input " + project + " --output " + out + " --error-on W001", exitDiagnostics},
+		{"--source sqldump --input " + dump + " --output " + out, exitParse},
+		{"--input " + project + " --output " + out, 0},
+	} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestExitCodes$")
+		cmd.Env = append(os.Environ(), "DJANGO2GO_ARGS="+c.args)
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != c.want {
+			t.Errorf("%s: exit code %d, want %d", c.args, code, c.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// geo.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// geoTypes maps the GeoDjango geometry fields to the geometry types of
+// their columns.
+var geoTypes = map[string]string{
+	"GeometryField":           "Geometry",
+	"PointField":              "Point",
+	"LineStringField":         "LineString",
+	"PolygonField":            "Polygon",
+	"MultiPointField":         "MultiPoint",
+	"MultiLineStringField":    "MultiLineString",
+	"MultiPolygonField":       "MultiPolygon",
+	"GeometryCollectionField": "GeometryCollection",
+}
+
+// defaultSRID is GeoDjango's default spatial reference system, WGS84.
+const defaultSRID = 4326
+
+// geoType returns the column type of a geometry field: a PostGIS geometry,
+// or geography with geography=True, constrained to the field's geometry
+// type and SRID, or the MySQL spatial type with the SRID.
+func geoType(f Field, dialect string) string {
+	srid := f.SRID
+	if srid == 0 {
+		srid = defaultSRID
+	}
+	if dialect == "mysql" {
+		return fmt.Sprintf("%s SRID %d", strings.ToUpper(geoTypes[f.Type]), srid)
+	}
+	kind := "geometry"
+	if f.Geography {
+		kind = "geography"
+	}
+	return fmt.Sprintf("%s(%s, %d)", kind, geoTypes[f.Type], srid)
+}
+
+// spatialIndexes returns the spatial indexes of the model's geometry fields
+// declared with spatial_index=True, GeoDjango's default: GiST indexes on
+// PostgreSQL, SPATIAL indexes on MySQL, where the columns must be NOT NULL.
+func spatialIndexes(m Model, dialect string) (stmts []string, warnings []Diagnostic) {
+	table := m.table()
+	for _, f := range m.Fields {
+		if geoTypes[f.Type] == "" || !f.SpatialIndex {
+			continue
+		}
+		col := columnName(f)
+		if dialect == "mysql" {
+			if f.Nullable {
+				warnings = append(warnings, diagnose(codeIndexSkipped, m, f.Name, "spatial index on %s.%s skipped: MySQL only indexes NOT NULL columns", table, col))
+				continue
+			}
+			stmts = append(stmts, fmt.Sprintf("CREATE SPATIAL INDEX %s_%s_spatial ON %s (%s);", table, col, table, col))
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX %s_%s_gist ON %s USING gist (%s);", table, col, table, col))
+	}
+	return stmts, warnings
+}
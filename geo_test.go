@@ -0,0 +1,59 @@
+// geo_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGeometryFields checks the PostGIS and MySQL column types of geometry
+// fields, the postgis extension and their spatial indexes.
+func TestGeometryFields(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"places/models.py": `from django.contrib.gis.db import models
+
+class Place(models.Model):
+    location = models.PointField()
+    area = models.PolygonField(srid=3857, null=True)
+    route = models.LineStringField(geography=True, spatial_index=False)
+`,
+	})
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{
+		"CREATE EXTENSION IF NOT EXISTS postgis;",
+		"location geometry(Point, 4326) NOT NULL",
+		"area geometry(Polygon, 3857)",
+		"route geography(LineString, 4326) NOT NULL",
+		"CREATE INDEX place_location_gist ON place USING gist (location);",
+		"CREATE INDEX place_area_gist ON place USING gist (area);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("postgres schema lacks %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "place_route") {
+		t.Errorf("index despite spatial_index=False:\n%s", sql)
+	}
+
+	opts.Dialect = "mysql"
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql = generateSQL(out.Models, opts)
+	for _, want := range []string{
+		"location POINT SRID 4326 NOT NULL",
+		"CREATE SPATIAL INDEX place_location_spatial ON place (location);",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("mysql schema lacks %q:\n%s", want, sql)
+		}
+	}
+	_, warnings := spatialIndexes(out.Models[0], "mysql")
+	if len(warnings) != 1 || warnings[0].Code != codeIndexSkipped || !strings.Contains(warnings[0].Message, "place.area") {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
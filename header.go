@@ -0,0 +1,173 @@
+// header.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+// version is the django2go release, set at build time with
+// -ldflags "-X main.version=v1.2.3". Without it the module version from the
+// build info is used.
+var version string
+
+// defaultHeader is the provenance header written when the config sets none.
+const defaultHeader = `Generated by django2go {{.Version}} from {{.Source}}{{with .Commit}} at commit {{.}}{{end}}.
+{{with .Params}}Parameters: {{.}}
+{{end}}{{if not .Editable}}Do not edit by hand: change the Django code and regenerate.
+{{end}}`
+
+// HeaderData is what the header template is rendered with.
+type HeaderData struct {
+	Version string
+	Source  string
+	// Commit is the git commit of the source, with a -dirty suffix when
+	// it has uncommitted changes; empty outside a git work tree.
+	Commit string
+	// Params are the command line flags the run was given.
+	Params string
+	// File is the path of the generated file, relative to the output.
+	File string
+	// Editable is set for the files meant to be edited by hand: the Go
+	// stubs to complete and sqlc.yaml, whose edits are merged on
+	// regeneration.
+	Editable bool
+}
+
+// Header renders the provenance comment at the top of generated files.
+type Header struct {
+	tmpl *template.Template
+	data HeaderData
+}
+
+// parseHeader parses a header template, the default one when text is
+// empty.
+func parseHeader(text string) (*template.Template, error) {
+	if text == "" {
+		text = defaultHeader
+	}
+	tmpl, err := template.New("header").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// Render once so references to unknown fields fail early.
+	if err := tmpl.Execute(&bytes.Buffer{}, HeaderData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// newHeader prepares the header of a run on source with the flags set on fs.
+func newHeader(text, source string, fs *flag.FlagSet) (*Header, error) {
+	tmpl, err := parseHeader(text)
+	if err != nil {
+		return nil, fmt.Errorf("header: %w", err)
+	}
+	return &Header{tmpl: tmpl, data: HeaderData{
+		Version: toolVersion(),
+		Source:  source,
+		Commit:  sourceCommit(source),
+		Params:  flagParams(fs),
+	}}, nil
+}
+
+// toolVersion returns the version of django2go.
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// sourceCommit returns the short commit of the git work tree holding path,
+// or "" when it is not in one.
+func sourceCommit(path string) string {
+	dir := path
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	commit := strings.TrimSpace(string(out))
+	if status, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--", ".").Output(); err == nil && len(status) > 0 {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// flagParams lists the flags set on fs as they would be passed again.
+func flagParams(fs *flag.FlagSet) string {
+	var params []string
+	fs.Visit(func(f *flag.Flag) {
+		params = append(params, fmt.Sprintf("--%s=%s", f.Name, f.Value))
+	})
+	return strings.Join(params, " ")
+}
+
+// commentPrefixes are the line comment markers of the generated file types,
+// by extension or, for files without one, by name. Files of other types,
+// such as JSON, cannot hold a header.
+var commentPrefixes = map[string]string{
+	".sql":     "-- ",
+	".go":      "// ",
+	".yaml":    "# ",
+	"Makefile": "# ",
+}
+
+// apply prepends the header to the content of the generated file at path.
+func (h *Header) apply(file, content string) string {
+	prefix, ok := commentPrefixes[path.Ext(file)]
+	if !ok {
+		prefix, ok = commentPrefixes[path.Base(file)]
+	}
+	if !ok || h == nil {
+		return content
+	}
+	data := h.data
+	data.File = file
+	data.Editable = strings.Contains(firstLine(content), "Edit as needed")
+	var buf bytes.Buffer
+	if err := h.tmpl.Execute(&buf, data); err != nil {
+		// parseHeader rendered the template once already.
+		return content
+	}
+	text := strings.TrimRight(buf.String(), "\n")
+	if text == "" {
+		return content
+	}
+	var sb strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		sb.WriteString(strings.TrimRight(prefix+line, " ") + "\n")
+	}
+	if prefix != "// " {
+		sb.WriteString("\n")
+	}
+	return sb.String() + content
+}
+
+// files prepends the header to every file keyed by its path.
+func (h *Header) files(files map[string]string) map[string]string {
+	for file, content := range files {
+		files[file] = h.apply(file, content)
+	}
+	return files
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,59 @@
+// header_test.go
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+// TestHeader checks the default header per file type and that the Go
+// stubs are not told off for being edited.
+func TestHeader(t *testing.T) {
+	fs := flag.NewFlagSet("django2go", flag.ContinueOnError)
+	fs.String("dialect", "postgres", "")
+	fs.String("output", "./generated", "")
+	if err := fs.Parse([]string{"--dialect=mysql"}); err != nil {
+		t.Fatal(err)
+	}
+	h, err := newHeader("", t.TempDir(), fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.data.Version, h.data.Source = "v1.0.0", "./myproject"
+
+	got := h.apply("schema.sql", "CREATE TABLE post ();\n")
+	want := "-- Generated by django2go v1.0.0 from ./myproject.\n" +
+		"-- Parameters: --dialect=mysql\n" +
+		"-- Do not edit by hand: change the Django code and regenerate.\n\n" +
+		"CREATE TABLE post ();\n"
+	if got != want {
+		t.Errorf("schema.sql =\n%s\nwant\n%s", got, want)
+	}
+	stub := h.apply("commands/x.go", "// Code generated by django2go. Edit as needed.\n\npackage commands\n")
+	if strings.Contains(stub, "Do not edit") || !strings.HasPrefix(stub, "// Generated by django2go v1.0.0") {
+		t.Errorf("stub header:\n%s", stub)
+	}
+	if got := h.apply("Makefile", "all:\n"); !strings.HasPrefix(got, "# Generated by django2go v1.0.0") {
+		t.Errorf("Makefile header:\n%s", got)
+	}
+	if got := h.apply("sqlc.yaml", "# Edit as needed: hand edits are kept on regeneration.\n"); strings.Contains(got, "Do not edit") {
+		t.Errorf("sqlc.yaml header:\n%s", got)
+	}
+	if got := h.apply("report.json", "{}"); got != "{}" {
+		t.Errorf("report.json = %q", got)
+	}
+}
+
+func TestParseHeaderErrors(t *testing.T) {
+	for _, text := range []string{"{{.Version", "{{.Unknown}}"} {
+		if _, err := parseHeader(text); err == nil {
+			t.Errorf("%q parsed", text)
+		}
+	}
+	h := &Header{data: HeaderData{Source: "src"}}
+	h.tmpl, _ = parseHeader("{{.File}} from {{.Source}}")
+	if got := h.apply("query.sql", "SELECT 1;"); got != "-- query.sql from src\n\nSELECT 1;" {
+		t.Errorf("custom header = %q", got)
+	}
+}
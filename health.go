@@ -0,0 +1,120 @@
+// health.go
+package main
+
+import "fmt"
+
+// generateHealth renders the health package, whose /healthz and /readyz
+// handlers replace Django's system checks in deployment probes: the
+// database must answer, and for readiness its schema must be at the latest
+// embedded migration according to golang-migrate's schema_migrations table.
+func generateHealth(module string, layout Layout) (map[string]string, error) {
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	migrations %[1]q
+)
+
+// Timeout bounds the checks of a probe.
+var Timeout = 2 * time.Second
+
+// Register serves Healthz at /healthz and Readyz at /readyz.
+func Register(mux *http.ServeMux, db *sql.DB) {
+	mux.Handle("/healthz", Healthz(db))
+	mux.Handle("/readyz", Readyz(db))
+}
+
+// Healthz reports the service alive as long as the database answers.
+func Healthz(db *sql.DB) http.Handler {
+	return probe(db.PingContext)
+}
+
+// Readyz reports the service ready to take traffic once the database
+// answers and its schema is migrated, so that instances are not routed
+// requests before the migrations ran.
+func Readyz(db *sql.DB) http.Handler {
+	return probe(func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return err
+		}
+		return CheckMigrations(ctx, db)
+	})
+}
+
+// probe serves the result of check: 200 when it passes, 503 otherwise.
+func probe(check func(context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), Timeout)
+		defer cancel()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := check(ctx); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+// CheckMigrations checks that the database is at the latest embedded
+// migration and that the last one did not fail halfway. A database ahead
+// of the code, as during a rolling deploy, passes.
+func CheckMigrations(ctx context.Context, db *sql.DB) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	var version int64
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("migrations: none applied, want %%d", latest)
+	case err != nil:
+		return fmt.Errorf("migrations: %%w", err)
+	case dirty:
+		return fmt.Errorf("migrations: version %%d is dirty", version)
+	case version < latest:
+		return fmt.Errorf("migrations: at version %%d, want %%d", version, latest)
+	}
+	return nil
+}
+
+// LatestVersion returns the version of the last embedded migration, the
+// number its file name starts with.
+func LatestVersion() (int64, error) {
+	names, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migrations: %%s: no version", name)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+`, layout.importPath(module, layout.Migrations))
+	files := map[string]string{}
+	if err := addGoFile(files, "health/health.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
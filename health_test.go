@@ -0,0 +1,24 @@
+// health_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateHealth(t *testing.T) {
+	files, err := generateHealth("example.com/app", defaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["health/health.go"]
+	for _, want := range []string{
+		`migrations "example.com/app/migrations"`,
+		`mux.Handle("/readyz", Readyz(db))`,
+		`return fmt.Errorf("migrations: at version %d, want %d", version, latest)`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("health.go lacks %q", want)
+		}
+	}
+}
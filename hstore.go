@@ -0,0 +1,124 @@
+// hstore.go
+package main
+
+import (
+	"path"
+	"path/filepath"
+	"slices"
+)
+
+// generateHstore renders hstore.go in the package sqlc generates when a
+// model has an HStoreField on PostgreSQL: the Hstore type the sqlc overrides
+// map hstore columns to, a map[string]string like the dict Django reads,
+// scanning and writing the hstore text format.
+func generateHstore(models []Model, layout Layout, dialect string) (map[string]string, error) {
+	used := slices.ContainsFunc(models, func(m Model) bool {
+		return slices.ContainsFunc(m.Fields, func(f Field) bool { return f.Type == "HStoreField" })
+	})
+	if !used || dialect != "postgres" {
+		return nil, nil
+	}
+	src := `// Code generated by django2go. DO NOT EDIT.
+
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hstore is the value of an hstore column, read like Django's HStoreField
+// into a map. NULL values read as empty strings; a nil Hstore is NULL.
+type Hstore map[string]string
+
+// Scan implements sql.Scanner, parsing the hstore text format.
+func (h *Hstore) Scan(src any) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		*h = nil
+		return nil
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	default:
+		return fmt.Errorf("hstore: cannot scan %T", src)
+	}
+	m := Hstore{}
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, err := hstoreToken(s)
+		if err != nil {
+			return err
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(rest), "=>")
+		if !ok {
+			return fmt.Errorf("hstore: expected => after %q", key)
+		}
+		value, rest, err := hstoreToken(strings.TrimSpace(rest))
+		if err != nil {
+			return err
+		}
+		m[key] = value
+		rest = strings.TrimSpace(rest)
+		if rest != "" {
+			if rest, ok = strings.CutPrefix(rest, ","); !ok {
+				return fmt.Errorf("hstore: expected , after %q", key)
+			}
+		}
+		s = strings.TrimSpace(rest)
+	}
+	*h = m
+	return nil
+}
+
+// hstoreToken reads a double-quoted string, or NULL, at the start of s.
+func hstoreToken(s string) (token, rest string, err error) {
+	if rest, ok := strings.CutPrefix(s, "NULL"); ok {
+		return "", rest, nil
+	}
+	if !strings.HasPrefix(s, "\"") {
+		return "", "", fmt.Errorf("hstore: expected a quoted string at %q", s)
+	}
+	var sb strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i++; i < len(s) {
+				sb.WriteByte(s[i])
+			}
+		case '"':
+			return sb.String(), s[i+1:], nil
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("hstore: unterminated string")
+}
+
+// Value implements driver.Valuer, rendering the hstore text format.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	quote := strings.NewReplacer("\\", "\\\\", "\"", "\\\"")
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = "\"" + quote.Replace(k) + "\"=>\"" + quote.Replace(h[k]) + "\""
+	}
+	return strings.Join(pairs, ", "), nil
+}
+`
+	files := map[string]string{}
+	if err := addGoFile(files, path.Join(filepath.ToSlash(layout.DB), "hstore.go"), src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,29 @@
+// hstore_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateHstore checks that the Hstore type is only generated for
+// PostgreSQL projects with an HStoreField, in the sqlc package.
+func TestGenerateHstore(t *testing.T) {
+	models := []Model{{Name: "Product", Fields: []Field{{Name: "attrs", Type: "HStoreField"}}}}
+	layout := defaultLayout
+	layout.DB = "internal/db"
+	files, err := generateHstore(models, layout, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src := files["internal/db/hstore.go"]; !strings.Contains(src, "type Hstore map[string]string") || !strings.Contains(src, "func (h *Hstore) Scan(src any) error") {
+		t.Errorf("files = %v", files)
+	}
+	if files, _ := generateHstore(models, layout, "mysql"); files != nil {
+		t.Errorf("mysql files = %v", files)
+	}
+	models[0].Fields[0].Type = "JSONField"
+	if files, _ := generateHstore(models, layout, "postgres"); files != nil {
+		t.Errorf("files without hstore = %v", files)
+	}
+}
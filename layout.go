@@ -0,0 +1,107 @@
+// layout.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Layout places the generated artifacts of every database tree, relative to
+// its output directory, so that they can land in an existing Go repository
+// instead of the default ./out structure. The Go packages of the database,
+// telemetry and the like stay at the top of --output.
+type Layout struct {
+	Schema     string `yaml:"schema"`
+	Migrations string `yaml:"migrations"`
+	Queries    string `yaml:"queries"`
+	Reports    string `yaml:"reports"`
+	SQLC       string `yaml:"sqlc"`
+	// DB is the directory of the Go package sqlc generates, imported by the
+	// generated Go code.
+	DB string `yaml:"db"`
+	// Report is the path of report.json, relative to --output.
+	Report string `yaml:"report"`
+	// SplitQueries writes the queries of each app to <app>.sql next to
+	// Queries, which keeps the queries belonging to no app.
+	SplitQueries bool `yaml:"split_queries"`
+}
+
+// defaultLayout is the layout of the files when the config sets none.
+var defaultLayout = Layout{
+	Schema:     "schema.sql",
+	Migrations: "migrations",
+	Queries:    "query.sql",
+	Reports:    "reports.sql",
+	SQLC:       "sqlc.yaml",
+	DB:         "db",
+	Report:     "report.json",
+}
+
+// validate checks that every path stays inside the output directory.
+func (l Layout) validate() error {
+	paths := []struct{ key, path string }{
+		{"schema", l.Schema}, {"migrations", l.Migrations}, {"queries", l.Queries}, {"reports", l.Reports},
+		{"sqlc", l.SQLC}, {"db", l.DB}, {"report", l.Report},
+	}
+	for _, p := range paths {
+		if !filepath.IsLocal(p.path) {
+			return fmt.Errorf("layout: %s: %q is not a relative path inside the output directory", p.key, p.path)
+		}
+	}
+	if l.Queries == l.Reports {
+		return fmt.Errorf("layout: queries and reports are both %q", l.Queries)
+	}
+	return nil
+}
+
+// sibling returns the path of a file next to the schema, such as
+// unmanaged.sql.
+func (l Layout) sibling(name string) string {
+	return filepath.Join(filepath.Dir(l.Schema), name)
+}
+
+// importPath returns the import path of the Go package in dir, relative to
+// the output directory, of the module rooted there.
+func (l Layout) importPath(module, dir string) string {
+	return path.Join(module, filepath.ToSlash(dir))
+}
+
+// queryFiles assigns the queries to the query files: the analytical ones to
+// Reports, those of each app to its own file with SplitQueries, and the
+// others to Queries. The files are returned in the order they are first
+// used, Queries always being one of them.
+func (l Layout) queryFiles(queries []TranslatedQuery) ([]string, map[string][]TranslatedQuery) {
+	files := []string{l.Queries}
+	byFile := map[string][]TranslatedQuery{}
+	for _, q := range queries {
+		file := l.Queries
+		switch {
+		case isReport(q):
+			file = l.Reports
+		case l.SplitQueries && q.App != "":
+			file = filepath.Join(filepath.Dir(l.Queries), q.App+".sql")
+		}
+		if _, ok := byFile[file]; !ok && file != l.Queries {
+			files = append(files, file)
+		}
+		byFile[file] = append(byFile[file], q)
+	}
+	return files, byFile
+}
+
+// sqlcPath returns the path of a file of the tree relative to sqlc.yaml, as
+// sqlc resolves it.
+func (l Layout) sqlcPath(file string) string {
+	rel, err := filepath.Rel(filepath.Dir(l.SQLC), file)
+	if err != nil {
+		// Both are relative to the output directory.
+		rel = file
+	}
+	rel = filepath.ToSlash(rel)
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
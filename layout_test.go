@@ -0,0 +1,57 @@
+// layout_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLayoutValidate checks that every path must stay inside the output
+// directory and that queries and reports need their own files.
+func TestLayoutValidate(t *testing.T) {
+	if err := defaultLayout.validate(); err != nil {
+		t.Errorf("default layout: %v", err)
+	}
+	l := defaultLayout
+	l.Schema = "../schema.sql"
+	if err := l.validate(); err == nil || !strings.Contains(err.Error(), "schema") {
+		t.Errorf("escaping schema: %v", err)
+	}
+	l = defaultLayout
+	l.Reports = l.Queries
+	if err := l.validate(); err == nil {
+		t.Error("queries and reports sharing a file passed")
+	}
+}
+
+// TestLayoutPaths checks split query files, the sqlc.yaml relative paths
+// and the import path of the db package.
+func TestLayoutPaths(t *testing.T) {
+	l := Layout{Schema: "sql/schema.sql", Migrations: "sql/migrations", Queries: "sql/queries/query.sql", Reports: "sql/queries/reports.sql",
+		SQLC: "sqlc/sqlc.yaml", DB: "internal/db", Report: "report.json", SplitQueries: true}
+	queries := []TranslatedQuery{
+		{Query: Query{App: "blog", File: "blog/views.py"}},
+		{Query: Query{App: "shop", File: "shop/reports.py"}},
+		{Name: "GetPostBySlug"},
+	}
+	files, byFile := l.queryFiles(queries)
+	if got := strings.Join(files, " "); got != "sql/queries/query.sql sql/queries/blog.sql sql/queries/reports.sql" {
+		t.Errorf("files = %s", got)
+	}
+	if len(byFile["sql/queries/query.sql"]) != 1 {
+		t.Errorf("query.sql = %+v", byFile["sql/queries/query.sql"])
+	}
+	cfg := generateSQLCConfig(nil, "postgres", l, []string{l.Schema, l.sibling("unmanaged.sql")}, files, nil, "")
+	for _, want := range []string{
+		`queries: ["../sql/queries/query.sql", "../sql/queries/blog.sql", "../sql/queries/reports.sql"]`,
+		`schema: ["../sql/schema.sql", "../sql/unmanaged.sql"]`,
+		`out: "../internal/db"`,
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("sqlc.yaml lacks %s:\n%s", want, cfg)
+		}
+	}
+	if got := l.importPath("example.com/app", l.DB); got != "example.com/app/internal/db" {
+		t.Errorf("import path = %s", got)
+	}
+}
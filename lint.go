@@ -0,0 +1,110 @@
+// lint.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// migrationLints match the statements of incremental migrations that lock
+// or rewrite tables with rows, per dialect ("" for both).
+var migrationLints = []struct {
+	dialect string
+	code    string
+	re      *regexp.Regexp
+}{
+	{"postgres", codeAddDefault, regexp.MustCompile(`^ALTER TABLE (\S+) ADD COLUMN (\S+) .*\bDEFAULT\b`)},
+	{"postgres", codeTableRewrite, regexp.MustCompile(`^ALTER TABLE (\S+) ALTER COLUMN (\S+) TYPE (.+);$`)},
+	{"mysql", codeTableRewrite, regexp.MustCompile(`^ALTER TABLE (\S+) MODIFY COLUMN (\S+) (.+);$`)},
+	{"postgres", codeSetNotNull, regexp.MustCompile(`^ALTER TABLE (\S+) ALTER COLUMN (\S+) SET NOT NULL;$`)},
+	{"", codeForeignKeyLock, regexp.MustCompile(`^ALTER TABLE (\S+) ADD FOREIGN KEY \((.+?)\)`)},
+	{"", codeDropInUse, regexp.MustCompile(`^ALTER TABLE (\S+) DROP COLUMN (\S+);$`)},
+	{"", codeDropInUse, regexp.MustCompile(`^DROP TABLE IF EXISTS (\S+);$`)},
+}
+
+// lintMigrations reports the statements of the up migrations that would
+// lock large tables or break the running code, with the safer order to
+// apply them in. The migrations are keyed by file name within dir.
+func lintMigrations(files map[string]string, dir, dialect string) []Diagnostic {
+	var names []string
+	for name := range files {
+		if strings.HasSuffix(name, ".up.sql") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var diags []Diagnostic
+	for _, name := range names {
+		for i, line := range strings.Split(files[name], "\n") {
+			for _, lint := range migrationLints {
+				if lint.dialect != "" && lint.dialect != dialect {
+					continue
+				}
+				m := lint.re.FindStringSubmatch(line)
+				if m == nil || lint.code == codeTableRewrite && !rewrites(m[3], files[strings.TrimSuffix(name, ".up.sql")+".down.sql"], m[1], m[2], dialect) {
+					continue
+				}
+				diags = append(diags, Diagnostic{
+					Code: lint.code, Severity: severity(lint.code), File: path.Join(filepath.ToSlash(dir), name), Line: i + 1,
+					Message: lintMessage(lint.code, m),
+				})
+			}
+		}
+	}
+	return diags
+}
+
+// collateClause matches the COLLATE clause of a column type.
+var collateClause = regexp.MustCompile(` COLLATE \S+$`)
+
+// rewrites reports whether changing a column to the given type rewrites its
+// table. The former type is read from the statement reverting the change in
+// the down migration; only widening a VARCHAR, making it TEXT or changing
+// the collation alone is known to keep the rows in place on PostgreSQL.
+// MySQL rebuilds the table for any MODIFY COLUMN.
+func rewrites(to, down, table, column, dialect string) bool {
+	if dialect == "mysql" {
+		return true
+	}
+	re := regexp.MustCompile(`(?m)^ALTER TABLE ` + regexp.QuoteMeta(table) + ` ALTER COLUMN ` + regexp.QuoteMeta(column) + ` TYPE (.+);$`)
+	m := re.FindStringSubmatch(down)
+	if m == nil {
+		return true
+	}
+	from, to := collateClause.ReplaceAllString(m[1], ""), collateClause.ReplaceAllString(to, "")
+	if from == to {
+		return false
+	}
+	fromLen, fromVarchar := varcharLength(from)
+	toLen, toVarchar := varcharLength(to)
+	return !(fromVarchar && (to == "TEXT" || toVarchar && toLen >= fromLen))
+}
+
+// lintMessage describes a lint and the safer alternative.
+func lintMessage(code string, m []string) string {
+	switch code {
+	case codeAddDefault:
+		return fmt.Sprintf("adding %s.%s with a DEFAULT rewrites the table before PostgreSQL 11; "+
+			"on older servers add it without the default, backfill it, then SET DEFAULT", m[1], m[2])
+	case codeTableRewrite:
+		return fmt.Sprintf("changing %s.%s to %s rewrites the table under lock; "+
+			"add a new column instead, backfill it, move the code over, then drop the old one", m[1], m[2], m[3])
+	case codeSetNotNull:
+		return fmt.Sprintf("SET NOT NULL on %[1]s.%[2]s scans the table under an exclusive lock; "+
+			"first ADD CONSTRAINT %[1]s_%[2]s_not_null CHECK (%[2]s IS NOT NULL) NOT VALID, "+
+			"then VALIDATE CONSTRAINT it in a separate migration, which PostgreSQL 12+ uses to skip the scan", m[1], m[2])
+	case codeForeignKeyLock:
+		return fmt.Sprintf("adding a foreign key on %s (%s) checks every row while locking both tables; "+
+			"on PostgreSQL add it NOT VALID, then VALIDATE CONSTRAINT in a separate migration", m[1], m[2])
+	}
+	what := m[1]
+	if len(m) > 2 {
+		what += "." + m[2]
+	}
+	return fmt.Sprintf("dropping %s breaks code still using it; "+
+		"deploy the code that no longer reads it first and apply this migration in a later release", what)
+}
@@ -0,0 +1,49 @@
+// lint_test.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestLintMigrations checks the lints reported for each statement of the
+// up migrations, and that only rewriting type changes are flagged.
+func TestLintMigrations(t *testing.T) {
+	files := map[string]string{
+		"1_alter_tables.up.sql": `ALTER TABLE post ADD COLUMN status TEXT DEFAULT 'draft';
+ALTER TABLE post ALTER COLUMN title TYPE VARCHAR(200);
+ALTER TABLE post ALTER COLUMN views TYPE BIGINT;
+ALTER TABLE post ADD FOREIGN KEY (author_id) REFERENCES author(id);`,
+		"1_alter_tables.down.sql": `ALTER TABLE post ALTER COLUMN views TYPE INTEGER;
+ALTER TABLE post ALTER COLUMN title TYPE VARCHAR(100);`,
+		"2_backfilled_not_null.up.sql": "ALTER TABLE post ALTER COLUMN status SET NOT NULL;",
+		"3_destructive.up.sql": `ALTER TABLE post DROP COLUMN legacy;
+DROP TABLE IF EXISTS tag;`,
+	}
+	var got []string
+	for _, d := range lintMigrations(files, "migrations", "postgres") {
+		got = append(got, fmt.Sprintf("%s:%d %s", d.File, d.Line, d.Code))
+	}
+	want := []string{
+		"migrations/1_alter_tables.up.sql:1 W206",
+		"migrations/1_alter_tables.up.sql:3 W207",
+		"migrations/1_alter_tables.up.sql:4 W209",
+		"migrations/2_backfilled_not_null.up.sql:1 W208",
+		"migrations/3_destructive.up.sql:1 W210",
+		"migrations/3_destructive.up.sql:2 W210",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("lints:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// MySQL has no PostgreSQL-only lints but rebuilds tables on any change.
+	got = nil
+	files["1_alter_tables.up.sql"] = "ALTER TABLE post MODIFY COLUMN title VARCHAR(200);"
+	for _, d := range lintMigrations(files, "migrations", "mysql") {
+		got = append(got, d.Code)
+	}
+	if strings.Join(got, " ") != "W207 W210 W210" {
+		t.Errorf("mysql lints = %v", got)
+	}
+}
@@ -0,0 +1,81 @@
+// lsp.go
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+)
+
+// LSP diagnostic severities.
+const (
+	lspError   = 1
+	lspWarning = 2
+)
+
+// lspFile is the diagnostics of a file as a Language Server Protocol
+// textDocument/publishDiagnostics notification carries them.
+type lspFile struct {
+	URI         string          `json:"uri"`
+	Diagnostics []lspDiagnostic `json:"diagnostics"`
+}
+
+// lspDiagnostic is a diagnostic in the Language Server Protocol's shape.
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+// lspRange spans lines and characters, both counted from 0.
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+// lspPosition is a position in a file.
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// fileURI returns the file: URI of a path.
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// lspDiagnostics groups the diagnostics by file, relative to root, for an
+// editor to show on the lines they were reported on. Every file listed in
+// files is included, without diagnostics when it has none, so that the
+// editor clears the ones it showed before. Diagnostics on no file, such as
+// those on the settings, go to root itself.
+func lspDiagnostics(root string, files []string, diags []Diagnostic) []lspFile {
+	byFile := map[string][]lspDiagnostic{}
+	for _, f := range files {
+		byFile[f] = []lspDiagnostic{}
+	}
+	for _, d := range diags {
+		severity := lspWarning
+		if d.Severity == "error" {
+			severity = lspError
+		}
+		// The line as a range covering all of it, or the start of the file
+		// when unknown.
+		line := max(d.Line-1, 0)
+		byFile[d.File] = append(byFile[d.File], lspDiagnostic{
+			Range:    lspRange{Start: lspPosition{Line: line}, End: lspPosition{Line: line + 1}},
+			Severity: severity,
+			Code:     d.Code,
+			Source:   "django2go",
+			Message:  d.located(),
+		})
+	}
+	result := make([]lspFile, 0, len(byFile))
+	for file, fileDiags := range byFile {
+		result = append(result, lspFile{URI: fileURI(filepath.Join(root, file)), Diagnostics: fileDiags})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].URI < result[j].URI })
+	return result
+}
@@ -0,0 +1,35 @@
+// lsp_test.go
+package main
+
+import "testing"
+
+// TestLSPDiagnostics checks the grouping by file, the ranges and that
+// files without diagnostics are listed to clear the editor's.
+func TestLSPDiagnostics(t *testing.T) {
+	diags := []Diagnostic{
+		{File: "blog/models.py", Line: 5, Severity: "error", Code: codeTableCollision, App: "blog", Model: "Post", Message: "table post collides with blog.Article"},
+		{File: "", Severity: "warning", Code: codeCrossDatabase, Message: "settings"},
+	}
+	got := lspDiagnostics("/srv/my project", []string{"blog/models.py", "shop/models.py"}, diags)
+	want := []lspFile{
+		{URI: "file:///srv/my%20project", Diagnostics: []lspDiagnostic{{Severity: lspWarning, Code: codeCrossDatabase, Source: "django2go", Message: "settings",
+			Range: lspRange{End: lspPosition{Line: 1}}}}},
+		{URI: "file:///srv/my%20project/blog/models.py", Diagnostics: []lspDiagnostic{{Severity: lspError, Code: codeTableCollision, Source: "django2go",
+			Message: "blog.Post: table post collides with blog.Article", Range: lspRange{Start: lspPosition{Line: 4}, End: lspPosition{Line: 5}}}}},
+		{URI: "file:///srv/my%20project/shop/models.py", Diagnostics: []lspDiagnostic{}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("files = %+v", got)
+	}
+	for i := range want {
+		if got[i].URI != want[i].URI || len(got[i].Diagnostics) != len(want[i].Diagnostics) {
+			t.Errorf("file %d = %+v, want %+v", i, got[i], want[i])
+			continue
+		}
+		for j := range want[i].Diagnostics {
+			if got[i].Diagnostics[j] != want[i].Diagnostics[j] {
+				t.Errorf("%s diagnostic %d = %+v, want %+v", want[i].URI, j, got[i].Diagnostics[j], want[i].Diagnostics[j])
+			}
+		}
+	}
+}
@@ -3,12 +3,17 @@ package main
 
 import (
 	"bytes"
+	_ "embed"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 )
@@ -19,28 +24,333 @@ type Field struct {
 	Type      string `json:"type"`
 	Nullable  bool   `json:"nullable"`
 	Unique    bool   `json:"unique"`
+	MaxLength int    `json:"max_length,omitempty"`
+	Line      int    `json:"line,omitempty"`
 	Relation  string `json:"relation,omitempty"`
 	RelatedTo string `json:"related_to,omitempty"`
+	// RelatedPK holds the primary key columns of the related model, filled
+	// in by resolveRelations.
+	RelatedPK []Column `json:"related_pk,omitempty"`
+	// RelatedTable is the table of the related model, filled in by
+	// resolveRelations.
+	RelatedTable string `json:"related_table,omitempty"`
+	// BaseType is the type of an ArrayField's base field.
+	BaseType string `json:"base_type,omitempty"`
+	// DBType overrides the column type derived from Type, as set by
+	// applyDialectTypes.
+	DBType string `json:"db_type,omitempty"`
+	// PrimaryKey is set for fields declared with primary_key=True.
+	PrimaryKey bool `json:"primary_key,omitempty"`
+	// Default is the field's default when it is a literal.
+	Default any `json:"default,omitempty"`
+	// DefaultCallable is the dotted name of the callable passed as the
+	// field's default, such as uuid.uuid4 or timezone.now.
+	DefaultCallable string `json:"default_callable,omitempty"`
+	// MaxDigits and DecimalPlaces are the precision and scale of a
+	// DecimalField.
+	MaxDigits     int `json:"max_digits,omitempty"`
+	DecimalPlaces int `json:"decimal_places,omitempty"`
+	// Protocol is the protocol of a GenericIPAddressField: both, IPv4 or
+	// IPv6.
+	Protocol string `json:"protocol,omitempty"`
+	// UploadTo is the upload_to of a FileField or ImageField when it is a
+	// literal prefix rather than a callable.
+	UploadTo string `json:"upload_to,omitempty"`
+	// Choices are the field's choices when they are literal values.
+	Choices []Choice `json:"choices,omitempty"`
+	// Enum is set when the choices are stored as an enum type instead of
+	// being checked, as set by applyDialectTypes.
+	Enum bool `json:"enum,omitempty"`
+	// PII is set for fields holding personal data, as set by applyPII.
+	PII bool `json:"pii,omitempty"`
+	// AutoNow and AutoNowAdd are set for date and time fields Django sets to
+	// the current time on every save and on creation.
+	AutoNow    bool `json:"auto_now,omitempty"`
+	AutoNowAdd bool `json:"auto_now_add,omitempty"`
+	// SRID, Geography and SpatialIndex are the options of a GeoDjango
+	// geometry field.
+	SRID         int  `json:"srid,omitempty"`
+	Geography    bool `json:"geography,omitempty"`
+	SpatialIndex bool `json:"spatial_index,omitempty"`
+	// RangeIndex is set for range fields declared with db_index=True, which
+	// are indexed with GiST rather than Django's B-tree.
+	RangeIndex bool `json:"range_index,omitempty"`
+	// DBIndex is set for fields declared with db_index=True, indexed by
+	// applyDBIndexes.
+	DBIndex bool `json:"db_index,omitempty"`
+	// Collation is the field's db_collation, resolved for the dialect by
+	// applyDialectTypes.
+	Collation string `json:"db_collation,omitempty"`
+	// DefaultCurrency and CurrencyMaxLength are the options of a
+	// django-money MoneyField, expanded by expandMoneyFields.
+	DefaultCurrency   string `json:"default_currency,omitempty"`
+	CurrencyMaxLength int    `json:"currency_max_length,omitempty"`
+	// OutputField, Expression and DBPersist are the options of a Django 5.0
+	// GeneratedField. applyDjangoVersion gives it the output field's type,
+	// sets Generated and translates the expression into GeneratedSQL.
+	OutputField  string `json:"output_field,omitempty"`
+	Expression   *Expr  `json:"expression,omitempty"`
+	DBPersist    bool   `json:"db_persist,omitempty"`
+	Generated    bool   `json:"generated,omitempty"`
+	GeneratedSQL string `json:"generated_sql,omitempty"`
+	// OnUpdate is set for auto_now fields the database updates itself, as
+	// set by applyDialectTypes with --auto-now-triggers.
+	OnUpdate bool `json:"on_update,omitempty"`
+}
+
+// relatedTable returns the table of the related model.
+func (f Field) relatedTable() string {
+	if f.RelatedTable != "" {
+		return f.RelatedTable
+	}
+	return toSnake(f.RelatedTo)
+}
+
+// Column is a database column name with the Django type it was derived from.
+type Column struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	DBType string `json:"db_type,omitempty"`
+}
+
+// sqlType returns the column type for the dialect, preferring an explicit
+// DBType.
+func (c Column) sqlType(dialect string) string {
+	if c.DBType != "" {
+		return c.DBType
+	}
+	return sqlType(c.Type, dialect)
 }
 
 // Model represents a Django model with its fields.
 type Model struct {
-	Name   string  `json:"name"`
-	Fields []Field `json:"fields"`
+	Name       string   `json:"name"`
+	App        string   `json:"app"`
+	File       string   `json:"file"`
+	Line       int      `json:"line,omitempty"`
+	Managed    bool     `json:"managed"`
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	// Label is the app's AppConfig.label, when its apps.py sets one.
+	Label string `json:"label,omitempty"`
+	// AutoField is the type of the implicit id primary key, from the app's
+	// AppConfig.default_auto_field or the DEFAULT_AUTO_FIELD setting.
+	AutoField string `json:"auto_field,omitempty"`
+	// OrderWithRespectTo names the relation set in Meta.order_with_respect_to.
+	// Django then adds an implicit _order column used as default ordering.
+	OrderWithRespectTo string `json:"order_with_respect_to,omitempty"`
+	// Ordering is Meta.ordering when it is a literal list of field names.
+	Ordering []string `json:"ordering,omitempty"`
+	// DBTable is Meta.db_table, the table name in the Django database.
+	DBTable string `json:"db_table,omitempty"`
+	// Table is the name of the generated table: DBTable when set, else the
+	// snake_cased model name, as set by applyTableNames.
+	Table string `json:"table,omitempty"`
+	// View is the SELECT defining a read-only view model, loaded from
+	// ViewFile when set by a "# django2go: view <file>" hint or the config.
+	View        string       `json:"view,omitempty"`
+	ViewFile    string       `json:"view_file,omitempty"`
+	Fields      []Field      `json:"fields"`
+	Properties  []Property   `json:"properties,omitempty"`
+	Constraints []Constraint `json:"constraints,omitempty"`
+	Indexes     []Index      `json:"indexes,omitempty"`
+	// IndexTogether lists the field groups of Meta.index_together, turned
+	// into Indexes by applyDjangoVersion.
+	IndexTogether [][]string `json:"index_together,omitempty"`
+	// UniqueTogether lists the field groups of Meta.unique_together, turned
+	// into unique Constraints by applyUniqueTogether.
+	UniqueTogether [][]string `json:"unique_together,omitempty"`
+	// Aliases names the proxy models of the model, which share its table;
+	// queries and relations naming them resolve to the model.
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// table returns the name of the model's table. Snapshots written before
+// Table existed name every table after its model.
+func (m Model) table() string {
+	if m.Table != "" {
+		return m.Table
+	}
+	return toSnake(m.Name)
+}
+
+// djangoTable returns the model's table name in the Django database:
+// Meta.db_table, or the app label and the lowercased model name.
+func djangoTable(m Model) string {
+	if m.DBTable != "" {
+		return m.DBTable
+	}
+	label := m.Label
+	if label == "" {
+		label = m.App
+	}
+	return label + "_" + strings.ToLower(m.Name)
+}
+
+// defaultOrder returns the ORDER BY columns Django applies to the model when
+// a queryset has no explicit ordering, or nil: _order, or those of
+// Meta.ordering.
+func (m Model) defaultOrder() []string {
+	if m.OrderWithRespectTo != "" {
+		return []string{"_order"}
+	}
+	order, _, _ := m.orderingColumns()
+	return order
+}
+
+// orderingColumns returns the ORDER BY columns of Meta.ordering, descending
+// for the names starting with "-". They stop before the first name that is
+// not a column, such as a relation traversal or "?", which is returned with
+// the reason, since leaving it out would sort on the names after it first.
+func (m Model) orderingColumns() (order []string, skipped string, err error) {
+	for _, name := range m.Ordering {
+		dir := ""
+		if strings.HasPrefix(name, "-") {
+			name, dir = name[1:], " DESC"
+		}
+		cols, err := resolveColumns(name, m)
+		if err != nil {
+			return order, name, err
+		}
+		for _, c := range cols {
+			order = append(order, c+dir)
+		}
+	}
+	return order, "", nil
+}
+
+// field returns the model field with the given name.
+func (m Model) field(name string) (Field, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// autoField returns the type of the implicit id primary key, AutoField
+// unless the project sets another one.
+func (m Model) autoField() string {
+	if _, ok := autoTypes[m.AutoField]; ok {
+		return m.AutoField
+	}
+	return "AutoField"
+}
+
+// idDef returns the definition of the implicit id primary key column.
+func (m Model) idDef(dialect string) string {
+	return "id " + sqlType(m.autoField(), dialect) + " PRIMARY KEY"
+}
+
+// pkColumns returns the primary key columns of the model: the implicit id
+// column, or the columns of a configured composite key. Auto-incrementing
+// columns are given the integer type of their range.
+func (m Model) pkColumns() []Column {
+	if len(m.PrimaryKey) == 0 {
+		return []Column{{Name: "id", Type: autoTypes[m.autoField()]}}
+	}
+	var cols []Column
+	for _, name := range m.PrimaryKey {
+		f, _ := m.field(name)
+		for _, c := range fieldColumns(f) {
+			if t, ok := autoTypes[c.Type]; ok {
+				c.Type = t
+			}
+			cols = append(cols, c)
+		}
+	}
+	return cols
+}
+
+// Options holds the generation settings taken from the command line.
+type Options struct {
+	Dialect string
+	// ComputedColumns controls how translatable properties are exposed:
+	// "" (not at all), "generated" (generated columns) or "view".
+	ComputedColumns string
+	// StrictChecks adds CHECK constraints validating the EmailField,
+	// URLField and SlugField columns.
+	StrictChecks bool
+	// CharAsText keeps CharFields as TEXT columns instead of
+	// VARCHAR(max_length).
+	CharAsText bool
+	// ColumnAliases is the --column-aliases strategy naming the selected
+	// columns.
+	ColumnAliases string
+	// Choices is the --choices strategy restricting columns to the values
+	// of their field's choices: check or enum.
+	Choices string
+	// NaiveDateTimes stores DateTimeFields without a time zone, as Django
+	// does with USE_TZ = False.
+	NaiveDateTimes bool
+	// AutoNowTriggers keeps auto_now fields current on UPDATE in the
+	// database too.
+	AutoNowTriggers bool
+	// Django is the project's Django version.
+	Django djangoVersion
+	// NoAppPrefix names the tables after the snake_cased model name alone,
+	// as --no-app-prefix does, rather than like Django.
+	NoAppPrefix bool
 }
 
 // Output represents the output from the Python parser, including models and queries.
 type Output struct {
-	Models  []Model  `json:"models"`
-	Queries []string `json:"queries"`
+	IRVersion    int          `json:"ir_version"`
+	Capabilities []string     `json:"capabilities"`
+	Models       []Model      `json:"models"`
+	Queries      []Query      `json:"queries"`
+	Admins       []Admin      `json:"admins"`
+	Commands     []Command    `json:"commands"`
+	Schedules    []Schedule   `json:"schedules"`
+	Migrations   []Migration  `json:"migrations"`
+	Settings     Settings     `json:"settings"`
+	Diagnostics  []Diagnostic `json:"diagnostics"`
 }
 
 // main is the entry point of the CLI application.
 func main() {
-	input := flag.String("input", "", "Path to Django app (required)")
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		runSeed(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "triage" {
+		runTriage(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+
+	input := flag.String("input", "", "Path to Django app, or to the schema dump with --source sqldump (required)")
+	source := flag.String("source", "django", "Input to read: django models or an sqldump from pg_dump/mysqldump")
 	output := flag.String("output", "./out", "Output directory")
 	dialect := flag.String("dialect", "postgres", "SQL dialect: postgres or mysql")
 	dryRun := flag.Bool("dry-run", false, "Dry run mode (prints output to stdout without writing files)")
+	configPath := flag.String("config", "", "Path to a YAML config file with model overrides")
+	computed := flag.String("computed-columns", "", "Expose simple computed properties as columns: generated or view")
+	maxWarnings := flag.Int("max-warnings", -1, "Fail when more warnings are reported (-1 for no limit)")
+	sessions := flag.Bool("sessions", false, "Generate the django_session table and session queries")
+	cache := flag.Bool("cache", false, "Generate the DatabaseCache tables and cache queries")
+	goModule := flag.String("go-module", "app", "Module path of the Go port, used to import the generated packages")
+	errorOn := flag.String("error-on", "", "Comma-separated warning codes to treat as errors, e.g. W201,W204")
+	diff := flag.String("diff", "", "Snapshot of an earlier run to generate an incremental migration against")
+	parserScriptPath := flag.String("parser-script", "", "Python script to run instead of the built-in parser, e.g. an extended copy of it")
+	parserEnv := parserEnvFlags(flag.CommandLine)
+	python2Models := flag.Bool("python2-models", false, "Read models files written in Python 2 syntax instead of skipping them")
+	allApps := flag.Bool("all-apps", false, "Generate the apps missing from INSTALLED_APPS too")
+	maxDepth := flag.Int("max-depth", 0, "Read the input no deeper than this many directories below it (0 for no limit)")
+	allowDestructive := flag.Bool("allow-destructive", false, "Allow --diff migrations that drop tables or columns or narrow column types")
+	columnAliases := flag.String("column-aliases", aliasNone, "Alias selected columns so sqlc names struct fields after them: none or attribute (the Django attribute names)")
+	charAsText := flag.Bool("char-as-text", false, "Generate CharFields as TEXT instead of VARCHAR(max_length)")
+	strict := flag.Bool("strict", false, "Fail on field types stored as TEXT for want of knowing them, like --error-on W110")
+	strictChecks := flag.Bool("strict-checks", false, "Add CHECK constraints validating EmailField, URLField and SlugField values")
+	choices := flag.String("choices", choicesCheck, "Restrict columns to their field's choices: check (CHECK constraints) or enum (enum types for string choices)")
+	autoNowTriggers := flag.Bool("auto-now-triggers", false, "Keep auto_now fields current on UPDATE: with a trigger on PostgreSQL, ON UPDATE CURRENT_TIMESTAMP on MySQL")
+	merge := flag.Bool("merge-queries", false, "Keep the hand-written queries of existing query files, regenerating only their marked generated section")
+	profile := flag.String("profile", "", "Profile of the config to generate with, bundling emit targets and flags")
+	noAppPrefix := flag.Bool("no-app-prefix", false, "Name tables after the model alone rather than <app_label>_<model> like Django")
+	profileOutput := flag.String("profile-output", "", "Directory to write CPU and heap profiles and the time of each phase of the run to, e.g. prof/")
 
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), `Usage of %s:
@@ -48,7 +358,7 @@ func main() {
 		fmt.Println("A CLI tool to convert Django models into SQL and sqlc configurations.")
 		fmt.Println("Flags:")
 		flag.PrintDefaults()
-		fmt.Println(`
+		fmt.Print(`
 Example:
   go run main.go --input ./myapp --output ./out --dialect postgres
 `)
@@ -56,19 +366,171 @@ Example:
 
 	flag.Parse()
 
+	// The dump may also follow --source, as in --source sqldump schema.sql.
+	if *input == "" && *source == "sqldump" && flag.NArg() > 0 {
+		*input = flag.Arg(0)
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
 	if *input == "" {
 		fmt.Println("Error: --input is required")
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+	if _, err := os.Stat(*input); err != nil {
+		fail(exitUsage, "Error: --input: %v", err)
+	}
+	var prof *profiler
+	if *profileOutput != "" {
+		var err error
+		if prof, err = startProfiling(*profileOutput); err != nil {
+			fail(exitIO, "Error: --profile-output: %v", err)
+		}
+		atExit = prof.stop
+		defer prof.stop()
+	}
+	prof.begin("config")
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fail(exitUsage, "Config error: %v", err)
+	}
+	if err := cfg.applyProfile(*profile, flag.CommandLine); err != nil {
+		fail(exitUsage, "Config error: %v", err)
+	}
+	goNames = cfg.GoNames
+
+	if *source != "django" && *source != "sqldump" {
+		fail(exitUsage, "Error: --source must be django or sqldump")
+	}
+	if !slices.Contains(dialects, *dialect) {
+		fail(exitUsage, "Error: --dialect must be one of %s", strings.Join(dialects, ", "))
+	}
+	if *computed != "" && *computed != "generated" && *computed != "view" {
+		fail(exitUsage, "Error: --computed-columns must be generated or view")
+	}
+	if *columnAliases != aliasNone && *columnAliases != aliasAttribute {
+		fail(exitUsage, "Error: --column-aliases must be none or attribute")
+	}
+	if *choices != choicesCheck && *choices != choicesEnum {
+		fail(exitUsage, "Error: --choices must be check or enum")
+	}
+	if *maxDepth < 0 {
+		fail(exitUsage, "Error: --max-depth must not be negative")
 	}
+	opts := Options{Dialect: *dialect, ComputedColumns: *computed, StrictChecks: *strictChecks, CharAsText: *charAsText, ColumnAliases: *columnAliases, Choices: *choices, AutoNowTriggers: *autoNowTriggers, NoAppPrefix: *noAppPrefix}
+	errorCodes, err := parseCodes(*errorOn)
+	if err != nil {
+		fail(exitUsage, "Error: --error-on: %v", err)
+	}
+	if *strict {
+		errorCodes = append(errorCodes, codeUnknownFieldType)
+	}
+	policy := DiagnosticPolicy{MaxWarnings: *maxWarnings, ErrorOn: errorCodes}
 
-	// Run Python parser
-	out, err := runPythonParser(*input)
+	header, err := newHeader(cfg.Header, *input, flag.CommandLine)
 	if err != nil {
-		fmt.Printf("Parser error: %v\n", err)
-		os.Exit(1)
+		fail(exitUsage, "Config error: %v", err)
 	}
 
+	// Run Python parser, or reverse the schema dump
+	prof.begin("parse")
+	var out *Output
+	if *source == "sqldump" {
+		out, err = parseSQLDump(*input)
+	} else {
+		out, err = runPythonParser(*input, *parserScriptPath, parserArgs(*python2Models, *maxDepth), parserEnv(), generateCapabilities...)
+	}
+	if err != nil {
+		fail(exitParse, "Parser error: %v", err)
+	}
+	if cfg.DjangoVersion != "" {
+		out.Settings.DjangoVersion = cfg.DjangoVersion
+	}
+	var skippedApps []string
+	if !*allApps {
+		skippedApps = skipUninstalledApps(out)
+	}
+	opts.Django = out.Settings.django()
+	opts.NaiveDateTimes = !out.Settings.useTZ()
+	prof.begin("normalize")
+	diags := append(out.Diagnostics, normalize(cfg, out.Models, opts)...)
+	if hasErrors(diags) {
+		checkDiagnostics(diags, policy, exitDiagnostics)
+	}
+	pending := applyWorkspace(cfg, out)
+	diags = append(diags, schemaDiagnostics(out.Models, *dialect)...)
+	tables, tableDiags := systemTables(out.Settings, *sessions, *cache)
+	diags = append(diags, tableDiags...)
+
+	prof.begin("queries")
+	queries, props, queryDiags := collectQueries(out, pending, tables, opts)
+	diags = append(diags, queryDiags...)
+	scheduler, schedulerDiags, err := generateScheduler(out.Schedules, *goModule, cfg.Layout)
+	if err != nil {
+		fail(exitError, "Error: %v", err)
+	}
+	diags = append(diags, schedulerDiags...)
+
+	// Each database gets its own tree. Unmanaged models map to existing
+	// tables, so they get no migrations and their DDL is only written for
+	// sqlc to read.
+	prof.begin("plan")
+	uniqueNames(queries)
+	targets := splitDatabases(cfg, out.Models, queries, *output, *diff)
+	// With django-tenants, the default database's models are split between
+	// the public schema and the schema of every tenant.
+	tenants := out.Settings.tenants() && *dialect == "postgres"
+	diags = append(diags, tenantDiagnostics(out.Settings, *dialect)...)
+	if tenants {
+		targets[0].managed, targets[0].tenant = splitTenants(out.Settings, targets[0].managed)
+	}
+	if *diff != "" {
+		for i := range targets {
+			t := &targets[i]
+			prev, err := loadSnapshot(t.snapshot)
+			if t.name != defaultDatabase && errors.Is(err, fs.ErrNotExist) {
+				// A database added since the snapshot starts out empty.
+				prev, err = &Snapshot{Dialect: *dialect}, nil
+			}
+			if err != nil {
+				fail(exitIO, "Error: --diff: %v", err)
+			}
+			if prev.Dialect != *dialect {
+				fail(exitUsage, "Error: --diff: snapshot is for %s, not %s", prev.Dialect, *dialect)
+			}
+			t.checksum = prev.Checksum
+			migrations := filepath.Join(t.dir, cfg.Layout.Migrations)
+			now := versionAfter(migrations, versionAfter(filepath.Join(migrations, "tenant"), time.Now()))
+			shared, tenant := prev.Models, []Model(nil)
+			if len(t.tenant) > 0 {
+				shared, tenant = splitTenants(out.Settings, prev.Models)
+			}
+			t.steps = diffModels(shared, t.managed, cfg.Renames, opts)
+			t.tenantSteps = diffModels(tenant, t.tenant, cfg.Renames, opts)
+			diags = append(diags, renameDiagnostics(append(slices.Clip(t.steps), t.tenantSteps...))...)
+			if !*allowDestructive {
+				diags = append(diags, destructiveDiagnostics(append(slices.Clip(t.steps), t.tenantSteps...))...)
+			}
+			t.diffFiles = header.files(diffMigrations(t.steps, t.snapshot, now))
+			for name, sql := range header.files(diffMigrations(t.tenantSteps, t.snapshot, now)) {
+				t.diffFiles["tenant/"+name] = sql
+			}
+			lints := lintMigrations(t.diffFiles, cfg.Layout.Migrations, *dialect)
+			for j := range lints {
+				if t.name != defaultDatabase {
+					lints[j].File = filepath.Join(t.name, lints[j].File)
+				}
+			}
+			diags = append(diags, lints...)
+		}
+	}
+	diags = checkDiagnostics(diags, policy, exitDiagnostics)
+	report := buildReport(out.Models, queries)
+	report.Compat.ComputedProperties = props
+	report.Compat.UnknownFieldTypes = unknownFieldTypes(diags)
+	report.SkippedApps = skippedApps
+	report.Diagnostics = diags
+
 	if *dryRun {
 		fmt.Println("=== Models ===")
 		for _, m := range out.Models {
@@ -76,28 +538,305 @@ Example:
 		}
 		fmt.Println("=== Queries ===")
 		for _, q := range out.Queries {
-			fmt.Println(q)
+			fmt.Printf("%s:%d: %s\n", q.File, q.Line, q.Source)
 		}
+		printReport(report)
 		return
 	}
 
-	// Prepare output directories
-	migrations := filepath.Join(*output, "migrations")
-	os.MkdirAll(migrations, 0755)
-
 	// Generate and write files
-	write(filepath.Join(*output, "schema.sql"), generateSQL(out.Models, *dialect))
-	write(filepath.Join(migrations, timestamp()+"_create_tables.up.sql"), generateSQL(out.Models, *dialect))
-	write(filepath.Join(migrations, timestamp()+"_create_tables.down.sql"), generateDownSQL(out.Models, *dialect))
-	write(filepath.Join(*output, "query.sql"), strings.Join(out.Queries, "\n\n"))
-	write(filepath.Join(*output, "sqlc.yaml"), generateSQLCConfig(*dialect))
+	prof.begin("write")
+	style := cfg.SQLStyle
+	for _, t := range targets {
+		layout := cfg.Layout
+		migrations := filepath.Join(t.dir, layout.Migrations)
+		os.MkdirAll(migrations, 0755)
+		schema := generateSQL(t.managed, opts)
+		drop := generateDownSQL(t.managed, opts)
+		module := *goModule
+		// The sessions and cache tables live in the default database.
+		system := ""
+		if t.name == defaultDatabase {
+			system = systemTablesSQL(tables)
+			schema += system
+			drop = systemTablesDownSQL(tables) + drop
+		} else {
+			module += "/" + t.name
+		}
+		schemas := []string{layout.Schema}
+		if cfg.Workspace.enabled() {
+			var fragments map[string]string
+			schemas, fragments = schemaFragments(layout, t.managed, opts, system)
+			for _, file := range schemas {
+				write(filepath.Join(t.dir, file), header.apply(file, style.format(fragments[file])))
+			}
+		} else {
+			write(filepath.Join(t.dir, layout.Schema), header.apply(layout.Schema, style.format(schema)))
+		}
+		// The checksum identifies the schema the migrations reach.
+		checksum := schemaChecksum(style.format(schema))
+		if *diff != "" {
+			checksum = recordDiffChecksum(t.diffFiles, style.format(schema), t.steps, t.checksum)
+			for name, sql := range t.diffFiles {
+				t.diffFiles[name] = style.format(sql)
+			}
+			writeFiles(migrations, t.diffFiles)
+			_, notNull, _ := groupSteps(t.steps)
+			_, tenantNotNull, _ := groupSteps(t.tenantSteps)
+			for _, s := range tenantNotNull {
+				s.backfill.tenant = true
+			}
+			notNull = append(notNull, tenantNotNull...)
+			if len(notNull) > 0 {
+				files, err := generateBackfill(notNull, *dialect)
+				if err != nil {
+					fail(exitError, "Error: %v", err)
+				}
+				writeFiles(t.dir, header.files(files))
+				var before []string
+				for name := range t.diffFiles {
+					if strings.HasSuffix(name, "_backfilled_not_null.up.sql") {
+						before = append(before, filepath.Join(migrations, name))
+					}
+				}
+				sort.Strings(before)
+				fmt.Printf("✅ Generated %s for %d new NOT NULL columns; run it before applying %s\n",
+					filepath.Join(t.dir, "backfill"), len(notNull), strings.Join(before, " and "))
+			}
+			if len(t.steps) == 0 && len(t.tenantSteps) == 0 {
+				fmt.Printf("✅ No schema changes since %s\n", t.snapshot)
+			}
+		} else {
+			ts := timestamp()
+			up, down := ts+"_create_tables.up.sql", ts+"_create_tables.down.sql"
+			write(filepath.Join(migrations, up), header.apply(up, style.format(schema+"\n"+checksumSQL(checksum))))
+			write(filepath.Join(migrations, down), header.apply(down, style.format(dropChecksumSQL+drop)))
+			if len(t.tenant) > 0 {
+				os.MkdirAll(filepath.Join(migrations, "tenant"), 0755)
+				write(filepath.Join(migrations, "tenant", up), header.apply(up, style.format(generateSQL(t.tenant, opts))))
+				write(filepath.Join(migrations, "tenant", down), header.apply(down, style.format(generateDownSQL(t.tenant, opts))))
+			}
+		}
+		managed, _ := splitManaged(t.models)
+		snapshot := &Snapshot{Dialect: *dialect, Models: managed, Checksum: checksum}
+		write(filepath.Join(migrations, snapshotFile), snapshot.JSON())
+		version, err := latestMigration(migrations)
+		if err != nil {
+			fail(exitIO, "Error: %v", err)
+		}
+		check, err := generateSchemaCheck(layout, version, checksum)
+		if err != nil {
+			fail(exitError, "Error: %v", err)
+		}
+		writeFiles(t.dir, header.files(check))
+		if len(t.tenant) > 0 {
+			tenantSchema := layout.sibling(tenantSchemaFile)
+			write(filepath.Join(t.dir, tenantSchema), header.apply(tenantSchema, style.format(generateSQL(t.tenant, opts))))
+			schemas = append(schemas, tenantSchema)
+			files, err := generateTenant(module, layout)
+			if err != nil {
+				fail(exitError, "Error: %v", err)
+			}
+			writeFiles(t.dir, header.files(files))
+			fmt.Printf("✅ Generated %s and tenant/ for the %d django-tenants tenant models\n", tenantSchema, len(t.tenant))
+		}
+		if len(t.unmanaged) > 0 {
+			unmanaged := layout.sibling("unmanaged.sql")
+			write(filepath.Join(t.dir, unmanaged), header.apply(unmanaged, style.format(unmanagedHeader+generateSQL(t.unmanaged, opts))))
+			schemas = append(schemas, unmanaged)
+		}
+		if external, file := externalModels(t.models, pending), layout.sibling("external.sql"); len(external) > 0 {
+			write(filepath.Join(t.dir, file), header.apply(file, style.format(externalHeader+generateSQL(external, opts))))
+			schemas = append(schemas, file)
+			fmt.Printf("✅ Generated %s referencing %d tables of apps not converted yet\n", filepath.Join(t.dir, file), len(external))
+		} else if cfg.Workspace.enabled() {
+			// The apps it referenced are all converted now.
+			os.Remove(filepath.Join(t.dir, file))
+		}
+		queryFiles, byFile := layout.queryFiles(t.queries)
+		for _, file := range queryFiles {
+			path := filepath.Join(t.dir, file)
+			render := func(queries []TranslatedQuery) string {
+				return header.apply(file, style.format(generateQueries(queries, t.models)))
+			}
+			if !*merge {
+				write(path, render(byFile[file]))
+				continue
+			}
+			existing, err := os.ReadFile(path)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				fail(exitIO, "Error: %v", err)
+			}
+			content, dropped := mergeQueries(string(existing), byFile[file], render)
+			write(path, content)
+			if len(dropped) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %s: left out %s, named like hand-written queries\n", path, strings.Join(dropped, ", "))
+			}
+		}
+		if reports := byFile[layout.Reports]; len(reports) > 0 {
+			fmt.Printf("✅ Generated %s with %d call sites of report modules and admin actions\n", filepath.Join(t.dir, layout.Reports), len(reports))
+		}
+		choices := ""
+		if cfg.Emit.Choices {
+			files, err := generateChoices(t.models)
+			if err != nil {
+				fail(exitError, "Error: %v", err)
+			}
+			writeFiles(t.dir, header.files(files))
+			if len(files) > 0 {
+				choices = layout.importPath(module, choicesPackage)
+			}
+		}
+		sqlcPath := filepath.Join(t.dir, layout.SQLC)
+		sqlcConfig := generateSQLCConfig(t.models, *dialect, layout, schemas, queryFiles, cfg.TypeOverrides, choices)
+		if existing, err := os.ReadFile(sqlcPath); err == nil {
+			if sqlcConfig, err = mergeSQLCConfig(string(existing), sqlcConfig); err != nil {
+				fail(exitError, "Error: %s: %v", sqlcPath, err)
+			}
+		}
+		write(sqlcPath, header.apply(layout.SQLC, sqlcConfig))
 
-	fmt.Println("✅ Generated schema.sql, migrations, query.sql, sqlc.yaml")
+		testDB, err := generateTestDB(module, layout, *dialect)
+		if err != nil {
+			fail(exitError, "Error: %v", err)
+		}
+		writeFiles(t.dir, header.files(testDB))
+		if cfg.Emit.QueryTests || cfg.Emit.TypeTests {
+			tests, err := generateDBTests(module, layout, t.queries, t.managed, *dialect, cfg.Emit)
+			if err != nil {
+				fail(exitError, "Error: %v", err)
+			}
+			writeFiles(t.dir, header.files(tests))
+		}
+		redact, err := generateRedact(t.models, layout)
+		if err != nil {
+			fail(exitError, "Error: %v", err)
+		}
+		writeFiles(t.dir, header.files(redact))
+		hstore, err := generateHstore(t.models, layout, *dialect)
+		if err != nil {
+			fail(exitError, "Error: %v", err)
+		}
+		writeFiles(t.dir, header.files(hstore))
+		if t.name != defaultDatabase {
+			fmt.Printf("✅ Generated %s for the %s database\n", t.dir, t.name)
+		}
+	}
+	write(filepath.Join(*output, cfg.Layout.Report), report.JSON())
+	prof.begin("emit")
+	emitters := []emitter{{name: "database", gen: func() (map[string]string, error) {
+		return generateDatabase(cfg.Pool, out.Settings, *dialect)
+	}}}
+	if cfg.Emit.OTel {
+		emitters = append(emitters, emitter{name: "telemetry", done: "✅ Generated telemetry/ with OpenTelemetry spans for queries and HTTP handlers",
+			gen: func() (map[string]string, error) { return generateTelemetry(*goModule, *dialect) }})
+	}
+	if cfg.Emit.QueryLog {
+		emitters = append(emitters, emitter{name: "querylog", done: "✅ Generated querylog/ logging queries with slog",
+			gen: func() (map[string]string, error) {
+				return generateQueryLog(cfg.Emit.SlowQuery, sensitiveArgs(queries, out.Models, *dialect))
+			}})
+	}
+	if cfg.Emit.Cache {
+		emitters = append(emitters, emitter{name: "cache", done: fmt.Sprintf("✅ Generated cache/ for %d hot read queries", len(report.HotQueries)),
+			gen: func() (map[string]string, error) { return generateCache(report.HotQueries, queries, cfg.Emit.CacheTTL) }})
+	}
+	if cfg.Emit.Health {
+		emitters = append(emitters, emitter{name: "health", done: "✅ Generated health/ with /healthz and /readyz probes",
+			gen: func() (map[string]string, error) { return generateHealth(*goModule, cfg.Layout) }})
+	}
+	if cfg.Emit.Shadow {
+		emitters = append(emitters, emitter{name: "shadow", done: "✅ Generated shadow/ comparing the Go service with the Django app",
+			gen: generateShadow})
+	}
+	if len(out.Commands) > 0 {
+		emitters = append(emitters, emitter{name: "commands", done: fmt.Sprintf("✅ Generated %d management command stubs in commands/", len(out.Commands)),
+			gen: func() (map[string]string, error) {
+				return generateCommands(out.Commands, *goModule, cfg.Layout, *dialect)
+			}})
+	}
+	emitted, err := runEmitters(emitters)
+	if err != nil {
+		fail(exitError, "Error: %v", err)
+	}
+	for i, e := range emitters {
+		writeFiles(*output, header.files(emitted[i]))
+		if e.done != "" {
+			fmt.Println(e.done)
+		}
+	}
+	if len(scheduler) > 0 {
+		writeFiles(*output, header.files(scheduler))
+		fmt.Println("✅ Generated scheduler/ and tasks/ for the periodic tasks")
+	}
+	if cfg.Emit.Workflow != "" {
+		generate, triage, err := workflowArgs(flag.CommandLine, *output, cfg.Layout)
+		if err != nil {
+			fail(exitError, "Error: workflow: %v", err)
+		}
+		files := generateWorkflow(cfg.Emit.Workflow, Workflow{Generate: generate, Triage: triage, Trees: workflowTrees(targets, *output, cfg.Layout)})
+		writeFiles(*output, header.files(files))
+		for file := range files {
+			fmt.Printf("✅ Generated %s with the regenerate, migrate, sqlc, test and drift-check steps\n", file)
+		}
+	}
+
+	fmt.Println("✅ Generated schema.sql, migrations, query.sql, sqlc.yaml, report.json, dbtest/, database/")
+	printReport(report)
+}
+
+// collectQueries returns the queries of the normalized models: the call
+// sites translated, those derived from the models, properties and
+// ModelAdmins, and those of the system tables, along with the computed
+// properties, and the page queries and admin options skipped.
+func collectQueries(out *Output, pending []Model, tables []systemTable, opts Options) ([]TranslatedQuery, []ComputedProperty, []Diagnostic) {
+	queries := translateQueries(out.Queries, out.Models, opts.Dialect)
+	markPending(queries, pending)
+	props := computedProperties(out.Models, opts.Dialect)
+	queries = append(queries, orderedQueries(out.Models)...)
+	queries = append(queries, propertyQueries(out.Models, props, opts)...)
+	queries = append(queries, viewQueries(out.Models)...)
+	queries = append(queries, slugQueries(out.Models, queries)...)
+	pageQs, pageDiags := pageQueries(out.Models)
+	queries = append(queries, pageQs...)
+	queries = append(queries, relationQueries(out.Models, opts.Dialect)...)
+	adminQs, adminDiags := adminQueries(out.Admins, out.Models, opts.Dialect)
+	queries = append(queries, adminQs...)
+	queries = append(queries, systemQueries(tables, opts.Dialect)...)
+	aliasColumns(queries, out.Models, opts)
+	return queries, props, append(pageDiags, adminDiags...)
+}
+
+// checkDiagnostics sorts and prints the diagnostics after applying the
+// policy, exiting with code when the run must fail.
+func checkDiagnostics(diags []Diagnostic, policy DiagnosticPolicy, code int) []Diagnostic {
+	sortDiagnostics(diags)
+	failure := policy.apply(diags)
+	for _, d := range diags {
+		fmt.Println(d)
+	}
+	if failure != nil {
+		fail(code, "Error: %v", failure)
+	}
+	return diags
 }
 
-// runPythonParser executes the embedded Python script on the specified Django app path.
-func runPythonParser(path string) (*Output, error) {
-	cmd := exec.Command("python3", "-c", pythonScript(), path)
+// parserScript is the Python script extracting the models, queries and
+// settings of a Django app as JSON.
+//
+//go:embed parser.py
+var parserScript string
+
+// runPythonParser executes the Python parser on the specified Django app
+// path: the embedded script, or the one at script when set, passing it the
+// parser flags args and the environment env. Its output must have the
+// capabilities listed in needs.
+func runPythonParser(path, script string, args, env []string, needs ...string) (*Output, error) {
+	cmd := exec.Command("python3", append([]string{"-c", parserScript, path}, args...)...)
+	if script != "" {
+		cmd = exec.Command("python3", append([]string{script, path}, args...)...)
+	}
+	cmd.Env = env
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = os.Stderr
@@ -105,68 +844,326 @@ func runPythonParser(path string) (*Output, error) {
 		return nil, err
 	}
 	var result Output
-	err := json.Unmarshal(out.Bytes(), &result)
-	return &result, err
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		return nil, err
+	}
+	if err := checkProtocol(&result, needs); err != nil {
+		return nil, err
+	}
+	return &result, nil
 }
 
-// write writes content to a file at the given path.
+// write writes content to a file at the given path, creating its directory
+// as needed. A Go file being regenerated keeps the code of its protected
+// regions.
 func write(path string, content string) {
-	os.WriteFile(path, []byte(content), 0644)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fail(exitIO, "Error: %v", err)
+	}
+	if existing, err := os.ReadFile(path); err == nil && strings.HasSuffix(path, ".go") {
+		var lost []string
+		content, lost = keepRegions(string(existing), content)
+		if len(lost) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s: dropped the code of the regions %s, which are no longer generated\n", path, strings.Join(lost, ", "))
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fail(exitIO, "Error: %v", err)
+	}
+}
+
+// writeFiles writes files keyed by path relative to dir.
+func writeFiles(dir string, files map[string]string) {
+	for path, content := range files {
+		write(filepath.Join(dir, path), content)
+	}
 }
 
 // timestamp returns a formatted timestamp string for file naming.
 func timestamp() string {
-	return time.Now().Format("20060102150405")
+	return time.Now().Format(versionLayout)
+}
+
+// versionLayout is the time layout of migration versions.
+const versionLayout = "20060102150405"
+
+// unmanagedHeader is written at the top of unmanaged.sql.
+const unmanagedHeader = `-- Reference only: these tables belong to models with Meta.managed = False.
+-- They are read by sqlc but must never be applied as a migration.
+
+`
+
+// splitManaged separates models whose tables or views are created by
+// migrations from unmanaged models that map to existing tables.
+func splitManaged(models []Model) (managed, unmanaged []Model) {
+	for _, m := range models {
+		if m.Managed || m.isView() {
+			managed = append(managed, m)
+		} else {
+			unmanaged = append(unmanaged, m)
+		}
+	}
+	return managed, unmanaged
 }
 
 // generateSQL generates CREATE TABLE SQL for the given models.
-func generateSQL(models []Model, dialect string) string {
+func generateSQL(models []Model, opts Options) string {
+	dialect := opts.Dialect
 	var sb strings.Builder
-	for _, m := range models {
-		sb.WriteString("CREATE TABLE " + toSnake(m.Name) + " (\n")
-		sb.WriteString("    id SERIAL PRIMARY KEY,\n")
+	if exts := requiredExtensions(models, dialect); len(exts) > 0 {
+		for _, ext := range exts {
+			sb.WriteString("CREATE EXTENSION IF NOT EXISTS " + ext + ";\n")
+		}
+		sb.WriteString("\n")
+	}
+	plan := planTables(models)
+	var joins strings.Builder
+	for _, m := range plan.models {
+		var defs []string
+		// notes maps a definition to the field it comes from.
+		notes := map[int]string{}
+		if len(m.PrimaryKey) == 0 {
+			defs = append(defs, m.idDef(dialect))
+		}
 		for _, f := range m.Fields {
-			col := "    " + toSnake(f.Name) + " " + sqlType(f.Type, dialect)
-			if !f.Nullable {
-				col += " NOT NULL"
+			if f.Relation == "many2many" {
+				continue
+			}
+			for _, c := range fieldColumns(f) {
+				notes[len(defs)] = m.Name + "." + f.Name + sourceRef(m.File, f.Line)
+				defs = append(defs, columnDef(f, c, dialect))
 			}
-			if f.Unique {
-				col += " UNIQUE"
+		}
+		if m.OrderWithRespectTo != "" {
+			defs = append(defs, "_order INTEGER NOT NULL DEFAULT 0")
+		}
+		if opts.ComputedColumns == "generated" {
+			for _, c := range propertyColumns(m, dialect) {
+				defs = append(defs, fmt.Sprintf("%s %s GENERATED ALWAYS AS (%s) STORED", c.Name, c.Type, c.SQL))
 			}
-			sb.WriteString(col + ",\n")
 		}
+		if len(m.PrimaryKey) > 0 {
+			defs = append(defs, "PRIMARY KEY ("+columnList(m.pkColumns())+")")
+		}
+		constraints, stmts, warnings := constraintDefs(m, dialect)
+		defs = append(defs, constraints...)
+		if opts.StrictChecks {
+			defs = append(defs, fieldChecks(m, dialect)...)
+		}
+		stmts = append(slugIndexStatements(m), stmts...)
+		stmts = append(stmts, columnComments(m, dialect)...)
+		if trigger, _ := autoNowTrigger(m, m.table(), dialect); trigger != "" {
+			stmts = append(stmts, trigger)
+		}
+		indexes, indexWarnings := indexStatements(m, dialect)
+		stmts = append(stmts, indexes...)
+		warnings = append(warnings, indexWarnings...)
 		for _, f := range m.Fields {
-			if f.Relation == "foreignkey" || f.Relation == "one2one" {
-				sb.WriteString(fmt.Sprintf("    FOREIGN KEY (%s_id) REFERENCES %s(id),\n",
-					toSnake(f.Name), toSnake(f.RelatedTo)))
+			if foreignKey(f) && !plan.deferred(m, f) {
+				defs = append(defs, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)",
+					columnList(fieldColumns(f)), f.relatedTable(), columnList(f.RelatedPK)))
 			}
 		}
-		sb.Truncate(sb.Len() - 2)
-		sb.WriteString("\n);\n\n")
+		for _, w := range warnings {
+			sb.WriteString("-- warning: " + w.Message + "\n")
+		}
+		types, _ := enumTypes(m, dialect)
+		for _, t := range types {
+			sb.WriteString(t + "\n")
+		}
+		sb.WriteString("-- " + m.App + "." + m.Name + sourceRef(m.File, m.Line) + "\n")
+		sb.WriteString(createTable(m.table(), defs, notes))
+		for _, stmt := range stmts {
+			sb.WriteString(stmt + "\n\n")
+		}
 
 		for _, f := range m.Fields {
-			if f.Relation == "many2many" {
-				join := toSnake(m.Name) + "_" + toSnake(f.Name)
-				sb.WriteString(fmt.Sprintf(
-					"CREATE TABLE %s (\n    %s_id INTEGER REFERENCES %s(id),\n    %s_id INTEGER REFERENCES %s(id)\n);\n\n",
-					join, toSnake(m.Name), toSnake(m.Name), toSnake(f.RelatedTo), toSnake(f.RelatedTo),
-				))
+			if f.Relation != "many2many" {
+				continue
 			}
+			if plan.laterJoins[joinTableName(m, f)] {
+				joins.WriteString(joinTableSQL(m, f, dialect))
+			} else {
+				sb.WriteString(joinTableSQL(m, f, dialect))
+			}
+		}
+	}
+	// Join tables and foreign keys referencing tables created after their
+	// own come once all tables exist.
+	sb.WriteString(joins.String())
+	for _, k := range plan.laterKeys {
+		sb.WriteString(k.addSQL() + "\n\n")
+	}
+	if opts.ComputedColumns == "view" {
+		for _, m := range models {
+			cols := propertyColumns(m, dialect)
+			if len(cols) == 0 {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("CREATE VIEW %s AS\nSELECT *", computedView(m)))
+			for _, c := range cols {
+				sb.WriteString(",\n    " + c.SQL + " AS " + c.Name)
+			}
+			sb.WriteString("\nFROM " + m.table() + ";\n\n")
+		}
+	}
+	sb.WriteString(generateViews(models))
+	return sb.String()
+}
+
+// columnDef renders the definition of one of a field's columns.
+func columnDef(f Field, c Column, dialect string) string {
+	col := c.Name + " " + c.sqlType(dialect) + collateSQL(f, dialect)
+	if f.Generated {
+		col += generatedClause(f, dialect)
+	}
+	if !f.Nullable {
+		col += " NOT NULL"
+	}
+	if def := defaultSQL(f, c, dialect); def != "" {
+		col += " DEFAULT " + def
+	}
+	if on := onUpdate(f, dialect); on != "" {
+		col += " " + on
+	}
+	if f.Unique && f.Type != "SlugField" {
+		col += " UNIQUE"
+	}
+	if positiveTypes[f.Type] != "" && dialect != "mysql" {
+		col += " CHECK (" + c.Name + " >= 0)"
+	}
+	if family := ipFamilies[strings.ToLower(f.Protocol)]; family != 0 && dialect == "postgres" {
+		col += fmt.Sprintf(" CHECK (family(%s) = %d)", c.Name, family)
+	}
+	col += choiceCheck(f, c.Name)
+	if note := columnNote(f); note != "" && dialect == "mysql" {
+		col += " COMMENT " + value(Arg{Literal: true, Value: note}, "", nil)
+	}
+	return col
+}
+
+// joinTableName returns the table of a many-to-many field.
+func joinTableName(m Model, f Field) string {
+	return m.table() + "_" + toSnake(f.Name)
+}
+
+// joinSide is one of the two foreign keys of a join table: the columns
+// naming it and the table and columns they reference.
+type joinSide struct {
+	names []string
+	table string
+	pk    []Column
+}
+
+// joinSides returns the foreign keys of a many-to-many field's join table,
+// to the model and to the related model. Like Django, the columns are named
+// after the models rather than their tables.
+func joinSides(m Model, f Field) []joinSide {
+	sides := []joinSide{{table: m.table(), pk: m.pkColumns()}, {table: f.relatedTable(), pk: f.RelatedPK}}
+	models := []string{toSnake(m.Name), toSnake(f.RelatedTo)}
+	for i, side := range sides {
+		for _, c := range side.pk {
+			name := models[i] + "_" + c.Name
+			if len(side.pk) == 1 {
+				name = models[i] + "_id"
+			}
+			sides[i].names = append(sides[i].names, name)
+		}
+	}
+	return sides
+}
+
+// joinTableSQL renders the CREATE TABLE of a many-to-many field's join
+// table.
+func joinTableSQL(m Model, f Field, dialect string) string {
+	var cols []string
+	for _, side := range joinSides(m, f) {
+		var refs []string
+		for i, c := range side.pk {
+			refs = append(refs, c.Name)
+			cols = append(cols, side.names[i]+" "+c.sqlType(dialect))
+		}
+		cols = append(cols, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s(%s)",
+			strings.Join(side.names, ", "), side.table, strings.Join(refs, ", ")))
+	}
+	return "-- " + m.App + "." + m.Name + "." + f.Name + sourceRef(m.File, f.Line) + "\n" +
+		createTable(joinTableName(m, f), cols, nil)
+}
+
+// createTable renders a CREATE TABLE statement from its definitions, each
+// followed by its note, if any, as a comment.
+func createTable(table string, defs []string, notes map[int]string) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE " + table + " (\n")
+	for i, def := range defs {
+		sb.WriteString("    " + def)
+		if i < len(defs)-1 {
+			sb.WriteString(",")
+		}
+		if note := notes[i]; note != "" {
+			sb.WriteString(" -- " + note)
 		}
+		sb.WriteString("\n")
 	}
+	sb.WriteString(");\n\n")
 	return sb.String()
 }
 
+// sourceRef formats the Python source location of a model or field as
+// " (file:line)", or "" when unknown.
+func sourceRef(file string, line int) string {
+	switch {
+	case file == "":
+		return ""
+	case line == 0:
+		return " (" + file + ")"
+	}
+	return fmt.Sprintf(" (%s:%d)", file, line)
+}
+
 // generateDownSQL generates DROP TABLE SQL statements for the models.
-func generateDownSQL(models []Model, dialect string) string {
+func generateDownSQL(models []Model, opts Options) string {
 	var sb strings.Builder
 	for _, m := range models {
+		if m.isView() {
+			sb.WriteString("DROP VIEW IF EXISTS " + m.table() + ";\n")
+		}
+	}
+	if opts.ComputedColumns == "view" {
+		for _, m := range models {
+			if len(propertyColumns(m, opts.Dialect)) > 0 {
+				sb.WriteString("DROP VIEW IF EXISTS " + computedView(m) + ";\n")
+			}
+		}
+	}
+	// The tables are dropped in the reverse of the order they were created
+	// in, after the foreign keys and join tables added last.
+	plan := planTables(models)
+	for _, k := range plan.laterKeys {
+		sb.WriteString(k.dropSQL(opts.Dialect) + "\n")
+	}
+	for _, m := range plan.models {
 		for _, f := range m.Fields {
-			if f.Relation == "many2many" {
-				sb.WriteString("DROP TABLE IF EXISTS " + toSnake(m.Name) + "_" + toSnake(f.Name) + ";\n")
+			if f.Relation == "many2many" && plan.laterJoins[joinTableName(m, f)] {
+				sb.WriteString("DROP TABLE IF EXISTS " + joinTableName(m, f) + ";\n")
 			}
 		}
-		sb.WriteString("DROP TABLE IF EXISTS " + toSnake(m.Name) + ";\n")
+	}
+	for i := len(plan.models) - 1; i >= 0; i-- {
+		m := plan.models[i]
+		for _, f := range m.Fields {
+			if f.Relation == "many2many" && !plan.laterJoins[joinTableName(m, f)] {
+				sb.WriteString("DROP TABLE IF EXISTS " + joinTableName(m, f) + ";\n")
+			}
+		}
+		if _, trigger := autoNowTrigger(m, m.table(), opts.Dialect); trigger != "" {
+			sb.WriteString(trigger + "\n")
+		}
+		sb.WriteString("DROP TABLE IF EXISTS " + m.table() + ";\n")
+		_, types := enumTypes(m, opts.Dialect)
+		for _, t := range types {
+			sb.WriteString(t + "\n")
+		}
 	}
 	return sb.String()
 }
@@ -176,86 +1173,225 @@ func sqlType(ftype, dialect string) string {
 	switch ftype {
 	case "CharField", "TextField":
 		return "TEXT"
-	case "IntegerField":
+	case "IntegerField", "ForeignKey", "OneToOneField":
 		return "INTEGER"
+	case "AutoField", "BigAutoField", "SmallAutoField":
+		if dialect == "mysql" {
+			return sqlType(autoTypes[ftype], dialect) + " AUTO_INCREMENT"
+		}
+		return serialTypes[ftype]
+	case "BigIntegerField":
+		return "BIGINT"
+	case "SmallIntegerField":
+		return "SMALLINT"
+	case "PositiveIntegerField", "PositiveBigIntegerField", "PositiveSmallIntegerField":
+		// MySQL has unsigned columns, as in Django; the other dialects check
+		// the sign with a constraint (see columnDef).
+		t := sqlType(positiveTypes[ftype], dialect)
+		if dialect == "mysql" {
+			t += " UNSIGNED"
+		}
+		return t
 	case "FloatField":
 		return "REAL"
+	case "DecimalField":
+		return "NUMERIC"
+	case "JSONField":
+		if dialect == "postgres" {
+			return "JSONB"
+		}
+		return "JSON"
 	case "BooleanField":
 		return "BOOLEAN"
-	case "DateField", "DateTimeField":
-		return "TIMESTAMP"
+	case "BinaryField":
+		// LONGBLOB, as in Django: BLOB holds at most 64 KiB.
+		if dialect == "mysql" {
+			return "LONGBLOB"
+		}
+		return "BYTEA"
+	case "DateField":
+		return "DATE"
+	case "TimeField":
+		// Microsecond precision, as Django's MySQL backend creates.
+		if dialect == "mysql" {
+			return "TIME(6)"
+		}
+		return "TIME"
+	case "DateTimeField":
+		// MySQL has no column type with a time zone; Django stores UTC
+		// there. Without USE_TZ, applyDialectTypes drops the time zone on
+		// PostgreSQL too.
+		if dialect == "mysql" {
+			return "DATETIME(6)"
+		}
+		return "TIMESTAMP WITH TIME ZONE"
+	case "IntegerRangeField", "BigIntegerRangeField", "DecimalRangeField", "DateRangeField", "DateTimeRangeField":
+		if dialect == "postgres" {
+			return rangeTypes[ftype]
+		}
+		return "TEXT"
+	case "HStoreField":
+		if dialect == "postgres" {
+			return "HSTORE"
+		}
+		return "TEXT"
+	case "GenericIPAddressField":
+		// The longest IPv6 address, as Django's MySQL backend uses.
+		if dialect == "postgres" {
+			return "INET"
+		}
+		return "VARCHAR(39)"
+	case "SearchVectorField":
+		if dialect == "postgres" {
+			return "TSVECTOR"
+		}
+		return "TEXT"
+	case "CICharField", "CIEmailField", "CITextField":
+		// MySQL's default collations already compare case-insensitively.
+		if dialect == "postgres" {
+			return "CITEXT"
+		}
+		return "TEXT"
 	default:
 		return "TEXT"
 	}
 }
 
+// requiredExtensions returns the PostgreSQL extensions the schema needs:
+// those providing field types, and btree_gist for exclusion constraints
+// comparing scalar columns with = or <>.
+func requiredExtensions(models []Model, dialect string) []string {
+	if dialect != "postgres" {
+		return nil
+	}
+	var exts []string
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if ext := fieldExtensions[f.Type]; ext != "" && !slices.Contains(exts, ext) {
+				exts = append(exts, ext)
+			}
+		}
+		for _, c := range m.Constraints {
+			for _, e := range c.Expressions {
+				f, ok := m.field(e.Field)
+				if ok && rangeTypes[f.Type] == "" && !slices.Contains(exts, "btree_gist") {
+					exts = append(exts, "btree_gist")
+				}
+			}
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// fieldExtensions maps Django field types to the PostgreSQL extension
+// providing their column type.
+var fieldExtensions = map[string]string{
+	"HStoreField":  "hstore",
+	"CICharField":  "citext",
+	"CIEmailField": "citext",
+	"CITextField":  "citext",
+	// GeoDjango's geometry fields.
+	"GeometryField":           "postgis",
+	"PointField":              "postgis",
+	"LineStringField":         "postgis",
+	"PolygonField":            "postgis",
+	"MultiPointField":         "postgis",
+	"MultiLineStringField":    "postgis",
+	"MultiPolygonField":       "postgis",
+	"GeometryCollectionField": "postgis",
+}
+
+// serialTypes maps the auto-incrementing fields to PostgreSQL types.
+var serialTypes = map[string]string{
+	"AutoField":      "SERIAL",
+	"BigAutoField":   "BIGSERIAL",
+	"SmallAutoField": "SMALLSERIAL",
+}
+
+// rangeTypes maps django.contrib.postgres range fields to PostgreSQL types.
+var rangeTypes = map[string]string{
+	"IntegerRangeField":    "INT4RANGE",
+	"BigIntegerRangeField": "INT8RANGE",
+	"DecimalRangeField":    "NUMRANGE",
+	"DateRangeField":       "DATERANGE",
+	"DateTimeRangeField":   "TSTZRANGE",
+}
+
+// columnName returns the database column name for a field, adding the
+// Django "_id" suffix for foreign keys.
+func columnName(f Field) string {
+	if f.Relation == "foreignkey" || f.Relation == "one2one" {
+		return toSnake(f.Name) + "_id"
+	}
+	return toSnake(f.Name)
+}
+
+// fieldColumns returns the columns backing a field. A foreign key has the
+// type of the primary key it references, and to a model with a composite
+// primary key expands to one column per key column.
+func fieldColumns(f Field) []Column {
+	if f.Relation != "foreignkey" && f.Relation != "one2one" {
+		return []Column{{Name: columnName(f), Type: f.Type, DBType: f.DBType}}
+	}
+	if len(f.RelatedPK) == 0 {
+		return []Column{{Name: columnName(f), Type: "ForeignKey"}}
+	}
+	if len(f.RelatedPK) == 1 {
+		c := f.RelatedPK[0]
+		return []Column{{Name: columnName(f), Type: c.Type, DBType: c.DBType}}
+	}
+	var cols []Column
+	for _, c := range f.RelatedPK {
+		cols = append(cols, Column{Name: toSnake(f.Name) + "_" + c.Name, Type: c.Type, DBType: c.DBType})
+	}
+	return cols
+}
+
+// columnList joins column names with commas.
+func columnList(cols []Column) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// resolveRelations records the primary key columns of each related model on
+// the relation fields pointing at it. Composite keys made of foreign keys
+// are resolved first so that their column types are known.
+func resolveRelations(models []Model) {
+	byName := map[string]*Model{}
+	for i := range models {
+		byName[models[i].Name] = &models[i]
+	}
+	var resolve func(m *Model, seen map[string]bool)
+	resolve = func(m *Model, seen map[string]bool) {
+		if seen[m.Name] {
+			return
+		}
+		seen[m.Name] = true
+		for i, f := range m.Fields {
+			if f.Relation == "" {
+				continue
+			}
+			target, ok := byName[f.RelatedTo]
+			if !ok {
+				m.Fields[i].RelatedPK = []Column{{Name: "id", Type: "IntegerField"}}
+				m.Fields[i].RelatedTable = toSnake(f.RelatedTo)
+				continue
+			}
+			resolve(target, seen)
+			m.Fields[i].RelatedPK = target.pkColumns()
+			m.Fields[i].RelatedTable = target.table()
+		}
+	}
+	for i := range models {
+		resolve(&models[i], map[string]bool{})
+	}
+}
+
 // toSnake converts a string to snake_case.
 func toSnake(s string) string {
 	return strings.ToLower(strings.ReplaceAll(s, " ", "_"))
 }
-
-// generateSQLCConfig returns a sqlc.yaml configuration string.
-func generateSQLCConfig(dialect string) string {
-	return fmt.Sprintf(`version: "2"
-sql:
-  - engine: %s
-    queries: "./query.sql"
-    schema: "./schema.sql"
-    gen:
-      go:
-        package: "db"
-        out: "./db"
-`, dialect)
-}
-
-// pythonScript returns the embedded Python script as a string.
-func pythonScript() string {
-	return `
-import sys, os, ast, json
-
-def extract_models(path: str):
-    result = []
-    queries = []
-    for root, _, files in os.walk(path):
-        for file in files:
-            if file.endswith(".py"):
-                full = os.path.join(root, file)
-                with open(full) as f:
-                    tree = ast.parse(f.read(), filename=full)
-                for node in tree.body:
-                    if isinstance(node, ast.ClassDef):
-                        bases = [b.id if isinstance(b, ast.Name) else "" for b in node.bases]
-                        if "Model" in bases:
-                            fields = []
-                            for stmt in node.body:
-                                if isinstance(stmt, ast.Assign) and isinstance(stmt.value, ast.Call):
-                                    fname = stmt.targets[0].id
-                                    ftype = stmt.value.func.attr if isinstance(stmt.value.func, ast.Attribute) else ""
-                                    kwargs = {k.arg: getattr(k.value, 's', getattr(k.value, 'value', None)) for k in stmt.value.keywords}
-                                    nullable = kwargs.get('null', False)
-                                    unique = kwargs.get('unique', False)
-                                    related = None
-                                    to = None
-                                    if ftype in ["ForeignKey", "OneToOneField", "ManyToManyField"]:
-                                        related = ftype.lower().replace("field", "")
-                                        to = stmt.value.args[0].id if isinstance(stmt.value.args[0], ast.Name) else ""
-                                    fields.append({
-                                        "name": fname,
-                                        "type": ftype,
-                                        "nullable": nullable,
-                                        "unique": unique,
-                                        "relation": related,
-                                        "related_to": to
-                                    })
-                            result.append({"name": node.name, "fields": fields})
-                with open(full) as f:
-                    code = f.read()
-                    if ".objects." in code:
-                        for line in code.splitlines():
-                            if ".objects." in line and ("filter(" in line or "get(" in line or "create(" in line):
-                                queries.append("-- from: %s\n-- %s" % (file, line.strip()))
-    print(json.dumps({"models": result, "queries": queries}))
-
-extract_models(sys.argv[1])
-`
-}
@@ -0,0 +1,126 @@
+// main_test.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseProject writes the files, by path, into a temporary project and
+// returns the parser's output for it.
+func parseProject(t *testing.T, files map[string]string) *Output {
+	t.Helper()
+	return parseProjectArgs(t, files, nil)
+}
+
+// parseProjectArgs is parseProject passing the parser flags args.
+func parseProjectArgs(t *testing.T, files map[string]string, args []string) *Output {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("no python3")
+	}
+	out, err := runPythonParser(writeProject(t, files), "", args, parserEnvironment("", nil))
+	if err != nil {
+		t.Fatalf("parser: %v", err)
+	}
+	return out
+}
+
+// writeProject writes the files, by path, into a temporary directory and
+// returns it.
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestUnmanagedModels checks that models with Meta.managed = False get no
+// DDL in the schema and are read by sqlc from unmanaged.sql.
+func TestUnmanagedModels(t *testing.T) {
+	out := parseProject(t, map[string]string{"shop/models.py": `from django.db import models
+
+class Order(models.Model):
+    total = models.IntegerField()
+
+class LegacyCustomer(models.Model):
+    name = models.CharField(max_length=50)
+
+    class Meta:
+        managed = False
+`})
+	managed, unmanaged := splitManaged(out.Models)
+	if len(managed) != 1 || managed[0].Name != "Order" || len(unmanaged) != 1 || unmanaged[0].Name != "LegacyCustomer" {
+		t.Fatalf("managed = %v, unmanaged = %v", managed, unmanaged)
+	}
+	if sql := generateSQL(managed, Options{Dialect: "postgres"}); strings.Contains(sql, "legacy") {
+		t.Errorf("schema creates the unmanaged table:\n%s", sql)
+	}
+	if cfg := generateSQLCConfig(out.Models, "postgres", defaultLayout, []string{"schema.sql", "unmanaged.sql"}, []string{"query.sql"}, nil, ""); !strings.Contains(cfg, "./unmanaged.sql") {
+		t.Errorf("sqlc.yaml does not read unmanaged.sql:\n%s", cfg)
+	}
+}
+
+// TestSourceAnnotations checks that tables, columns and generated queries
+// point back to the Python code they come from.
+func TestSourceAnnotations(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Tag(models.Model):
+    name = models.CharField(max_length=50)
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+    tags = models.ManyToManyField(Tag)
+`,
+	})
+	resolveRelations(out.Models)
+	schema := generateSQL(out.Models, Options{Dialect: "postgres"})
+	for _, want := range []string{
+		"-- blog.Post (blog/models.py:6)\nCREATE TABLE post (\n    id SERIAL PRIMARY KEY,\n    title TEXT NOT NULL -- Post.title (blog/models.py:7)\n);",
+		"-- blog.Post.tags (blog/models.py:8)\nCREATE TABLE post_tags (",
+	} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("schema lacks %q:\n%s", want, schema)
+		}
+	}
+
+	queries := []TranslatedQuery{{Query: Query{App: "blog", Model: "Post", Source: "primary key lookup"}, Name: "GetPost", Kind: ":one", SQL: "SELECT * FROM post WHERE id = $1;"}}
+	if got := generateQueries(queries, out.Models); !strings.Contains(got, "-- generated: primary key lookup; model blog.Post (blog/models.py:6)\n") {
+		t.Errorf("query.sql:\n%s", got)
+	}
+}
+
+// TestParserScript checks that --parser-script runs the given script on
+// the project instead of the embedded parser.
+func TestParserScript(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	script := filepath.Join(t.TempDir(), "parser.py")
+	src := `import json, sys
+print(json.dumps({"ir_version": 1, "capabilities": ["models"], "models": [{"name": "Post", "app": "blog", "file": sys.argv[1], "managed": True, "fields": []}]}))
+`
+	if err := os.WriteFile(script, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runPythonParser("myproject", script, nil, nil, capModels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Models) != 1 || out.Models[0].File != "myproject" {
+		t.Errorf("models = %+v", out.Models)
+	}
+}
@@ -0,0 +1,49 @@
+// merge.go
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The markers delimiting the generated section of a query file merged with
+// --merge-queries. Everything outside them is left as the user wrote it.
+const (
+	generatedBegin = "-- django2go:begin generated queries"
+	generatedEnd   = "-- django2go:end generated queries"
+)
+
+// queryName matches the sqlc annotation naming a query.
+var queryName = regexp.MustCompile(`(?m)^--\s*name:\s*(\w+)`)
+
+// mergeQueries merges generated queries into the existing content of a query
+// file, replacing the section between the markers, or appending one to a
+// hand-maintained file that has none yet. Queries named like a hand-written
+// one are left out, as sqlc rejects duplicate names; their names are
+// returned. render renders the queries of the section.
+func mergeQueries(existing string, queries []TranslatedQuery, render func([]TranslatedQuery) string) (string, []string) {
+	before, after := existing, ""
+	if i := strings.Index(existing, generatedBegin); i >= 0 {
+		before = existing[:i]
+		if j := strings.Index(existing[i:], generatedEnd); j >= 0 {
+			after = strings.TrimPrefix(existing[i+j+len(generatedEnd):], "\n")
+		}
+	}
+	hand := map[string]bool{}
+	for _, m := range queryName.FindAllStringSubmatch(before+after, -1) {
+		hand[m[1]] = true
+	}
+	var kept []TranslatedQuery
+	var dropped []string
+	for _, q := range queries {
+		if q.Translated() && hand[q.Name] {
+			dropped = append(dropped, q.Name)
+			continue
+		}
+		kept = append(kept, q)
+	}
+	if before != "" && !strings.HasSuffix(before, "\n\n") {
+		before = strings.TrimRight(before, "\n") + "\n\n"
+	}
+	return before + generatedBegin + "\n" + render(kept) + generatedEnd + "\n" + after, dropped
+}
@@ -0,0 +1,45 @@
+// merge_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMergeQueries checks that hand-written queries around the generated
+// section survive a merge, and that generated queries named like them are
+// left out.
+func TestMergeQueries(t *testing.T) {
+	render := func(queries []TranslatedQuery) string {
+		var sb strings.Builder
+		for _, q := range queries {
+			sb.WriteString("-- name: " + q.Name + " :many\n" + q.SQL + "\n")
+		}
+		return sb.String()
+	}
+	queries := []TranslatedQuery{
+		{Name: "ListPost", Kind: ":many", SQL: "SELECT * FROM post;"},
+		{Name: "GetPostBySlug", Kind: ":one", SQL: "SELECT * FROM post WHERE slug = $1;"},
+	}
+
+	got, dropped := mergeQueries("", queries, render)
+	if !strings.HasPrefix(got, generatedBegin+"\n-- name: ListPost") || !strings.HasSuffix(got, generatedEnd+"\n") || len(dropped) != 0 {
+		t.Errorf("new file:\n%s", got)
+	}
+
+	hand := "-- name: GetPostBySlug :one\nSELECT * FROM post WHERE slug = $1 LIMIT 1;\n"
+	got, dropped = mergeQueries(hand, queries, render)
+	if !strings.HasPrefix(got, hand+"\n"+generatedBegin) || strings.Count(got, "GetPostBySlug") != 1 {
+		t.Errorf("appended section:\n%s", got)
+	}
+	if len(dropped) != 1 || dropped[0] != "GetPostBySlug" {
+		t.Errorf("dropped = %v", dropped)
+	}
+
+	existing := hand + "\n" + generatedBegin + "\n-- name: Stale :one\nSELECT 1;\n" + generatedEnd + "\n-- name: Trailer :exec\nDELETE FROM post;\n"
+	got, _ = mergeQueries(existing, queries[:1], render)
+	want := hand + "\n" + generatedBegin + "\n-- name: ListPost :many\nSELECT * FROM post;\n" + generatedEnd + "\n-- name: Trailer :exec\nDELETE FROM post;\n"
+	if got != want {
+		t.Errorf("regenerated section:\n%s\nwant:\n%s", got, want)
+	}
+}
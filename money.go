@@ -0,0 +1,44 @@
+// money.go
+package main
+
+// currencySuffix is appended by django-money to the name of a MoneyField
+// to name the field holding its currency.
+const currencySuffix = "_currency"
+
+// defaultCurrencyLength is the length of django-money's currency codes,
+// ISO 4217 ones.
+const defaultCurrencyLength = 3
+
+// expandMoneyFields replaces every django-money MoneyField by the two fields
+// django-money stores it in: a CurrencyField named after it with the
+// currency code, nullable unless the field has a default_currency, and the
+// amount as a DecimalField of the MoneyField's precision. Only numeric
+// defaults are kept, Money(...) ones being calls.
+func expandMoneyFields(models []Model) {
+	for i := range models {
+		m := &models[i]
+		var fields []Field
+		for _, f := range m.Fields {
+			if f.Type != "MoneyField" {
+				fields = append(fields, f)
+				continue
+			}
+			currency := Field{Name: f.Name + currencySuffix, Type: "CharField", Line: f.Line, MaxLength: f.CurrencyMaxLength,
+				Nullable: f.DefaultCurrency == ""}
+			if currency.MaxLength == 0 {
+				currency.MaxLength = defaultCurrencyLength
+			}
+			if f.DefaultCurrency != "" {
+				currency.Default = f.DefaultCurrency
+			}
+			amount := f
+			amount.Type = "DecimalField"
+			amount.DefaultCurrency, amount.CurrencyMaxLength = "", 0
+			if _, ok := f.Default.(float64); !ok {
+				amount.Default = nil
+			}
+			fields = append(fields, currency, amount)
+		}
+		m.Fields = fields
+	}
+}
@@ -0,0 +1,36 @@
+// money_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestMoneyFields checks that a MoneyField becomes a currency column and an
+// amount column of its precision.
+func TestMoneyFields(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"shop/models.py": `from django.db import models
+from djmoney.models.fields import MoneyField
+
+class Product(models.Model):
+    price = MoneyField(max_digits=10, decimal_places=2, default_currency="EUR", default=0.0)
+    deposit = MoneyField(max_digits=8, decimal_places=2, null=True, currency_max_length=5)
+`,
+	})
+	opts := Options{Dialect: "postgres"}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{
+		"price_currency VARCHAR(3) NOT NULL DEFAULT 'EUR'",
+		"price NUMERIC(10,2) NOT NULL DEFAULT 0",
+		"deposit_currency VARCHAR(5),",
+		"deposit NUMERIC(8,2)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// names.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GoNames controls the spelling of the Go identifiers generated for the
+// port, and of those sqlc derives from the schema, so that query methods,
+// struct fields and enum constants agree.
+type GoNames struct {
+	// Initialisms are the words written all in upper case, such as ID and
+	// API, where each word is otherwise capitalized.
+	Initialisms []string `yaml:"initialisms"`
+	// Rename maps snake_case names, such as columns named type or func, to
+	// the Go identifier replacing the derived one.
+	Rename map[string]string `yaml:"rename"`
+}
+
+// goNames holds the naming rules of the run, set from the config before
+// anything is generated.
+var goNames GoNames
+
+// validate checks that the renamed identifiers are exported Go names.
+func (n GoNames) validate() error {
+	for from, to := range n.Rename {
+		if to == "" || !unicode.IsUpper([]rune(to)[0]) || strings.ContainsFunc(to, func(r rune) bool {
+			return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			return fmt.Errorf("go_names: rename: %s: %q is not an exported Go identifier", from, to)
+		}
+	}
+	return nil
+}
+
+// initialism reports whether a word is one of the configured initialisms.
+func (n GoNames) initialism(word string) bool {
+	for _, i := range n.Initialisms {
+		if strings.EqualFold(i, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// camel converts a snake_case or CamelCase name to a CamelCase Go name,
+// applying the renames and initialisms.
+func (n GoNames) camel(s string) string {
+	if name, ok := n.Rename[s]; ok {
+		return name
+	}
+	parts := words(s)
+	for i, p := range parts {
+		if n.initialism(p) {
+			parts[i] = strings.ToUpper(p)
+		} else {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// sqlcName returns the Go name sqlc derives from a table or column name.
+// Without configured initialisms sqlc still writes ID in upper case.
+func sqlcName(s string) string {
+	n := goNames
+	if len(n.Initialisms) == 0 {
+		n.Initialisms = []string{"id"}
+	}
+	return n.camel(s)
+}
+
+// sqlcStruct returns the name of the struct sqlc generates for a table,
+// which it singularizes, as in shop_orders to ShopOrder.
+func sqlcStruct(table string) string {
+	return sqlcName(singular(table))
+}
+
+// uncountable lists the words sqlc's inflection keeps as they are.
+var uncountable = map[string]bool{
+	"equipment": true, "information": true, "rice": true, "money": true, "species": true,
+	"series": true, "fish": true, "sheep": true, "jeans": true, "police": true, "news": true,
+}
+
+// singular singularizes the last word of a snake_case name the way sqlc
+// does for regular English plurals.
+func singular(name string) string {
+	word := name[strings.LastIndex(name, "_")+1:]
+	if uncountable[word] {
+		return name
+	}
+	stem := strings.TrimSuffix(name, word)
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return stem + strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return stem + strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"), strings.HasSuffix(word, "is"):
+		return name
+	case strings.HasSuffix(word, "s") && len(word) > 1:
+		return stem + strings.TrimSuffix(word, "s")
+	}
+	return name
+}
+
+// sqlcOptions renders the naming rules as sqlc gen.go options, indented
+// for sqlc.yaml.
+func (n GoNames) sqlcOptions() string {
+	var sb strings.Builder
+	if len(n.Initialisms) > 0 {
+		words := make([]string, len(n.Initialisms))
+		for i, w := range n.Initialisms {
+			words[i] = fmt.Sprintf("%q", strings.ToLower(w))
+		}
+		sb.WriteString("        initialisms: [" + strings.Join(words, ", ") + "]\n")
+	}
+	if len(n.Rename) > 0 {
+		names := make([]string, 0, len(n.Rename))
+		for name := range n.Rename {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sb.WriteString("        rename:\n")
+		for _, name := range names {
+			fmt.Fprintf(&sb, "          %s: %q\n", name, n.Rename[name])
+		}
+	}
+	return sb.String()
+}
+
+// words splits a snake_case or CamelCase name into its words, keeping runs
+// of capitals such as API in APIKey together.
+func words(s string) []string {
+	var result []string
+	runes := []rune(s)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && runes[i] != '_' {
+			prev, cur := runes[i-1], runes[i]
+			next := cur
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			lowerToUpper := !unicode.IsUpper(prev) && prev != '_' && unicode.IsUpper(cur)
+			acronymEnd := unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next)
+			if !lowerToUpper && !acronymEnd {
+				continue
+			}
+		}
+		if word := strings.Trim(string(runes[start:i]), "_"); word != "" {
+			result = append(result, word)
+		}
+		start = i
+	}
+	return result
+}
@@ -0,0 +1,41 @@
+// names_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGoNames checks that the configured initialisms and renames spell the
+// query names and reach sqlc.yaml.
+func TestGoNames(t *testing.T) {
+	defer func(saved GoNames) { goNames = saved }(goNames)
+	goNames = GoNames{Initialisms: []string{"ID", "API"}, Rename: map[string]string{"type": "Kind"}}
+
+	for in, want := range map[string]string{
+		"user_id":       "UserID",
+		"APIKey":        "APIKey",
+		"GetPostByID":   "GetPostByID",
+		"list_api_keys": "ListAPIKeys",
+		"type":          "Kind",
+		"ListPostById":  "ListPostByID",
+	} {
+		if got := camel(in); got != want {
+			t.Errorf("camel(%q) = %s, want %s", in, got, want)
+		}
+	}
+	queries := []TranslatedQuery{{Name: "GetUserById", SQL: "SELECT 1;"}, {Name: "GetUserByID", SQL: "SELECT 2;"}}
+	uniqueNames(queries)
+	if queries[0].Name != "GetUserByID" || queries[1].Name != "GetUserByID2" {
+		t.Errorf("names = %s, %s", queries[0].Name, queries[1].Name)
+	}
+	cfg := generateSQLCConfig(nil, "postgres", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "")
+	for _, want := range []string{`initialisms: ["id", "api"]`, "rename:\n          type: \"Kind\""} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("sqlc.yaml lacks %q:\n%s", want, cfg)
+		}
+	}
+	if err := (GoNames{Rename: map[string]string{"func": "fn"}}).validate(); err == nil {
+		t.Error("unexported rename passed")
+	}
+}
@@ -0,0 +1,197 @@
+// normalize.go
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// normalize turns the parsed models into the canonical IR the generators
+// assume: config overrides applied, relations resolved, column types fixed
+// for the dialect, and table and column names checked for collisions. It
+// stops after the first stage reporting errors, since later stages rely on
+// the earlier ones.
+func normalize(cfg *Config, models []Model, opts Options) []Diagnostic {
+	if err := applyConfig(cfg, models); err != nil {
+		return []Diagnostic{{Code: codeConfig, Severity: severity(codeConfig), File: cfg.path, Message: err.Error()}}
+	}
+	diags := applyDjangoVersion(models, opts)
+	applyTableNames(models, opts.NoAppPrefix)
+	applyUniqueTogether(models)
+	applyAutoFields(models)
+	expandMoneyFields(models)
+	applyDBIndexes(models)
+	applyProxyAliases(models)
+	diags = append(diags, checkRelations(models)...)
+	diags = append(diags, checkDatabases(cfg, models)...)
+	diags = append(diags, checkWorkspace(cfg, models)...)
+	if hasErrors(diags) {
+		return diags
+	}
+	// Column types come first so that foreign keys copy them from the
+	// primary keys they reference.
+	diags = append(diags, applyDialectTypes(cfg, models, opts)...)
+	resolveRelations(models)
+	diags = append(diags, checkOrdering(models)...)
+	diags = append(diags, checkNames(models)...)
+	sortDiagnostics(diags)
+	return diags
+}
+
+// applyTableNames names the tables like Django, so that the schema matches
+// a database Django created: after Meta.db_table when set, else after the
+// app label and the lowercased model name. Without the app prefix they are
+// named after the snake_cased model name alone.
+func applyTableNames(models []Model, noAppPrefix bool) {
+	for i := range models {
+		m := &models[i]
+		m.Table = djangoTable(*m)
+		if noAppPrefix && m.DBTable == "" {
+			m.Table = toSnake(m.Name)
+		}
+	}
+}
+
+// applyUniqueTogether adds a unique constraint per Meta.unique_together
+// entry, but for the one a configured composite primary key already
+// enforces.
+func applyUniqueTogether(models []Model) {
+	for i := range models {
+		m := &models[i]
+		for _, fields := range m.UniqueTogether {
+			if slices.Equal(fields, m.PrimaryKey) {
+				continue
+			}
+			m.Constraints = append(m.Constraints, Constraint{Kind: "unique", Fields: fields})
+		}
+	}
+}
+
+// applyDBIndexes adds a B-tree index per field declared with db_index=True,
+// like Django. Keys and unique fields are indexed already, and range and
+// geometry fields get their own GiST indexes. A field Meta.indexes indexes
+// alone is left to that index.
+func applyDBIndexes(models []Model) {
+	for i := range models {
+		m := &models[i]
+		for _, f := range m.Fields {
+			if !f.DBIndex || f.PrimaryKey || f.Unique || rangeTypes[f.Type] != "" || geoTypes[f.Type] != "" {
+				continue
+			}
+			if slices.ContainsFunc(m.Indexes, func(idx Index) bool {
+				return slices.Equal(idx.Fields, []string{f.Name}) && idx.Condition == nil
+			}) {
+				continue
+			}
+			m.Indexes = append(m.Indexes, Index{Fields: []string{f.Name}})
+		}
+	}
+}
+
+// applyAutoFields makes the auto-incrementing fields declared with
+// primary_key=True the primary keys of their models: an id field replaces
+// the implicit one, keeping its type, and one named otherwise becomes the
+// key like a configured one.
+func applyAutoFields(models []Model) {
+	for i := range models {
+		m := &models[i]
+		if len(m.PrimaryKey) > 0 {
+			continue
+		}
+		for j, f := range m.Fields {
+			if _, ok := autoTypes[f.Type]; !ok || !f.PrimaryKey {
+				continue
+			}
+			if f.Name == "id" {
+				m.AutoField = f.Type
+				m.Fields = slices.Delete(m.Fields, j, j+1)
+			} else {
+				m.PrimaryKey = []string{f.Name}
+			}
+			break
+		}
+	}
+}
+
+// checkRelations reports relations to models that were not found. They are
+// kept, assuming the target has an integer id primary key.
+func checkRelations(models []Model) []Diagnostic {
+	known := map[string]bool{}
+	for _, m := range models {
+		known[m.Name] = true
+	}
+	var diags []Diagnostic
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if f.Relation == "" {
+				continue
+			}
+			if f.RelatedTo == "" {
+				diags = append(diags, diagnose(codeRelationTarget, m, f.Name, "%s target could not be determined", f.Type))
+			} else if !known[f.RelatedTo] {
+				diags = append(diags, diagnose(codeRelationNotFound, m, f.Name, "related model %s not found; assuming an integer id primary key", f.RelatedTo))
+			}
+		}
+	}
+	return diags
+}
+
+// checkOrdering reports the Meta.ordering names the generated queries
+// cannot sort on.
+func checkOrdering(models []Model) []Diagnostic {
+	var diags []Diagnostic
+	for _, m := range models {
+		if m.OrderWithRespectTo != "" {
+			continue
+		}
+		if order, skipped, err := m.orderingColumns(); err != nil {
+			kept := "get no default ordering"
+			if len(order) > 0 {
+				kept = "sort by " + strings.Join(order, ", ")
+			}
+			diags = append(diags, diagnose(codeOrdering, m, "", "Meta.ordering %q not carried over to queries (%v); queries %s", skipped, err, kept))
+		}
+	}
+	return diags
+}
+
+// checkNames reports tables and columns that would be generated twice.
+func checkNames(models []Model) []Diagnostic {
+	var diags []Diagnostic
+	tables := map[string]string{}
+	addTable := func(m Model, field, table, owner string) {
+		if prev, ok := tables[table]; ok {
+			diags = append(diags, diagnose(codeTableCollision, m, field, "table %s collides with %s", table, prev))
+			return
+		}
+		tables[table] = owner
+	}
+	for _, m := range models {
+		addTable(m, "", m.table(), m.App+"."+m.Name)
+		columns := map[string]string{}
+		addColumn := func(field, column, owner string) {
+			if prev, ok := columns[column]; ok {
+				diags = append(diags, diagnose(codeColumnCollision, m, field, "column %s collides with %s", column, prev))
+				return
+			}
+			columns[column] = owner
+		}
+		if len(m.PrimaryKey) == 0 {
+			addColumn("", "id", "the implicit primary key")
+		}
+		if m.OrderWithRespectTo != "" {
+			addColumn("", "_order", "the order_with_respect_to column")
+		}
+		for _, f := range m.Fields {
+			if f.Relation == "many2many" {
+				join := joinTableName(m, f)
+				addTable(m, f.Name, join, m.App+"."+m.Name+"."+f.Name)
+				continue
+			}
+			for _, c := range fieldColumns(f) {
+				addColumn(f.Name, c.Name, "field "+f.Name)
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,165 @@
+// normalize_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNormalize checks the diagnostics for unknown relation targets and
+// colliding table and column names, sorted by file and line.
+func TestNormalize(t *testing.T) {
+	models := []Model{
+		{Name: "Post", App: "blog", File: "blog/models.py", Line: 3, Fields: []Field{
+			{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "User", Line: 4},
+			{Name: "author_id", Type: "IntegerField", Line: 5},
+			{Name: "id", Type: "IntegerField", Line: 6},
+		}},
+		{Name: "Post", App: "archive", DBTable: "blog_post", File: "archive/models.py", Line: 8, Fields: []Field{{Name: "title", Type: "CharField", Line: 9}}},
+	}
+	var got []string
+	for _, d := range normalize(&Config{}, models, Options{Dialect: "postgres"}) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		"archive/models.py:8: error E104: archive.Post: table blog_post collides with blog.Post",
+		"blog/models.py:4: warning W103: blog.Post.author: related model User not found; assuming an integer id primary key",
+		"blog/models.py:5: error E105: blog.Post.author_id: column author_id collides with field author",
+		"blog/models.py:6: error E105: blog.Post.id: column id collides with the implicit primary key",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diagnostics = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diagnostic %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestNormalizeStopsOnRelationErrors(t *testing.T) {
+	models := []Model{{Name: "Post", App: "blog", Fields: []Field{
+		{Name: "author", Type: "ForeignKey", Relation: "foreignkey"},
+	}}}
+	diags := normalize(&Config{}, models, Options{Dialect: "postgres"})
+	if len(diags) != 1 || diags[0].Code != codeRelationTarget {
+		t.Fatalf("diagnostics = %v", diags)
+	}
+	if models[0].Fields[0].RelatedPK != nil {
+		t.Error("relations resolved despite errors")
+	}
+}
+
+// TestAutoFields checks the id types set by DEFAULT_AUTO_FIELD, an app's
+// default_auto_field and explicit auto fields, and that foreign keys share
+// them.
+func TestAutoFields(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"site/settings.py": `DEFAULT_AUTO_FIELD = "django.db.models.BigAutoField"` + "\n",
+		"shop/apps.py": `from django.apps import AppConfig
+
+class ShopConfig(AppConfig):
+    default_auto_field = "django.db.models.AutoField"
+    name = "shop"
+`,
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.TextField()
+
+class Tag(models.Model):
+    id = models.SmallAutoField(primary_key=True)
+
+class Comment(models.Model):
+    number = models.AutoField(primary_key=True)
+    post = models.ForeignKey(Post, on_delete=models.CASCADE)
+    tag = models.ForeignKey(Tag, on_delete=models.CASCADE)
+`,
+		"shop/models.py": `from django.db import models
+
+class Order(models.Model):
+    total = models.IntegerField()
+`,
+	})
+	if diags := normalize(&Config{}, out.Models, Options{Dialect: "postgres", NoAppPrefix: true}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, Options{Dialect: "postgres"})
+	for _, want := range []string{"id BIGSERIAL PRIMARY KEY", "id SMALLSERIAL PRIMARY KEY", "number SERIAL", "post_id BIGINT NOT NULL", "tag_id SMALLINT NOT NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	for _, m := range out.Models {
+		if m.Name == "Order" && m.autoField() != "AutoField" {
+			t.Errorf("Order id is %s, want the app's AutoField", m.autoField())
+		}
+	}
+	if got := sqlType("BigAutoField", "mysql"); got != "BIGINT AUTO_INCREMENT" {
+		t.Errorf("mysql BigAutoField = %s", got)
+	}
+}
+
+// TestUniqueTogether checks the unique constraints of both forms of
+// Meta.unique_together, relations included.
+func TestUniqueTogether(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    slug = models.SlugField()
+    year = models.IntegerField()
+
+    class Meta:
+        unique_together = ("slug", "year")
+
+class Vote(models.Model):
+    post = models.ForeignKey(Post, on_delete=models.CASCADE)
+    voter = models.CharField(max_length=50)
+    kind = models.CharField(max_length=10)
+
+    class Meta:
+        unique_together = [["post", "voter"], ("voter", "kind")]
+`,
+	})
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{
+		"CONSTRAINT post_slug_year_uniq UNIQUE (slug, year)",
+		"CONSTRAINT vote_post_voter_uniq UNIQUE (post_id, voter)",
+		"CONSTRAINT vote_voter_kind_uniq UNIQUE (voter, kind)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+}
+
+// TestDBIndexes checks that fields declared with db_index=True are indexed
+// once, unless unique, keys or indexed by Meta.indexes.
+func TestDBIndexes(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    slug = models.SlugField(db_index=True)
+    code = models.CharField(max_length=10, unique=True, db_index=True)
+    rank = models.IntegerField(db_index=True)
+    title = models.CharField(max_length=100)
+
+    class Meta:
+        indexes = [models.Index(fields=["rank"], name="post_rank")]
+`,
+	})
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	if strings.Count(sql, "CREATE INDEX") != 2 || !strings.Contains(sql, "CREATE INDEX post_slug_idx ON post (slug);") || !strings.Contains(sql, "CREATE INDEX post_rank ON post (rank);") {
+		t.Errorf("indexes:\n%s", sql)
+	}
+}
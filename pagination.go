@@ -0,0 +1,143 @@
+// pagination.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pageOrder returns the columns of the model's Meta.ordering followed by
+// the primary key columns it lacks, which make the order total, and which
+// of them sort descending; the primary key sorts like the last ordering
+// column. Models ordered with respect to a parent sort on _order first.
+// err tells why the ordering cannot drive keyset pagination: it sorts on a
+// nullable field, or on a name that is not a column, where the columns
+// stop.
+func pageOrder(m Model) (cols []string, desc []bool, err error) {
+	last := false
+	if m.OrderWithRespectTo != "" {
+		cols, desc = []string{"_order"}, []bool{false}
+	}
+	for _, name := range m.Ordering {
+		d := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		fc, cerr := resolveColumns(name, m)
+		if cerr != nil {
+			err = fmt.Errorf("%q: %v", name, cerr)
+			break
+		}
+		if f, found := m.field(name); found && f.Nullable && err == nil {
+			err = fmt.Errorf("%s is nullable", name)
+		}
+		for _, c := range fc {
+			cols, desc = append(cols, c), append(desc, d)
+		}
+		last = d
+	}
+	for _, c := range m.pkColumns() {
+		if !strings.Contains(", "+strings.Join(cols, ", ")+", ", ", "+c.Name+", ") {
+			cols, desc = append(cols, c.Name), append(desc, last)
+		}
+	}
+	return cols, desc, err
+}
+
+// keysetCondition returns the WHERE condition selecting the rows after the
+// one whose columns are args: a row comparison when all columns sort the
+// same way, else its expansion, one column at a time.
+func keysetCondition(cols []string, desc []bool, args []string) string {
+	op := func(i int) string {
+		if desc[i] {
+			return " < "
+		}
+		return " > "
+	}
+	mixed := false
+	for _, d := range desc {
+		mixed = mixed || d != desc[0]
+	}
+	if !mixed {
+		if len(cols) == 1 {
+			return cols[0] + op(0) + args[0]
+		}
+		return "(" + strings.Join(cols, ", ") + ")" + op(0) + "(" + strings.Join(args, ", ") + ")"
+	}
+	terms := make([]string, len(cols))
+	for i := range cols {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, cols[j]+" = "+args[j])
+		}
+		terms[i] = "(" + strings.Join(append(conds, cols[i]+op(i)+args[i]), " AND ") + ")"
+	}
+	return strings.Join(terms, " OR ")
+}
+
+// pageQueries generates a List<Model>PageOffset query returning a page of
+// every model by LIMIT and OFFSET, in its Meta.ordering and primary key.
+// Models with a Meta.ordering also get a List<Model>Page query returning
+// the first page, and a List<Model>PageAfter query returning the page after
+// a row: unlike OFFSET, the cursor lets the database seek straight to the
+// page through an index on the ordering columns. Orderings unsuited to
+// keyset pagination are reported.
+func pageQueries(models []Model) ([]TranslatedQuery, []Diagnostic) {
+	var result []TranslatedQuery
+	var diags []Diagnostic
+	for _, m := range models {
+		if m.isView() {
+			continue
+		}
+		cols, desc, err := pageOrder(m)
+		params := map[string]int{}
+		order := make([]string, len(cols))
+		args := make([]string, len(cols))
+		for i, c := range cols {
+			order[i] = c
+			if desc[i] {
+				order[i] += " DESC"
+			}
+			args[i] = "sqlc.arg(" + param(c, params) + ")"
+		}
+		orderBy := " ORDER BY " + strings.Join(order, ", ")
+		limit := " LIMIT sqlc.arg(" + param("limit", params) + ")"
+		source := "primary key"
+		if m.OrderWithRespectTo != "" {
+			source = fmt.Sprintf("Meta.order_with_respect_to = %q", m.OrderWithRespectTo)
+		}
+		if len(m.Ordering) > 0 {
+			quoted := make([]string, len(m.Ordering))
+			for i, name := range m.Ordering {
+				quoted[i] = fmt.Sprintf("%q", name)
+			}
+			source = "Meta.ordering = [" + strings.Join(quoted, ", ") + "]"
+		}
+		table := m.table()
+		result = append(result, TranslatedQuery{
+			Query: Query{App: m.App, Model: m.Name, Source: source},
+			Name:  "List" + m.Name + "PageOffset",
+			Kind:  ":many",
+			SQL:   "SELECT * FROM " + table + orderBy + limit + " OFFSET sqlc.arg(" + param("offset", params) + ");",
+		})
+		if len(m.Ordering) == 0 {
+			continue
+		}
+		if err != nil {
+			diags = append(diags, diagnose(codeKeysetSkipped, m, "", "no keyset pagination queries for Meta.ordering: %v", err))
+			continue
+		}
+		result = append(result,
+			TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: source},
+				Name:  "List" + m.Name + "Page",
+				Kind:  ":many",
+				SQL:   "SELECT * FROM " + table + orderBy + limit + ";",
+			},
+			TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: source},
+				Name:  "List" + m.Name + "PageAfter",
+				Kind:  ":many",
+				SQL:   "SELECT * FROM " + table + " WHERE " + keysetCondition(cols, desc, args) + orderBy + limit + ";",
+			})
+	}
+	return result, diags
+}
@@ -0,0 +1,71 @@
+// pagination_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPageQueries parses Meta.ordering and checks the offset and keyset
+// page queries, and that orderings unsuited to keyset pagination are
+// reported.
+func TestPageQueries(t *testing.T) {
+	out := parseProject(t, map[string]string{"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    published = models.DateTimeField()
+    title = models.CharField(max_length=100)
+
+    class Meta:
+        ordering = ["-published", "-title"]
+
+class Comment(models.Model):
+    created = models.DateTimeField()
+
+    class Meta:
+        ordering = ["created"]
+
+class Mixed(models.Model):
+    a = models.IntegerField()
+    b = models.IntegerField()
+
+    class Meta:
+        ordering = ["a", "-b"]
+
+class Draft(models.Model):
+    saved = models.DateTimeField(null=True)
+
+    class Meta:
+        ordering = ["saved"]
+
+class Tag(models.Model):
+    name = models.CharField(max_length=50)
+`})
+	if got := strings.Join(out.Models[0].Ordering, ","); got != "-published,-title" {
+		t.Fatalf("ordering = %s", got)
+	}
+	queries, diags := pageQueries(out.Models)
+	var got []string
+	for _, q := range queries {
+		got = append(got, q.Name+": "+q.SQL)
+	}
+	want := []string{
+		"ListPostPageOffset: SELECT * FROM post ORDER BY published DESC, title DESC, id DESC LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+		"ListPostPage: SELECT * FROM post ORDER BY published DESC, title DESC, id DESC LIMIT sqlc.arg(limit);",
+		"ListPostPageAfter: SELECT * FROM post WHERE (published, title, id) < (sqlc.arg(published), sqlc.arg(title), sqlc.arg(id)) ORDER BY published DESC, title DESC, id DESC LIMIT sqlc.arg(limit);",
+		"ListCommentPageOffset: SELECT * FROM comment ORDER BY created, id LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+		"ListCommentPage: SELECT * FROM comment ORDER BY created, id LIMIT sqlc.arg(limit);",
+		"ListCommentPageAfter: SELECT * FROM comment WHERE (created, id) > (sqlc.arg(created), sqlc.arg(id)) ORDER BY created, id LIMIT sqlc.arg(limit);",
+		"ListMixedPageOffset: SELECT * FROM mixed ORDER BY a, b DESC, id DESC LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+		"ListMixedPage: SELECT * FROM mixed ORDER BY a, b DESC, id DESC LIMIT sqlc.arg(limit);",
+		"ListMixedPageAfter: SELECT * FROM mixed WHERE (a > sqlc.arg(a)) OR (a = sqlc.arg(a) AND b < sqlc.arg(b)) OR (a = sqlc.arg(a) AND b = sqlc.arg(b) AND id < sqlc.arg(id)) ORDER BY a, b DESC, id DESC LIMIT sqlc.arg(limit);",
+		"ListDraftPageOffset: SELECT * FROM draft ORDER BY saved, id LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+		"ListTagPageOffset: SELECT * FROM tag ORDER BY id LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("queries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(diags) != 1 || diags[0].Code != codeKeysetSkipped || diags[0].Model != "Draft" || !strings.Contains(diags[0].Message, "saved is nullable") {
+		t.Errorf("diagnostics = %v", diags)
+	}
+}
@@ -0,0 +1,1066 @@
+# parser.py
+import sys, os, re, io, ast, json, tokenize
+
+ROOT = os.path.abspath(sys.argv[1])
+PYTHON2_MODELS = "--python2-models" in sys.argv[2:]
+SETTINGS_MODULE = os.environ.get("DJANGO_SETTINGS_MODULE")
+MAX_DEPTH = next((int(a.split("=", 1)[1]) for a in sys.argv[2:] if a.startswith("--max-depth=")), 0)
+RELATIONS = {"ForeignKey": "foreignkey", "OneToOneField": "one2one", "ManyToManyField": "many2many"}
+RANGE_FIELDS = {"IntegerRangeField", "BigIntegerRangeField", "DecimalRangeField", "DateRangeField", "DateTimeRangeField"}
+GEOMETRY_FIELDS = {"GeometryField", "PointField", "LineStringField", "PolygonField", "MultiPointField", "MultiLineStringField", "MultiPolygonField", "GeometryCollectionField"}
+RANGE_OPERATORS = {
+    "EQUAL": "=", "NOT_EQUAL": "<>", "CONTAINS": "@>", "CONTAINED_BY": "<@", "OVERLAPS": "&&",
+    "FULLY_LT": "<<", "FULLY_GT": ">>", "NOT_LT": "&>", "NOT_GT": "&<", "ADJACENT_TO": "-|-",
+}
+BINOPS = {ast.Add: "+", ast.Sub: "-", ast.Mult: "*", ast.Div: "/"}
+TEXT_FUNCTIONS = {"Lower": "LOWER", "Upper": "UPPER"}
+VIEW_HINT = re.compile(r"#\s*django2go:\s*view\s+(\S+)")
+TEMPLATE_EXTENSIONS = (".html", ".jinja", ".jinja2", ".j2")
+TEMPLATE_TAG = re.compile(r"\{[{%](.*?)[}%]\}")
+TEMPLATE_ATTRIBUTE = re.compile(r"\.([A-Za-z_]\w*)")
+NOT_LITERAL = object()
+SEARCH_FUNCTIONS = ("SearchVector", "SearchQuery", "SearchRank")
+DJANGO_REQUIREMENT = re.compile(r"""(?im)(?<![\w.-])django(?![\w.-])(?:\[[^\]]*\])?["']?\s*(?:=\s*["']\s*(?:===|==|~=|>=|\^|~)?|===|==|~=|>=|\^|~)\s*(\d+)\.(\d+)""")
+REQUIREMENT_FILES = ("pyproject.toml", "setup.cfg", "setup.py", "Pipfile")
+# IR_VERSION and CAPABILITIES describe the printed document to django2go;
+# see protocol.go before changing them.
+IR_VERSION = 1
+CAPABILITIES = ["models", "constraints", "indexes", "properties", "queries", "admins", "commands", "schedules", "migrations", "settings", "tenants", "pool", "decimals", "ordering", "search", "actions", "timezone", "auto_fields", "choices", "defaults", "auto_now", "geometry", "collation", "ranges", "money", "django_version", "app_labels"]
+MIGRATION_OPERATIONS = {
+    "CreateModel": ["name", "fields", "options"], "DeleteModel": ["name"], "RenameModel": ["old_name", "new_name"],
+    "AlterModelTable": ["name", "table"], "AddField": ["model_name", "name", "field"],
+    "RemoveField": ["model_name", "name"], "AlterField": ["model_name", "name", "field"],
+    "RenameField": ["model_name", "old_name", "new_name"],
+}
+
+def app_label(path):
+    d = os.path.dirname(path)
+    while len(d) >= len(ROOT):
+        if any(os.path.exists(os.path.join(d, n)) for n in ("apps.py", "models.py")):
+            return os.path.basename(d)
+        d = os.path.dirname(d)
+    return os.path.basename(ROOT)
+
+def literal(node):
+    if isinstance(node, ast.Constant) and isinstance(node.value, (str, int, float, bool, type(None))):
+        return node.value
+    if isinstance(node, ast.UnaryOp) and isinstance(node.op, ast.USub) and isinstance(literal(node.operand), (int, float)):
+        return -node.operand.value
+    if isinstance(node, (ast.List, ast.Tuple)):
+        values = [literal(e) for e in node.elts]
+        return NOT_LITERAL if NOT_LITERAL in values else values
+    return NOT_LITERAL
+
+def arg(node):
+    value = literal(node)
+    if value is NOT_LITERAL:
+        result = {"literal": False, "value": None}
+        if isinstance(node, ast.Call) and base_name(node.func) == "F" and len(node.args) == 1 and isinstance(literal(node.args[0]), str):
+            result["field"] = literal(node.args[0])
+        search = search_expression(node)
+        if search:
+            result["search"] = search
+        return result
+    return {"literal": True, "value": value}
+
+def search_expression(node):
+    if not (isinstance(node, ast.Call) and base_name(node.func) in SEARCH_FUNCTIONS):
+        return None
+    kwargs = {k.arg: k.value for k in node.keywords if k.arg}
+    expr = {"function": base_name(node.func), "args": [arg(a) for a in node.args]}
+    if "config" in kwargs:
+        expr["config"] = arg(kwargs["config"])
+    for key in ("weight", "search_type"):
+        if isinstance(option(kwargs, key), str):
+            expr[key] = option(kwargs, key)
+    return expr
+
+def base_name(node):
+    if isinstance(node, ast.Name):
+        return node.id
+    if isinstance(node, ast.Attribute):
+        return node.attr
+    return ""
+
+def dotted_name(node):
+    if isinstance(node, ast.Name):
+        return node.id
+    if isinstance(node, ast.Attribute):
+        prefix = dotted_name(node.value)
+        return prefix + "." + node.attr if prefix else ""
+    return ""
+
+def related_model(call, model):
+    target = call.args[0] if call.args else next((k.value for k in call.keywords if k.arg == "to"), None)
+    name = base_name(target)
+    if isinstance(target, ast.Constant) and isinstance(target.value, str):
+        name = target.value.split(".")[-1]
+    return model if name == "self" else name
+
+def meta_options(node, classes=None, rel=None, seen=()):
+    meta = next((s for s in node.body if isinstance(s, ast.ClassDef) and s.name == "Meta"), None)
+    options = {}
+    parents = node.bases if meta is None else [b.value for b in meta.bases if isinstance(b, ast.Attribute) and b.attr == "Meta"]
+    for base in reversed(parents):
+        parent = parent_class(classes, base, rel)
+        if parent is not None and parent["node"] not in seen + (node,) and abstract(parent["node"]):
+            options.update(meta_options(parent["node"], classes, parent["rel"], seen + (node,)))
+            options.pop("abstract", None)
+    if meta is not None:
+        options.update({t.id: s.value for s in meta.body if isinstance(s, ast.Assign) for t in s.targets if isinstance(t, ast.Name)})
+    return options
+
+def abstract(node):
+    return option(meta_options(node), "abstract") is True
+
+def parent_class(classes, base, rel):
+    entries = (classes or {}).get(base_name(base), [])
+    return next((e for e in entries if e["rel"] == rel), entries[0] if entries else None)
+
+def model_class(node, rel, classes, seen=()):
+    for base in node.bases:
+        if base_name(base) == "Model":
+            return True
+        parent = parent_class(classes, base, rel)
+        if parent is not None and parent["node"] not in seen + (node,) and abstract(parent["node"]) and model_class(parent["node"], parent["rel"], classes, seen + (node,)):
+            return True
+    return False
+
+def proxy(node):
+    return option(meta_options(node), "proxy") is True
+
+def concrete_parent(entry, classes, seen=()):
+    for base in entry["node"].bases:
+        parent = parent_class(classes, base, entry["rel"])
+        if parent is None or parent["node"] in seen + (entry["node"],):
+            continue
+        if proxy(parent["node"]):
+            return concrete_parent(parent, classes, seen + (entry["node"],))
+        if model_class(parent["node"], parent["rel"], classes) and not abstract(parent["node"]):
+            return parent["node"].name
+    return None
+
+def inherited(entry, classes, extract, seen=()):
+    node = entry["node"]
+    items = []
+    for base in node.bases:
+        parent = parent_class(classes, base, entry["rel"])
+        if parent is None or parent["node"] in seen + (node,) or not abstract(parent["node"]):
+            continue
+        for item, rel in inherited(parent, classes, extract, seen + (node,)):
+            items = [i for i in items if i[0]["name"] != item["name"]] + [(item, rel)]
+    removed = {t.id for s in node.body if isinstance(s, ast.Assign) and isinstance(s.value, ast.Constant) and s.value.value is None
+               for t in s.targets if isinstance(t, ast.Name)}
+    own = extract(entry)
+    names = removed | {i["name"] for i in own}
+    return [i for i in items if i[0]["name"] not in names] + [(i, entry["rel"]) for i in own]
+
+def option(meta, key, default=None):
+    value = literal(meta[key]) if key in meta else default
+    return default if value is NOT_LITERAL else value
+
+def view_hint(node, lines, full):
+    for line in lines[node.lineno - 1:node.end_lineno]:
+        match = VIEW_HINT.search(line)
+        if match:
+            return os.path.join(os.path.dirname(full), match.group(1))
+    return None
+
+def extract_model(entry, classes, label=None):
+    node, app, rel = entry["node"], entry["app"], entry["rel"]
+    meta = meta_options(node, classes, rel)
+    managed = option(meta, "managed", True) is not False
+    fields = inherited(entry, classes, lambda e: extract_fields(e["node"], e["choices"], node.name))
+    properties = inherited(entry, classes, lambda e: extract_properties(e["node"]))
+    for item, source in fields + properties:
+        if source != rel:
+            item["line"] = node.lineno
+    named = {"app_label": (label or app).lower(), "class": node.name.lower()}
+    constraints, indexes = extract_constraints(meta), extract_indexes(meta)
+    for item in constraints + indexes:
+        if isinstance(item["name"], str):
+            item["name"] = re.sub(r"%\((app_label|class)\)s", lambda m: named[m.group(1)], item["name"])
+    return {
+        "name": node.name,
+        "app": app,
+        "label": label,
+        "file": rel,
+        "line": node.lineno,
+        "managed": managed,
+        "view_file": None if managed else view_hint(node, entry["lines"], entry["full"]),
+        "order_with_respect_to": option(meta, "order_with_respect_to"),
+        "ordering": option(meta, "ordering"),
+        "db_table": option(meta, "db_table"),
+        "fields": [f for f, _ in fields],
+        "properties": [p for p, _ in properties],
+        "constraints": constraints,
+        "indexes": indexes,
+        "index_together": together(meta, "index_together"),
+        "unique_together": together(meta, "unique_together"),
+        "aliases": [],
+    }
+
+def string_list(node):
+    if not isinstance(node, (ast.List, ast.Tuple)):
+        return []
+    return [literal(e) for e in node.elts if isinstance(literal(e), str)]
+
+def extract_constraints(meta):
+    result = []
+    node = meta.get("constraints")
+    if not isinstance(node, (ast.List, ast.Tuple)):
+        return result
+    for call in node.elts:
+        if not isinstance(call, ast.Call):
+            continue
+        kwargs = {k.arg: k.value for k in call.keywords if k.arg}
+        name = option(kwargs, "name")
+        if base_name(call.func) == "UniqueConstraint":
+            result.append({
+                "kind": "unique",
+                "name": name if isinstance(name, str) else None,
+                "fields": string_list(kwargs.get("fields")),
+                "functional": [field_expression(a) for a in call.args],
+                "deferrable": base_name(kwargs["deferrable"]).lower() if "deferrable" in kwargs else None,
+                "condition": q_expression(kwargs["condition"]) if "condition" in kwargs else None,
+            })
+        elif base_name(call.func) == "CheckConstraint":
+            condition = kwargs.get("condition", kwargs.get("check"))
+            result.append({
+                "kind": "check",
+                "name": name if isinstance(name, str) else None,
+                "fields": [],
+                "condition": q_expression(condition) if condition is not None else None,
+            })
+        elif base_name(call.func) == "ExclusionConstraint":
+            index_type = option(kwargs, "index_type", "gist")
+            result.append({
+                "kind": "exclusion",
+                "name": name if isinstance(name, str) else None,
+                "fields": [],
+                "expressions": [exclusion_expression(e) for e in getattr(kwargs.get("expressions"), "elts", [])],
+                "index_type": index_type.lower() if isinstance(index_type, str) else "gist",
+                "condition": q_expression(kwargs["condition"]) if "condition" in kwargs else None,
+            })
+    return result
+
+def exclusion_expression(node):
+    if not (isinstance(node, ast.Tuple) and len(node.elts) == 2):
+        return {"field": None, "operator": None}
+    target, op = node.elts
+    field = literal(target)
+    if isinstance(target, ast.Call) and base_name(target.func) == "F" and target.args:
+        field = literal(target.args[0])
+    operator = literal(op)
+    if not isinstance(operator, str):
+        operator = RANGE_OPERATORS.get(base_name(op))
+    return {"field": field if isinstance(field, str) else None, "operator": operator}
+
+def extract_indexes(meta):
+    result = []
+    for call in getattr(meta.get("indexes"), "elts", []):
+        if isinstance(call, ast.Call) and base_name(call.func) in ("Index", "GistIndex", "GinIndex"):
+            kwargs = {k.arg: k.value for k in call.keywords if k.arg}
+            method = base_name(call.func)[:-len("Index")].lower()
+            result.append({
+                "name": option(kwargs, "name"),
+                "fields": string_list(kwargs.get("fields")),
+                "functional": [field_expression(a) for a in call.args],
+                "method": method,
+                "condition": q_expression(kwargs["condition"]) if "condition" in kwargs else None,
+            })
+    return result
+
+def together(meta, name):
+    node = meta.get(name)
+    if not isinstance(node, (ast.List, ast.Tuple)):
+        return []
+    if all(isinstance(literal(e), str) for e in node.elts):
+        return [string_list(node)] if node.elts else []
+    return [string_list(e) for e in node.elts if string_list(e)]
+
+def q_expression(node):
+    if isinstance(node, ast.Call) and base_name(node.func) == "Q":
+        children = [q_expression(a) for a in node.args]
+        children += [{"op": "lookup", "lookup": dict(arg(k.value), key=k.arg)} for k in node.keywords if k.arg]
+        return children[0] if len(children) == 1 else {"op": "and", "children": children}
+    if isinstance(node, ast.BinOp) and isinstance(node.op, (ast.BitAnd, ast.BitOr)):
+        op = "and" if isinstance(node.op, ast.BitAnd) else "or"
+        return {"op": op, "children": [q_expression(node.left), q_expression(node.right)]}
+    if isinstance(node, ast.UnaryOp) and isinstance(node.op, ast.Invert):
+        return {"op": "not", "children": [q_expression(node.operand)]}
+    return {"op": "unsupported"}
+
+def extract_properties(node):
+    props = []
+    for stmt in node.body:
+        if not isinstance(stmt, ast.FunctionDef):
+            continue
+        if not any(base_name(d) in ("property", "cached_property") for d in stmt.decorator_list):
+            continue
+        body = [b for b in stmt.body if not (isinstance(b, ast.Expr) and isinstance(b.value, ast.Constant))]
+        expr = None
+        if len(body) == 1 and isinstance(body[0], ast.Return) and body[0].value is not None:
+            expr = expression(body[0].value)
+        props.append({"name": stmt.name, "line": stmt.lineno, "expr": expr, "referenced_by": []})
+    return props
+
+def field_expression(node):
+    if isinstance(literal(node), str):
+        return {"kind": "field", "name": literal(node)}
+    return expression(node)
+
+def expression(node):
+    if isinstance(node, ast.Attribute) and isinstance(node.value, ast.Name) and node.value.id == "self":
+        return {"kind": "field", "name": node.attr}
+    value = literal(node)
+    if value is not NOT_LITERAL and value is not None:
+        return {"kind": "const", "value": value}
+    if isinstance(node, ast.Call) and base_name(node.func) == "F" and len(node.args) == 1 and isinstance(literal(node.args[0]), str):
+        return {"kind": "field", "name": literal(node.args[0])}
+    if isinstance(node, ast.Call) and base_name(node.func) == "Value" and len(node.args) == 1:
+        return expression(node.args[0])
+    if isinstance(node, ast.Call) and base_name(node.func) == "Concat":
+        parts = [field_expression(a) for a in node.args]
+        return {"kind": "concat", "parts": parts} if parts and None not in parts else None
+    if isinstance(node, ast.Call) and base_name(node.func) in TEXT_FUNCTIONS and len(node.args) == 1:
+        part = field_expression(node.args[0])
+        return {"kind": "func", "name": TEXT_FUNCTIONS[base_name(node.func)], "parts": [part]} if part else None
+    if isinstance(node, ast.BinOp) and type(node.op) in BINOPS:
+        left, right = expression(node.left), expression(node.right)
+        if left and right:
+            return {"kind": "binop", "op": BINOPS[type(node.op)], "left": left, "right": right}
+    if isinstance(node, ast.JoinedStr):
+        parts = []
+        for v in node.values:
+            if isinstance(v, ast.FormattedValue):
+                part = expression(v.value) if v.format_spec is None else None
+            else:
+                part = expression(v)
+            if part is None:
+                return None
+            parts.append(part)
+        return {"kind": "concat", "parts": parts}
+    return None
+
+def app_list(node, lists):
+    if isinstance(node, (ast.List, ast.Tuple)):
+        values = [literal(e) for e in node.elts]
+        return values if all(isinstance(v, str) for v in values) else None
+    if isinstance(node, ast.Name):
+        return lists.get(node.id)
+    if isinstance(node, ast.BinOp) and isinstance(node.op, ast.Add):
+        left, right = app_list(node.left, lists), app_list(node.right, lists)
+        return left + right if left is not None and right is not None else None
+    return None
+
+def installed_apps(settings, apps, replace=False):
+    if replace:
+        settings["installed_apps"] = []
+        settings["installed_apps_unresolved"] = False
+    if apps is None:
+        settings["installed_apps_unresolved"] = True
+        return
+    installed = settings.setdefault("installed_apps", [])
+    installed += [a for a in apps if a not in installed]
+
+def setting(node):
+    if isinstance(node, ast.Call) and base_name(node.func) == "int" and len(node.args) == 1:
+        value = setting(node.args[0])
+        try:
+            return int(value) if isinstance(value, str) else value
+        except ValueError:
+            return NOT_LITERAL
+    if isinstance(node, ast.Call) and dotted_name(node.func) in ("os.environ.get", "os.getenv", "environ.get", "getenv") and node.args:
+        name = literal(node.args[0])
+        default = literal(node.args[1]) if len(node.args) > 1 else None
+        return os.environ.get(name, default) if isinstance(name, str) else NOT_LITERAL
+    if isinstance(node, ast.Subscript) and dotted_name(node.value) in ("os.environ", "environ"):
+        name = literal(node.slice)
+        return os.environ.get(name, NOT_LITERAL) if isinstance(name, str) else NOT_LITERAL
+    return literal(node)
+
+def settings_file(module, package=None, level=0):
+    bases = [package] if level else [ROOT, os.path.dirname(ROOT)]
+    for base in bases:
+        for _ in range(level - 1):
+            base = os.path.dirname(base)
+        path = os.path.join(base, *module.split(".")) if module else base
+        for full in (path + ".py", os.path.join(path, "__init__.py")):
+            if os.path.isfile(full):
+                return full
+    return None
+
+def settings_chain(full, chain, seen):
+    if full in seen:
+        return
+    seen.add(full)
+    try:
+        tree = ast.parse(read_source(full))
+    except (OSError, UnicodeDecodeError, SyntaxError):
+        return
+    for node in tree.body:
+        if isinstance(node, ast.ImportFrom) and any(a.name == "*" for a in node.names):
+            base = settings_file(node.module, os.path.dirname(full), node.level)
+            if base:
+                settings_chain(base, chain, seen)
+    chain.append(tree)
+
+def settings_refs(tree, settings, lists, merge=True):
+    for node in tree.body:
+        if isinstance(node, ast.AugAssign) and isinstance(node.target, ast.Name) and node.target.id == "INSTALLED_APPS":
+            installed_apps(settings, app_list(node.value, lists))
+            continue
+        if not (isinstance(node, ast.Assign) and len(node.targets) == 1 and isinstance(node.targets[0], ast.Name)):
+            continue
+        name = node.targets[0].id
+        lists[name] = app_list(node.value, lists)
+        if name == "SESSION_ENGINE" and isinstance(setting(node.value), str):
+            settings["session_engine"] = setting(node.value)
+        elif name == "DEFAULT_AUTO_FIELD" and isinstance(setting(node.value), str):
+            settings["default_auto_field"] = setting(node.value)
+        elif name == "USE_TZ" and isinstance(literal(node.value), bool):
+            settings["use_tz"] = literal(node.value)
+        elif name == "INSTALLED_APPS":
+            installed_apps(settings, lists[name], replace=not merge)
+        elif name in ("SHARED_APPS", "TENANT_APPS") and isinstance(node.value, (ast.List, ast.Tuple)):
+            settings[name.lower()] = string_list(node.value)
+        elif name == "DATABASES" and isinstance(node.value, ast.Dict):
+            for key, db in zip(node.value.keys, node.value.values):
+                if key is None or literal(key) != "default" or not isinstance(db, ast.Dict):
+                    continue
+                options = {literal(k): setting(v) for k, v in zip(db.keys, db.values) if k is not None}
+                age = options.get("CONN_MAX_AGE", 0)
+                if age is None:
+                    settings["conn_max_age"] = -1
+                elif isinstance(age, int) and not isinstance(age, bool):
+                    settings["conn_max_age"] = age
+        elif name == "CACHES" and isinstance(node.value, ast.Dict):
+            for cache in node.value.values:
+                if not isinstance(cache, ast.Dict):
+                    continue
+                options = {literal(k): setting(v) for k, v in zip(cache.keys, cache.values) if k is not None}
+                backend, location = options.get("BACKEND"), options.get("LOCATION")
+                if isinstance(backend, str) and backend.endswith("DatabaseCache") and isinstance(location, str):
+                    settings.setdefault("cache_tables", []).append(location)
+
+def app_config(tree, name):
+    for node in tree.body:
+        if isinstance(node, ast.ClassDef) and "AppConfig" in [base_name(b) for b in node.bases]:
+            for stmt in node.body:
+                if isinstance(stmt, ast.Assign) and name in [t.id for t in stmt.targets if isinstance(t, ast.Name)] and isinstance(literal(stmt.value), str):
+                    return literal(stmt.value)
+    return None
+
+def management_command(tree, rel, app, file):
+    for node in tree.body:
+        if not (isinstance(node, ast.ClassDef) and node.name == "Command"):
+            continue
+        attrs = {t.id: s.value for s in node.body if isinstance(s, ast.Assign) for t in s.targets if isinstance(t, ast.Name)}
+        arguments = []
+        for stmt in node.body:
+            if not (isinstance(stmt, ast.FunctionDef) and stmt.name == "add_arguments"):
+                continue
+            for call in ast.walk(stmt):
+                if not (isinstance(call, ast.Call) and base_name(call.func) == "add_argument"):
+                    continue
+                kwargs = {k.arg: k.value for k in call.keywords if k.arg}
+                value = lambda key: literal(kwargs[key]) if key in kwargs and literal(kwargs[key]) is not NOT_LITERAL else None
+                arguments.append({
+                    "flags": [literal(a) for a in call.args if isinstance(literal(a), str)],
+                    "type": base_name(kwargs.get("type")) or None,
+                    "default": value("default"),
+                    "help": value("help"),
+                    "action": value("action"),
+                    "nargs": str(value("nargs")) if value("nargs") is not None else None,
+                    "dest": value("dest"),
+                    "required": value("required") is True,
+                    "choices": value("choices"),
+                })
+        help_text = literal(attrs["help"]) if "help" in attrs else None
+        return {
+            "name": file[:-3],
+            "app": app,
+            "file": rel,
+            "line": node.lineno,
+            "help": help_text if isinstance(help_text, str) else None,
+            "arguments": arguments,
+        }
+    return None
+
+CRONTAB_FIELDS = ("minute", "hour", "day_of_week", "day_of_month", "month_of_year")
+INTERVAL_UNITS = {"weeks": 604800, "days": 86400, "hours": 3600, "minutes": 60, "seconds": 1, "milliseconds": 0.001}
+
+def schedule(node):
+    value = literal(node) if node is not None else NOT_LITERAL
+    if isinstance(value, (int, float)) and not isinstance(value, bool):
+        return {"kind": "interval", "seconds": value}
+    if isinstance(node, ast.Call):
+        name = base_name(node.func)
+        kwargs = {k.arg: literal(k.value) for k in node.keywords if k.arg}
+        if name == "crontab":
+            kwargs.update(zip(CRONTAB_FIELDS, [literal(a) for a in node.args]))
+            fields = {k: str(v) for k, v in kwargs.items() if k in CRONTAB_FIELDS and isinstance(v, (str, int))}
+            if len(fields) == len([k for k in kwargs if k in CRONTAB_FIELDS]):
+                return {"kind": "crontab", "fields": fields}
+        elif name == "timedelta":
+            kwargs.update(zip(("days", "seconds"), [literal(a) for a in node.args]))
+            values = [(k, v) for k, v in kwargs.items() if k in INTERVAL_UNITS]
+            if all(isinstance(v, (int, float)) for _, v in values):
+                return {"kind": "interval", "seconds": sum(INTERVAL_UNITS[k] * v for k, v in values)}
+        elif name == "schedule":
+            every = node.args[0] if node.args else next((k.value for k in node.keywords if k.arg == "run_every"), None)
+            return schedule(every)
+    return {"kind": "unsupported", "source": ast.unparse(node) if node is not None else ""}
+
+def schedule_refs(tree, rel):
+    entries = []
+    for node in tree.body:
+        if not (isinstance(node, ast.Assign) and len(node.targets) == 1):
+            continue
+        target = node.targets[0]
+        name = target.id if isinstance(target, ast.Name) else target.attr if isinstance(target, ast.Attribute) else ""
+        if name in ("CELERY_BEAT_SCHEDULE", "beat_schedule") and isinstance(node.value, ast.Dict):
+            for key, value in zip(node.value.keys, node.value.values):
+                if not isinstance(value, ast.Dict):
+                    continue
+                options = {literal(k): v for k, v in zip(value.keys, value.values) if k is not None}
+                task = literal(options["task"]) if "task" in options else None
+                if not isinstance(task, str):
+                    continue
+                label = literal(key) if key is not None else None
+                entries.append({"name": label if isinstance(label, str) else task, "task": task,
+                                "schedule": schedule(options.get("schedule")), "file": rel, "line": value.lineno})
+        elif name == "CRONJOBS" and isinstance(node.value, (ast.List, ast.Tuple)):
+            for job in node.value.elts:
+                if not (isinstance(job, (ast.List, ast.Tuple)) and len(job.elts) >= 2):
+                    continue
+                spec, task = literal(job.elts[0]), literal(job.elts[1])
+                if isinstance(spec, str) and isinstance(task, str):
+                    entries.append({"name": task, "task": task, "schedule": {"kind": "cron", "spec": spec},
+                                    "file": rel, "line": job.lineno})
+    return entries
+
+def migration_keys(node):
+    keys = [literal(e) for e in getattr(node, "elts", [])]
+    return [k for k in keys if isinstance(k, list) and len(k) == 2 and all(isinstance(p, str) for p in k)]
+
+def migration(tree, rel, app, file):
+    for node in tree.body:
+        if not (isinstance(node, ast.ClassDef) and node.name == "Migration"):
+            continue
+        values = {t.id: s.value for s in node.body if isinstance(s, ast.Assign) for t in s.targets if isinstance(t, ast.Name)}
+        operations = []
+        for call in getattr(values.get("operations"), "elts", []):
+            params = MIGRATION_OPERATIONS.get(base_name(getattr(call, "func", None)))
+            if params is None:
+                continue
+            kwargs = dict(zip(params, call.args))
+            kwargs.update({k.arg: k.value for k in call.keywords if k.arg})
+            strings = {k: literal(v) for k, v in kwargs.items() if isinstance(literal(v), str)}
+            op = {"op": base_name(call.func), "line": call.lineno, "managed": True}
+            op["model"] = strings.get("model_name", strings.get("name"))
+            op["name"] = strings.get("name") if "model_name" in params else None
+            op["old_name"], op["new_name"], op["table"] = strings.get("old_name"), strings.get("new_name"), strings.get("table")
+            if isinstance(kwargs.get("field"), ast.Call):
+                op["field"] = field(op["name"], kwargs["field"], op["model"])
+            op["fields"] = [field(literal(t.elts[0]), t.elts[1], op["model"]) for t in getattr(kwargs.get("fields"), "elts", [])
+                            if isinstance(t, ast.Tuple) and len(t.elts) == 2 and isinstance(t.elts[1], ast.Call)]
+            if isinstance(kwargs.get("options"), ast.Dict):
+                options = {literal(k): literal(v) for k, v in zip(kwargs["options"].keys, kwargs["options"].values)}
+                op["managed"] = options.get("managed") is not False
+                if isinstance(options.get("db_table"), str):
+                    op["table"] = options["db_table"]
+            operations.append(op)
+        return {"app": app, "name": file[:-3], "file": rel, "dependencies": migration_keys(values.get("dependencies")),
+                "replaces": migration_keys(values.get("replaces")), "operations": operations}
+    return None
+
+def admin_options(node):
+    assigned = {t.id: s.value for s in node.body if isinstance(s, ast.Assign) for t in s.targets if isinstance(t, ast.Name)}
+    options = {}
+    for key in ("list_display", "list_filter", "search_fields", "ordering"):
+        items = assigned.get(key)
+        if not isinstance(items, (ast.List, ast.Tuple)):
+            continue
+        # list_filter entries may be (field, FilterClass) tuples.
+        heads = [e.elts[0] if isinstance(e, (ast.List, ast.Tuple)) and e.elts else e for e in items.elts]
+        options[key] = [literal(e) for e in heads if isinstance(literal(e), str)]
+    return options
+
+def admin_refs(tree, rel):
+    classes = {}
+    registered = []
+    for node in tree.body:
+        if isinstance(node, ast.ClassDef) and "ModelAdmin" in [base_name(b) for b in node.bases]:
+            classes[node.name] = node
+            for d in node.decorator_list:
+                if isinstance(d, ast.Call) and base_name(d.func) == "register":
+                    registered += [(base_name(a), node.name) for a in d.args]
+        elif isinstance(node, ast.Expr) and isinstance(node.value, ast.Call) and base_name(node.value.func) == "register":
+            args = node.value.args
+            if len(args) < 2:
+                continue
+            targets = args[0].elts if isinstance(args[0], (ast.List, ast.Tuple)) else [args[0]]
+            registered += [(base_name(t), base_name(args[1])) for t in targets]
+    result = []
+    for model, name in registered:
+        node = classes.get(name)
+        if node is not None:
+            result.append(dict(admin_options(node), model=model, admin=name, file=rel, line=node.lineno))
+    return result
+
+def serializer_refs(node, rel):
+    meta = meta_options(node)
+    model = base_name(meta.get("model"))
+    if not model:
+        return []
+    names = []
+    fields = meta.get("fields")
+    if isinstance(fields, (ast.List, ast.Tuple)):
+        names += [(literal(e), e.lineno) for e in fields.elts if isinstance(literal(e), str)]
+    for stmt in node.body:
+        if isinstance(stmt, ast.Assign) and isinstance(stmt.value, ast.Call):
+            source = next((literal(k.value) for k in stmt.value.keywords if k.arg == "source"), None)
+            for t in stmt.targets:
+                if isinstance(t, ast.Name):
+                    names.append((source if isinstance(source, str) else t.id, stmt.lineno))
+    return [(model, name, "%s:%d" % (rel, line)) for name, line in names]
+
+def template_refs(full, rel):
+    refs = []
+    try:
+        with open(full, encoding="utf-8-sig", errors="replace") as f:
+            for lineno, line in enumerate(f, 1):
+                for tag in TEMPLATE_TAG.findall(line):
+                    refs += [(name, "%s:%d" % (rel, lineno)) for name in TEMPLATE_ATTRIBUTE.findall(tag)]
+    except OSError:
+        pass
+    return refs
+
+def attach_references(models, serializers, templates):
+    by_model = {m["name"]: {p["name"]: p for p in m["properties"]} for m in models}
+    for model, name, ref in serializers:
+        prop = by_model.get(model, {}).get(name)
+        if prop is not None and ref not in prop["referenced_by"]:
+            prop["referenced_by"].append(ref)
+    for name, ref in templates:
+        for props in by_model.values():
+            if name in props and ref not in props[name]["referenced_by"]:
+                props[name]["referenced_by"].append(ref)
+
+def extract_fields(node, choice_classes, model=None):
+    fields = []
+    for stmt in node.body:
+        if not (isinstance(stmt, ast.Assign) and isinstance(stmt.value, ast.Call) and isinstance(stmt.targets[0], ast.Name)):
+            continue
+        ftype = base_name(stmt.value.func)
+        if not ftype.endswith("Field") and ftype not in RELATIONS:
+            continue
+        fields.append(field(stmt.targets[0].id, stmt.value, model or node.name, choice_classes))
+    return fields
+
+def field(name, call, model, choice_classes=None):
+    ftype = base_name(call.func)
+    kwargs = {k.arg: literal(k.value) for k in call.keywords if k.arg}
+    choices = next((k.value for k in call.keywords if k.arg == "choices"), None)
+    default = next((k.value for k in call.keywords if k.arg == "default"), None)
+    output = next((k.value for k in call.keywords if k.arg == "output_field"), None) if ftype == "GeneratedField" else None
+    if isinstance(output, ast.Call):
+        kwargs = dict({k.arg: literal(k.value) for k in output.keywords if k.arg}, **kwargs)
+    return {
+        "name": name,
+        "type": ftype,
+        "line": call.lineno,
+        "nullable": kwargs.get("null") is True,
+        "unique": kwargs.get("unique") is True,
+        "primary_key": kwargs.get("primary_key") is True,
+        "auto_now": kwargs.get("auto_now") is True,
+        "auto_now_add": kwargs.get("auto_now_add") is True,
+        "srid": int_kwarg(kwargs, "srid"),
+        "geography": kwargs.get("geography") is True,
+        "spatial_index": ftype in GEOMETRY_FIELDS and kwargs.get("spatial_index") is not False,
+        "range_index": ftype in RANGE_FIELDS and kwargs.get("db_index") is True,
+        "db_index": kwargs.get("db_index") is True,
+        "default": None if kwargs.get("default", NOT_LITERAL) is NOT_LITERAL else kwargs["default"],
+        "default_callable": dotted_name(default) or None if default is not None else None,
+        "max_length": int_kwarg(kwargs, "max_length"),
+        "max_digits": int_kwarg(kwargs, "max_digits"),
+        "decimal_places": int_kwarg(kwargs, "decimal_places"),
+        "protocol": str_kwarg(kwargs, "protocol"),
+        "upload_to": str_kwarg(kwargs, "upload_to"),
+        "db_collation": str_kwarg(kwargs, "db_collation"),
+        "default_currency": str_kwarg(kwargs, "default_currency"),
+        "currency_max_length": int_kwarg(kwargs, "currency_max_length"),
+        "relation": RELATIONS.get(ftype),
+        "related_to": related_model(call, model) if ftype in RELATIONS else None,
+        "base_type": array_base(call) if ftype == "ArrayField" else None,
+        "choices": field_choices(choices, choice_classes or {}) if choices is not None else None,
+        "output_field": base_name(output.func) if isinstance(output, ast.Call) else None,
+        "expression": generated_expression(call) if ftype == "GeneratedField" else None,
+        "db_persist": kwargs.get("db_persist") is True,
+    }
+
+def generated_expression(call):
+    node = next((k.value for k in call.keywords if k.arg == "expression"), call.args[0] if call.args else None)
+    return expression(node) if node is not None else None
+
+def choice_label(node):
+    if isinstance(node, ast.Call) and len(node.args) == 1:
+        node = node.args[0]
+    value = literal(node)
+    return value if isinstance(value, str) else None
+
+def field_choices(node, choice_classes):
+    if isinstance(node, ast.Attribute) and node.attr == "choices":
+        return choice_classes.get(base_name(node.value))
+    if not isinstance(node, (ast.List, ast.Tuple)):
+        return None
+    result = []
+    for e in node.elts:
+        if not (isinstance(e, (ast.List, ast.Tuple)) and len(e.elts) == 2):
+            return None
+        if isinstance(e.elts[1], (ast.List, ast.Tuple)):
+            group = field_choices(e.elts[1], choice_classes)
+            if group is None:
+                return None
+            result += group
+            continue
+        value, label = literal(e.elts[0]), choice_label(e.elts[1])
+        if value is NOT_LITERAL or label is None:
+            return None
+        result.append({"value": value, "label": label})
+    return result
+
+def choice_classes(tree):
+    classes = {}
+    for node in ast.walk(tree):
+        if not (isinstance(node, ast.ClassDef) and {"TextChoices", "IntegerChoices"} & {base_name(b) for b in node.bases}):
+            continue
+        members = []
+        for stmt in node.body:
+            if not (isinstance(stmt, ast.Assign) and len(stmt.targets) == 1 and isinstance(stmt.targets[0], ast.Name)):
+                continue
+            name = stmt.targets[0].id
+            if name.startswith("_"):
+                continue
+            value, label = stmt.value, name.replace("_", " ").title()
+            if isinstance(value, ast.Tuple) and len(value.elts) == 2:
+                value, label = value.elts[0], choice_label(value.elts[1])
+            if literal(value) is NOT_LITERAL or isinstance(literal(value), list) or label is None:
+                members = None
+                break
+            members.append({"value": literal(value), "label": label})
+        if members:
+            classes[node.name] = members
+    return classes
+
+def int_kwarg(kwargs, name):
+    value = kwargs.get(name)
+    return value if isinstance(value, int) and not isinstance(value, bool) else None
+
+def str_kwarg(kwargs, name):
+    value = kwargs.get(name)
+    return value if isinstance(value, str) else None
+
+def array_base(call):
+    if call.args and isinstance(call.args[0], ast.Call):
+        return base_name(call.args[0].func)
+    kwargs = {k.arg: k.value for k in call.keywords}
+    if isinstance(kwargs.get("base_field"), ast.Call):
+        return base_name(kwargs["base_field"].func)
+    return None
+
+def unwind(call):
+    chain = []
+    node = call
+    while isinstance(node, ast.Call) and isinstance(node.func, ast.Attribute):
+        chain.insert(0, node)
+        node = node.func.value
+    if not (isinstance(node, ast.Attribute) and node.attr == "objects" and isinstance(node.value, ast.Name)):
+        return None, []
+    return node.value.id, chain
+
+def admin_action_calls(tree):
+    calls = set()
+    for node in ast.walk(tree):
+        if not isinstance(node, (ast.FunctionDef, ast.AsyncFunctionDef)):
+            continue
+        decorated = any(base_name(d.func if isinstance(d, ast.Call) else d) == "action" for d in node.decorator_list)
+        if decorated or [a.arg for a in node.args.args][1:3] == ["request", "queryset"]:
+            calls.update(id(n) for n in ast.walk(node) if isinstance(n, ast.Call))
+    return calls
+
+def call_site(call, model, chain, rel, app, lines, admin_action):
+    return {
+        "file": rel,
+        "line": call.lineno,
+        "app": app,
+        "model": model,
+        "admin_action": admin_action,
+        "chain": [{
+            "method": c.func.attr,
+            "args": [arg(a) for a in c.args],
+            "kwargs": [dict(arg(k.value), key=k.arg) for k in c.keywords],
+        } for c in chain],
+        "source": lines[call.lineno - 1].strip(),
+    }
+
+def read_source(full):
+    with open(full, "rb") as f:
+        data = f.read()
+    encoding, _ = tokenize.detect_encoding(io.BytesIO(data).readline)
+    return data.decode(encoding)
+
+def django_version(path):
+    for directory in (path, os.path.dirname(path)):
+        if not os.path.isdir(directory):
+            continue
+        names = sorted(os.listdir(directory))
+        files = [n for n in names if n in REQUIREMENT_FILES or (n.startswith("requirements") and n.endswith(".txt"))]
+        if os.path.isdir(os.path.join(directory, "requirements")):
+            files += [os.path.join("requirements", n) for n in sorted(os.listdir(os.path.join(directory, "requirements"))) if n.endswith(".txt")]
+        for name in files:
+            try:
+                with open(os.path.join(directory, name), encoding="utf-8-sig", errors="replace") as f:
+                    match = DJANGO_REQUIREMENT.search(f.read())
+            except OSError:
+                continue
+            if match:
+                return "%s.%s" % match.groups()
+    return None
+
+def top_level(tokens, string):
+    depth = 0
+    for i, tok in enumerate(tokens):
+        if depth == 0 and tok.string == string:
+            return i
+        if tok.string in ("(", "[", "{"):
+            depth += 1
+        elif tok.string in (")", "]", "}"):
+            depth -= 1
+    return None
+
+def py2_statement(toks, edits):
+    depth = 0
+    backtick = False
+    starts, semicolons = [0], []
+    for i, tok in enumerate(toks):
+        s, nxt = tok.string, toks[i + 1] if i + 1 < len(toks) else None
+        adjacent = nxt is not None and nxt.start == tok.end
+        if s in ("(", "[", "{"):
+            depth += 1
+        elif s in (")", "]", "}"):
+            depth -= 1
+        elif depth == 0 and tok.type == tokenize.OP and s in (":", ";"):
+            starts.append(i + 1)
+            if s == ";":
+                semicolons.append(i)
+        if tok.type == tokenize.ERRORTOKEN and s == "`":
+            edits.append((tok.start, tok.end, ")" if backtick else "repr("))
+            backtick = not backtick
+        elif s == "<" and adjacent and nxt.string == ">":
+            edits.append((tok.start, nxt.end, "!="))
+        elif tok.type == tokenize.NUMBER and adjacent and nxt.type == tokenize.NAME and nxt.string in ("L", "l"):
+            edits.append((nxt.start, nxt.end, ""))
+        elif s == "0" and adjacent and nxt.type == tokenize.NUMBER and nxt.string.isdigit():
+            edits.append((tok.start, tok.end, "0o"))
+        elif tok.type == tokenize.NAME and s.lower() == "ur" and adjacent and nxt.type == tokenize.STRING:
+            edits.append((tok.start, tok.end, "r"))
+    for start in starts:
+        if start >= len(toks) or toks[start].type != tokenize.NAME:
+            continue
+        first = toks[start]
+        body = toks[start + 1:next((j for j in semicolons if j >= start), len(toks))]
+        if first.string in ("print", "exec") and not (body and body[0].string == "("):
+            if body and body[0].string == ">>":
+                comma = top_level(body, ",")
+                edits.append((body[0].start, body[-1 if comma is None else comma].end, ""))
+                body = [] if comma is None else body[comma + 1:]
+            if body:
+                edits += [(first.end, first.end, "("), (body[-1].end, body[-1].end, ")")]
+            else:
+                edits.append((first.end, first.end, "()"))
+        elif first.string == "except":
+            comma, colon = top_level(body, ","), top_level(body, ":")
+            if comma is not None and (colon is None or comma < colon):
+                edits.append((body[comma].start, body[comma].end, " as"))
+        elif first.string == "raise":
+            comma = top_level(body, ",")
+            if comma is not None:
+                edits += [(body[comma].start, body[comma].end, "("), (body[-1].end, body[-1].end, ")")]
+
+def py2_parse(code):
+    code = "".join(line[:len(line) - len(line.lstrip(" \t"))].expandtabs(8) + line.lstrip(" \t") for line in code.splitlines(True))
+    edits, statement = [], []
+    try:
+        for tok in tokenize.generate_tokens(io.StringIO(code).readline):
+            if tok.type in (tokenize.NEWLINE, tokenize.ENDMARKER):
+                py2_statement(statement, edits)
+                statement = []
+            elif tok.type not in (tokenize.NL, tokenize.COMMENT, tokenize.INDENT, tokenize.DEDENT) and tok.string.strip():
+                statement.append(tok)
+    except (tokenize.TokenError, SyntaxError):
+        return None
+    offsets = [0]
+    for line in code.splitlines(True):
+        offsets.append(offsets[-1] + len(line))
+    for start, end, text in sorted(edits, key=lambda e: e[0], reverse=True):
+        a, b = offsets[start[0] - 1] + start[1], offsets[end[0] - 1] + end[1]
+        code = code[:a] + text + code[b:]
+    try:
+        return ast.parse(code)
+    except SyntaxError:
+        return None
+
+def inside_root(real):
+    root = os.path.realpath(ROOT)
+    return os.path.commonpath([real, root]) == root
+
+def walk(path, diagnostics):
+    seen = {os.path.realpath(path)}
+    for root, dirs, files in os.walk(path, followlinks=True):
+        depth = 0 if root == path else os.path.relpath(root, path).count(os.sep) + 1
+        kept = []
+        for d in dirs:
+            full = os.path.join(root, d)
+            real = os.path.realpath(full)
+            if MAX_DEPTH and depth >= MAX_DEPTH or real in seen:
+                continue
+            if os.path.islink(full) and not inside_root(real):
+                diagnostics.append({"code": "W006", "severity": "warning", "file": os.path.relpath(full, ROOT), "line": 0,
+                                    "message": "directory skipped: symbolic link to %s, outside the input" % real})
+                continue
+            seen.add(real)
+            kept.append(d)
+        dirs[:] = kept
+        kept = []
+        for file in sorted(files):
+            full = os.path.join(root, file)
+            real = os.path.realpath(full)
+            if real in seen:
+                continue
+            if os.path.islink(full) and not inside_root(real):
+                if file.endswith(".py") or file.endswith(TEMPLATE_EXTENSIONS):
+                    diagnostics.append({"code": "W006", "severity": "warning", "file": os.path.relpath(full, ROOT), "line": 0,
+                                        "message": "file skipped: symbolic link to %s, outside the input" % real})
+                continue
+            seen.add(real)
+            kept.append(file)
+        yield root, kept
+
+def extract_models(path: str):
+    result = []
+    queries = []
+    serializers = []
+    templates = []
+    admins = []
+    schedules = []
+    commands = []
+    migrations = []
+    settings = {"django_version": django_version(os.path.abspath(path))}
+    auto_fields = {}
+    labels = {}
+    diagnostics = []
+    settings_trees = None
+    classes = {}
+    order = []
+    if SETTINGS_MODULE:
+        full = settings_file(SETTINGS_MODULE)
+        if full:
+            settings_trees = []
+            settings_chain(full, settings_trees, set())
+        else:
+            diagnostics.append({"code": "W007", "severity": "warning", "file": "", "line": 0,
+                                "message": "settings module %s not found; reading every settings module" % SETTINGS_MODULE})
+    for root, files in walk(path, diagnostics):
+        for file in files:
+            full = os.path.join(root, file)
+            rel = os.path.relpath(full, ROOT)
+            if file.endswith(TEMPLATE_EXTENSIONS):
+                templates += template_refs(full, rel)
+            if not file.endswith(".py"):
+                continue
+            app = app_label(os.path.abspath(full))
+            try:
+                code = read_source(full)
+            except OSError as e:
+                diagnostics.append({"code": "W001", "severity": "warning", "file": rel, "line": 0,
+                                    "message": "file skipped: unreadable: %s" % e.strerror})
+                continue
+            except UnicodeDecodeError as e:
+                diagnostics.append({"code": "W001", "severity": "warning", "file": rel, "line": e.object[:e.start].count(b"\n") + 1,
+                                    "message": "file skipped: not valid %s: %s" % (e.encoding, e.reason)})
+                continue
+            except SyntaxError as e:
+                diagnostics.append({"code": "W001", "severity": "warning", "file": rel, "line": e.lineno or 0,
+                                    "message": "file skipped: encoding declaration: %s" % e.msg})
+                continue
+            try:
+                tree = ast.parse(code, filename=full)
+            except SyntaxError as e:
+                tree = py2_parse(code)
+                models_file = file == "models.py" or os.path.basename(root) == "models"
+                if tree is None or not (PYTHON2_MODELS and models_file):
+                    diagnostics.append({"code": "W001", "severity": "warning", "file": rel, "line": e.lineno or 0,
+                                        "message": "file skipped: %s: %s" % ("Python 2 syntax" if tree else "syntax error", e.msg)})
+                    continue
+                diagnostics.append({"code": "W005", "severity": "warning", "file": rel, "line": e.lineno or 0,
+                                    "message": "Python 2 syntax: read as its Python 3 equivalent (%s)" % e.msg})
+            lines = code.splitlines()
+            admins += admin_refs(tree, rel)
+            schedules += schedule_refs(tree, rel)
+            if root.endswith(os.path.join("management", "commands")) and not file.startswith("_"):
+                command = management_command(tree, rel, app, file)
+                if command:
+                    commands.append(command)
+            if os.path.basename(root) == "migrations" and file != "__init__.py":
+                record = migration(tree, rel, app, file)
+                if record:
+                    migrations.append(record)
+            if file == "apps.py":
+                auto_fields[app] = app_config(tree, "default_auto_field")
+                labels[app] = app_config(tree, "label")
+            if settings_trees is None and (file.startswith("settings") or os.path.basename(root) == "settings"):
+                settings_refs(tree, settings, {})
+            choices = choice_classes(tree)
+            for node in tree.body:
+                if not isinstance(node, ast.ClassDef):
+                    continue
+                entry = {"node": node, "app": app, "rel": rel, "lines": lines, "full": full, "choices": choices}
+                classes.setdefault(node.name, []).append(entry)
+                order.append(entry)
+                if "Model" not in [base_name(b) for b in node.bases]:
+                    serializers += serializer_refs(node, rel)
+            if ".objects." not in code:
+                continue
+            seen = set()
+            actions = admin_action_calls(tree)
+            for node in ast.walk(tree):
+                if not isinstance(node, ast.Call) or id(node) in seen:
+                    continue
+                model, chain = unwind(node)
+                if not chain:
+                    continue
+                seen.update(id(c) for c in chain)
+                queries.append(call_site(node, model, chain, rel, app, lines, id(node) in actions))
+    for entry in order:
+        if model_class(entry["node"], entry["rel"], classes) and not abstract(entry["node"]) and not proxy(entry["node"]):
+            result.append(extract_model(entry, classes, labels.get(entry["app"])))
+    by_name = {m["name"]: m for m in result}
+    for entry in order:
+        if proxy(entry["node"]):
+            target = by_name.get(concrete_parent(entry, classes))
+            if target is not None:
+                target["aliases"].append(entry["node"].name)
+    lists = {}
+    for tree in settings_trees or []:
+        settings_refs(tree, settings, lists, merge=False)
+    attach_references(result, serializers, templates)
+    for m in result:
+        auto = auto_fields.get(m["app"]) or settings.get("default_auto_field")
+        if auto:
+            m["auto_field"] = auto.rsplit(".", 1)[-1]
+    print(json.dumps({"ir_version": IR_VERSION, "capabilities": CAPABILITIES, "models": result, "queries": queries, "admins": admins, "commands": commands, "schedules": schedules, "migrations": migrations, "settings": settings, "diagnostics": diagnostics}))
+
+extract_models(sys.argv[1])
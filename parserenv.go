@@ -0,0 +1,67 @@
+// parserenv.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// parserBaseEnv lists the environment variables every parser run gets, which
+// locate and configure Python itself.
+var parserBaseEnv = []string{
+	"PATH", "HOME", "LANG", "LC_ALL", "LC_CTYPE", "TMPDIR", "TEMP", "TMP", "SYSTEMROOT",
+	"PYTHONPATH", "PYTHONHOME", "PYTHONIOENCODING", "VIRTUAL_ENV", "CONDA_PREFIX", "PYENV_ROOT", "PYENV_VERSION",
+	"DJANGO_SETTINGS_MODULE",
+}
+
+// parserEnvFlags registers --settings and --env on the flag set, returning
+// the function building the parser's environment from them once parsed.
+func parserEnvFlags(fs *flag.FlagSet) func() []string {
+	settings := fs.String("settings", "", "Django settings module to read, e.g. myproj.settings.prod (default $DJANGO_SETTINGS_MODULE)")
+	pass := fs.String("env", "", "Comma-separated environment variables to pass to the parser, e.g. SECRET_KEY,DATABASE_URL")
+	return func() []string {
+		var names []string
+		for _, name := range strings.Split(*pass, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		return parserEnvironment(*settings, names)
+	}
+}
+
+// parserEnvironment returns the environment of the parser: the variables of
+// parserBaseEnv and those passed by name, with DJANGO_SETTINGS_MODULE set to
+// the settings module when given. Other variables, such as secrets the
+// parser has no use for, are not passed.
+func parserEnvironment(settings string, names []string) []string {
+	// A nil environment would be the whole one of django2go.
+	env := []string{}
+	for _, name := range append(parserBaseEnv, names...) {
+		if name == "DJANGO_SETTINGS_MODULE" && settings != "" {
+			continue
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	if settings != "" {
+		env = append(env, "DJANGO_SETTINGS_MODULE="+settings)
+	}
+	return env
+}
+
+// parserArgs returns the parser flags reading Python 2 models files and
+// limiting the depth of the walk.
+func parserArgs(python2Models bool, maxDepth int) []string {
+	var args []string
+	if python2Models {
+		args = append(args, "--python2-models")
+	}
+	if maxDepth > 0 {
+		args = append(args, fmt.Sprintf("--max-depth=%d", maxDepth))
+	}
+	return args
+}
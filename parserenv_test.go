@@ -0,0 +1,69 @@
+// parserenv_test.go
+package main
+
+import (
+	"os/exec"
+	"slices"
+	"testing"
+)
+
+// TestParserEnvironment checks that only the base variables and those
+// passed by name reach the parser, with --settings overriding
+// DJANGO_SETTINGS_MODULE.
+func TestParserEnvironment(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	t.Setenv("DATABASE_URL", "postgres://db")
+	t.Setenv("DJANGO_SETTINGS_MODULE", "proj.settings.dev")
+	env := parserEnvironment("proj.settings.prod", []string{"DATABASE_URL"})
+	if !slices.Contains(env, "DATABASE_URL=postgres://db") || !slices.Contains(env, "DJANGO_SETTINGS_MODULE=proj.settings.prod") {
+		t.Errorf("env = %q", env)
+	}
+	for _, v := range env {
+		if v == "SECRET_KEY=secret" || v == "DJANGO_SETTINGS_MODULE=proj.settings.dev" {
+			t.Errorf("env has %q", v)
+		}
+	}
+}
+
+// TestSettingsModule checks that --settings reads the chosen module and the
+// ones it star-imports, with environment lookups resolved from the
+// variables passed.
+func TestSettingsModule(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("no python3")
+	}
+	dir := writeProject(t, map[string]string{
+		"proj/settings/__init__.py": "",
+		"proj/settings/base.py": `INSTALLED_APPS = ["blog"]
+SESSION_ENGINE = "django.contrib.sessions.backends.cache"
+`,
+		"proj/settings/prod.py": `import os
+from .base import *
+
+INSTALLED_APPS += ["shop"]
+SESSION_ENGINE = os.environ.get("SESSION_ENGINE", "django.contrib.sessions.backends.db")
+DATABASES = {"default": {"CONN_MAX_AGE": int(os.environ["CONN_MAX_AGE"])}}
+`,
+		"proj/settings/dev.py": `from .base import *
+
+INSTALLED_APPS += ["debug_toolbar"]
+`,
+	})
+	t.Setenv("CONN_MAX_AGE", "60")
+	out, err := runPythonParser(dir, "", nil, parserEnvironment("proj.settings.prod", []string{"CONN_MAX_AGE"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := out.Settings
+	if !slices.Equal(s.InstalledApps, []string{"blog", "shop"}) || s.SessionEngine != "django.contrib.sessions.backends.db" || s.ConnMaxAge != 60 {
+		t.Errorf("settings = %+v", s)
+	}
+
+	out, err = runPythonParser(dir, "", nil, parserEnvironment("proj.settings.missing", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Diagnostics) != 1 || out.Diagnostics[0].Code != codeSettingsModule {
+		t.Errorf("diagnostics = %+v", out.Diagnostics)
+	}
+}
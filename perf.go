@@ -0,0 +1,120 @@
+// perf.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+)
+
+// profiler writes the CPU and heap profiles of a run, and how long each of
+// its phases took, to a directory. A nil profiler does nothing, so that runs
+// without --profile-output need no checks.
+type profiler struct {
+	dir    string
+	cpu    *os.File
+	start  time.Time
+	phases []phaseTime
+	// current is the phase under way, begun at since with alloc bytes
+	// allocated.
+	current string
+	since   time.Time
+	alloc   uint64
+}
+
+// phaseTime is the wall time and memory allocated by a phase of the run.
+type phaseTime struct {
+	name    string
+	elapsed time.Duration
+	alloc   uint64
+}
+
+// startProfiling starts profiling the run into dir, creating it if needed.
+func startProfiling(dir string) (*profiler, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	cpu, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpu); err != nil {
+		cpu.Close()
+		return nil, err
+	}
+	return &profiler{dir: dir, cpu: cpu, start: time.Now()}, nil
+}
+
+// totalAlloc returns the bytes allocated on the heap since the start of
+// the process.
+func (p *profiler) totalAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.TotalAlloc
+}
+
+// begin ends the phase under way, if any, and begins the named one.
+func (p *profiler) begin(name string) {
+	if p == nil {
+		return
+	}
+	now, alloc := time.Now(), p.totalAlloc()
+	if p.current != "" {
+		p.phases = append(p.phases, phaseTime{name: p.current, elapsed: now.Sub(p.since), alloc: alloc - p.alloc})
+	}
+	p.current, p.since, p.alloc = name, now, alloc
+}
+
+// stop ends the phase under way, stops the CPU profile and writes the heap
+// profile and phases.txt. It is also run by fail, so that a failing run
+// reports where it spent its time too; errors writing the files are only
+// warned about.
+func (p *profiler) stop() {
+	if p == nil {
+		return
+	}
+	p.begin("")
+	pprof.StopCPUProfile()
+	p.cpu.Close()
+	if err := p.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --profile-output: %v\n", err)
+		return
+	}
+	fmt.Printf("✅ Wrote cpu.pprof, heap.pprof and phases.txt to %s\n", p.dir)
+}
+
+// write writes the heap profile and the phase timings.
+func (p *profiler) write() error {
+	heap, err := os.Create(filepath.Join(p.dir, "heap.pprof"))
+	if err != nil {
+		return err
+	}
+	defer heap.Close()
+	// Up to date statistics of the memory still in use.
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(heap); err != nil {
+		return err
+	}
+	phases, err := os.Create(filepath.Join(p.dir, "phases.txt"))
+	if err != nil {
+		return err
+	}
+	defer phases.Close()
+	total := time.Since(p.start)
+	w := tabwriter.NewWriter(phases, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "PHASE\tSECONDS\tSHARE\tALLOCATED MB\t")
+	for _, ph := range p.phases {
+		fmt.Fprintf(w, "%s\t%.3f\t%.1f%%\t%.1f\t\n", ph.name, ph.elapsed.Seconds(),
+			100*ph.elapsed.Seconds()/total.Seconds(), float64(ph.alloc)/(1<<20))
+	}
+	fmt.Fprintf(w, "total\t%.3f\t100.0%%\t\t\n", total.Seconds())
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(phases, "\n%s, GOMAXPROCS %d\n", runtime.Version(), runtime.GOMAXPROCS(0))
+	return err
+}
@@ -0,0 +1,44 @@
+// perf_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProfiler checks the files written and the phases timed, and that a
+// nil profiler does nothing.
+func TestProfiler(t *testing.T) {
+	var none *profiler
+	none.begin("parse")
+	none.stop()
+
+	dir := filepath.Join(t.TempDir(), "prof")
+	p, err := startProfiling(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.begin("parse")
+	p.begin("write")
+	p.stop()
+	for _, name := range []string{"cpu.pprof", "heap.pprof"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "phases.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var phases []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			phases = append(phases, fields[0])
+		}
+	}
+	if got := strings.Join(phases[:4], " "); got != "PHASE parse write total" {
+		t.Errorf("phases.txt:\n%s", data)
+	}
+}
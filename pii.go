@@ -0,0 +1,248 @@
+// pii.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// piiNames are the field names detected as personal data unless the config
+// ignores them.
+var piiNames = map[string]bool{
+	"email": true, "phone": true, "phone_number": true, "mobile": true,
+	"first_name": true, "last_name": true, "full_name": true,
+	"date_of_birth": true, "birth_date": true, "birthday": true,
+	"address": true, "street_address": true, "postal_code": true,
+	"ssn": true, "tax_id": true, "passport_number": true, "ip_address": true,
+}
+
+// piiTypes are the field types detected as personal data whatever their
+// name.
+var piiTypes = map[string]bool{"EmailField": true, "GenericIPAddressField": true}
+
+// PIIConfig marks the fields holding personal data, keyed like anonymize
+// rules by "Model.field" or "*.field".
+type PIIConfig struct {
+	// Fields are personal data besides the detected ones.
+	Fields []string `yaml:"fields"`
+	// Ignore lists detected fields that are not personal data.
+	Ignore []string `yaml:"ignore"`
+}
+
+// piiMatch reports whether one of the keys names the model's field.
+func piiMatch(keys []string, m Model, field string) bool {
+	return slices.Contains(keys, m.Name+"."+field) || slices.Contains(keys, "*."+field)
+}
+
+// applyPII marks the fields configured or detected as personal data.
+func applyPII(cfg *Config, models []Model) error {
+	for _, key := range append(slices.Clone(cfg.PII.Fields), cfg.PII.Ignore...) {
+		model, field, ok := strings.Cut(key, ".")
+		if !ok {
+			return fmt.Errorf("config: pii %s: expected Model.field or *.field", key)
+		}
+		if model == "*" {
+			continue
+		}
+		i := slices.IndexFunc(models, func(m Model) bool { return m.Name == model })
+		if i < 0 {
+			return fmt.Errorf("config: pii %s: unknown model %q", key, model)
+		}
+		if _, ok := models[i].field(field); !ok {
+			return fmt.Errorf("config: pii %s: field not found", key)
+		}
+	}
+	for i := range models {
+		m := &models[i]
+		for j := range m.Fields {
+			f := &m.Fields[j]
+			if f.Relation == "many2many" {
+				continue
+			}
+			detected := (piiNames[f.Name] || piiTypes[f.Type]) && !piiMatch(cfg.PII.Ignore, *m, f.Name)
+			f.PII = detected || piiMatch(cfg.PII.Fields, *m, f.Name)
+		}
+	}
+	return nil
+}
+
+// piiColumns returns the columns of the model's fields holding personal
+// data.
+func piiColumns(m Model) []string {
+	var cols []string
+	for _, f := range m.Fields {
+		if f.PII {
+			for _, c := range fieldColumns(f) {
+				cols = append(cols, c.Name)
+			}
+		}
+	}
+	return cols
+}
+
+// columnOverrides returns the sqlc override entries of single columns: the
+// Go type of the fields of type_overrides, or of their choices when the
+// choices package at the import path choices is generated, and the struct
+// tag pii:"true" of personal data columns, for the loggers and encoders of
+// the port to act on. A column has one entry holding both.
+func columnOverrides(models []Model, types map[string]TypeOverride, choices string) []string {
+	var entries []string
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if f.Relation == "many2many" {
+				continue
+			}
+			goType := types[f.Type].goType(f.Nullable)
+			if goType != "" {
+				goType = goTypeYAML(goType)
+			} else if choices != "" && choiceGoType(f) != "" {
+				goType = fmt.Sprintf("\n              import: %q\n              type: %q", choices, choiceTypeName(m, f))
+				if f.Nullable {
+					goType += "\n              pointer: true"
+				}
+			}
+			if goType == "" && !f.PII {
+				continue
+			}
+			for _, c := range fieldColumns(f) {
+				entry := fmt.Sprintf("          - column: %q\n", m.table()+"."+c.Name)
+				if goType != "" {
+					entry += fmt.Sprintf("            go_type:%s\n", goType)
+				}
+				if f.PII {
+					entry += fmt.Sprintf("            go_struct_tag: %q\n", `pii:"true"`)
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+	return entries
+}
+
+// generateRedact renders redact.go in the package sqlc generates: a Redact
+// method for every model struct with personal data, returning a copy with
+// those fields zeroed for logs and error reports. The structs and fields
+// are named as sqlc names them.
+func generateRedact(models []Model, layout Layout) (map[string]string, error) {
+	var sb strings.Builder
+	for _, m := range models {
+		cols := piiColumns(m)
+		if len(cols) == 0 {
+			continue
+		}
+		name := sqlcStruct(m.table())
+		fmt.Fprintf(&sb, "\n// Redact returns a copy of the %s with its personal data zeroed.\nfunc (x %s) Redact() %s {\n", name, name, name)
+		for _, c := range cols {
+			fmt.Fprintf(&sb, "\tredact(&x.%s)\n", sqlcName(c))
+		}
+		sb.WriteString("\treturn x\n}\n")
+	}
+	if sb.Len() == 0 {
+		return nil, nil
+	}
+	src := `// Code generated by django2go. DO NOT EDIT.
+
+package db
+
+// redact zeroes a field holding personal data.
+func redact[T any](v *T) {
+	var zero T
+	*v = zero
+}
+` + sb.String()
+	files := map[string]string{}
+	if err := addGoFile(files, path.Join(filepath.ToSlash(layout.DB), "redact.go"), src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// queryParam matches the sqlc parameters of a query.
+var queryParam = regexp.MustCompile(`sqlc\.(arg|narg|slice)\((\w+)\)`)
+
+// paramSuffix matches the number param adds to repeated parameter names.
+var paramSuffix = regexp.MustCompile(`_\d+$`)
+
+// sensitiveArgs returns, per query name, the positions of the arguments
+// holding personal data: those comparing or setting a personal data column
+// of the query's model, and admin searches over one. PostgreSQL numbers each parameter once, MySQL every
+// occurrence; a MySQL slice makes the positions vary, marked by -1.
+func sensitiveArgs(queries []TranslatedQuery, models []Model, dialect string) map[string][]int {
+	pii := map[string][]string{}
+	for _, m := range models {
+		pii[m.Name] = piiColumns(m)
+	}
+	result := map[string][]int{}
+	for _, q := range queries {
+		cols := pii[q.Model]
+		if !q.Translated() || len(cols) == 0 {
+			continue
+		}
+		var names []string
+		variable := false
+		for _, p := range queryParam.FindAllStringSubmatch(q.SQL, -1) {
+			if dialect != "mysql" && slices.Contains(names, p[2]) {
+				continue
+			}
+			names = append(names, p[2])
+			variable = variable || dialect == "mysql" && p[1] == "slice"
+		}
+		var positions []int
+		for i, name := range names {
+			base := paramSuffix.ReplaceAllString(name, "")
+			searched := base == "search" && slices.ContainsFunc(cols, func(c string) bool { return strings.Contains(q.SQL, c+" ILIKE") || strings.Contains(q.SQL, c+" LIKE") })
+			if searched || slices.Contains(cols, name) || slices.Contains(cols, base) || slices.Contains(cols, strings.TrimSuffix(base, "s")) {
+				positions = append(positions, i)
+			}
+		}
+		if len(positions) > 0 && variable {
+			positions = []int{-1}
+		}
+		if len(positions) > 0 {
+			result[q.Name] = positions
+		}
+	}
+	return result
+}
+
+// renderSensitiveArgs renders the positions as a Go map literal.
+func renderSensitiveArgs(args map[string][]int) string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	sb.WriteString("map[string][]int{")
+	for _, name := range names {
+		positions := make([]string, len(args[name]))
+		for i, p := range args[name] {
+			positions[i] = fmt.Sprint(p)
+		}
+		fmt.Fprintf(&sb, "\n\t%q: {%s},", name, strings.Join(positions, ", "))
+	}
+	if len(names) > 0 {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// piiRule returns the anonymize rule seeding a personal data field that has
+// none configured, or "" when no rule fits its type.
+func piiRule(f Field) string {
+	switch {
+	case f.Type == "EmailField" || f.Name == "email":
+		return "hash_email"
+	case f.Type == "CharField" || f.Type == "TextField":
+		return "hash"
+	case f.Nullable:
+		return "nullify"
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+// pii_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// piiModels returns a Customer with detected, configured and ignored
+// personal data fields, marked by applyPII.
+func piiModels(t *testing.T) []Model {
+	t.Helper()
+	models := []Model{{Name: "Customer", App: "shop", Fields: []Field{
+		{Name: "email", Type: "EmailField"},
+		{Name: "first_name", Type: "CharField"},
+		{Name: "nickname", Type: "CharField"},
+		{Name: "address", Type: "TextField"},
+		{Name: "credits", Type: "IntegerField"},
+	}}}
+	cfg := &Config{PII: PIIConfig{Fields: []string{"Customer.nickname"}, Ignore: []string{"*.address"}}}
+	if err := applyPII(cfg, models); err != nil {
+		t.Fatal(err)
+	}
+	return models
+}
+
+// TestApplyPII checks the detected and configured personal data, and the
+// config keys naming unknown models or fields.
+func TestApplyPII(t *testing.T) {
+	if got := strings.Join(piiColumns(piiModels(t)[0]), " "); got != "email first_name nickname" {
+		t.Errorf("pii columns = %s", got)
+	}
+	models := []Model{{Name: "Customer", Fields: []Field{{Name: "email"}}}}
+	for _, key := range []string{"email", "Order.email", "Customer.phone"} {
+		if err := applyPII(&Config{PII: PIIConfig{Fields: []string{key}}}, models); err == nil {
+			t.Errorf("pii %s passed", key)
+		}
+	}
+}
+
+// TestRedact checks the generated Redact method, the sqlc struct tags and
+// the query arguments redacted in logs.
+func TestRedact(t *testing.T) {
+	models := piiModels(t)
+	files, err := generateRedact(models, defaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["db/redact.go"]
+	for _, want := range []string{"func (x Customer) Redact() Customer {", "redact(&x.Email)", "redact(&x.FirstName)", "redact(&x.Nickname)"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("redact.go lacks %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "Address") {
+		t.Errorf("ignored field redacted:\n%s", src)
+	}
+	if got := columnOverrides(models, nil, ""); len(got) != 3 || !strings.Contains(got[0], `column: "customer.email"`) {
+		t.Errorf("overrides = %q", got)
+	}
+
+	queries := []TranslatedQuery{
+		{Query: Query{Model: "Customer", File: "shop/views.py"}, Name: "ListCustomerByEmail", SQL: "SELECT * FROM customer WHERE credits > sqlc.arg(credits) AND email = sqlc.arg(email);"},
+		{Query: Query{Model: "Customer", File: "shop/views.py"}, Name: "ListCustomerByCredits", SQL: "SELECT * FROM customer WHERE credits > sqlc.arg(credits);"},
+	}
+	args := sensitiveArgs(queries, models, "postgres")
+	if len(args) != 1 || len(args["ListCustomerByEmail"]) != 1 || args["ListCustomerByEmail"][0] != 1 {
+		t.Errorf("sensitive args = %v", args)
+	}
+	if got := renderSensitiveArgs(args); got != "map[string][]int{\n\t\"ListCustomerByEmail\": {1},\n}" {
+		t.Errorf("rendered = %s", got)
+	}
+}
@@ -0,0 +1,177 @@
+// pool.go
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// PoolConfig tunes the connection pool of the generated database package,
+// set in the config's pool section. Zero values keep the defaults.
+type PoolConfig struct {
+	MaxOpenConns int `yaml:"max_open_conns"`
+	MaxIdleConns int `yaml:"max_idle_conns"`
+	// ConnMaxLifetime defaults to Django's CONN_MAX_AGE.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// Retries is how many times a transaction failing to serialize is run
+	// again.
+	Retries int `yaml:"retries"`
+}
+
+// defaultRetries is the number of retries of a transaction failing to
+// serialize when the config sets none.
+const defaultRetries = 3
+
+// validate checks the pool settings.
+func (p PoolConfig) validate() error {
+	if p.MaxOpenConns < 0 || p.MaxIdleConns < 0 || p.ConnMaxLifetime < 0 || p.ConnMaxIdleTime < 0 || p.Retries < 0 {
+		return fmt.Errorf("pool: settings must not be negative")
+	}
+	return nil
+}
+
+// retryErrors holds, per dialect, the imports and the test of the
+// generated Retryable, recognizing the errors after which a transaction
+// can be run again: serialization failures and deadlocks.
+var retryErrors = map[string]struct {
+	Imports string
+	Test    string
+}{
+	"postgres": {
+		Imports: "\"github.com/jackc/pgx/v5/pgconn\"\n\t_ \"github.com/jackc/pgx/v5/stdlib\"",
+		Test: `var pgErr *pgconn.PgError
+	// serialization_failure and deadlock_detected.
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")`,
+	},
+	"mysql": {
+		Imports: "\"github.com/go-sql-driver/mysql\"",
+		Test: `var myErr *mysql.MySQLError
+	// ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT.
+	return errors.As(err, &myErr) && (myErr.Number == 1213 || myErr.Number == 1205)`,
+	},
+}
+
+// generateDatabase renders the database package, which opens the
+// connection pool tuned from the config and Django's CONN_MAX_AGE, and runs
+// transactions retried on serialization failures like the retry decorators
+// around transaction.atomic.
+func generateDatabase(pool PoolConfig, s Settings, dialect string) (map[string]string, error) {
+	lifetime := pool.ConnMaxLifetime
+	if lifetime == 0 && s.ConnMaxAge > 0 {
+		lifetime = time.Duration(s.ConnMaxAge) * time.Second
+	}
+	idle := pool.MaxIdleConns
+	if idle == 0 {
+		// database/sql's default.
+		idle = 2
+	}
+	retries := pool.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	retry := retryErrors[dialect]
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package database opens the connection pool and runs transactions, retrying
+// them when they fail to serialize.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	%[1]s
+)
+
+// Pool settings, from the django2go config and Django's CONN_MAX_AGE. Zero
+// means no limit.
+var (
+	MaxOpenConns    = %[2]d
+	MaxIdleConns    = %[3]d
+	ConnMaxLifetime = %[4]s
+	ConnMaxIdleTime = %[5]s
+)
+
+// Retries is how many times InTx runs a transaction again after it failed
+// to serialize.
+var Retries = %[6]d
+
+// Open opens the connection pool for dsn and checks that the database is
+// reachable.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(%[7]q, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(MaxOpenConns)
+	db.SetMaxIdleConns(MaxIdleConns)
+	db.SetConnMaxLifetime(ConnMaxLifetime)
+	db.SetConnMaxIdleTime(ConnMaxIdleTime)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// InTx runs fn in a transaction, like transaction.atomic, committing it
+// when fn returns nil. A transaction failing to serialize is run again up
+// to Retries times after a short, growing delay, so fn must not have side
+// effects outside the transaction.
+func InTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts, fn)
+		if err == nil || attempt >= Retries || !Retryable(err) {
+			return err
+		}
+		delay := time.Duration(10<<attempt) * time.Millisecond
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay + rand.N(delay)):
+		}
+	}
+}
+
+// runTx runs fn in a single transaction.
+func runTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Retryable reports whether err is a serialization failure or a deadlock,
+// after which the transaction can be run again.
+func Retryable(err error) bool {
+	%[8]s
+}
+`, retry.Imports, pool.MaxOpenConns, idle, goDuration(lifetime), goDuration(pool.ConnMaxIdleTime), retries, sqlDrivers[dialect][0], retry.Test)
+	files := map[string]string{}
+	if err := addGoFile(files, "database/database.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// goDuration renders a duration as a Go expression, such as 10 * time.Minute.
+func goDuration(d time.Duration) string {
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{{time.Hour, "Hour"}, {time.Minute, "Minute"}, {time.Second, "Second"}, {time.Millisecond, "Millisecond"}} {
+		if d != 0 && d%unit.d == 0 {
+			return fmt.Sprintf("%d * time.%s", d/unit.d, unit.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
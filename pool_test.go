@@ -0,0 +1,60 @@
+// pool_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGenerateDatabase checks that the pool defaults to Django's
+// CONN_MAX_AGE and that the config's settings win over it.
+func TestGenerateDatabase(t *testing.T) {
+	out := parseProject(t, map[string]string{"mysite/settings.py": `DATABASES = {
+    "default": {"ENGINE": "django.db.backends.postgresql", "NAME": "blog", "CONN_MAX_AGE": 600},
+}
+`})
+	if out.Settings.ConnMaxAge != 600 {
+		t.Fatalf("settings = %+v", out.Settings)
+	}
+	files, err := generateDatabase(PoolConfig{MaxOpenConns: 20}, out.Settings, "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["database/database.go"]
+	for _, want := range []string{
+		"MaxOpenConns    = 20",
+		"MaxIdleConns    = 2",
+		"ConnMaxLifetime = 10 * time.Minute",
+		"ConnMaxIdleTime = time.Duration(0)",
+		"var Retries = 3",
+		`sql.Open("pgx", dsn)`,
+		`pgErr.Code == "40001"`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("database.go lacks %q:\n%s", want, src)
+		}
+	}
+
+	files, err = generateDatabase(PoolConfig{ConnMaxLifetime: 90 * time.Second, Retries: 5}, out.Settings, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = files["database/database.go"]
+	for _, want := range []string{"ConnMaxLifetime = 90 * time.Second", "var Retries = 5", "myErr.Number == 1213"} {
+		if !strings.Contains(src, want) {
+			t.Errorf("mysql database.go lacks %q", want)
+		}
+	}
+}
+
+func TestConnMaxAgeNone(t *testing.T) {
+	out := parseProject(t, map[string]string{"mysite/settings.py": `DATABASES = {"default": {"CONN_MAX_AGE": None}}
+`})
+	if out.Settings.ConnMaxAge != -1 {
+		t.Errorf("conn_max_age = %d", out.Settings.ConnMaxAge)
+	}
+	if err := (PoolConfig{Retries: -1}).validate(); err == nil {
+		t.Error("negative retries validated")
+	}
+}
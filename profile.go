@@ -0,0 +1,78 @@
+// profile.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile bundles the emit targets and options of a kind of generation,
+// such as "db-only" or "full-service", selected with --profile so that every
+// team generating from the same models gets the same outputs.
+type Profile struct {
+	// Emit replaces the config's emit section when set; an empty one turns
+	// every optional target off.
+	Emit *EmitConfig `yaml:"emit"`
+	// Flags sets command line flags, by name without dashes, unless they
+	// are given explicitly.
+	Flags map[string]string `yaml:"flags"`
+}
+
+// reservedFlags lists the flags a profile cannot set, as they select the
+// input, the config and the profile themselves.
+var reservedFlags = map[string]bool{"config": true, "profile": true, "input": true}
+
+// checkFlags checks that the profile only sets flags of fs it may set.
+func (p Profile) checkFlags(fs *flag.FlagSet) error {
+	for name := range p.Flags {
+		if reservedFlags[name] || fs.Lookup(name) == nil {
+			return fmt.Errorf("flags: %s cannot be set by a profile", name)
+		}
+	}
+	return nil
+}
+
+// applyProfile applies the named profile of the config: its emit section
+// replaces the config's, and its flags are set on fs unless they were given
+// on the command line.
+func (cfg *Config) applyProfile(name string, fs *flag.FlagSet) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		var names []string
+		for n := range cfg.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("profile %q: the config defines no profiles", name)
+		}
+		return fmt.Errorf("profile %q not found; the config defines %s", name, strings.Join(names, ", "))
+	}
+	if err := p.checkFlags(fs); err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+	if p.Emit != nil {
+		cfg.Emit = *p.Emit
+	}
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	var flags []string
+	for flagName := range p.Flags {
+		flags = append(flags, flagName)
+	}
+	sort.Strings(flags)
+	for _, flagName := range flags {
+		if explicit[flagName] {
+			continue
+		}
+		if err := fs.Set(flagName, p.Flags[flagName]); err != nil {
+			return fmt.Errorf("profile %s: flags: %s: %w", name, flagName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+// profile_test.go
+package main
+
+import (
+	"flag"
+	"testing"
+)
+
+// TestApplyProfile checks that a profile replaces the emit section and sets
+// the flags not given on the command line.
+func TestApplyProfile(t *testing.T) {
+	fs := flag.NewFlagSet("django2go", flag.ContinueOnError)
+	dialect := fs.String("dialect", "postgres", "")
+	output := fs.String("output", "./generated", "")
+	fs.String("input", "", "")
+	if err := fs.Parse([]string{"--output=./out"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Emit: EmitConfig{OTel: true}, Profiles: map[string]Profile{
+		"db-only":      {Emit: &EmitConfig{}, Flags: map[string]string{"dialect": "mysql", "output": "./db"}},
+		"full-service": {Emit: &EmitConfig{OTel: true, Health: true}},
+	}}
+	if err := cfg.applyProfile("db-only", fs); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Emit != (EmitConfig{}) || *dialect != "mysql" || *output != "./out" {
+		t.Errorf("emit %+v, dialect %s, output %s", cfg.Emit, *dialect, *output)
+	}
+
+	want := `profile "staging" not found; the config defines db-only, full-service`
+	if err := cfg.applyProfile("staging", fs); err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+	cfg.Profiles["input"] = Profile{Flags: map[string]string{"input": "."}}
+	want = "profile input: flags: input cannot be set by a profile"
+	if err := cfg.applyProfile("input", fs); err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,273 @@
+// property.go
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Property is a @property defined on a model. It has no backing column, so
+// any serializer or template using it needs an SQL expression or Go code.
+type Property struct {
+	Name         string   `json:"name"`
+	Line         int      `json:"line"`
+	Expr         *Expr    `json:"expr"`
+	ReferencedBy []string `json:"referenced_by"`
+}
+
+// Expr is a simple Python expression over the model's own fields, as
+// extracted from a property's return statement.
+type Expr struct {
+	Kind  string  `json:"kind"` // field, const, binop, concat or func
+	Name  string  `json:"name,omitempty"`
+	Value any     `json:"value,omitempty"`
+	Op    string  `json:"op,omitempty"`
+	Left  *Expr   `json:"left,omitempty"`
+	Right *Expr   `json:"right,omitempty"`
+	Parts []*Expr `json:"parts,omitempty"`
+}
+
+// ComputedProperty is a compat report entry for a referenced property,
+// either translated to an SQL expression or left to be computed in Go.
+type ComputedProperty struct {
+	Model        string   `json:"model"`
+	Property     string   `json:"property"`
+	Action       string   `json:"action"` // sql_expression or go_computed
+	SQL          string   `json:"sql,omitempty"`
+	Reason       string   `json:"reason,omitempty"`
+	ReferencedBy []string `json:"referenced_by"`
+}
+
+// textTypes lists the Django field types holding strings, which turn "+"
+// into concatenation.
+var textTypes = map[string]bool{
+	"CharField": true, "TextField": true, "EmailField": true, "SlugField": true, "URLField": true,
+}
+
+// computedProperties lists the properties referenced by serializers or
+// templates, translating the simple ones to SQL expressions.
+func computedProperties(models []Model, dialect string) []ComputedProperty {
+	var result []ComputedProperty
+	for _, m := range models {
+		for _, p := range m.Properties {
+			if len(p.ReferencedBy) == 0 {
+				continue
+			}
+			c := ComputedProperty{Model: m.Name, Property: p.Name, Action: "go_computed", ReferencedBy: p.ReferencedBy}
+			if p.Expr == nil {
+				c.Reason = "property body is not a single simple expression"
+			} else if sql, err := p.Expr.sql(m, dialect); err != nil {
+				c.Reason = err.Error()
+			} else {
+				c.Action, c.SQL = "sql_expression", sql
+			}
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// propertyColumn is a property translated to an SQL expression, exposed as a
+// generated column or a view column.
+type propertyColumn struct {
+	Name string
+	Type string
+	SQL  string
+}
+
+// propertyColumns returns every property of the model that is a pure
+// expression over the model's own fields.
+func propertyColumns(m Model, dialect string) []propertyColumn {
+	var cols []propertyColumn
+	for _, p := range m.Properties {
+		if p.Expr == nil || !p.Expr.usesFields() {
+			continue
+		}
+		sql, err := p.Expr.sql(m, dialect)
+		if err != nil {
+			continue
+		}
+		cols = append(cols, propertyColumn{Name: toSnake(p.Name), Type: p.Expr.sqlType(m), SQL: sql})
+	}
+	return cols
+}
+
+// computedView returns the name of the view exposing a model's properties.
+func computedView(m Model) string {
+	return m.table() + "_computed"
+}
+
+// propertyQueries generates a list query per model selecting the rows
+// together with every property that could be translated to SQL. Generated
+// columns need no extra query, and the view mode selects from the view.
+func propertyQueries(models []Model, props []ComputedProperty, opts Options) []TranslatedQuery {
+	if opts.ComputedColumns == "generated" {
+		return nil
+	}
+	var result []TranslatedQuery
+	for _, m := range models {
+		var cols, names []string
+		for _, p := range props {
+			if p.Model == m.Name && p.SQL != "" {
+				cols = append(cols, p.SQL+" AS "+toSnake(p.Property))
+				names = append(names, p.Property)
+			}
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		sql := "SELECT *, " + strings.Join(cols, ", ") + " FROM " + m.table() + ";"
+		if opts.ComputedColumns == "view" {
+			sql = "SELECT * FROM " + computedView(m) + ";"
+		}
+		result = append(result, TranslatedQuery{
+			Query: Query{App: m.App, Model: m.Name, Source: "computed properties " + strings.Join(names, ", ")},
+			Name:  "List" + m.Name + "WithProperties",
+			Kind:  ":many",
+			SQL:   sql,
+		})
+	}
+	return result
+}
+
+// sql renders the expression for the given dialect.
+func (e *Expr) sql(m Model, dialect string) (string, error) {
+	switch e.Kind {
+	case "field":
+		col, err := resolveColumn(e.Name, m)
+		if err != nil {
+			return "", err
+		}
+		return col, nil
+	case "const":
+		return value(Arg{Literal: true, Value: e.Value}, "", nil), nil
+	case "binop":
+		if e.Op == "+" && e.isText(m) {
+			return concat([]*Expr{e.Left, e.Right}, m, dialect)
+		}
+		left, err := e.Left.sql(m, dialect)
+		if err != nil {
+			return "", err
+		}
+		right, err := e.Right.sql(m, dialect)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + e.Op + " " + right + ")", nil
+	case "concat":
+		return concat(e.Parts, m, dialect)
+	case "func":
+		args := make([]string, len(e.Parts))
+		for i, p := range e.Parts {
+			s, err := p.sql(m, dialect)
+			if err != nil {
+				return "", err
+			}
+			args[i] = s
+		}
+		return e.Name + "(" + strings.Join(args, ", ") + ")", nil
+	}
+	return "", fmt.Errorf("unsupported expression %q", e.Kind)
+}
+
+// sqlType infers the column type of the expression's result.
+func (e *Expr) sqlType(m Model) string {
+	if e.isText(m) {
+		return "TEXT"
+	}
+	if e.isFloat(m) {
+		return "REAL"
+	}
+	return "INTEGER"
+}
+
+// usesFields reports whether the expression references any model field.
+func (e *Expr) usesFields() bool {
+	switch e.Kind {
+	case "field":
+		return true
+	case "binop":
+		return e.Left.usesFields() || e.Right.usesFields()
+	case "concat", "func":
+		for _, p := range e.Parts {
+			if p.usesFields() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFloat reports whether a numeric expression may produce a fraction.
+func (e *Expr) isFloat(m Model) bool {
+	switch e.Kind {
+	case "field":
+		f, ok := m.field(e.Name)
+		return ok && (f.Type == "FloatField" || f.Type == "DecimalField")
+	case "const":
+		// JSON numbers decode as float64, so only fractions count.
+		v, ok := e.Value.(float64)
+		return ok && v != math.Trunc(v)
+	case "binop":
+		return e.Op == "/" || e.Left.isFloat(m) || e.Right.isFloat(m)
+	}
+	return false
+}
+
+// isText reports whether the expression produces a string.
+func (e *Expr) isText(m Model) bool {
+	switch e.Kind {
+	case "field":
+		f, ok := m.field(e.Name)
+		return ok && textTypes[f.Type]
+	case "const":
+		_, ok := e.Value.(string)
+		return ok
+	case "concat", "func":
+		return true
+	case "binop":
+		return e.Left.isText(m) || e.Right.isText(m)
+	}
+	return false
+}
+
+// concat joins string expressions for the given dialect.
+func concat(parts []*Expr, m Model, dialect string) (string, error) {
+	sqls, err := concatParts(parts, m, dialect)
+	if err != nil {
+		return "", err
+	}
+	if dialect == "mysql" {
+		return "CONCAT(" + strings.Join(sqls, ", ") + ")", nil
+	}
+	return strings.Join(sqls, " || "), nil
+}
+
+// concatParts renders the operands of a concatenation, flattening nested
+// concatenations and string additions into a single list.
+func concatParts(parts []*Expr, m Model, dialect string) ([]string, error) {
+	var sqls []string
+	for _, p := range parts {
+		var nested []*Expr
+		if p.Kind == "concat" {
+			nested = p.Parts
+		} else if p.Kind == "binop" && p.Op == "+" && p.isText(m) {
+			nested = []*Expr{p.Left, p.Right}
+		}
+		if nested != nil {
+			s, err := concatParts(nested, m, dialect)
+			if err != nil {
+				return nil, err
+			}
+			sqls = append(sqls, s...)
+			continue
+		}
+		s, err := p.sql(m, dialect)
+		if err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, s)
+	}
+	return sqls, nil
+}
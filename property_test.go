@@ -0,0 +1,126 @@
+// property_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestComputedProperties parses properties referenced from a serializer and
+// a template and checks which ones translate to SQL expressions.
+func TestComputedProperties(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"people/models.py": `from django.db import models
+
+class Person(models.Model):
+    first = models.CharField(max_length=50)
+    last = models.CharField(max_length=50)
+    age = models.IntegerField()
+
+    @property
+    def full_name(self):
+        return f"{self.first} {self.last}"
+
+    @property
+    def age_next_year(self):
+        return self.age + 1
+
+    @property
+    def initials(self):
+        parts = [self.first, self.last]
+        return "".join(p[0] for p in parts)
+
+    @property
+    def unused(self):
+        return self.age * 2
+`,
+		"people/serializers.py": `from rest_framework import serializers
+from .models import Person
+
+class PersonSerializer(serializers.ModelSerializer):
+    class Meta:
+        model = Person
+        fields = ["first", "full_name", "initials"]
+`,
+		"people/templates/person.html": `<p>{{ person.age_next_year }}</p>`,
+	})
+	props := computedProperties(out.Models, "postgres")
+	got := map[string]ComputedProperty{}
+	for _, p := range props {
+		got[p.Property] = p
+	}
+	if len(got) != 3 {
+		t.Fatalf("got properties %+v, want full_name, age_next_year and initials", props)
+	}
+	if p := got["full_name"]; p.Action != "sql_expression" || p.SQL != "first || ' ' || last" {
+		t.Errorf("full_name = %s %q", p.Action, p.SQL)
+	}
+	if p := got["age_next_year"]; p.SQL != "(age + 1)" || len(p.ReferencedBy) != 1 || !strings.HasPrefix(p.ReferencedBy[0], "people/templates/person.html:1") {
+		t.Errorf("age_next_year = %q referenced by %v", p.SQL, p.ReferencedBy)
+	}
+	if p := got["initials"]; p.Action != "go_computed" || p.Reason == "" {
+		t.Errorf("initials = %s %q, want go_computed with a reason", p.Action, p.Reason)
+	}
+
+	if sql := computedPropertiesSQL(t, out.Models, "mysql"); !strings.Contains(sql, "CONCAT(first, ' ', last) AS full_name") {
+		t.Errorf("mysql property query = %q", sql)
+	}
+}
+
+// computedPropertiesSQL returns the SQL of the single generated property
+// query for the given dialect.
+func computedPropertiesSQL(t *testing.T, models []Model, dialect string) string {
+	t.Helper()
+	queries := propertyQueries(models, computedProperties(models, dialect), Options{Dialect: dialect})
+	if len(queries) != 1 {
+		t.Fatalf("got %d property queries, want 1", len(queries))
+	}
+	return queries[0].SQL
+}
+
+// TestComputedColumns checks the generated column and view modes for pure
+// expressions over a model's fields.
+func TestComputedColumns(t *testing.T) {
+	models := []Model{{Name: "Item", App: "shop", Managed: true,
+		Fields: []Field{
+			{Name: "price", Type: "IntegerField"},
+			{Name: "quantity", Type: "IntegerField"},
+		},
+		Properties: []Property{
+			{Name: "total", Expr: &Expr{Kind: "binop", Op: "*", Left: &Expr{Kind: "field", Name: "price"}, Right: &Expr{Kind: "field", Name: "quantity"}}},
+			{Name: "half", Expr: &Expr{Kind: "binop", Op: "/", Left: &Expr{Kind: "field", Name: "price"}, Right: &Expr{Kind: "const", Value: float64(2)}}},
+			{Name: "answer", Expr: &Expr{Kind: "const", Value: float64(42)}},
+		},
+	}}
+
+	generated := Options{Dialect: "postgres", ComputedColumns: "generated"}
+	sql := generateSQL(models, generated)
+	for _, want := range []string{
+		"total INTEGER GENERATED ALWAYS AS ((price * quantity)) STORED",
+		"half REAL GENERATED ALWAYS AS ((price / 2)) STORED",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "answer") {
+		t.Errorf("constant property became a column:\n%s", sql)
+	}
+
+	view := Options{Dialect: "postgres", ComputedColumns: "view"}
+	if sql := generateSQL(models, view); !strings.Contains(sql, "CREATE VIEW item_computed AS\nSELECT *,\n    (price * quantity) AS total") {
+		t.Errorf("missing view in:\n%s", sql)
+	}
+	if down := generateDownSQL(models, view); !strings.HasPrefix(down, "DROP VIEW IF EXISTS item_computed;") {
+		t.Errorf("view not dropped first:\n%s", down)
+	}
+
+	models[0].Properties[0].ReferencedBy = []string{"shop/serializers.py:7"}
+	props := computedProperties(models, "postgres")
+	if q := propertyQueries(models, props, generated); q != nil {
+		t.Errorf("generated mode produced queries %+v", q)
+	}
+	if q := propertyQueries(models, props, view); len(q) != 1 || q[0].SQL != "SELECT * FROM item_computed;" {
+		t.Errorf("view mode queries = %+v", q)
+	}
+}
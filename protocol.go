@@ -0,0 +1,74 @@
+// protocol.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// irVersion is the version of the JSON document the Python parser prints.
+// It is raised whenever a change to the document would make an older
+// parser's output read wrongly, such as a renamed key or a field changing
+// meaning; additions only get a new capability.
+const irVersion = 1
+
+// Parser capabilities: the parts of the document a parser fills in. A
+// custom parser missing one the run needs would leave the generated code
+// silently incomplete, so the run is refused instead.
+const (
+	capModels        = "models"
+	capConstraints   = "constraints"
+	capIndexes       = "indexes"
+	capProperties    = "properties"
+	capQueries       = "queries"
+	capAdmins        = "admins"
+	capCommands      = "commands"
+	capSchedules     = "schedules"
+	capMigrations    = "migrations"
+	capSettings      = "settings"
+	capTenants       = "tenants"
+	capPool          = "pool"
+	capDecimals      = "decimals"
+	capOrdering      = "ordering"
+	capSearch        = "search"
+	capActions       = "actions"
+	capTimezone      = "timezone"
+	capAutoFields    = "auto_fields"
+	capDefaults      = "defaults"
+	capAutoNow       = "auto_now"
+	capGeometry      = "geometry"
+	capCollation     = "collation"
+	capRanges        = "ranges"
+	capMoney         = "money"
+	capDjangoVersion = "django_version"
+	capAppLabels     = "app_labels"
+)
+
+// generateCapabilities are the capabilities generation needs.
+var generateCapabilities = []string{
+	capModels, capConstraints, capIndexes, capProperties, capQueries, capAdmins, capCommands, capSchedules, capSettings, capTenants, capPool,
+	capDecimals, capOrdering, capSearch, capActions, capTimezone, capAutoFields, capDefaults, capAutoNow, capGeometry, capCollation, capRanges,
+	capMoney, capDjangoVersion, capAppLabels,
+}
+
+// checkProtocol validates the version and capabilities announced by the
+// parser against the ones the run needs.
+func checkProtocol(out *Output, needs []string) error {
+	if out.IRVersion == 0 {
+		return fmt.Errorf("parser output has no ir_version; a custom --parser-script must be updated from the built-in parser.py (ir_version %d)", irVersion)
+	}
+	if out.IRVersion != irVersion {
+		return fmt.Errorf("parser output has ir_version %d, django2go reads %d; update the --parser-script from the built-in parser.py", out.IRVersion, irVersion)
+	}
+	var missing []string
+	for _, c := range needs {
+		if !slices.Contains(out.Capabilities, c) {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("parser lacks capabilities needed by this run: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
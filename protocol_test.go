@@ -0,0 +1,34 @@
+// protocol_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckProtocol(t *testing.T) {
+	for _, c := range []struct {
+		out  Output
+		want string
+	}{
+		{Output{IRVersion: irVersion, Capabilities: []string{capModels, capQueries, capAdmins}}, ""},
+		{Output{}, "has no ir_version"},
+		{Output{IRVersion: irVersion + 1, Capabilities: []string{capModels}}, "has ir_version 2, django2go reads 1"},
+		{Output{IRVersion: irVersion, Capabilities: []string{capModels}}, "lacks capabilities needed by this run: queries, admins"},
+	} {
+		err := checkProtocol(&c.out, []string{capModels, capQueries, capAdmins})
+		if c.want == "" && err != nil || c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)) {
+			t.Errorf("%+v: error %v, want %q", c.out, err, c.want)
+		}
+	}
+}
+
+// TestParserCapabilities checks that the built-in parser announces every
+// capability the runs need.
+func TestParserCapabilities(t *testing.T) {
+	out := parseProject(t, map[string]string{"blog/models.py": ""})
+	all := []string{capModels, capConstraints, capIndexes, capProperties, capQueries, capAdmins, capCommands, capSchedules, capMigrations, capSettings, capTenants, capPool, capDecimals, capOrdering, capSearch, capActions, capTimezone, capAutoFields, capDefaults, capAutoNow, capGeometry, capCollation, capRanges, capMoney, capDjangoVersion, capAppLabels}
+	if err := checkProtocol(out, all); err != nil {
+		t.Error(err)
+	}
+}
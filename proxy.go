@@ -0,0 +1,40 @@
+// proxy.go
+package main
+
+// modelsByName indexes the models by name and by the names of their proxy
+// models.
+func modelsByName(models []Model) map[string]Model {
+	byName := map[string]Model{}
+	for _, m := range models {
+		for _, alias := range m.Aliases {
+			byName[alias] = m
+		}
+	}
+	// A model named like another's proxy takes precedence.
+	for _, m := range models {
+		byName[m.Name] = m
+	}
+	return byName
+}
+
+// applyProxyAliases points the relations to proxy models, which get no
+// table of their own, at their concrete models.
+func applyProxyAliases(models []Model) {
+	concrete := map[string]string{}
+	for _, m := range models {
+		for _, alias := range m.Aliases {
+			concrete[alias] = m.Name
+		}
+	}
+	// A model named like another's proxy keeps its relations.
+	for _, m := range models {
+		concrete[m.Name] = ""
+	}
+	for i := range models {
+		for j, f := range models[i].Fields {
+			if name := concrete[f.RelatedTo]; f.Relation != "" && name != "" {
+				models[i].Fields[j].RelatedTo = name
+			}
+		}
+	}
+}
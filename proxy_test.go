@@ -0,0 +1,58 @@
+// proxy_test.go
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestProxyModels checks that proxy models get no table, and that the
+// relations and queries naming them resolve to their concrete model.
+func TestProxyModels(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+
+class PublishedPost(Post):
+    class Meta:
+        proxy = True
+
+class FeaturedPost(PublishedPost):
+    class Meta:
+        proxy = True
+
+class Comment(models.Model):
+    post = models.ForeignKey(FeaturedPost, on_delete=models.CASCADE)
+`,
+		"blog/views.py": `from .models import PublishedPost
+
+def published(request):
+    return PublishedPost.objects.filter(title="x")
+`,
+	})
+	var names []string
+	for _, m := range out.Models {
+		names = append(names, m.Name)
+	}
+	if !slices.Equal(names, []string{"Post", "Comment"}) {
+		t.Fatalf("models = %v", names)
+	}
+	if !slices.Equal(out.Models[0].Aliases, []string{"PublishedPost", "FeaturedPost"}) {
+		t.Errorf("aliases = %v", out.Models[0].Aliases)
+	}
+
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	if sql := generateSQL(out.Models, opts); !strings.Contains(sql, "REFERENCES post(id)") {
+		t.Errorf("schema:\n%s", sql)
+	}
+	queries := translateQueries(out.Queries, out.Models, opts.Dialect)
+	if len(queries) != 1 || !strings.Contains(queries[0].SQL, "FROM post WHERE title = ") {
+		t.Errorf("queries = %+v", queries)
+	}
+}
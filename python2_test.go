@@ -0,0 +1,50 @@
+// python2_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// python2Project has a models file and a views file in Python 2 syntax.
+var python2Project = map[string]string{
+	"legacy/models.py": `from django.db import models
+
+class Invoice(models.Model):
+    number = models.CharField(max_length=20)
+    total = models.BigIntegerField(default=0L)
+
+    def __unicode__(self):
+        print "invoice", self.number
+        return u"%s" % ` + "`self.number`" + `
+`,
+	"legacy/views.py": `def index(request):
+    print "index"
+`,
+}
+
+// TestPython2Syntax checks that Python 2 files are told apart from syntax
+// errors, and that only models files are read with --python2-models.
+func TestPython2Syntax(t *testing.T) {
+	out := parseProject(t, python2Project)
+	if len(out.Models) != 0 {
+		t.Errorf("models read without --python2-models: %+v", out.Models)
+	}
+	for _, d := range out.Diagnostics {
+		if d.Code != codeFileSkipped || !strings.Contains(d.Message, "file skipped: Python 2 syntax") {
+			t.Errorf("diagnostic = %+v", d)
+		}
+	}
+
+	out = parseProjectArgs(t, python2Project, []string{"--python2-models"})
+	if len(out.Models) != 1 || out.Models[0].Name != "Invoice" || len(out.Models[0].Fields) != 2 {
+		t.Fatalf("models = %+v", out.Models)
+	}
+	var codes []string
+	for _, d := range out.Diagnostics {
+		codes = append(codes, d.File+" "+d.Code)
+	}
+	if got := strings.Join(codes, ", "); got != "legacy/models.py W005, legacy/views.py W001" {
+		t.Errorf("diagnostics = %s", got)
+	}
+}
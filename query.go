@@ -0,0 +1,544 @@
+// query.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query represents an ORM call site discovered by the Python parser, such as
+// Book.objects.filter(author=a).order_by("-title").
+type Query struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	App    string `json:"app"`
+	Model  string `json:"model"`
+	Chain  []Step `json:"chain"`
+	Source string `json:"source"`
+	// AdminAction is set for call sites in admin actions.
+	AdminAction bool `json:"admin_action,omitempty"`
+}
+
+// Step is a single method call in a queryset chain.
+type Step struct {
+	Method string `json:"method"`
+	Args   []Arg  `json:"args"`
+	Kwargs []Arg  `json:"kwargs"`
+}
+
+// Arg is a positional or keyword argument of a queryset method. Literal
+// values are inlined into the SQL; everything else becomes a query parameter.
+type Arg struct {
+	Key     string `json:"key,omitempty"`
+	Literal bool   `json:"literal"`
+	Value   any    `json:"value"`
+	// Field names the field an F() expression compares against.
+	Field string `json:"field,omitempty"`
+	// Search is set for non-literal full-text search expressions.
+	Search *SearchExpr `json:"search,omitempty"`
+}
+
+// TranslatedQuery pairs a call site with its sqlc query, or the reason it
+// could not be translated.
+type TranslatedQuery struct {
+	Query
+	Name   string `json:"name,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	SQL    string `json:"sql,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Translated reports whether the call site was turned into a sqlc query.
+func (t TranslatedQuery) Translated() bool {
+	return t.SQL != ""
+}
+
+// Generated reports whether the query was generated from the models rather
+// than translated from a call site. Source then describes why it exists.
+func (t TranslatedQuery) Generated() bool {
+	return t.File == ""
+}
+
+// lookupOperators maps Django field lookups to SQL comparison operators.
+var lookupOperators = map[string]string{
+	"exact": "=",
+	"gt":    ">",
+	"gte":   ">=",
+	"lt":    "<",
+	"lte":   "<=",
+}
+
+// knownLookups lists the field lookups understood by lookupCondition.
+var knownLookups = map[string]bool{
+	"exact": true, "iexact": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	"contains": true, "icontains": true, "startswith": true, "istartswith": true,
+	"endswith": true, "iendswith": true, "in": true, "isnull": true, "range": true,
+}
+
+// translateQueries converts every discovered call site into a sqlc query
+// where possible. Call sites on proxy models query their concrete model.
+func translateQueries(queries []Query, models []Model, dialect string) []TranslatedQuery {
+	byName := modelsByName(models)
+	var result []TranslatedQuery
+	for _, q := range queries {
+		t := TranslatedQuery{Query: q}
+		m, ok := byName[q.Model]
+		if !ok {
+			t.Reason = fmt.Sprintf("unknown model %q", q.Model)
+		} else if name, kind, sql, err := translateQuery(q, m, dialect); err != nil {
+			t.Reason = err.Error()
+		} else {
+			t.Name, t.Kind, t.SQL = name, kind, sql
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// uniqueNames spells the query names, which embed model names, by the
+// naming rules of the config, and numbers repeated ones so every sqlc query
+// is unique.
+func uniqueNames(queries []TranslatedQuery) {
+	names := map[string]int{}
+	for i, q := range queries {
+		if !q.Translated() {
+			continue
+		}
+		q.Name = camel(q.Name)
+		queries[i].Name = q.Name
+		names[q.Name]++
+		if names[q.Name] > 1 {
+			queries[i].Name = fmt.Sprintf("%s%d", q.Name, names[q.Name])
+		}
+	}
+}
+
+// translateQuery builds the sqlc query name, command and SQL for a single
+// call site.
+func translateQuery(q Query, m Model, dialect string) (string, string, string, error) {
+	table := m.table()
+	params := map[string]int{}
+	search := newSearchScope(m, dialect, params)
+	var where, order, byCols, selected []string
+	verb, kind := "List", ":many"
+	var final Step
+
+	for _, step := range q.Chain {
+		final = step
+		switch step.Method {
+		case "all":
+		case "filter", "exclude", "get", "count", "exists", "delete", "first":
+			if step.Method == "delete" && m.isView() {
+				return "", "", "", fmt.Errorf("%s is a read-only view", m.Name)
+			}
+			if len(step.Args) > 0 {
+				return "", "", "", fmt.Errorf("positional arguments to %s() (Q objects) are not supported", step.Method)
+			}
+			var conds []string
+			for _, kw := range step.Kwargs {
+				cond, ok, err := search.match(kw)
+				col := strings.Split(kw.Key, "__")[0]
+				if !ok {
+					cond, col, err = lookupCondition(kw, m, dialect, params)
+				}
+				if err != nil {
+					return "", "", "", err
+				}
+				conds = append(conds, cond)
+				byCols = append(byCols, col)
+			}
+			if len(conds) == 0 {
+				break
+			}
+			if step.Method == "exclude" {
+				where = append(where, "NOT ("+strings.Join(conds, " AND ")+")")
+			} else {
+				where = append(where, conds...)
+			}
+		case "order_by":
+			// Like Django, order_by() replaces the ordering, and without
+			// arguments clears the model's default one.
+			order = []string{}
+			for _, a := range step.Args {
+				s, ok := a.Value.(string)
+				if !a.Literal || !ok || s == "?" {
+					return "", "", "", fmt.Errorf("unsupported order_by() argument")
+				}
+				dir := ""
+				if strings.HasPrefix(s, "-") {
+					s, dir = s[1:], " DESC"
+				}
+				if _, ok := search.ranks[s]; ok {
+					order = append(order, s+dir)
+					continue
+				}
+				col, err := resolveColumn(s, m)
+				if err != nil {
+					return "", "", "", err
+				}
+				order = append(order, col+dir)
+			}
+		case "annotate":
+			if len(step.Args) > 0 {
+				return "", "", "", fmt.Errorf("positional arguments to annotate() are not supported")
+			}
+			for _, kw := range step.Kwargs {
+				item, err := search.annotate(kw)
+				if err != nil {
+					return "", "", "", err
+				}
+				if item != "" {
+					selected = append(selected, item)
+				}
+			}
+		case "create", "update":
+			if m.isView() {
+				return "", "", "", fmt.Errorf("%s is a read-only view", m.Name)
+			}
+			if len(step.Args) > 0 || len(step.Kwargs) == 0 {
+				return "", "", "", fmt.Errorf("%s() requires keyword arguments", step.Method)
+			}
+		default:
+			return "", "", "", fmt.Errorf("unsupported queryset method %s()", step.Method)
+		}
+	}
+
+	columns := strings.Join(append([]string{"*"}, selected...), ", ")
+	var sql string
+	switch final.Method {
+	case "get":
+		verb, kind = "Get", ":one"
+		sql = "SELECT " + columns + " FROM " + table + whereClause(where)
+	case "first":
+		verb, kind = "GetFirst", ":one"
+		if order == nil {
+			order = m.defaultOrder()
+		}
+		if len(order) == 0 {
+			for _, c := range m.pkColumns() {
+				order = append(order, c.Name)
+			}
+		}
+		sql = "SELECT " + columns + " FROM " + table + whereClause(where) + " ORDER BY " + strings.Join(order, ", ") + " LIMIT 1"
+	case "count":
+		verb, kind = "Count", ":one"
+		sql = "SELECT COUNT(*) FROM " + table + whereClause(where)
+	case "exists":
+		verb, kind = "Exists", ":one"
+		sql = "SELECT EXISTS (SELECT 1 FROM " + table + whereClause(where) + ")"
+	case "delete":
+		verb, kind = "Delete", ":execrows"
+		sql = "DELETE FROM " + table + whereClause(where)
+	case "create":
+		verb, kind = "Create", ":one"
+		var cols, vals []string
+		for _, kw := range final.Kwargs {
+			kwCols, err := resolveColumns(kw.Key, m)
+			if err != nil {
+				return "", "", "", err
+			}
+			if len(kwCols) > 1 && kw.Literal {
+				return "", "", "", fmt.Errorf("composite foreign key %s requires a model instance", kw.Key)
+			}
+			for _, col := range kwCols {
+				cols = append(cols, col)
+				vals = append(vals, value(kw, col, params))
+			}
+		}
+		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(vals, ", "))
+		if dialect == "mysql" {
+			kind = ":execresult"
+		} else {
+			sql += " RETURNING *"
+		}
+	case "update":
+		verb, kind = "Update", ":execrows"
+		var sets []string
+		for _, kw := range final.Kwargs {
+			col, err := resolveColumn(kw.Key, m)
+			if err != nil {
+				return "", "", "", err
+			}
+			sets = append(sets, col+" = "+value(kw, col, params))
+		}
+		sql = "UPDATE " + table + " SET " + strings.Join(sets, ", ") + whereClause(where)
+	default:
+		sql = "SELECT " + columns + " FROM " + table + whereClause(where)
+		if order == nil {
+			order = m.defaultOrder()
+		}
+		if len(order) > 0 {
+			sql += " ORDER BY " + strings.Join(order, ", ")
+		}
+	}
+
+	name := verb + m.Name
+	if len(byCols) > 0 {
+		parts := make([]string, len(byCols))
+		for i, c := range byCols {
+			parts[i] = camel(c)
+		}
+		name += "By" + strings.Join(parts, "And")
+	}
+	return name, kind, sql + ";", nil
+}
+
+// lookupCondition translates a single filter keyword such as title__icontains
+// into a SQL condition, returning the condition and the column it targets.
+func lookupCondition(kw Arg, m Model, dialect string, params map[string]int) (string, string, error) {
+	field, lookup := kw.Key, "exact"
+	if i := strings.LastIndex(kw.Key, "__"); i >= 0 && knownLookups[kw.Key[i+2:]] {
+		field, lookup = kw.Key[:i], kw.Key[i+2:]
+	}
+	cols, err := resolveColumns(field, m)
+	if err != nil {
+		return "", "", err
+	}
+	if len(cols) > 1 {
+		// A foreign key to a composite key compares every key column.
+		if lookup != "exact" || kw.Literal {
+			return "", "", fmt.Errorf("%s lookup on composite foreign key %s is not supported", lookup, field)
+		}
+		conds := make([]string, len(cols))
+		for i, c := range cols {
+			conds[i] = c + " = " + value(kw, c, params)
+		}
+		return strings.Join(conds, " AND "), field, nil
+	}
+	col := cols[0]
+
+	if lookup == "isnull" {
+		b, ok := kw.Value.(bool)
+		if !kw.Literal || !ok {
+			return "", "", fmt.Errorf("isnull lookup on %s requires a literal boolean", field)
+		}
+		if b {
+			return col + " IS NULL", col, nil
+		}
+		return col + " IS NOT NULL", col, nil
+	}
+	if kw.Literal && kw.Value == nil && lookup == "exact" {
+		return col + " IS NULL", col, nil
+	}
+	if kw.Field != "" {
+		op, ok := lookupOperators[lookup]
+		if !ok {
+			return "", "", fmt.Errorf("%s lookup on %s cannot compare against F(%q)", lookup, field, kw.Field)
+		}
+		other, err := resolveColumn(kw.Field, m)
+		if err != nil {
+			return "", "", err
+		}
+		return col + " " + op + " " + other, col, nil
+	}
+
+	if op, ok := lookupOperators[lookup]; ok {
+		return col + " " + op + " " + value(kw, col, params), col, nil
+	}
+	switch lookup {
+	case "iexact":
+		return "LOWER(" + col + ") = LOWER(" + value(kw, col, params) + ")", col, nil
+	case "contains", "icontains", "startswith", "istartswith", "endswith", "iendswith":
+		v := value(kw, col, params)
+		var parts []string
+		if !strings.HasSuffix(lookup, "startswith") {
+			parts = append(parts, "'%'")
+		}
+		parts = append(parts, v)
+		if !strings.HasSuffix(lookup, "endswith") {
+			parts = append(parts, "'%'")
+		}
+		pattern := "CONCAT(" + strings.Join(parts, ", ") + ")"
+		if dialect != "mysql" {
+			pattern = strings.Join(parts, " || ")
+		}
+		op := "LIKE"
+		if strings.HasPrefix(lookup, "i") && dialect != "mysql" {
+			op = "ILIKE"
+		}
+		return col + " " + op + " " + pattern, col, nil
+	case "in":
+		if kw.Literal {
+			values, ok := kw.Value.([]any)
+			if !ok || len(values) == 0 {
+				return "", "", fmt.Errorf("in lookup on %s requires a non-empty list", field)
+			}
+			items := make([]string, len(values))
+			for i, v := range values {
+				items[i] = value(Arg{Literal: true, Value: v}, col, params)
+			}
+			return col + " IN (" + strings.Join(items, ", ") + ")", col, nil
+		}
+		if dialect == "mysql" {
+			return col + " IN (sqlc.slice(" + param(col+"s", params) + "))", col, nil
+		}
+		return col + " = ANY(sqlc.arg(" + param(col+"s", params) + ")::" + sqlType(fieldType(field, m), dialect) + "[])", col, nil
+	case "range":
+		if !kw.Literal {
+			return col + " BETWEEN sqlc.arg(" + param(col+"_from", params) + ") AND sqlc.arg(" + param(col+"_to", params) + ")", col, nil
+		}
+		bounds, ok := kw.Value.([]any)
+		if !ok || len(bounds) != 2 {
+			return "", "", fmt.Errorf("range lookup on %s requires a pair of bounds", field)
+		}
+		low, high := value(Arg{Literal: true, Value: bounds[0]}, col, params), value(Arg{Literal: true, Value: bounds[1]}, col, params)
+		return col + " BETWEEN " + low + " AND " + high, col, nil
+	}
+	return "", "", fmt.Errorf("unsupported lookup %q on %s", lookup, field)
+}
+
+// resolveColumn maps a Django field reference (name, name_id or pk) to its
+// column on the model's table. Relation traversals are rejected.
+func resolveColumn(name string, m Model) (string, error) {
+	cols, err := resolveColumns(name, m)
+	if err != nil {
+		return "", err
+	}
+	if len(cols) > 1 {
+		return "", fmt.Errorf("%q spans the composite key columns %s", name, strings.Join(cols, ", "))
+	}
+	return cols[0], nil
+}
+
+// resolveColumns is like resolveColumn but allows references that span
+// several columns, such as foreign keys to models with composite keys.
+func resolveColumns(name string, m Model) ([]string, error) {
+	if strings.Contains(name, "__") {
+		return nil, fmt.Errorf("relation traversal %q requires a join", name)
+	}
+	if name == "_order" && m.OrderWithRespectTo != "" {
+		return []string{"_order"}, nil
+	}
+	if name == "pk" || (name == "id" && len(m.PrimaryKey) == 0) {
+		if len(m.PrimaryKey) > 0 {
+			return nil, fmt.Errorf("%s has a composite primary key; filter on its fields instead of pk", m.Name)
+		}
+		return []string{"id"}, nil
+	}
+	for _, f := range m.Fields {
+		if f.Name != name && columnName(f) != name {
+			continue
+		}
+		if f.Relation == "many2many" {
+			return nil, fmt.Errorf("many-to-many field %q requires a join", name)
+		}
+		var cols []string
+		for _, c := range fieldColumns(f) {
+			cols = append(cols, c.Name)
+		}
+		return cols, nil
+	}
+	return nil, fmt.Errorf("unknown field %q on %s", name, m.Name)
+}
+
+// fieldType returns the Django type of the named field, treating the
+// implicit primary key as an IntegerField.
+func fieldType(name string, m Model) string {
+	for _, f := range m.Fields {
+		if f.Name == name || columnName(f) == name {
+			return f.Type
+		}
+	}
+	return "IntegerField"
+}
+
+// value renders a literal as SQL or allocates a named sqlc parameter.
+func value(a Arg, col string, params map[string]int) string {
+	if !a.Literal {
+		return "sqlc.arg(" + param(col, params) + ")"
+	}
+	switch v := a.Value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// param returns a unique parameter name derived from the column name.
+func param(name string, params map[string]int) string {
+	params[name]++
+	if params[name] > 1 {
+		return fmt.Sprintf("%s_%d", name, params[name])
+	}
+	return name
+}
+
+// whereClause joins conditions into a WHERE clause, or returns "".
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// camel converts a snake_case or CamelCase name to a CamelCase Go name,
+// following the renames and initialisms of the config.
+func camel(s string) string {
+	return goNames.camel(s)
+}
+
+// orderedQueries generates a list query for every model using
+// Meta.order_with_respect_to, returning the rows of one parent in _order
+// order like Django's related manager does.
+func orderedQueries(models []Model) []TranslatedQuery {
+	var result []TranslatedQuery
+	for _, m := range models {
+		if m.OrderWithRespectTo == "" {
+			continue
+		}
+		cols, err := resolveColumns(m.OrderWithRespectTo, m)
+		if err != nil {
+			continue
+		}
+		params := map[string]int{}
+		conds := make([]string, len(cols))
+		for i, c := range cols {
+			conds[i] = c + " = sqlc.arg(" + param(c, params) + ")"
+		}
+		result = append(result, TranslatedQuery{
+			Query: Query{App: m.App, Model: m.Name, Source: fmt.Sprintf("Meta.order_with_respect_to = %q", m.OrderWithRespectTo)},
+			Name:  "List" + m.Name + "By" + camel(m.OrderWithRespectTo),
+			Kind:  ":many",
+			SQL:   "SELECT * FROM " + m.table() + whereClause(conds) + " ORDER BY _order;",
+		})
+	}
+	return result
+}
+
+// generateQueries renders query.sql, keeping untranslated call sites as
+// comments so they can be ported by hand.
+func generateQueries(queries []TranslatedQuery, models []Model) string {
+	byName := map[string]Model{}
+	for _, m := range models {
+		byName[m.App+"."+m.Name] = m
+	}
+	var blocks []string
+	for _, q := range queries {
+		model := ""
+		if q.Model != "" {
+			model = "; model " + q.App + "." + q.Model
+		}
+		block := fmt.Sprintf("-- from: %s:%d%s\n-- %s\n", q.File, q.Line, model, q.Source)
+		if q.Generated() {
+			if m, ok := byName[q.App+"."+q.Model]; ok {
+				model += sourceRef(m.File, m.Line)
+			}
+			block = "-- generated: " + q.Source + model + "\n"
+		}
+		if q.Translated() {
+			block = fmt.Sprintf("-- name: %s %s\n", q.Name, q.Kind) + block + q.SQL
+		} else {
+			block += "-- untranslated: " + q.Reason
+		}
+		blocks = append(blocks, block)
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
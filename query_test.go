@@ -0,0 +1,168 @@
+// query_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// queryModels returns the models the query tests translate against.
+func queryModels() []Model {
+	return []Model{{Name: "Book", App: "library", Fields: []Field{
+		{Name: "title", Type: "CharField"},
+		{Name: "pages", Type: "IntegerField"},
+		{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+	}}}
+}
+
+// TestTranslateQueries checks the name, kind and SQL of translated call
+// sites, and that unsupported ones keep a reason.
+func TestTranslateQueries(t *testing.T) {
+	queries := []Query{
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "title__icontains", Value: "title"}}}}},
+		{Model: "Book", Chain: []Step{{Method: "get", Kwargs: []Arg{{Key: "pages", Literal: true, Value: float64(10)}}}}},
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "pages__gt", Value: "n"}}}, {Method: "count"}}},
+		{Model: "Book", Chain: []Step{{Method: "all"}, {Method: "order_by", Args: []Arg{{Literal: true, Value: "-pages"}}}}},
+		{Model: "Book", Chain: []Step{{Method: "annotate", Args: []Arg{{Value: "n"}}}}},
+		{Model: "Shelf", Chain: []Step{{Method: "all"}}},
+	}
+	got := translateQueries(queries, queryModels(), "postgres")
+	for i, want := range []struct{ name, kind, sql string }{
+		{"ListBookByTitle", ":many", "WHERE title ILIKE"},
+		{"GetBookByPages", ":one", "WHERE pages = 10"},
+		{"CountBookByPages", ":one", "SELECT COUNT(*)"},
+		{"ListBook", ":many", "ORDER BY pages DESC"},
+	} {
+		q := got[i]
+		if q.Name != want.name || q.Kind != want.kind || !strings.Contains(q.SQL, want.sql) {
+			t.Errorf("query %d = %s %s %q, want %s %s containing %q", i, q.Name, q.Kind, q.SQL, want.name, want.kind, want.sql)
+		}
+	}
+	for _, q := range got[4:] {
+		if q.Translated() || q.Reason == "" {
+			t.Errorf("%s query translated as %q, want a reason", q.Model, q.SQL)
+		}
+	}
+}
+
+// TestOrderWithRespectTo checks the _order column, the default ordering it
+// implies and the generated per-parent list query.
+func TestOrderWithRespectTo(t *testing.T) {
+	models := []Model{
+		{Name: "Question", App: "polls", Managed: true, Fields: []Field{{Name: "text", Type: "TextField"}}},
+		{Name: "Answer", App: "polls", Managed: true, OrderWithRespectTo: "question", Fields: []Field{
+			{Name: "text", Type: "TextField"},
+			{Name: "question", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Question"},
+		}},
+	}
+	resolveRelations(models)
+	if sql := generateSQL(models, Options{Dialect: "postgres"}); !strings.Contains(sql, "_order INTEGER NOT NULL DEFAULT 0") {
+		t.Errorf("missing _order column:\n%s", sql)
+	}
+
+	got := translateQueries([]Query{{Model: "Answer", Chain: []Step{{Method: "all"}}}}, models, "postgres")
+	if !strings.Contains(got[0].SQL, "ORDER BY _order") {
+		t.Errorf("all() = %q, want the _order default ordering", got[0].SQL)
+	}
+
+	ordered := orderedQueries(models)
+	if len(ordered) != 1 {
+		t.Fatalf("got %d ordered queries, want 1", len(ordered))
+	}
+	q := ordered[0]
+	if q.Name != "ListAnswerByQuestion" || !q.Generated() ||
+		q.SQL != "SELECT * FROM answer WHERE question_id = sqlc.arg(question_id) ORDER BY _order;" {
+		t.Errorf("ordered query = %s %q", q.Name, q.SQL)
+	}
+
+	dup := []TranslatedQuery{{Name: "ListAnswer", SQL: "a"}, {Name: "ListAnswer", SQL: "b"}, {Name: "ListAnswer"}}
+	uniqueNames(dup)
+	if dup[1].Name != "ListAnswer2" || dup[2].Name != "ListAnswer" {
+		t.Errorf("uniqueNames = %s, %s", dup[1].Name, dup[2].Name)
+	}
+}
+
+// TestExpressionLookups checks range lookups and comparisons against F()
+// expressions, in queries and in constraints.
+func TestExpressionLookups(t *testing.T) {
+	queries := []Query{
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "pages__range", Literal: true, Value: []any{float64(100), float64(200)}}}}}},
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "pages__range", Value: "bounds"}}}}},
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "pages__gt", Field: "author"}}}}},
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "title__icontains", Field: "author"}}}}},
+	}
+	got := translateQueries(queries, queryModels(), "postgres")
+	for i, want := range []string{
+		"WHERE pages BETWEEN 100 AND 200",
+		"WHERE pages BETWEEN sqlc.arg(pages_from) AND sqlc.arg(pages_to)",
+		"WHERE pages > author_id",
+	} {
+		if !strings.Contains(got[i].SQL, want) {
+			t.Errorf("query %d = %q, want %q", i, got[i].SQL, want)
+		}
+	}
+	if got[3].Translated() {
+		t.Errorf("icontains against F() translated as %q", got[3].SQL)
+	}
+
+	out := parseProject(t, map[string]string{"shop/models.py": `from django.db import models
+from django.db.models import F, Q
+from django.db.models.functions import Lower
+
+class Sale(models.Model):
+    email = models.CharField(max_length=100)
+    opens = models.DateField()
+    closes = models.DateField()
+
+    class Meta:
+        constraints = [
+            models.UniqueConstraint(Lower("email"), name="sale_email_ci"),
+            models.CheckConstraint(check=Q(closes__gt=F("opens")), name="sale_dates"),
+        ]
+`})
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{"CONSTRAINT sale_dates CHECK (closes > opens)", "CREATE UNIQUE INDEX sale_email_ci ON sale ((LOWER(email)));"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+}
+
+// TestMetaOrdering checks that list queries and first() sort by
+// Meta.ordering unless the call site orders them, and that the ordering
+// stops at the first name that is not a column.
+func TestMetaOrdering(t *testing.T) {
+	models := queryModels()
+	models[0].Ordering = []string{"-pages", "title"}
+	queries := []Query{
+		{Model: "Book", Chain: []Step{{Method: "filter", Kwargs: []Arg{{Key: "title", Value: "title"}}}}},
+		{Model: "Book", Chain: []Step{{Method: "first"}}},
+		{Model: "Book", Chain: []Step{{Method: "all"}, {Method: "order_by"}}},
+		{Model: "Book", Chain: []Step{{Method: "all"}, {Method: "order_by", Args: []Arg{{Literal: true, Value: "title"}}}}},
+	}
+	got := translateQueries(queries, models, "postgres")
+	for i, want := range []string{
+		"WHERE title = sqlc.arg(title) ORDER BY pages DESC, title;",
+		"ORDER BY pages DESC, title LIMIT 1;",
+		"FROM book;",
+		"ORDER BY title;",
+	} {
+		if !strings.HasSuffix(got[i].SQL, want) {
+			t.Errorf("query %d = %q, want it ending %q", i, got[i].SQL, want)
+		}
+	}
+
+	models[0].Ordering = []string{"title", "author__name", "pages"}
+	diags := checkOrdering(models)
+	if len(diags) != 1 || diags[0].Code != codeOrdering || !strings.Contains(diags[0].Message, `"author__name"`) ||
+		!strings.HasSuffix(diags[0].Message, "queries sort by title") {
+		t.Errorf("diagnostics = %v", diags)
+	}
+	if got := translateQueries(queries[:1], models, "postgres"); !strings.HasSuffix(got[0].SQL, "ORDER BY title;") {
+		t.Errorf("query = %q, want the ordering before author__name", got[0].SQL)
+	}
+}
@@ -0,0 +1,154 @@
+// querylog.go
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultSlowQuery is the duration from which the generated query logger
+// reports queries as slow when the config sets none.
+const defaultSlowQuery = 200 * time.Millisecond
+
+// generateQueryLog renders the querylog package. Its DB wraps the handle
+// given to the sqlc queries and logs every query with slog, as Django's
+// django.db.backends logger does, raising the level of slow and failed ones.
+// The arguments at the sensitive positions of a query are never logged.
+func generateQueryLog(slow time.Duration, sensitive map[string][]int) (map[string]string, error) {
+	if slow == 0 {
+		slow = defaultSlowQuery
+	}
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package querylog logs the database queries with log/slog.
+package querylog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// SlowQuery is the duration from which a query is logged at warning level
+// rather than debug. Zero logs every successful query at debug level.
+var SlowQuery = %[1]s
+
+// LogArgs logs the arguments of the queries, except those holding personal
+// data, which are logged as [REDACTED].
+var LogArgs = false
+
+// sensitiveArgs holds, per query, the positions of the arguments holding
+// personal data; -1 redacts every argument of the query.
+var sensitiveArgs = %[3]s
+
+// DBTX is the database handle of the sqlc queries.
+type DBTX interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// DB wraps the handle given to the sqlc queries, as in
+// db.New(querylog.DB(conn, logger)), so that every query is logged with its
+// name, duration and number of arguments. The arguments themselves are only
+// logged with LogArgs. A nil logger uses slog.Default.
+// Query durations stop when the rows are returned, before they are read.
+func DB(db DBTX, logger *slog.Logger) DBTX {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return loggedDB{db: db, logger: logger}
+}
+
+type loggedDB struct {
+	db     DBTX
+	logger *slog.Logger
+}
+
+func (l loggedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	res, err := l.db.ExecContext(ctx, query, args...)
+	l.log(ctx, query, args, start, err)
+	return res, err
+}
+
+func (l loggedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	start := time.Now()
+	stmt, err := l.db.PrepareContext(ctx, query)
+	l.log(ctx, query, nil, start, err)
+	return stmt, err
+}
+
+func (l loggedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := l.db.QueryContext(ctx, query, args...)
+	l.log(ctx, query, args, start, err)
+	return rows, err
+}
+
+func (l loggedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	start := time.Now()
+	row := l.db.QueryRowContext(ctx, query, args...)
+	l.log(ctx, query, args, start, row.Err())
+	return row
+}
+
+var queryName = regexp.MustCompile(%[2]s)
+
+// log logs a query that started at start: failures other than no rows at
+// error level, slow queries at warning level and the others at debug level.
+func (l loggedDB) log(ctx context.Context, query string, args []any, start time.Time, err error) {
+	elapsed := time.Since(start)
+	name := "query"
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		name = m[1]
+	}
+	level, msg := slog.LevelDebug, "query"
+	switch {
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		level, msg = slog.LevelError, "query failed"
+	case SlowQuery > 0 && elapsed >= SlowQuery:
+		level, msg = slog.LevelWarn, "slow query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("query", name),
+		slog.Duration("duration", elapsed),
+		slog.Int("args", len(args)),
+	}
+	if LogArgs {
+		attrs = append(attrs, slog.Any("arguments", redact(name, args)))
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// redact returns the arguments of the named query with those holding
+// personal data replaced.
+func redact(name string, args []any) []any {
+	positions := sensitiveArgs[name]
+	logged := make([]any, len(args))
+	for i, a := range args {
+		logged[i] = a
+		if slices.Contains(positions, i) || slices.Contains(positions, -1) {
+			logged[i] = "[REDACTED]"
+		}
+	}
+	return logged
+}
+`, goDuration(slow), "`^-- name: (\\w+)`", renderSensitiveArgs(sensitive))
+	files := map[string]string{}
+	if err := addGoFile(files, "querylog/querylog.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
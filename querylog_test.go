@@ -0,0 +1,39 @@
+// querylog_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateQueryLog(t *testing.T) {
+	for slow, want := range map[time.Duration]string{
+		0:                       "var SlowQuery = 200 * time.Millisecond",
+		time.Second:             "var SlowQuery = 1 * time.Second",
+		1500 * time.Millisecond: "var SlowQuery = 1500 * time.Millisecond",
+	} {
+		files, err := generateQueryLog(slow, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if src := files["querylog/querylog.go"]; !strings.Contains(src, want) {
+			t.Errorf("slow %v: querylog.go lacks %q", slow, want)
+		}
+	}
+}
+
+func TestEmitConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		emit EmitConfig
+		ok   bool
+	}{
+		{EmitConfig{QueryLog: true, SlowQuery: time.Second}, true},
+		{EmitConfig{SlowQuery: time.Second}, false},
+		{EmitConfig{QueryLog: true, SlowQuery: -time.Second}, false},
+	} {
+		if err := c.emit.validate(); (err == nil) != c.ok {
+			t.Errorf("%+v: error %v", c.emit, err)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// querytest.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// rejectedErrors holds, per dialect, the imports and the test of the
+// generated rejected, recognizing the errors of values the schema refuses:
+// constraint violations and invalid data.
+var rejectedErrors = map[string]struct {
+	Std    string
+	Driver string
+	Test   string
+}{
+	"postgres": {
+		Std:    "strings",
+		Driver: "github.com/jackc/pgx/v5/pgconn",
+		Test: `var pgErr *pgconn.PgError
+	// Classes 22, data exception, and 23, integrity constraint violation.
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23"))`,
+	},
+	"mysql": {
+		Std:    "slices",
+		Driver: "github.com/go-sql-driver/mysql",
+		Test: `var myErr *mysql.MySQLError
+	// NOT NULL, duplicate key, foreign key, CHECK and invalid value errors.
+	return errors.As(err, &myErr) && slices.Contains([]uint16{1048, 1062, 1264, 1265, 1292, 1366, 1406, 1451, 1452, 3140, 3819}, myErr.Number)`,
+	},
+}
+
+// unfilteredList matches the :many queries returning every row of a table,
+// which must return the fixture row.
+var unfilteredList = regexp.MustCompile(`^SELECT (?:[^;]*? )?FROM (\w+)(?: ORDER BY [\w, ]+)?;$`)
+
+// geoFixtures holds a WKT value of each geometry type.
+var geoFixtures = map[string]string{
+	"Geometry":           "POINT(0 0)",
+	"Point":              "POINT(0 0)",
+	"LineString":         "LINESTRING(0 0, 1 1)",
+	"Polygon":            "POLYGON((0 0, 1 0, 1 1, 0 0))",
+	"MultiPoint":         "MULTIPOINT((0 0))",
+	"MultiLineString":    "MULTILINESTRING((0 0, 1 1))",
+	"MultiPolygon":       "MULTIPOLYGON(((0 0, 1 0, 1 1, 0 0)))",
+	"GeometryCollection": "GEOMETRYCOLLECTION(POINT(0 0))",
+}
+
+// fixtureValue returns the SQL value a fixture row stores in a column of the
+// field: its literal default or first choice, else a fixed value of the
+// column type that its checks accept.
+func fixtureValue(f Field, c Column, dialect string) string {
+	switch f.Default.(type) {
+	case string, float64, bool:
+		return value(Arg{Literal: true, Value: f.Default}, "", nil)
+	}
+	if len(f.Choices) > 0 {
+		return value(Arg{Literal: true, Value: f.Choices[0].Value}, "", nil)
+	}
+	if wkt := geoFixtures[geoTypes[f.Type]]; wkt != "" {
+		srid := f.SRID
+		if srid == 0 {
+			srid = defaultSRID
+		}
+		if dialect == "mysql" {
+			return fmt.Sprintf("ST_GeomFromText('%s', %d)", wkt, srid)
+		}
+		return fmt.Sprintf("'SRID=%d;%s'", srid, wkt)
+	}
+	t := strings.ToUpper(c.sqlType(dialect))
+	switch {
+	case strings.HasSuffix(t, "[]"):
+		return "'{}'"
+	case strings.HasSuffix(t, "RANGE"):
+		return "'empty'"
+	case strings.HasPrefix(t, "NUMERIC") || strings.HasPrefix(t, "DECIMAL"):
+		if f.MaxDigits > 0 && f.MaxDigits == f.DecimalPlaces {
+			return "0"
+		}
+		return "1"
+	case strings.Contains(t, "INT") || strings.Contains(t, "SERIAL") || t == "REAL" || strings.HasPrefix(t, "DOUBLE") || strings.HasPrefix(t, "FLOAT"):
+		return "1"
+	case strings.HasPrefix(t, "BOOL"):
+		return "TRUE"
+	case strings.HasPrefix(t, "TIMESTAMP") || strings.HasPrefix(t, "DATETIME"):
+		return "'2000-01-01 00:00:00'"
+	case t == "DATE":
+		return "'2000-01-01'"
+	case strings.HasPrefix(t, "TIME"):
+		return "'00:00:00'"
+	case strings.HasPrefix(t, "INTERVAL"):
+		return "'1 second'"
+	case t == "UUID":
+		return "'00000000-0000-0000-0000-000000000001'"
+	case strings.HasPrefix(t, "JSON"):
+		return "'{}'"
+	case t == "INET":
+		return "'127.0.0.1'"
+	}
+	return "''"
+}
+
+// fixtureRows returns a fixture row for every table of the models, in the
+// order the tables are created in, those referenced by non-nullable foreign
+// keys first, with the join tables last.
+// Auto-incrementing keys are left to the database, which numbers the first
+// row 1; nullable columns are left NULL.
+func fixtureRows(models []Model, dialect string) (tables, inserts []string) {
+	byName := map[string]Model{}
+	for _, m := range models {
+		byName[m.Name] = m
+	}
+	// pks holds the primary key values of the rows, by model and column.
+	pks := map[string]map[string]string{}
+	var add func(m Model)
+	add = func(m Model) {
+		if pks[m.Name] != nil {
+			return
+		}
+		pks[m.Name] = map[string]string{}
+		for _, f := range m.Fields {
+			if rel, ok := byName[f.RelatedTo]; ok && f.Relation != "many2many" && !f.Nullable {
+				add(rel)
+			}
+		}
+		if len(m.PrimaryKey) == 0 {
+			pks[m.Name]["id"] = "1"
+		}
+		var cols, vals []string
+		for _, f := range m.Fields {
+			if f.Relation == "many2many" || f.Generated {
+				continue
+			}
+			for i, c := range fieldColumns(f) {
+				v := "NULL"
+				switch {
+				case f.Relation != "":
+					if !f.Nullable && len(f.RelatedPK) > i {
+						v = pks[f.RelatedTo][f.RelatedPK[i].Name]
+					}
+				case autoTypes[c.Type] != "":
+					pks[m.Name][c.Name] = "1"
+					continue
+				case !f.Nullable:
+					v = fixtureValue(f, c, dialect)
+				}
+				if v == "" {
+					v = "NULL"
+				}
+				if f.PrimaryKey || slices.Contains(m.PrimaryKey, f.Name) {
+					pks[m.Name][c.Name] = v
+				}
+				cols, vals = append(cols, c.Name), append(vals, v)
+			}
+		}
+		table := m.table()
+		insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(vals, ", "))
+		if len(cols) == 0 {
+			insert = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table)
+			if dialect == "mysql" {
+				insert = fmt.Sprintf("INSERT INTO %s () VALUES ()", table)
+			}
+		}
+		tables, inserts = append(tables, table), append(inserts, insert)
+	}
+	// In the order the tables are created in, which inserts the rows of
+	// the tables referenced by foreign keys first.
+	for _, m := range planTables(models).models {
+		add(m)
+	}
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if f.Relation != "many2many" || pks[f.RelatedTo] == nil || m.isView() {
+				continue
+			}
+			var cols, vals []string
+			for i, side := range joinSides(m, f) {
+				model := []string{m.Name, f.RelatedTo}[i]
+				for j, c := range side.pk {
+					cols, vals = append(cols, side.names[j]), append(vals, pks[model][c.Name])
+				}
+			}
+			if slices.Contains(vals, "") {
+				continue
+			}
+			table := joinTableName(m, f)
+			tables = append(tables, table)
+			inserts = append(inserts, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(vals, ", ")))
+		}
+	}
+	return tables, inserts
+}
+
+// generateDBTests renders the tests of the dbtest package selected by the
+// emit settings. fixtures_test.go inserts a fixture row per table, which
+// queries_test.go runs every translated query against, so that the
+// translation is checked against the real schema, and types_test.go stores
+// boundary values in (see roundTrips).
+func generateDBTests(module string, layout Layout, queries []TranslatedQuery, models []Model, dialect string, emit EmitConfig) (map[string]string, error) {
+	tables, inserts := fixtureRows(models, dialect)
+	seeded := map[string]bool{}
+	var fixtures strings.Builder
+	for i, table := range tables {
+		seeded[table] = true
+		fmt.Fprintf(&fixtures, "\t{%q, %q},\n", table, inserts[i])
+	}
+	rejected := rejectedErrors[dialect]
+	files := map[string]string{}
+	src := fmt.Sprintf(`// Code generated by django2go. DO NOT EDIT.
+
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	%[1]q
+	"testing"
+
+	%[2]q
+)
+
+// fixtures inserts a row into every table, those referenced first.
+var fixtures = []struct {
+	table  string
+	insert string
+}{
+%[3]s}
+
+// seed inserts the fixtures, returning the tables holding their row.
+func seed(t *testing.T, conn *sql.DB) map[string]bool {
+	t.Helper()
+	seeded := map[string]bool{}
+	for _, f := range fixtures {
+		if _, err := conn.ExecContext(context.Background(), f.insert); err != nil {
+			t.Logf("fixture %%s: %%v", f.table, err)
+			continue
+		}
+		seeded[f.table] = true
+	}
+	return seeded
+}
+
+// rejected reports whether err is a constraint violation or invalid data,
+// as zero and boundary values often are.
+func rejected(err error) bool {
+	%[4]s
+}
+`, rejected.Std, rejected.Driver, fixtures.String(), rejected.Test)
+	if err := addGoFile(files, path.Join("dbtest", "fixtures_test.go"), src); err != nil {
+		return nil, err
+	}
+
+	var tests strings.Builder
+	for _, q := range queries {
+		if !q.Translated() {
+			continue
+		}
+		table := ""
+		if m := unfilteredList.FindStringSubmatch(q.SQL); m != nil && q.Kind == ":many" && seeded[m[1]] &&
+			!strings.Contains(q.SQL, " WHERE ") && !strings.Contains(q.SQL, " JOIN ") && !strings.Contains(q.SQL, " GROUP BY ") {
+			table = m[1]
+		}
+		fmt.Fprintf(&tests, "\t{%q, %q},\n", q.Name, table)
+	}
+	if emit.QueryTests && tests.Len() > 0 {
+		src := fmt.Sprintf(`// Code generated by django2go. DO NOT EDIT.
+
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	%[1]q
+)
+
+// queryTests lists the translated queries, with the table whose every row
+// a :many query returns, if any.
+var queryTests = []struct {
+	name  string
+	table string
+}{
+%[2]s}
+
+// TestQueries runs every translated query against the fixtures with
+// zero-valued arguments, each in a transaction rolled back afterwards.
+// Queries whose arguments the schema rejects are skipped; those listing a
+// whole table must return its fixture row.
+func TestQueries(t *testing.T) {
+	conn := NewTestConn(t)
+	ctx := context.Background()
+	seeded := seed(t, conn)
+	for _, qt := range queryTests {
+		t.Run(qt.name, func(t *testing.T) {
+			tx, err := conn.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+			method := reflect.ValueOf(db.New(tx)).MethodByName(qt.name)
+			if !method.IsValid() {
+				t.Fatalf("db.Queries has no method %%s", qt.name)
+			}
+			args := []reflect.Value{reflect.ValueOf(ctx)}
+			for i := 1; i < method.Type().NumIn(); i++ {
+				args = append(args, reflect.Zero(method.Type().In(i)))
+			}
+			out := method.Call(args)
+			err, _ = out[len(out)-1].Interface().(error)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+			case rejected(err):
+				t.Skipf("arguments rejected by the schema: %%v", err)
+			case err != nil:
+				t.Fatal(err)
+			}
+			if qt.table != "" && seeded[qt.table] {
+				if n := out[0].Len(); n != 1 {
+					t.Errorf("got %%d rows from %%s, want its fixture row", n, qt.table)
+				}
+			}
+		})
+	}
+}
+`, layout.importPath(module, layout.DB), tests.String())
+		if err := addGoFile(files, path.Join("dbtest", "queries_test.go"), src); err != nil {
+			return nil, err
+		}
+	}
+
+	if emit.TypeTests {
+		if err := addGoFile(files, path.Join("dbtest", "types_test.go"), roundTripTests(module, layout, models, dialect)); err != nil {
+			return nil, err
+		}
+	}
+	if len(files) == 1 {
+		return nil, nil
+	}
+	return files, nil
+}
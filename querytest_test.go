@@ -0,0 +1,89 @@
+// querytest_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateDBTests checks the fixture rows, referenced rows first,
+// and the table each unfiltered list query must return.
+func TestGenerateDBTests(t *testing.T) {
+	models := []Model{
+		{Name: "Book", App: "library", Fields: []Field{
+			{Name: "title", Type: "CharField", Choices: []Choice{{Value: "a", Label: "A"}}},
+			{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+			{Name: "note", Type: "TextField", Nullable: true},
+		}},
+		{Name: "Author", App: "library", Fields: []Field{
+			{Name: "name", Type: "CharField", Default: "anon"},
+			{Name: "born", Type: "DateField"},
+		}},
+	}
+	resolveRelations(models)
+	tables, inserts := fixtureRows(models, "postgres")
+	if strings.Join(tables, " ") != "author book" {
+		t.Errorf("fixture tables = %v", tables)
+	}
+	for i, want := range []string{
+		"INSERT INTO author (name, born) VALUES ('anon', '2000-01-01')",
+		"INSERT INTO book (title, author_id, note) VALUES ('a', 1, NULL)",
+	} {
+		if i >= len(inserts) || inserts[i] != want {
+			t.Errorf("fixture %d = %q, want %q", i, inserts, want)
+		}
+	}
+
+	queries := []TranslatedQuery{
+		{Name: "ListBook", Kind: ":many", SQL: "SELECT * FROM book ORDER BY title;"},
+		{Name: "ListBookByTitle", Kind: ":many", SQL: "SELECT * FROM book WHERE title = sqlc.arg(title);"},
+		{Name: "CountBook", Kind: ":one", SQL: "SELECT COUNT(*) FROM book;"},
+		{Query: Query{Model: "Book"}, Reason: "unsupported"},
+	}
+	files, err := generateDBTests("example.com/app", defaultLayout, queries, models, "postgres", EmitConfig{QueryTests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["dbtest/queries_test.go"]
+	for _, want := range []string{`{"ListBook", "book"},`, `{"ListBookByTitle", ""},`, `{"CountBook", ""},`, `"example.com/app/db"`} {
+		if !strings.Contains(src, want) {
+			t.Errorf("queries_test.go lacks %s:\n%s", want, src)
+		}
+	}
+	if fixtures := files["dbtest/fixtures_test.go"]; !strings.Contains(fixtures, `"github.com/jackc/pgx/v5/pgconn"`) || !strings.Contains(fixtures, "INSERT INTO author") {
+		t.Errorf("fixtures_test.go:\n%s", fixtures)
+	}
+	if files, _ := generateDBTests("example.com/app", defaultLayout, queries[3:], models, "postgres", EmitConfig{QueryTests: true}); files != nil {
+		t.Errorf("files without translated queries: %v", files)
+	}
+}
+
+// TestFixtureRowsForeignKeyOrder checks that the fixtures of models
+// referencing tables declared after them are inserted into tables the
+// schema has created, after the rows they reference.
+func TestFixtureRowsForeignKeyOrder(t *testing.T) {
+	models := forwardModels()
+	for _, dialect := range []string{"postgres", "mysql"} {
+		created := map[string]bool{}
+		for _, line := range strings.Split(generateSQL(models, Options{Dialect: dialect}), "\n") {
+			if m := createdTable.FindStringSubmatch(line); m != nil {
+				created[m[1]] = true
+			}
+		}
+		tables, inserts := fixtureRows(models, dialect)
+		inserted := map[string]int{}
+		for i, table := range tables {
+			if !created[table] {
+				t.Errorf("%s: fixture of %s, which the schema does not create", dialect, table)
+			}
+			inserted[table] = i
+		}
+		want := []string{"books_tag", "books_author", "books_book", "books_book_tags"}
+		if got := strings.Join(tables, " "); got != strings.Join(want, " ") {
+			t.Errorf("%s: fixtures = %s, want %s", dialect, got, strings.Join(want, " "))
+		}
+		if book := inserts[inserted["books_book"]]; !strings.HasSuffix(book, "(author_id) VALUES (1)") {
+			t.Errorf("%s: book fixture %q does not reference the author row", dialect, book)
+		}
+	}
+}
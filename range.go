@@ -0,0 +1,32 @@
+// range.go
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// rangeIndexes returns the indexes of the model's range fields declared with
+// db_index=True. Django gives them a B-tree index, which only serves
+// equality and ordering; a GiST index serves the overlap and containment
+// lookups ranges are queried with. Other dialects store ranges as text
+// through dialect_types, so their indexes are skipped. Fields already
+// covered by a GistIndex of Meta.indexes are left to it.
+func rangeIndexes(m Model, dialect string) (stmts []string, warnings []Diagnostic) {
+	table := m.table()
+	for _, f := range m.Fields {
+		if rangeTypes[f.Type] == "" || !f.RangeIndex || slices.ContainsFunc(m.Indexes, func(idx Index) bool {
+			return idx.Method == "gist" && slices.Equal(idx.Fields, []string{f.Name})
+		}) {
+			continue
+		}
+		col := columnName(f)
+		name := fmt.Sprintf("%s_%s_gist", table, col)
+		if dialect != "postgres" {
+			warnings = append(warnings, diagnose(codeIndexSkipped, m, f.Name, "gist index %s is not supported by %s", name, dialect))
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX %s ON %s USING gist (%s);", name, table, col))
+	}
+	return stmts, warnings
+}
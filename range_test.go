@@ -0,0 +1,35 @@
+// range_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRangeIndexes checks that range fields declared with db_index get a
+// GiST index on PostgreSQL and a skipped-index warning elsewhere.
+func TestRangeIndexes(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"events/models.py": `from django.db import models
+from django.contrib.postgres.fields import DateTimeRangeField, IntegerRangeField
+from django.contrib.postgres.indexes import GistIndex
+
+class Event(models.Model):
+    during = DateTimeRangeField(db_index=True)
+    seats = IntegerRangeField(db_index=True)
+    ages = IntegerRangeField()
+
+    class Meta:
+        indexes = [GistIndex(fields=["seats"], name="event_seats_idx")]
+`,
+	})
+	m := out.Models[0]
+	stmts, warnings := rangeIndexes(m, "postgres")
+	if want := []string{"CREATE INDEX event_during_gist ON event USING gist (during);"}; strings.Join(stmts, "\n") != strings.Join(want, "\n") || len(warnings) != 0 {
+		t.Errorf("postgres: stmts = %q, warnings = %+v", stmts, warnings)
+	}
+	stmts, warnings = rangeIndexes(m, "mysql")
+	if len(stmts) != 0 || len(warnings) != 1 || warnings[0].Code != codeIndexSkipped {
+		t.Errorf("mysql: stmts = %q, warnings = %+v", stmts, warnings)
+	}
+}
@@ -0,0 +1,76 @@
+// regions.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// regionMarker matches the lines opening and closing a protected region of
+// a generated Go stub. The code between them is the user's: it is kept when
+// the file is regenerated.
+var regionMarker = regexp.MustCompile(`^\s*// django2go:(begin|end) (\S+)\s*$`)
+
+// region returns the lines delimiting the protected region name, indented
+// with indent, around its default content.
+func region(indent, name, content string) string {
+	return fmt.Sprintf("%s// django2go:begin %s\n%s%s// django2go:end %s\n", indent, name, content, indent, name)
+}
+
+// regionContents returns the content of every protected region of src.
+func regionContents(src string) map[string]string {
+	contents := map[string]string{}
+	name := ""
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(src, "\n") {
+		m := regionMarker.FindStringSubmatch(line)
+		switch {
+		case m != nil && m[1] == "begin":
+			name = m[2]
+			sb.Reset()
+		case m != nil && m[2] == name:
+			contents[name] = sb.String()
+			name = ""
+		case name != "":
+			sb.WriteString(line)
+		}
+	}
+	return contents
+}
+
+// keepRegions carries the protected regions of the existing file over to
+// its regenerated content, and returns the names of the regions that no
+// longer exist, whose code is lost.
+func keepRegions(existing, generated string) (string, []string) {
+	kept := regionContents(existing)
+	if len(kept) == 0 {
+		return generated, nil
+	}
+	var sb strings.Builder
+	skipping := ""
+	for _, line := range strings.SplitAfter(generated, "\n") {
+		m := regionMarker.FindStringSubmatch(line)
+		switch {
+		case m != nil && m[1] == "begin":
+			sb.WriteString(line)
+			if content, ok := kept[m[2]]; ok {
+				sb.WriteString(content)
+				delete(kept, m[2])
+				skipping = m[2]
+			}
+		case m != nil && m[2] == skipping:
+			sb.WriteString(line)
+			skipping = ""
+		case skipping == "":
+			sb.WriteString(line)
+		}
+	}
+	var lost []string
+	for name := range kept {
+		lost = append(lost, name)
+	}
+	sort.Strings(lost)
+	return sb.String(), lost
+}
@@ -0,0 +1,32 @@
+// regions_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestKeepRegions checks that the code of protected regions survives a
+// regeneration, and that regions no longer generated are reported.
+func TestKeepRegions(t *testing.T) {
+	generated := "package tasks\n\nfunc Run() error {\n" + region("\t", "Run", "\treturn nil\n") + "}\n\n" + region("", "helpers", "")
+	existing := strings.Replace(generated, "\treturn nil\n", "\treturn work()\n", 1)
+	existing = strings.Replace(existing, "// django2go:begin helpers\n", "// django2go:begin helpers\nfunc work() error { return nil }\n", 1)
+	existing += region("", "Old", "var old = 1\n")
+
+	got, lost := keepRegions(existing, generated)
+	for _, want := range []string{"\treturn work()\n", "func work() error { return nil }\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("lost %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\treturn nil\n") || strings.Contains(got, "var old") {
+		t.Errorf("kept generated or stale code:\n%s", got)
+	}
+	if len(lost) != 1 || lost[0] != "Old" {
+		t.Errorf("lost = %v", lost)
+	}
+	if got, lost := keepRegions("package tasks\n", generated); got != generated || lost != nil {
+		t.Errorf("file without regions changed the generated code:\n%s", got)
+	}
+}
@@ -0,0 +1,301 @@
+// report.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Complexity weights used for the rough per-app migration score. Relations
+// and untranslated queries dominate because they need hand-written Go.
+const (
+	weightModel        = 2
+	weightField        = 1
+	weightRelation     = 3
+	weightManyToMany   = 5
+	weightTranslated   = 1
+	weightUntranslated = 5
+)
+
+// hotCallSites is the number of call sites running the same read query from
+// which it is reported as hot.
+const hotCallSites = 3
+
+// Stats holds the counts collected for a set of models and queries.
+type Stats struct {
+	Models              int            `json:"models"`
+	Fields              int            `json:"fields"`
+	FieldsByType        map[string]int `json:"fields_by_type"`
+	Relations           map[string]int `json:"relations"`
+	TranslatedQueries   int            `json:"translated_queries"`
+	UntranslatedQueries int            `json:"untranslated_queries"`
+	Complexity          int            `json:"complexity"`
+}
+
+// AppStats holds the statistics for a single Django app.
+type AppStats struct {
+	Name string `json:"name"`
+	Stats
+}
+
+// Compat lists the Django features that need attention when porting.
+type Compat struct {
+	ComputedProperties []ComputedProperty `json:"computed_properties"`
+	UnknownFieldTypes  []UnknownFieldType `json:"unknown_field_types"`
+}
+
+// UnknownFieldType is a field class the schema stores as TEXT for want of
+// knowing it, with the fields declared with it as "app.Model.field".
+type UnknownFieldType struct {
+	Class  string   `json:"class"`
+	Fields []string `json:"fields"`
+}
+
+// unknownFieldTypes groups the W110 diagnostics by field class, in class
+// order.
+func unknownFieldTypes(diags []Diagnostic) []UnknownFieldType {
+	byClass := map[string][]string{}
+	for _, d := range diags {
+		if d.Code == codeUnknownFieldType {
+			byClass[d.Class] = append(byClass[d.Class], d.App+"."+d.Model+"."+d.Field)
+		}
+	}
+	var result []UnknownFieldType
+	for class, fields := range byClass {
+		result = append(result, UnknownFieldType{Class: class, Fields: fields})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Class < result[j].Class })
+	return result
+}
+
+// Report summarizes a generation run to help plan the porting effort.
+type Report struct {
+	Totals  Stats             `json:"totals"`
+	Apps    []AppStats        `json:"apps"`
+	Queries []TranslatedQuery `json:"queries"`
+	// HotQueries lists the read queries run from many call sites, which
+	// are worth caching.
+	HotQueries []HotQuery `json:"hot_queries"`
+	// Coverage counts the call sites by how far they are ported, in total
+	// and per Python module, to track the progress of the port.
+	Coverage       Coverage   `json:"coverage"`
+	ModuleCoverage []Coverage `json:"module_coverage"`
+	Compat         Compat     `json:"compat"`
+	// SkippedApps lists the apps left out for missing from INSTALLED_APPS.
+	SkippedApps []string `json:"skipped_apps,omitempty"`
+	// Diagnostics lists the warnings reported during the run.
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// HotQuery is a read query run from at least hotCallSites call sites.
+type HotQuery struct {
+	// Names lists the sqlc queries the call sites were translated to, all
+	// with the same SQL.
+	Names []string `json:"names"`
+	// Tables lists the tables the query reads.
+	Tables    []string `json:"tables"`
+	CallSites []string `json:"call_sites"`
+}
+
+// newStats returns an empty Stats value with its maps initialized.
+func newStats() Stats {
+	return Stats{FieldsByType: map[string]int{}, Relations: map[string]int{}}
+}
+
+// addModel counts a model and its fields.
+func (s *Stats) addModel(m Model) {
+	s.Models++
+	s.Complexity += weightModel
+	for _, f := range m.Fields {
+		s.Fields++
+		s.FieldsByType[f.Type]++
+		s.Complexity += weightField
+		if f.Relation != "" {
+			s.Relations[f.Relation]++
+			if f.Relation == "many2many" {
+				s.Complexity += weightManyToMany
+			} else {
+				s.Complexity += weightRelation
+			}
+		}
+	}
+}
+
+// addQuery counts a call site as translated or untranslated.
+func (s *Stats) addQuery(q TranslatedQuery) {
+	if q.Translated() {
+		s.TranslatedQueries++
+		s.Complexity += weightTranslated
+	} else {
+		s.UntranslatedQueries++
+		s.Complexity += weightUntranslated
+	}
+}
+
+// buildReport collects totals and per-app statistics for the run.
+func buildReport(models []Model, queries []TranslatedQuery) *Report {
+	report := &Report{Totals: newStats(), Queries: queries}
+	apps := map[string]*Stats{}
+	app := func(name string) *Stats {
+		if apps[name] == nil {
+			s := newStats()
+			apps[name] = &s
+		}
+		return apps[name]
+	}
+	for _, m := range models {
+		report.Totals.addModel(m)
+		app(m.App).addModel(m)
+	}
+	for _, q := range queries {
+		if q.Generated() {
+			continue
+		}
+		report.Totals.addQuery(q)
+		app(q.App).addQuery(q)
+	}
+	for name, s := range apps {
+		report.Apps = append(report.Apps, AppStats{Name: name, Stats: *s})
+	}
+	sort.Slice(report.Apps, func(i, j int) bool { return report.Apps[i].Name < report.Apps[j].Name })
+	report.HotQueries = hotQueries(queries)
+	report.Coverage, report.ModuleCoverage = queryCoverage(queries)
+	return report
+}
+
+// sqlTables matches the tables named by a statement.
+var sqlTables = regexp.MustCompile(`(?i)\b(?:from|into|update|join)\s+([a-z_][\w.]*)`)
+
+// queryTables returns the tables a statement names, in order.
+func queryTables(sql string) []string {
+	var tables []string
+	for _, m := range sqlTables.FindAllStringSubmatch(sql, -1) {
+		if !slices.Contains(tables, m[1]) {
+			tables = append(tables, m[1])
+		}
+	}
+	return tables
+}
+
+// isRead reports whether a statement only reads.
+func isRead(sql string) bool {
+	return strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "SELECT")
+}
+
+// hotQueries groups the translated call sites by SQL and returns the read
+// queries run from at least hotCallSites of them, in query order.
+func hotQueries(queries []TranslatedQuery) []HotQuery {
+	var order []string
+	bySQL := map[string]*HotQuery{}
+	for _, q := range queries {
+		if !q.Translated() || q.Generated() || !isRead(q.SQL) {
+			continue
+		}
+		h, ok := bySQL[q.SQL]
+		if !ok {
+			h = &HotQuery{Tables: queryTables(q.SQL)}
+			bySQL[q.SQL] = h
+			order = append(order, q.SQL)
+		}
+		h.Names = append(h.Names, q.Name)
+		h.CallSites = append(h.CallSites, fmt.Sprintf("%s:%d", q.File, q.Line))
+	}
+	var hot []HotQuery
+	for _, sql := range order {
+		if h := bySQL[sql]; len(h.CallSites) >= hotCallSites {
+			hot = append(hot, *h)
+		}
+	}
+	return hot
+}
+
+// JSON returns the report as indented JSON for report.json.
+func (r *Report) JSON() string {
+	b, _ := json.MarshalIndent(r, "", "  ")
+	return string(b) + "\n"
+}
+
+// printReport prints a human-readable summary of the report.
+func printReport(r *Report) {
+	t := r.Totals
+	fmt.Println("=== Summary ===")
+	fmt.Printf("Models: %d, fields: %d, relations: %d\n", t.Models, t.Fields, sum(t.Relations))
+	fmt.Printf("Fields by type: %s\n", formatCounts(t.FieldsByType))
+	if len(t.Relations) > 0 {
+		fmt.Printf("Relations: %s\n", formatCounts(t.Relations))
+	}
+	fmt.Printf("Queries: %d translated, %d untranslated\n", t.TranslatedQueries, t.UntranslatedQueries)
+	if c := r.Coverage; c.CallSites > 0 {
+		fmt.Printf("Coverage: %.1f%% of %d call sites (%d translated, %d generated, %d untranslated)\n",
+			c.Percent, c.CallSites, c.Translated, c.Generated, c.Untranslated)
+	}
+	for _, h := range r.HotQueries {
+		fmt.Printf("Hot query: %s, read from %d call sites\n", h.Names[0], len(h.CallSites))
+	}
+
+	if props := r.Compat.ComputedProperties; len(props) > 0 {
+		sqlProps := 0
+		for _, p := range props {
+			if p.SQL != "" {
+				sqlProps++
+			}
+		}
+		fmt.Printf("Computed properties: %d as SQL expressions, %d to implement in Go\n", sqlProps, len(props)-sqlProps)
+	}
+	for _, u := range r.Compat.UnknownFieldTypes {
+		fmt.Printf("Unknown field type %s stored as TEXT: %s\n", u.Class, strings.Join(u.Fields, ", "))
+	}
+	if len(r.SkippedApps) > 0 {
+		fmt.Printf("Apps not in INSTALLED_APPS skipped: %s (pass --all-apps to include them)\n", strings.Join(r.SkippedApps, ", "))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tMODELS\tFIELDS\tRELATIONS\tQUERIES (OK/TODO)\tCOMPLEXITY")
+	for _, a := range r.Apps {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d/%d\t%d\n", a.Name, a.Models, a.Fields, sum(a.Relations),
+			a.TranslatedQueries, a.UntranslatedQueries, a.Complexity)
+	}
+	w.Flush()
+
+	if len(r.ModuleCoverage) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Fprintln(w, "MODULE\tCALL SITES\tTRANSLATED\tGENERATED\tUNTRANSLATED\tCOVERAGE")
+	for _, c := range r.ModuleCoverage {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.1f%%\n", c.Module, c.CallSites, c.Translated, c.Generated, c.Untranslated, c.Percent)
+	}
+	w.Flush()
+}
+
+// formatCounts renders a count map as "a: 1, b: 2" in key order.
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s := ""
+	for i, k := range keys {
+		if i > 0 {
+			s += ", "
+		}
+		s += fmt.Sprintf("%s: %d", k, counts[k])
+	}
+	return s
+}
+
+// sum adds up the values of a count map.
+func sum(counts map[string]int) int {
+	n := 0
+	for _, v := range counts {
+		n += v
+	}
+	return n
+}
@@ -0,0 +1,73 @@
+// report_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBuildReport checks the totals and per-app counts and complexity.
+func TestBuildReport(t *testing.T) {
+	models := []Model{
+		{Name: "Post", App: "blog", Fields: []Field{
+			{Name: "title", Type: "CharField"},
+			{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+			{Name: "tags", Type: "ManyToManyField", Relation: "many2many", RelatedTo: "Tag"},
+		}},
+		{Name: "Author", App: "people", Fields: []Field{{Name: "name", Type: "CharField"}}},
+	}
+	queries := []TranslatedQuery{
+		{Query: Query{App: "blog", Model: "Post", File: "blog/views.py"}, SQL: "SELECT * FROM post;"},
+		{Query: Query{App: "blog", Model: "Post", File: "blog/views.py"}, Reason: "unsupported"},
+		{Query: Query{App: "blog", Model: "Post"}, SQL: "SELECT * FROM post ORDER BY _order;"},
+	}
+	r := buildReport(models, queries)
+	if r.Totals.Models != 2 || r.Totals.Fields != 4 || r.Totals.FieldsByType["CharField"] != 2 {
+		t.Errorf("totals = %+v", r.Totals)
+	}
+	if r.Totals.TranslatedQueries != 1 || r.Totals.UntranslatedQueries != 1 {
+		t.Errorf("queries = %d translated, %d untranslated, want 1 and 1", r.Totals.TranslatedQueries, r.Totals.UntranslatedQueries)
+	}
+	if len(r.Apps) != 2 || r.Apps[0].Name != "blog" || r.Apps[1].Name != "people" {
+		t.Fatalf("apps = %+v, want blog and people", r.Apps)
+	}
+	blog := weightModel + 3*weightField + weightRelation + weightManyToMany + weightTranslated + weightUntranslated
+	if r.Apps[0].Complexity != blog {
+		t.Errorf("blog complexity = %d, want %d", r.Apps[0].Complexity, blog)
+	}
+	if want := weightModel + weightField; r.Apps[1].Complexity != want {
+		t.Errorf("people complexity = %d, want %d", r.Apps[1].Complexity, want)
+	}
+}
+
+// TestUnknownFieldTypes checks that the report groups the fields of unknown
+// types by class.
+func TestUnknownFieldTypes(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"crm/models.py": `from django.db import models
+from colorfield.fields import ColorField
+from phonenumber_field.modelfields import PhoneNumberField
+
+class Contact(models.Model):
+    phone = PhoneNumberField()
+    color = ColorField()
+
+class Tag(models.Model):
+    color = ColorField()
+`,
+	})
+	diags := normalize(&Config{}, out.Models, Options{Dialect: "postgres"})
+	got := unknownFieldTypes(diags)
+	want := []UnknownFieldType{
+		{Class: "ColorField", Fields: []string{"crm.Contact.color", "crm.Tag.color"}},
+		{Class: "PhoneNumberField", Fields: []string{"crm.Contact.phone"}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unknown field types = %+v", got)
+	}
+	for i := range want {
+		if got[i].Class != want[i].Class || strings.Join(got[i].Fields, ",") != strings.Join(want[i].Fields, ",") {
+			t.Errorf("unknown field types[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
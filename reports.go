@@ -0,0 +1,29 @@
+// reports.go
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// reportModules names the modules, and packages, whose call sites are
+// analytical: dashboards and exports aggregating many rows.
+var reportModules = map[string]bool{"reports": true, "analytics": true}
+
+// isReport reports whether the query comes from an analytical module or an
+// admin action, whose bulk reads and updates are reviewed and optimized
+// apart from the per-request queries.
+func isReport(q TranslatedQuery) bool {
+	if q.Generated() {
+		return false
+	}
+	if q.AdminAction {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(q.File), "/") {
+		if reportModules[strings.TrimSuffix(part, ".py")] {
+			return true
+		}
+	}
+	return false
+}
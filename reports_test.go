@@ -0,0 +1,69 @@
+// reports_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestReportQueryFiles checks that call sites of report modules and admin
+// actions go to reports.sql, and that generated queries stay in query.sql.
+func TestReportQueryFiles(t *testing.T) {
+	queries := []TranslatedQuery{
+		{Query: Query{File: "blog/views.py"}, Name: "ListPost"},
+		{Query: Query{File: "blog/reports/monthly.py"}, Name: "CountPost"},
+		{Query: Query{File: "shop/analytics.py"}, Name: "ListOrder"},
+		{Query: Query{File: "shop/admin.py", AdminAction: true}, Name: "UpdateOrder"},
+		{Name: "GetPostBySlug"},
+	}
+	files, byFile := defaultLayout.queryFiles(queries)
+	if strings.Join(files, " ") != "query.sql reports.sql" {
+		t.Errorf("files = %v", files)
+	}
+	var names []string
+	for _, q := range byFile["reports.sql"] {
+		names = append(names, q.Name)
+	}
+	if got := strings.Join(names, " "); got != "CountPost ListOrder UpdateOrder" {
+		t.Errorf("reports = %s", got)
+	}
+	if crud := byFile["query.sql"]; len(crud) != 2 || crud[0].Name != "ListPost" || crud[1].Name != "GetPostBySlug" {
+		t.Errorf("crud = %+v", crud)
+	}
+	cfg := generateSQLCConfig(nil, "postgres", defaultLayout, []string{"schema.sql"}, files, nil, "")
+	if !strings.Contains(cfg, `queries: ["./query.sql", "./reports.sql"]`) {
+		t.Errorf("sqlc.yaml queries:\n%s", cfg)
+	}
+}
+
+// TestAdminActionCalls checks that the parser marks the call sites in
+// admin actions, decorated or not.
+func TestAdminActionCalls(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"shop/models.py": `from django.db import models
+
+class Order(models.Model):
+    paid = models.BooleanField(default=False)
+`,
+		"shop/admin.py": `from django.contrib import admin
+from shop.models import Order
+
+@admin.action(description="Mark paid")
+def mark_paid(modeladmin, request, queryset):
+    Order.objects.filter(paid=False).update(paid=True)
+
+def unpaid(modeladmin, request, queryset):
+    return Order.objects.filter(paid=False).count()
+
+def helper():
+    return Order.objects.all()
+`,
+	})
+	var got []bool
+	for _, q := range out.Queries {
+		got = append(got, q.AdminAction)
+	}
+	if len(got) != 3 || !got[0] || !got[1] || got[2] {
+		t.Errorf("admin actions = %v", got)
+	}
+}
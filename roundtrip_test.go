@@ -0,0 +1,67 @@
+// roundtrip_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestBoundaryValues checks the edge values stored in columns of various
+// types, and that types without a scalar Go mapping get none.
+func TestBoundaryValues(t *testing.T) {
+	labels := func(f Field, dialect string) string {
+		var got []string
+		for _, b := range boundaryValues(f, fieldColumns(f)[0], dialect) {
+			got = append(got, b.label+"="+b.sql)
+		}
+		return strings.Join(got, " ")
+	}
+	for _, c := range []struct {
+		field   Field
+		dialect string
+		want    string
+	}{
+		{Field{Name: "n", Type: "SmallIntegerField"}, "postgres", "min=-32768 max=32767"},
+		{Field{Name: "n", Type: "PositiveIntegerField", DBType: "INTEGER UNSIGNED"}, "mysql", "min=0 max=4294967295"},
+		{Field{Name: "price", Type: "DecimalField", MaxDigits: 5, DecimalPlaces: 2, DBType: "NUMERIC(5,2)"}, "postgres", "max=999.99 min=-999.99"},
+		{Field{Name: "ok", Type: "BooleanField", Nullable: true}, "postgres", "null=NULL true=TRUE false=FALSE"},
+		{Field{Name: "size", Type: "CharField", Choices: []Choice{{Value: "s"}, {Value: "l"}}}, "postgres", "choice_0='s' choice_1='l'"},
+		{Field{Name: "day", Type: "DateField"}, "mysql", "min='1000-01-01' leap_day='2024-02-29' max='9999-12-31'"},
+		{Field{Name: "tags", Type: "ArrayField", BaseType: "CharField", DBType: "TEXT[]"}, "postgres", ""},
+	} {
+		if got := labels(c.field, c.dialect); got != c.want {
+			t.Errorf("%s on %s: %s, want %s", c.field.Type, c.dialect, got, c.want)
+		}
+	}
+}
+
+// TestRoundTripTests checks the generated types_test.go and that it is only
+// emitted with type_tests.
+func TestRoundTripTests(t *testing.T) {
+	models := []Model{{Name: "Post", App: "blog", Fields: []Field{
+		{Name: "title", Type: "TextField"},
+		{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+	}}}
+	files, err := generateDBTests("example.com/app", defaultLayout, nil, models, "postgres", EmitConfig{TypeTests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["dbtest/types_test.go"]
+	for _, want := range []string{
+		`"post": func() any { return new(db.Post) },`,
+		`{"post", "title", "empty", "''", "title IS NOT DISTINCT FROM $1"},`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("types_test.go lacks %s:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, `"author_id"`) {
+		t.Errorf("relation column round-tripped:\n%s", src)
+	}
+	if _, ok := files["dbtest/queries_test.go"]; ok {
+		t.Error("queries_test.go emitted without query_tests")
+	}
+	if mysql := roundTripTests("example.com/app", defaultLayout, models, "mysql"); !strings.Contains(mysql, "title <=> ?") {
+		t.Errorf("mysql match:\n%s", mysql)
+	}
+}
@@ -0,0 +1,186 @@
+// schedule.go
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schedule is a periodic task from CELERY_BEAT_SCHEDULE, a Celery app's
+// conf.beat_schedule or django-crontab's CRONJOBS.
+type Schedule struct {
+	Name     string       `json:"name"`
+	Task     string       `json:"task"` // dotted path of the task function
+	File     string       `json:"file"`
+	Line     int          `json:"line"`
+	Schedule ScheduleSpec `json:"schedule"`
+}
+
+// ScheduleSpec is when a periodic task runs.
+type ScheduleSpec struct {
+	Kind string `json:"kind"` // crontab, interval, cron or unsupported
+	// Fields holds the crontab() arguments, Seconds the interval and Spec a
+	// five-field cron expression.
+	Fields  map[string]string `json:"fields,omitempty"`
+	Seconds float64           `json:"seconds,omitempty"`
+	Spec    string            `json:"spec,omitempty"`
+	// Source is the Python expression of an unsupported schedule.
+	Source string `json:"source,omitempty"`
+}
+
+// crontabFields lists the Celery crontab() arguments in cron field order.
+var crontabFields = []string{"minute", "hour", "day_of_month", "month_of_year", "day_of_week"}
+
+// cronSpec returns the schedule as a robfig/cron spec.
+func (s ScheduleSpec) cronSpec() (string, error) {
+	switch s.Kind {
+	case "crontab":
+		fields := make([]string, len(crontabFields))
+		for i, name := range crontabFields {
+			fields[i] = "*"
+			if v := s.Fields[name]; v != "" {
+				fields[i] = strings.ReplaceAll(v, " ", "")
+			}
+		}
+		return strings.Join(fields, " "), nil
+	case "interval":
+		d := time.Duration(s.Seconds * float64(time.Second))
+		if d < time.Second {
+			return "", fmt.Errorf("interval of %gs is too short", s.Seconds)
+		}
+		return "@every " + d.String(), nil
+	case "cron":
+		if len(strings.Fields(s.Spec)) != 5 && !strings.HasPrefix(s.Spec, "@") {
+			return "", fmt.Errorf("cron expression %q does not have five fields", s.Spec)
+		}
+		return s.Spec, nil
+	}
+	return "", fmt.Errorf("unsupported schedule %s", s.Source)
+}
+
+// taskFuncs names the Go stub for every task path, using the last path
+// element unless two tasks share it.
+func taskFuncs(paths []string) map[string]string {
+	count := map[string]int{}
+	for _, p := range paths {
+		count[p[strings.LastIndex(p, ".")+1:]]++
+	}
+	names := map[string]string{}
+	for _, p := range paths {
+		short := p[strings.LastIndex(p, ".")+1:]
+		if count[short] > 1 {
+			short = strings.ReplaceAll(p, ".", "_")
+		}
+		names[p] = camel(short)
+	}
+	return names
+}
+
+// generateScheduler renders a robfig/cron scheduler registering the periodic
+// tasks, and a stub for every task it runs, keyed by file path relative to
+// the output directory. Schedules that cannot be expressed as a cron spec
+// are reported and left out.
+func generateScheduler(schedules []Schedule, module string, layout Layout) (map[string]string, []Diagnostic, error) {
+	type job struct {
+		Schedule
+		spec string
+	}
+	var jobs []job
+	var diags []Diagnostic
+	seen := map[string]bool{}
+	var paths []string
+	for _, s := range schedules {
+		spec, err := s.Schedule.cronSpec()
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				Code: codeScheduleSkipped, Severity: severity(codeScheduleSkipped), File: s.File, Line: s.Line,
+				Message: fmt.Sprintf("periodic task %q skipped: %v", s.Name, err),
+			})
+			continue
+		}
+		jobs = append(jobs, job{s, spec})
+		if !seen[s.Task] {
+			seen[s.Task] = true
+			paths = append(paths, s.Task)
+		}
+	}
+	if len(jobs) == 0 {
+		return nil, diags, nil
+	}
+	sort.Strings(paths)
+	funcs := taskFuncs(paths)
+	files := map[string]string{}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `// Code generated by django2go. Edit as needed.
+
+// Package scheduler runs the ported periodic tasks.
+package scheduler
+
+import (
+	"context"
+	"log"
+
+	"github.com/robfig/cron/v3"
+
+	%q
+	%q
+)
+
+// New returns a cron scheduler with every ported periodic task registered.
+// Call Start to run it.
+func New(q *db.Queries) (*cron.Cron, error) {
+	c := cron.New()
+	jobs := []struct {
+		name string
+		spec string
+		run  func(context.Context, *db.Queries) error
+	}{
+`, layout.importPath(module, layout.DB), module+"/tasks")
+	for _, j := range jobs {
+		fmt.Fprintf(&sb, "\t\t{%q, %q, tasks.%s}, // %s:%d\n", j.Name, j.spec, funcs[j.Task], j.File, j.Line)
+	}
+	sb.WriteString(`	}
+	for _, job := range jobs {
+		job := job
+		_, err := c.AddFunc(job.spec, func() {
+			if err := job.run(context.Background(), q); err != nil {
+				log.Printf("%s: %v", job.name, err)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+`)
+	if err := addGoFile(files, "scheduler/scheduler.go", sb.String()); err != nil {
+		return nil, nil, err
+	}
+
+	sb.Reset()
+	fmt.Fprintf(&sb, `// Code generated by django2go. Edit as needed.
+
+// Package tasks holds the ported periodic tasks.
+package tasks
+
+import (
+	"context"
+
+	%q
+
+%s)
+`, layout.importPath(module, layout.DB), region("\t", "imports", "\t\"errors\"\n"))
+	for _, p := range paths {
+		body := region("\t", funcs[p], fmt.Sprintf("\treturn errors.New(%q)\n", p+" is not implemented yet"))
+		fmt.Fprintf(&sb, "\n// %s ports the %s task.\nfunc %s(ctx context.Context, q *db.Queries) error {\n%s}\n", funcs[p], p, funcs[p], body)
+	}
+	sb.WriteString("\n" + region("", "helpers", ""))
+	if err := addGoFile(files, "tasks/tasks.go", sb.String()); err != nil {
+		return nil, nil, err
+	}
+	return files, diags, nil
+}
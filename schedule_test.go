@@ -0,0 +1,58 @@
+// schedule_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateScheduler parses Celery beat and django-crontab schedules and
+// checks the cron specs registered by the generated scheduler.
+func TestGenerateScheduler(t *testing.T) {
+	out := parseProject(t, map[string]string{"mysite/settings.py": `from datetime import timedelta
+from celery.schedules import crontab
+
+CELERY_BEAT_SCHEDULE = {
+    "nightly-report": {"task": "reports.tasks.send_report", "schedule": crontab(minute=0, hour="2")},
+    "poll": {"task": "feeds.tasks.poll", "schedule": timedelta(minutes=5)},
+    "every-ten": {"task": "feeds.tasks.refresh", "schedule": 10.0},
+    "sunrise": {"task": "weather.tasks.poll", "schedule": solar("sunrise", 0, 0)},
+}
+
+CRONJOBS = [
+    ("*/15 * * * *", "feeds.cron.cleanup"),
+]
+`})
+	if len(out.Schedules) != 5 {
+		t.Fatalf("schedules = %+v", out.Schedules)
+	}
+	files, diags, err := generateScheduler(out.Schedules, "example.com/site", defaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 1 || diags[0].Code != codeScheduleSkipped || !strings.Contains(diags[0].Message, `"sunrise"`) {
+		t.Errorf("diagnostics = %v", diags)
+	}
+	scheduler := files["scheduler/scheduler.go"]
+	for _, want := range []string{
+		`{"nightly-report", "0 2 * * *", tasks.SendReport},`,
+		"// mysite/settings.py:5\n",
+		`{"poll", "@every 5m0s", tasks.Poll},`,
+		`{"every-ten", "@every 10s", tasks.Refresh},`,
+		`{"feeds.cron.cleanup", "*/15 * * * *", tasks.Cleanup},`,
+	} {
+		if !strings.Contains(scheduler, want) {
+			t.Errorf("scheduler missing %q:\n%s", want, scheduler)
+		}
+	}
+	if tasks := files["tasks/tasks.go"]; !strings.Contains(tasks, "func SendReport(ctx context.Context, q *db.Queries) error") {
+		t.Errorf("tasks.go:\n%s", tasks)
+	}
+}
+
+func TestTaskFuncs(t *testing.T) {
+	funcs := taskFuncs([]string{"feeds.tasks.poll", "weather.tasks.poll", "reports.tasks.send_report"})
+	if funcs["feeds.tasks.poll"] != "FeedsTasksPoll" || funcs["reports.tasks.send_report"] != "SendReport" {
+		t.Errorf("funcs = %v", funcs)
+	}
+}
@@ -0,0 +1,193 @@
+// search.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchExpr is a django.contrib.postgres.search expression passed to a
+// queryset method: a SearchVector, SearchQuery or SearchRank call.
+type SearchExpr struct {
+	Function string `json:"function"`
+	Args     []Arg  `json:"args"`
+	Config   *Arg   `json:"config,omitempty"`
+	// Weight is the SearchVector weight, A to D.
+	Weight string `json:"weight,omitempty"`
+	// SearchType is the SearchQuery search_type: plain, phrase, raw or
+	// websearch.
+	SearchType string `json:"search_type,omitempty"`
+}
+
+// tsqueryFunctions maps SearchQuery search types to the PostgreSQL function
+// parsing the query text.
+var tsqueryFunctions = map[string]string{
+	"":          "plainto_tsquery",
+	"plain":     "plainto_tsquery",
+	"phrase":    "phraseto_tsquery",
+	"raw":       "to_tsquery",
+	"websearch": "websearch_to_tsquery",
+}
+
+// searchVector is the SQL of a tsvector expression, with the configuration
+// a query matched against it defaults to.
+type searchVector struct {
+	sql    string
+	config *Arg
+}
+
+// searchScope holds the search expressions annotated on a queryset, by
+// annotation name: the vectors, and the ts_rank expressions of the ranks.
+type searchScope struct {
+	m       Model
+	dialect string
+	params  map[string]int
+	vectors map[string]searchVector
+	ranks   map[string]string
+}
+
+// newSearchScope returns the scope of a queryset on the model, adding its
+// parameters to params.
+func newSearchScope(m Model, dialect string, params map[string]int) *searchScope {
+	return &searchScope{m: m, dialect: dialect, params: params, vectors: map[string]searchVector{}, ranks: map[string]string{}}
+}
+
+// regconfig renders a text search configuration argument.
+func (s *searchScope) regconfig(config *Arg) string {
+	return value(*config, "config", s.params) + "::regconfig"
+}
+
+// vector renders an argument used as a tsvector: a SearchVector call, a
+// SearchVectorField, or a field name wrapped in to_tsvector as Django does.
+func (s *searchScope) vector(a Arg) (searchVector, error) {
+	if name, ok := a.Value.(string); ok && a.Literal {
+		if v, ok := s.vectors[name]; ok {
+			return v, nil
+		}
+		if f, ok := s.m.field(name); ok && f.Type == "SearchVectorField" {
+			return searchVector{sql: columnName(f)}, nil
+		}
+		a = Arg{Search: &SearchExpr{Function: "SearchVector", Args: []Arg{a}}}
+	}
+	if a.Search == nil || a.Search.Function != "SearchVector" {
+		return searchVector{}, fmt.Errorf("unsupported search vector")
+	}
+	e := a.Search
+	var parts []string
+	for _, fa := range e.Args {
+		name, ok := fa.Value.(string)
+		if !fa.Literal || !ok {
+			return searchVector{}, fmt.Errorf("SearchVector() arguments must be field names")
+		}
+		col, err := resolveColumn(name, s.m)
+		if err != nil {
+			return searchVector{}, err
+		}
+		parts = append(parts, "COALESCE("+col+", '')")
+	}
+	if len(parts) == 0 {
+		return searchVector{}, fmt.Errorf("SearchVector() requires fields")
+	}
+	text := strings.Join(parts, " || ' ' || ")
+	sql := "to_tsvector(" + text + ")"
+	if e.Config != nil {
+		sql = "to_tsvector(" + s.regconfig(e.Config) + ", " + text + ")"
+	}
+	if e.Weight != "" {
+		sql = "setweight(" + sql + ", " + value(Arg{Literal: true, Value: e.Weight}, "", s.params) + ")"
+	}
+	return searchVector{sql: sql, config: e.Config}, nil
+}
+
+// query renders an argument used as a tsquery: a SearchQuery call, or a
+// value parsed like SearchQuery does with the configuration of the vector
+// it is matched against.
+func (s *searchScope) query(a Arg, config *Arg) (string, error) {
+	e := &SearchExpr{Function: "SearchQuery", Args: []Arg{a}, Config: config}
+	if a.Search != nil {
+		e = a.Search
+	}
+	if e.Function != "SearchQuery" || len(e.Args) != 1 || e.Args[0].Search != nil {
+		return "", fmt.Errorf("unsupported search query")
+	}
+	fn, ok := tsqueryFunctions[e.SearchType]
+	if !ok {
+		return "", fmt.Errorf("unsupported SearchQuery search_type %q", e.SearchType)
+	}
+	text := value(e.Args[0], "query", s.params)
+	if e.Config != nil {
+		return fn + "(" + s.regconfig(e.Config) + ", " + text + ")", nil
+	}
+	return fn + "(" + text + ")", nil
+}
+
+// annotate records an annotate() keyword, returning the select list item of
+// a SearchRank.
+func (s *searchScope) annotate(kw Arg) (string, error) {
+	if kw.Search == nil {
+		return "", fmt.Errorf("annotate() only supports search expressions")
+	}
+	if s.dialect != "postgres" {
+		return "", fmt.Errorf("full-text search requires PostgreSQL")
+	}
+	switch kw.Search.Function {
+	case "SearchVector":
+		v, err := s.vector(kw)
+		if err != nil {
+			return "", err
+		}
+		s.vectors[kw.Key] = v
+		return "", nil
+	case "SearchRank":
+		if len(kw.Search.Args) != 2 {
+			return "", fmt.Errorf("SearchRank() requires a vector and a query")
+		}
+		v, err := s.vector(kw.Search.Args[0])
+		if err != nil {
+			return "", err
+		}
+		q, err := s.query(kw.Search.Args[1], v.config)
+		if err != nil {
+			return "", err
+		}
+		s.ranks[kw.Key] = "ts_rank(" + v.sql + ", " + q + ")"
+		return s.ranks[kw.Key] + " AS " + kw.Key, nil
+	}
+	return "", fmt.Errorf("%s() cannot be annotated", kw.Search.Function)
+}
+
+// match translates a filter() keyword on a search annotation or
+// SearchVectorField, or using the search lookup, into a condition: @@ for
+// vectors, a comparison for ranks. ok is false for any other keyword.
+func (s *searchScope) match(kw Arg) (cond string, ok bool, err error) {
+	name, lookup := kw.Key, "exact"
+	if i := strings.LastIndex(kw.Key, "__"); i >= 0 {
+		name, lookup = kw.Key[:i], kw.Key[i+2:]
+	}
+	f, found := s.m.field(name)
+	_, annotated := s.vectors[name]
+	rank, ranked := s.ranks[name]
+	switch {
+	case ranked:
+		op, known := lookupOperators[lookup]
+		if !known {
+			return "", true, fmt.Errorf("unsupported lookup %q on %s", lookup, name)
+		}
+		return rank + " " + op + " " + value(kw, name, s.params), true, nil
+	case lookup == "search", lookup == "exact" && (annotated || found && f.Type == "SearchVectorField"):
+	default:
+		return "", false, nil
+	}
+	if s.dialect != "postgres" {
+		return "", true, fmt.Errorf("full-text search requires PostgreSQL")
+	}
+	v, err := s.vector(Arg{Literal: true, Value: name})
+	if err != nil {
+		return "", true, err
+	}
+	q, err := s.query(kw, v.config)
+	if err != nil {
+		return "", true, err
+	}
+	return v.sql + " @@ " + q, true, nil
+}
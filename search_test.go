@@ -0,0 +1,53 @@
+// search_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSearchQueries checks that SearchVector, SearchQuery and SearchRank call
+// sites translate to PostgreSQL text search and are refused elsewhere.
+func TestSearchQueries(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+from django.contrib.postgres.search import SearchVectorField
+
+class Post(models.Model):
+    title = models.CharField(max_length=200)
+    body = models.TextField()
+    search = SearchVectorField(null=True)
+`,
+		"blog/views.py": `from django.contrib.postgres.search import SearchVector, SearchQuery, SearchRank
+from blog.models import Post
+
+def find(q):
+    return Post.objects.filter(body__search=q)
+
+def ranked(q):
+    return Post.objects.annotate(rank=SearchRank(SearchVector("title"), SearchQuery(q, search_type="websearch"))).order_by("-rank")
+`,
+	})
+	got := translateQueries(out.Queries, out.Models, "postgres")
+	var sql []string
+	for _, q := range got {
+		if !q.Translated() {
+			t.Errorf("%s not translated: %s", q.Name, q.Reason)
+		}
+		sql = append(sql, q.SQL)
+	}
+	all := strings.Join(sql, "\n")
+	for _, want := range []string{"@@ plainto_tsquery(", "ts_rank(", "websearch_to_tsquery(", "ORDER BY rank DESC"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in:\n%s", want, all)
+		}
+	}
+	for _, q := range translateQueries(out.Queries, out.Models, "mysql") {
+		if q.Translated() || !strings.Contains(q.Reason, "PostgreSQL") {
+			t.Errorf("mysql %s = %q (%s), want a PostgreSQL reason", q.Name, q.SQL, q.Reason)
+		}
+	}
+	if sql := generateSQL(out.Models, Options{Dialect: "postgres"}); !strings.Contains(sql, "search TSVECTOR") {
+		t.Errorf("missing TSVECTOR column:\n%s", sql)
+	}
+}
@@ -0,0 +1,312 @@
+// seed.go
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// anonymizers are the rules the config's anonymize section can apply to a
+// sampled column value. Hashes are deterministic so that repeated values,
+// including unique ones, stay consistent across tables.
+var anonymizers = map[string]func(v any) any{
+	"keep":    func(v any) any { return v },
+	"nullify": func(v any) any { return nil },
+	"redact": func(v any) any {
+		if v == nil {
+			return nil
+		}
+		return "REDACTED"
+	},
+	"hash": func(v any) any {
+		if v == nil {
+			return nil
+		}
+		return digest(v)
+	},
+	"hash_email": func(v any) any {
+		if v == nil {
+			return nil
+		}
+		return "user_" + digest(v) + "@example.com"
+	},
+}
+
+// digest returns a short stable hash of a value.
+func digest(v any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// tableColumns returns the stored columns of a model's table, with the
+// fields they belong to ("" for implicit columns).
+func tableColumns(m Model) (cols []string, fields []string) {
+	if len(m.PrimaryKey) == 0 {
+		cols, fields = append(cols, "id"), append(fields, "")
+	}
+	for _, f := range m.Fields {
+		if f.Relation == "many2many" {
+			continue
+		}
+		for _, c := range fieldColumns(f) {
+			cols, fields = append(cols, c.Name), append(fields, f.Name)
+		}
+	}
+	if m.OrderWithRespectTo != "" {
+		cols, fields = append(cols, "_order"), append(fields, "")
+	}
+	return cols, fields
+}
+
+// joinColumns returns the columns of the table Django creates for a
+// many-to-many field, pointing at the model and the related model.
+func joinColumns(m Model, f Field) (source, target string) {
+	source, target = strings.ToLower(m.Name)+"_id", strings.ToLower(f.RelatedTo)+"_id"
+	if source == target {
+		source, target = "from_"+source, "to_"+target
+	}
+	return source, target
+}
+
+// anonymizer returns the rule configured for a model field, checking
+// "Model.field" before "*.field". Personal data without a rule gets the one
+// fitting its type.
+func anonymizer(cfg *Config, m Model, field string) string {
+	if rule, ok := cfg.Anonymize[m.Name+"."+field]; ok {
+		return rule
+	}
+	if rule, ok := cfg.Anonymize["*."+field]; ok {
+		return rule
+	}
+	if f, ok := m.field(field); ok && f.PII {
+		return piiRule(f)
+	}
+	return ""
+}
+
+// checkAnonymize validates the anonymize rules against the models.
+func checkAnonymize(cfg *Config, models []Model) error {
+	for key, rule := range cfg.Anonymize {
+		if _, ok := anonymizers[rule]; !ok {
+			return fmt.Errorf("config: anonymize %s: unknown rule %q", key, rule)
+		}
+		model, field, ok := strings.Cut(key, ".")
+		if !ok {
+			return fmt.Errorf("config: anonymize %s: expected Model.field or *.field", key)
+		}
+		for _, m := range models {
+			if model != "*" && m.Name != model {
+				continue
+			}
+			f, found := m.field(field)
+			if !found && model != "*" {
+				return fmt.Errorf("config: anonymize %s: field not found", key)
+			}
+			if found && rule == "nullify" && !f.Nullable {
+				return fmt.Errorf("config: anonymize %s: cannot nullify a non-nullable field", key)
+			}
+		}
+	}
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if f.PII && f.Relation != "many2many" && anonymizer(cfg, m, f.Name) == "" {
+				return fmt.Errorf("config: anonymize: %s.%s holds personal data; give it a rule, keep to sample it as is", m.Name, f.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// runSeed implements the seed subcommand: it samples rows from an existing
+// Django database, anonymizes them and writes INSERT statements for the
+// generated schema.
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	input := fs.String("input", "", "Path to Django app (required)")
+	configPath := fs.String("config", "", "Path to a YAML config file with model overrides and anonymize rules")
+	dialect := fs.String("dialect", "postgres", "SQL dialect: postgres or mysql")
+	databaseURL := fs.String("database-url", os.Getenv("DATABASE_URL"), "Connection string of the Django database")
+	rows := fs.Int("rows", 100, "Rows to sample per table")
+	output := fs.String("output", "seed.sql", "Seed file to write, or - for stdout")
+	parserScriptPath := fs.String("parser-script", "", "Python script to run instead of the built-in parser")
+	noAppPrefix := fs.Bool("no-app-prefix", false, "Name the tables after their model alone, as generated with --no-app-prefix")
+	parserEnv := parserEnvFlags(fs)
+	fs.Parse(args)
+
+	if *input == "" || *databaseURL == "" {
+		fmt.Println("Error: seed requires --input and --database-url")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	driver, ok := sqlDrivers[*dialect]
+	if !ok {
+		fail(exitUsage, "Error: --dialect must be one of %s", strings.Join(dialects, ", "))
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fail(exitUsage, "Config error: %v", err)
+	}
+	header, err := newHeader(cfg.Header, *input, fs)
+	if err != nil {
+		fail(exitUsage, "Config error: %v", err)
+	}
+	out, err := runPythonParser(*input, *parserScriptPath, nil, parserEnv(), capModels)
+	if err != nil {
+		fail(exitParse, "Parser error: %v", err)
+	}
+	if diags := normalize(cfg, out.Models, Options{Dialect: *dialect, NoAppPrefix: *noAppPrefix}); hasErrors(diags) {
+		checkDiagnostics(diags, DiagnosticPolicy{MaxWarnings: -1}, exitDiagnostics)
+	}
+	if err := checkAnonymize(cfg, out.Models); err != nil {
+		fail(exitUsage, "Error: %v", err)
+	}
+
+	conn, err := sql.Open(driver[0], *databaseURL)
+	if err != nil {
+		fail(exitIO, "Error: %v", err)
+	}
+	defer conn.Close()
+	seed, err := sampleSeed(context.Background(), conn, cfg, out.Models, *dialect, *rows)
+	if err != nil {
+		fail(exitIO, "Error: %v", err)
+	}
+	if *output == "-" {
+		fmt.Print(seed)
+		return
+	}
+	write(*output, header.apply(filepath.Base(*output), seed))
+	fmt.Printf("✅ Generated %s\n", *output)
+}
+
+// sampleSeed reads up to n rows of every table and renders them as INSERT
+// statements. Foreign key checks are disabled while loading since samples
+// of related tables need not match.
+func sampleSeed(ctx context.Context, conn *sql.DB, cfg *Config, models []Model, dialect string, n int) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("-- Seed data sampled from the Django database by django2go.\n\n")
+	if dialect == "mysql" {
+		sb.WriteString("SET FOREIGN_KEY_CHECKS = 0;\n\n")
+	} else {
+		sb.WriteString("SET session_replication_role = replica;\n\n")
+	}
+	for _, m := range models {
+		if m.isView() {
+			continue
+		}
+		cols, fields := tableColumns(m)
+		// Generated columns are computed again by the database.
+		for i := len(fields) - 1; i >= 0; i-- {
+			if f, ok := m.field(fields[i]); ok && f.Generated {
+				cols, fields = slices.Delete(cols, i, i+1), slices.Delete(fields, i, i+1)
+			}
+		}
+		rules := make([]func(any) any, len(cols))
+		for i, f := range fields {
+			rules[i] = anonymizers["keep"]
+			if rule := anonymizer(cfg, m, f); f != "" && rule != "" {
+				rules[i] = anonymizers[rule]
+			}
+		}
+		if err := sampleTable(ctx, &sb, conn, dialect, djangoTable(m), m.table(), cols, rules, n); err != nil {
+			return "", err
+		}
+		for _, f := range m.Fields {
+			if f.Relation != "many2many" {
+				continue
+			}
+			source, target := joinColumns(m, f)
+			keep := []func(any) any{anonymizers["keep"], anonymizers["keep"]}
+			join := joinTableName(m, f)
+			if err := sampleTable(ctx, &sb, conn, dialect, djangoTable(m)+"_"+toSnake(f.Name), join,
+				[]string{source, target}, keep, n); err != nil {
+				return "", err
+			}
+		}
+	}
+	if dialect == "mysql" {
+		sb.WriteString("SET FOREIGN_KEY_CHECKS = 1;\n")
+	} else {
+		sb.WriteString("SET session_replication_role = DEFAULT;\n")
+	}
+	return sb.String(), nil
+}
+
+// sampleTable copies up to n rows of the given columns from a Django table
+// into an INSERT statement for the generated table.
+func sampleTable(ctx context.Context, sb *strings.Builder, conn *sql.DB, dialect, from, to string, cols []string, rules []func(any) any, n int) error {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s LIMIT %d", strings.Join(cols, ", "), from, n))
+	if err != nil {
+		return fmt.Errorf("sample %s: %w", from, err)
+	}
+	defer rows.Close()
+	var tuples []string
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("sample %s: %w", from, err)
+		}
+		literals := make([]string, len(cols))
+		for i, v := range values {
+			literals[i] = sqlLiteral(rules[i](v), dialect)
+		}
+		tuples = append(tuples, "("+strings.Join(literals, ", ")+")")
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("sample %s: %w", from, err)
+	}
+	if len(tuples) == 0 {
+		return nil
+	}
+	fmt.Fprintf(sb, "INSERT INTO %s (%s) VALUES\n    %s;\n\n", to, strings.Join(cols, ", "), strings.Join(tuples, ",\n    "))
+	return nil
+}
+
+// sqlLiteral renders a scanned value as an SQL literal.
+func sqlLiteral(v any, dialect string) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999") + "'"
+	case []byte:
+		return quote(string(v), dialect)
+	case string:
+		return quote(v, dialect)
+	}
+	return quote(fmt.Sprint(v), dialect)
+}
+
+// quote renders a string literal, doubling single quotes. MySQL also treats
+// backslashes as escapes.
+func quote(s, dialect string) string {
+	if dialect == "mysql" {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,94 @@
+// seed_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedModels returns a user model with personal data to anonymize.
+func seedModels() []Model {
+	return []Model{{Name: "Customer", App: "shop", Fields: []Field{
+		{Name: "email", Type: "EmailField"},
+		{Name: "phone", Type: "CharField", Nullable: true},
+		{Name: "name", Type: "CharField"},
+	}}}
+}
+
+// TestCheckAnonymize checks the validation of the config's anonymize rules.
+func TestCheckAnonymize(t *testing.T) {
+	for rules, want := range map[string]string{
+		"Customer.email=hash_email,*.phone=nullify": "",
+		"Customer.email=scramble":                   "unknown rule",
+		"email=hash":                                "expected Model.field",
+		"Customer.address=redact":                   "field not found",
+		"Customer.name=nullify":                     "cannot nullify",
+		"*.address=redact":                          "",
+	} {
+		cfg := &Config{Anonymize: map[string]string{}}
+		for _, rule := range strings.Split(rules, ",") {
+			key, value, _ := strings.Cut(rule, "=")
+			cfg.Anonymize[key] = value
+		}
+		err := checkAnonymize(cfg, seedModels())
+		if (want == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), want)) {
+			t.Errorf("%s: error %v, want %q", rules, err, want)
+		}
+	}
+}
+
+// TestAnonymizers checks that the rules are deterministic and keep NULLs.
+func TestAnonymizers(t *testing.T) {
+	cfg := &Config{Anonymize: map[string]string{"Customer.email": "hash_email", "*.email": "redact"}}
+	m := seedModels()[0]
+	if rule := anonymizer(cfg, m, "email"); rule != "hash_email" {
+		t.Errorf("rule = %q, want the model rule first", rule)
+	}
+	hashed := anonymizers["hash_email"]("ann@example.org")
+	if hashed != anonymizers["hash_email"]("ann@example.org") || !strings.HasPrefix(hashed.(string), "user_") {
+		t.Errorf("hash_email = %v", hashed)
+	}
+	for name, rule := range anonymizers {
+		if name != "keep" && rule(nil) != nil {
+			t.Errorf("%s(nil) = %v", name, rule(nil))
+		}
+	}
+}
+
+func TestSeedTables(t *testing.T) {
+	m := seedModels()[0]
+	if got := djangoTable(m); got != "shop_customer" {
+		t.Errorf("djangoTable = %q", got)
+	}
+	m.DBTable = "customers"
+	if got := djangoTable(m); got != "customers" {
+		t.Errorf("djangoTable with db_table = %q", got)
+	}
+	cols, fields := tableColumns(m)
+	if strings.Join(cols, ",") != "id,email,phone,name" || fields[0] != "" || fields[1] != "email" {
+		t.Errorf("columns %v, fields %v", cols, fields)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		value   any
+		dialect string
+		want    string
+	}{
+		{nil, "postgres", "NULL"},
+		{true, "postgres", "TRUE"},
+		{int64(42), "postgres", "42"},
+		{1.5, "postgres", "1.5"},
+		{at, "postgres", "'2024-05-01 12:30:00'"},
+		{[]byte("it's"), "postgres", "'it''s'"},
+		{`a\b`, "postgres", `'a\b'`},
+		{`a\b`, "mysql", `'a\\b'`},
+	} {
+		if got := sqlLiteral(c.value, c.dialect); got != c.want {
+			t.Errorf("sqlLiteral(%v, %s) = %s, want %s", c.value, c.dialect, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,452 @@
+// serve.go
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serveRequest is the JSON body of the serve endpoints. Paths are read on
+// the server's file system.
+type serveRequest struct {
+	Input   string `json:"input"`
+	Dialect string `json:"dialect"`
+	// Config is the path of the config file, as given to --config.
+	Config        string `json:"config"`
+	Python2Models bool   `json:"python2_models"`
+	MaxDepth      int    `json:"max_depth"`
+	AllApps       bool   `json:"all_apps"`
+	Sessions      bool   `json:"sessions"`
+	Cache         bool   `json:"cache"`
+	NoAppPrefix   bool   `json:"no_app_prefix"`
+	// Snapshot is the snapshot /diff diffs against, as given to --diff.
+	Snapshot         string `json:"snapshot"`
+	AllowDestructive bool   `json:"allow_destructive"`
+}
+
+// serveResponse is the JSON body answering /generate and /diff, and any
+// failed request.
+type serveResponse struct {
+	Files       map[string]string `json:"files,omitempty"`
+	Diagnostics []Diagnostic      `json:"diagnostics"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// serveError is a request failure answered with its HTTP status.
+type serveError struct {
+	status int
+	err    error
+}
+
+func (e *serveError) Error() string { return e.err.Error() }
+
+// badRequest returns the error answering a request that cannot be served.
+func badRequest(format string, args ...any) error {
+	return &serveError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// parsed is the parser output of an input, kept until its files change.
+type parsed struct {
+	fingerprint string
+	output      []byte
+	// read holds the Python files the parse read, relative to the input.
+	read map[string]bool
+}
+
+// server answers the serve endpoints, keeping the parser output of every
+// input it read. Generation reads package state such as goNames, so the
+// requests are served one at a time.
+type server struct {
+	// parser runs the parser on an input with the given arguments.
+	parser func(root string, args []string) (*Output, error)
+	// retries is how many times a failing parser is rerun, waiting backoff
+	// before the first rerun and twice as long before each next one.
+	retries int
+	backoff time.Duration
+	mu      sync.Mutex
+	parsed  map[string]parsed
+}
+
+// analysis is a project normalized and translated as for generation.
+type analysis struct {
+	cfg     *Config
+	out     *Output
+	opts    Options
+	tables  []systemTable
+	queries []TranslatedQuery
+	props   []ComputedProperty
+	skipped []string
+	diags   []Diagnostic
+}
+
+// runServe implements the serve subcommand: an HTTP server parsing and
+// generating projects on request, for platforms and editors that would
+// otherwise run django2go once per change.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8484", "Address to listen on")
+	parserScriptPath := fs.String("parser-script", "", "Python script to run instead of the built-in parser")
+	retries := fs.Int("parser-retries", 2, "Times to rerun a failing parser before answering from the last good parse")
+	backoff := fs.Duration("parser-backoff", 250*time.Millisecond, "Wait before rerunning a failing parser, doubled for each further rerun")
+	parserEnv := parserEnvFlags(fs)
+	fs.Parse(args)
+	if *retries < 0 || *backoff < 0 {
+		fail(exitUsage, "Error: --parser-retries and --parser-backoff must not be negative")
+	}
+
+	env := parserEnv()
+	s := &server{
+		parser: func(root string, args []string) (*Output, error) {
+			return runPythonParser(root, *parserScriptPath, args, env, generateCapabilities...)
+		},
+		retries: *retries,
+		backoff: *backoff,
+		parsed:  map[string]parsed{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /parse", s.handle(s.parseHandler))
+	mux.HandleFunc("POST /generate", s.handle(s.generateHandler))
+	mux.HandleFunc("POST /diff", s.handle(s.diffHandler))
+	mux.HandleFunc("POST /report", s.handle(s.reportHandler))
+	mux.HandleFunc("POST /diagnostics", s.handle(s.diagnosticsHandler))
+	fmt.Printf("✅ Serving /parse, /generate, /diff, /report and /diagnostics on http://%s\n", *addr)
+	if err := http.ListenAndServe(*addr, mux); err != nil {
+		fail(exitIO, "Error: %v", err)
+	}
+}
+
+// handle decodes the request and encodes the endpoint's answer as JSON.
+func (s *server) handle(endpoint func(serveRequest) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req serveRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		var body any
+		status := http.StatusOK
+		err := dec.Decode(&req)
+		if err != nil {
+			err = badRequest("request: %v", err)
+		} else {
+			s.mu.Lock()
+			body, err = endpoint(req)
+			s.mu.Unlock()
+		}
+		if err != nil {
+			status = http.StatusInternalServerError
+			var se *serveError
+			if errors.As(err, &se) {
+				status = se.status
+			}
+			if resp, ok := body.(serveResponse); ok {
+				resp.Error = err.Error()
+				body = resp
+			} else {
+				body = serveResponse{Error: err.Error()}
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// parse runs the parser on the input of the request, or returns a copy of
+// its last output when none of the input's files changed since. When the
+// parser keeps failing, as on a file caught mid-save, the last good output
+// is answered instead, with a W008 warning, and the parser is run again on
+// the next request. A file the last good parse read now skipped with W001
+// counts as a failure, being most likely caught mid-save too.
+func (s *server) parse(req serveRequest) (*Output, error) {
+	if req.Input == "" {
+		return nil, badRequest("input is required")
+	}
+	if req.MaxDepth < 0 {
+		return nil, badRequest("max_depth must not be negative")
+	}
+	root, err := filepath.Abs(req.Input)
+	if err != nil {
+		return nil, badRequest("input: %v", err)
+	}
+	args := parserArgs(req.Python2Models, req.MaxDepth)
+	key := strings.Join(append([]string{root}, args...), " ")
+	fingerprint, files, err := inputFingerprint(root)
+	if err != nil {
+		return nil, badRequest("input: %v", err)
+	}
+	last, ok := s.parsed[key]
+	if ok && last.fingerprint == fingerprint {
+		return last.decode()
+	}
+	out, err := s.runParser(root, args, last.read)
+	if err != nil {
+		if !ok {
+			return nil, fmt.Errorf("parser: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: parser: %v; answering from the last good parse of %s\n", err, root)
+		out, derr := last.decode()
+		if derr != nil {
+			return nil, derr
+		}
+		out.Diagnostics = append(out.Diagnostics, Diagnostic{Code: codeStaleParse, Severity: severity(codeStaleParse),
+			Message: fmt.Sprintf("parser failed, answered from the last good parse: %v", err)})
+		return out, nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	}
+	read := map[string]bool{}
+	for _, f := range files {
+		read[f] = true
+	}
+	for _, d := range out.Diagnostics {
+		if d.Code == codeFileSkipped {
+			delete(read, d.File)
+		}
+	}
+	s.parsed[key] = parsed{fingerprint, data, read}
+	return out, nil
+}
+
+// decode returns a copy of the kept parser output.
+func (p parsed) decode() (*Output, error) {
+	var out Output
+	if err := json.Unmarshal(p.output, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// runParser runs the parser, rerunning it with backoff while it fails or
+// skips one of the files in read, up to s.retries times.
+func (s *server) runParser(root string, args []string, read map[string]bool) (*Output, error) {
+	wait := s.backoff
+	for attempt := 0; ; attempt++ {
+		out, err := s.parser(root, args)
+		if err == nil {
+			err = skippedRead(out, read)
+		}
+		if err == nil || attempt == s.retries {
+			return out, err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// skippedRead returns an error naming the first file of read the parser
+// skipped with W001.
+func skippedRead(out *Output, read map[string]bool) error {
+	for _, d := range out.Diagnostics {
+		if d.Code == codeFileSkipped && read[d.File] {
+			return fmt.Errorf("%s: %s", d.File, d.Message)
+		}
+	}
+	return nil
+}
+
+// inputFingerprint identifies the state of the files under root by their
+// paths, sizes and modification times. It also returns the Python files
+// among them, relative to root.
+func inputFingerprint(root string) (string, []string, error) {
+	h := sha256.New()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		if rel, err := filepath.Rel(root, path); err == nil && strings.HasSuffix(rel, ".py") {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil)), files, err
+}
+
+// analyze parses, normalizes and translates the project of the request as
+// the generate command does. Errors found while normalizing are returned
+// among the diagnostics, with no queries.
+func (s *server) analyze(req serveRequest) (*analysis, error) {
+	if req.Dialect == "" {
+		req.Dialect = "postgres"
+	}
+	if !slices.Contains(dialects, req.Dialect) {
+		return nil, badRequest("dialect must be one of %s", strings.Join(dialects, ", "))
+	}
+	cfg, err := loadConfig(req.Config)
+	if err != nil {
+		return nil, badRequest("config: %v", err)
+	}
+	goNames = cfg.GoNames
+	out, err := s.parse(req)
+	if err != nil {
+		return nil, err
+	}
+	a := &analysis{cfg: cfg, out: out}
+	if cfg.DjangoVersion != "" {
+		out.Settings.DjangoVersion = cfg.DjangoVersion
+	}
+	if !req.AllApps {
+		a.skipped = skipUninstalledApps(out)
+	}
+	a.opts = Options{Dialect: req.Dialect, ColumnAliases: aliasNone, Choices: choicesCheck,
+		Django: out.Settings.django(), NaiveDateTimes: !out.Settings.useTZ(), NoAppPrefix: req.NoAppPrefix}
+	a.diags = append(out.Diagnostics, normalize(cfg, out.Models, a.opts)...)
+	if hasErrors(a.diags) {
+		sortDiagnostics(a.diags)
+		return a, nil
+	}
+	pending := applyWorkspace(cfg, out)
+	a.diags = append(a.diags, schemaDiagnostics(out.Models, req.Dialect)...)
+	tables, tableDiags := systemTables(out.Settings, req.Sessions, req.Cache)
+	a.tables = tables
+	a.diags = append(a.diags, tableDiags...)
+	queries, props, queryDiags := collectQueries(out, pending, tables, a.opts)
+	uniqueNames(queries)
+	a.queries, a.props = queries, props
+	a.diags = append(a.diags, queryDiags...)
+	sortDiagnostics(a.diags)
+	return a, nil
+}
+
+// schema returns the formatted schema of the managed models and the system
+// tables, as written to schema.sql.
+func (a *analysis) schema(managed []Model) string {
+	return a.cfg.SQLStyle.format(generateSQL(managed, a.opts) + systemTablesSQL(a.tables))
+}
+
+// failed returns the answer to a request whose diagnostics hold errors.
+func (a *analysis) failed() (any, error) {
+	return serveResponse{Diagnostics: a.diags},
+		&serveError{http.StatusUnprocessableEntity, errors.New("the project has errors; see the diagnostics")}
+}
+
+// parseHandler answers /parse with the parser's output.
+func (s *server) parseHandler(req serveRequest) (any, error) {
+	return s.parse(req)
+}
+
+// generateHandler answers /generate with the schema, queries and sqlc.yaml
+// of the project, laid out as configured. The other generated files need a
+// run of the generate command.
+func (s *server) generateHandler(req serveRequest) (any, error) {
+	a, err := s.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	if hasErrors(a.diags) {
+		return a.failed()
+	}
+	layout, style := a.cfg.Layout, a.cfg.SQLStyle
+	managed, unmanaged := splitManaged(a.out.Models)
+	files := map[string]string{layout.Schema: a.schema(managed)}
+	schemas := []string{layout.Schema}
+	if len(unmanaged) > 0 {
+		file := layout.sibling("unmanaged.sql")
+		files[file] = style.format(unmanagedHeader + generateSQL(unmanaged, a.opts))
+		schemas = append(schemas, file)
+	}
+	queryFiles, byFile := layout.queryFiles(a.queries)
+	for _, file := range queryFiles {
+		files[file] = style.format(generateQueries(byFile[file], a.out.Models))
+	}
+	files[layout.SQLC] = generateSQLCConfig(a.out.Models, a.opts.Dialect, layout, schemas, queryFiles, a.cfg.TypeOverrides, "")
+	return serveResponse{Files: files, Diagnostics: a.diags}, nil
+}
+
+// diffHandler answers /diff with the migrations from the snapshot to the
+// project, named as in the migrations directory and recording the schema
+// checksum as the generate command's do.
+func (s *server) diffHandler(req serveRequest) (any, error) {
+	if req.Snapshot == "" {
+		return nil, badRequest("snapshot is required")
+	}
+	prev, err := loadSnapshot(req.Snapshot)
+	if err != nil {
+		return nil, badRequest("snapshot: %v", err)
+	}
+	a, err := s.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	if prev.Dialect != a.opts.Dialect {
+		return nil, badRequest("snapshot is for %s, not %s", prev.Dialect, a.opts.Dialect)
+	}
+	if hasErrors(a.diags) {
+		return a.failed()
+	}
+	managed, _ := splitManaged(a.out.Models)
+	steps := diffModels(prev.Models, managed, a.cfg.Renames, a.opts)
+	a.diags = append(a.diags, renameDiagnostics(steps)...)
+	if !req.AllowDestructive {
+		a.diags = append(a.diags, destructiveDiagnostics(steps)...)
+	}
+	sortDiagnostics(a.diags)
+	if hasErrors(a.diags) {
+		return a.failed()
+	}
+	files := diffMigrations(steps, req.Snapshot, time.Now())
+	recordDiffChecksum(files, a.schema(managed), steps, prev.Checksum)
+	for name, sql := range files {
+		files[name] = a.cfg.SQLStyle.format(sql)
+	}
+	return serveResponse{Files: files, Diagnostics: a.diags}, nil
+}
+
+// reportHandler answers /report with report.json.
+func (s *server) reportHandler(req serveRequest) (any, error) {
+	a, err := s.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	if hasErrors(a.diags) {
+		return a.failed()
+	}
+	report := buildReport(a.out.Models, a.queries)
+	report.Compat.ComputedProperties = a.props
+	report.Compat.UnknownFieldTypes = unknownFieldTypes(a.diags)
+	report.SkippedApps = a.skipped
+	report.Diagnostics = a.diags
+	return report, nil
+}
+
+// diagnosticsHandler answers /diagnostics with the diagnostics of the
+// project, errors included, as the Language Server Protocol publishes them:
+// one entry per models file, without diagnostics for those that have none.
+func (s *server) diagnosticsHandler(req serveRequest) (any, error) {
+	a, err := s.analyze(req)
+	if err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(req.Input)
+	if err != nil {
+		return nil, badRequest("input: %v", err)
+	}
+	var files []string
+	for _, m := range a.out.Models {
+		if !slices.Contains(files, m.File) {
+			files = append(files, m.File)
+		}
+	}
+	return lspDiagnostics(root, files, a.diags), nil
+}
@@ -0,0 +1,298 @@
+// serve_test.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// serveJSON posts body to the endpoint and decodes its answer into resp,
+// returning the HTTP status.
+func serveJSON(t *testing.T, h http.HandlerFunc, body string, resp any) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+		t.Fatalf("answer %q: %v", w.Body.String(), err)
+	}
+	return w.Code
+}
+
+// TestServeGenerate checks that /generate answers the schema, queries and
+// sqlc.yaml of the project, and that bad requests are told apart from
+// projects with errors.
+func TestServeGenerate(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("no python3")
+	}
+	input := writeProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+`,
+	})
+	env := parserEnvironment("", nil)
+	s := &server{
+		parser: func(root string, args []string) (*Output, error) {
+			return runPythonParser(root, "", args, env, generateCapabilities...)
+		},
+		parsed: map[string]parsed{},
+	}
+	generate := s.handle(s.generateHandler)
+	body, _ := json.Marshal(serveRequest{Input: input})
+
+	var resp serveResponse
+	if code := serveJSON(t, generate, string(body), &resp); code != http.StatusOK {
+		t.Fatalf("status %d: %+v", code, resp)
+	}
+	if !strings.Contains(resp.Files[defaultLayout.Schema], "CREATE TABLE blog_post (") {
+		t.Errorf("schema:\n%s", resp.Files[defaultLayout.Schema])
+	}
+	if _, ok := resp.Files[defaultLayout.SQLC]; !ok {
+		t.Errorf("files = %v, want %s", resp.Files, defaultLayout.SQLC)
+	}
+
+	for body, want := range map[string]int{
+		`{}`:                              http.StatusBadRequest,
+		`{"input": "x", "unknown": true}`: http.StatusBadRequest,
+		`{"input": "` + input + `", "dialect": "oracle"}`: http.StatusBadRequest,
+	} {
+		resp = serveResponse{}
+		if code := serveJSON(t, generate, body, &resp); code != want || resp.Error == "" {
+			t.Errorf("%s: status %d, %+v, want %d with an error", body, code, resp, want)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(input, "blog", "models.py"), []byte(`from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+
+class Article(models.Model):
+    class Meta:
+        db_table = "blog_post"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resp = serveResponse{}
+	if code := serveJSON(t, generate, string(body), &resp); code != http.StatusUnprocessableEntity || !hasErrors(resp.Diagnostics) {
+		t.Errorf("status %d, %+v, want %d with the project's errors", code, resp, http.StatusUnprocessableEntity)
+	}
+}
+
+// TestInputFingerprint checks that the fingerprint changes with the files
+// of the input but not with those under .git.
+func TestInputFingerprint(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fingerprint := func() string {
+		f, files, err := inputFingerprint(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(files, []string{"blog/models.py"}) {
+			t.Errorf("Python files = %v", files)
+		}
+		return f
+	}
+	write("blog/models.py", "v1")
+	write(".git/index", "v1")
+	first := fingerprint()
+	write(".git/index", "v2 longer")
+	if fingerprint() != first {
+		t.Error(".git changed the fingerprint")
+	}
+	write("blog/models.py", "v2 longer")
+	if fingerprint() == first {
+		t.Error("editing models.py kept the fingerprint")
+	}
+}
+
+// TestServeParseRetries checks that a failing parser is rerun up to the
+// retries, and that a parser still failing answers the input's last good
+// parse with a W008 warning, but fails an input never parsed.
+func TestServeParseRetries(t *testing.T) {
+	input := t.TempDir()
+	models := filepath.Join(input, "models.py")
+	if err := os.WriteFile(models, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	calls, failures := 0, 0
+	s := &server{
+		parser: func(root string, args []string) (*Output, error) {
+			calls++
+			if calls <= failures {
+				return nil, errors.New("models.py: caught mid-save")
+			}
+			return &Output{Models: []Model{{Name: "Post"}}}, nil
+		},
+		retries: 2,
+		parsed:  map[string]parsed{},
+	}
+	req := serveRequest{Input: input}
+
+	// Never parsed: failing more than the retries fails the request.
+	failures = 3
+	if _, err := s.parse(req); err == nil || calls != 3 {
+		t.Fatalf("parse = %v after %d calls, want an error after 3", err, calls)
+	}
+	// A failure within the retries is not seen.
+	calls, failures = 0, 2
+	out, err := s.parse(req)
+	if err != nil || calls != 3 || len(out.Diagnostics) != 0 {
+		t.Fatalf("parse = %v, %v after %d calls, want the output after 3", out, err, calls)
+	}
+
+	if err := os.WriteFile(models, []byte("v2, mid-save"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	calls, failures = 0, 3
+	out, err = s.parse(req)
+	if err != nil {
+		t.Fatalf("parse = %v, want the last good parse", err)
+	}
+	if len(out.Models) != 1 || len(out.Diagnostics) != 1 || out.Diagnostics[0].Code != codeStaleParse {
+		t.Fatalf("parse = %+v, want the last good parse with a %s warning", out, codeStaleParse)
+	}
+	// The next request runs the parser again, and the stale answer is not kept.
+	calls, failures = 0, 0
+	if out, err = s.parse(req); err != nil || calls != 1 || len(out.Diagnostics) != 0 {
+		t.Fatalf("parse = %+v, %v after %d calls, want a fresh parse", out, err, calls)
+	}
+}
+
+// TestServeSkippedFiles checks that a file the last good parse read, now
+// skipped with W001, fails the parse like a parser error, while a new file
+// skipped does not, and that the W008 warning is published on the input.
+func TestServeSkippedFiles(t *testing.T) {
+	input := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(input, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("blog/models.py", "v1")
+	calls, skipped := 0, ""
+	s := &server{
+		parser: func(root string, args []string) (*Output, error) {
+			calls++
+			out := &Output{Models: []Model{{Name: "Post", App: "blog", File: "blog/models.py", Line: 3}}}
+			if skipped != "" {
+				out.Diagnostics = []Diagnostic{{Code: codeFileSkipped, Severity: severity(codeFileSkipped), File: skipped, Line: 4, Message: "file skipped: invalid syntax"}}
+			}
+			return out, nil
+		},
+		retries: 1,
+		parsed:  map[string]parsed{},
+	}
+	req := serveRequest{Input: input, AllApps: true}
+	if _, err := s.parse(req); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new file caught mid-save was never read: its W001 stands.
+	write("blog/views.py", "def index(")
+	calls, skipped = 0, "blog/views.py"
+	out, err := s.parse(req)
+	if err != nil || calls != 1 || len(out.Diagnostics) != 1 || out.Diagnostics[0].Code != codeFileSkipped {
+		t.Fatalf("parse = %+v, %v after %d calls, want the W001 after 1", out, err, calls)
+	}
+
+	// models.py was read: skipping it is retried, then answered stale.
+	write("blog/models.py", "v2, mid-save")
+	calls, skipped = 0, "blog/models.py"
+	resp, err := s.diagnosticsHandler(req)
+	if err != nil || calls != 2 {
+		t.Fatalf("diagnostics = %v after %d calls, want an answer after 2", err, calls)
+	}
+	root, err := filepath.Abs(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stale []string
+	for _, f := range resp.([]lspFile) {
+		for _, d := range f.Diagnostics {
+			if d.Code == codeStaleParse {
+				stale = append(stale, f.URI)
+			}
+		}
+	}
+	if want := fileURI(root); !slices.Equal(stale, []string{want}) {
+		t.Errorf("%s published on %v, want %s", codeStaleParse, stale, want)
+	}
+}
+
+// TestServeDiff checks that /diff records the checksum of the schema the
+// migrations reach, and the snapshot's back in the down migration, like the
+// generate command.
+func TestServeDiff(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("no python3")
+	}
+	input := writeProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+`,
+	})
+	snapshot := filepath.Join(t.TempDir(), snapshotFile)
+	if err := os.WriteFile(snapshot, []byte((&Snapshot{Dialect: "postgres", Checksum: "sha256:before"}).JSON()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := parserEnvironment("", nil)
+	s := &server{
+		parser: func(root string, args []string) (*Output, error) {
+			return runPythonParser(root, "", args, env, generateCapabilities...)
+		},
+		parsed: map[string]parsed{},
+	}
+	body, _ := json.Marshal(serveRequest{Input: input, Snapshot: snapshot})
+	var generated, diffed serveResponse
+	if code := serveJSON(t, s.handle(s.generateHandler), string(body), &generated); code != http.StatusOK {
+		t.Fatalf("generate: status %d: %+v", code, generated)
+	}
+	if code := serveJSON(t, s.handle(s.diffHandler), string(body), &diffed); code != http.StatusOK {
+		t.Fatalf("diff: status %d: %+v", code, diffed)
+	}
+	checksum := schemaChecksum(generated.Files[defaultLayout.Schema])
+	var ups, downs int
+	for name, sql := range diffed.Files {
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			ups++
+			if !strings.Contains(sql, "CREATE TABLE blog_post (") || !strings.Contains(sql, "VALUES ('"+checksum+"')") {
+				t.Errorf("%s:\n%s", name, sql)
+			}
+		case strings.HasSuffix(name, ".down.sql"):
+			downs++
+			if !strings.Contains(sql, "VALUES ('sha256:before')") {
+				t.Errorf("%s:\n%s", name, sql)
+			}
+		}
+	}
+	if ups != 1 || downs != 1 {
+		t.Errorf("files = %v", diffed.Files)
+	}
+}
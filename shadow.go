@@ -0,0 +1,301 @@
+// shadow.go
+package main
+
+// generateShadow renders the shadow package, the harness for running the
+// Django app and the Go service side by side: its Handler serves every
+// request from Django while replaying the safe ones against the Go service
+// and logging where the JSON responses differ, and CompareRows does the
+// same for the rows of a query run against both databases.
+func generateShadow() (map[string]string, error) {
+	src := `// Code generated by django2go. Edit as needed.
+
+// Package shadow compares the Go service with the Django app it replaces
+// while both run: requests are served by Django and replayed against the Go
+// service, and queries are run on both databases, reporting where the
+// results differ.
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Methods are the request methods replayed against the Go service. Others,
+// which change data, are only served by Django.
+var Methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+
+// Ignore lists the JSON paths left out of comparisons, such as
+// "$.generated_at" or "$.results[*].id".
+var Ignore []string
+
+// Timeout bounds a replayed request.
+var Timeout = 10 * time.Second
+
+// Client sends the requests to both stacks.
+var Client = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+// OnDiff, if set, is called with every request whose responses differ, to
+// count or store them besides the log.
+var OnDiff func(Diff)
+
+// Diff is a request whose responses differ.
+type Diff struct {
+	Method      string
+	Path        string
+	Status      [2]int // Django's, then the Go service's
+	Differences []string
+}
+
+// Handler serves requests from the Django app at django, returning its
+// responses, and replays those with one of Methods against the Go service at
+// service in the background. Differing responses are logged at warning
+// level with the differences found, and passed to OnDiff.
+func Handler(django, service *url.URL, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status, header, want, err := forward(r.Context(), django, r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		w.Write(want)
+		if !slices.Contains(Methods, r.Method) {
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), Timeout)
+			defer cancel()
+			d := Diff{Method: r.Method, Path: r.URL.RequestURI(), Status: [2]int{status}}
+			var got []byte
+			var err error
+			d.Status[1], _, got, err = forward(ctx, service, r, body)
+			if err != nil {
+				logger.Error("shadow request failed", "method", d.Method, "path", d.Path, "error", err)
+				return
+			}
+			if d.Status[0] != d.Status[1] {
+				d.Differences = append(d.Differences, fmt.Sprintf("status: %d != %d", d.Status[0], d.Status[1]))
+			}
+			d.Differences = append(d.Differences, CompareJSON(want, got)...)
+			if len(d.Differences) == 0 {
+				return
+			}
+			logger.Warn("shadow response differs", "method", d.Method, "path", d.Path,
+				"status", d.Status, "differences", d.Differences)
+			if OnDiff != nil {
+				OnDiff(d)
+			}
+		}()
+	})
+}
+
+// forward sends a copy of r to the stack at base and reads its response.
+func forward(ctx context.Context, base *url.URL, r *http.Request, body []byte) (int, http.Header, []byte, error) {
+	target := *base
+	target.Path = strings.TrimSuffix(base.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+	req, err := http.NewRequestWithContext(ctx, r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	req.Header = r.Header.Clone()
+	req.Host = r.Host
+	resp, err := Client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header, b, err
+}
+
+// CompareJSON returns the differences between two JSON documents, by path,
+// leaving out the paths in Ignore. Bodies that are not both JSON are
+// compared byte for byte.
+func CompareJSON(want, got []byte) []string {
+	var a, b any
+	if decode(want, &a) != nil || decode(got, &b) != nil {
+		if bytes.Equal(want, got) {
+			return nil
+		}
+		return []string{"body differs"}
+	}
+	var diffs []string
+	compare("$", a, b, &diffs)
+	return diffs
+}
+
+// decode decodes JSON keeping numbers as written, so that 1 and 1.0 differ
+// as they would for a client.
+func decode(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// compare appends the differences between a and b at path.
+func compare(path string, a, b any, diffs *[]string) {
+	if ignored(path) {
+		return
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for k := range a {
+			keys[k] = true
+		}
+		for k := range b {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			va, inA := a[k]
+			vb, inB := b[k]
+			switch {
+			case ignored(path + "." + k):
+			case !inA:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: only in the Go response", path, k))
+			case !inB:
+				*diffs = append(*diffs, fmt.Sprintf("%s.%s: missing from the Go response", path, k))
+			default:
+				compare(path+"."+k, va, vb, diffs)
+			}
+		}
+		return
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			break
+		}
+		if len(a) != len(b) {
+			*diffs = append(*diffs, fmt.Sprintf("%s: %d items != %d", path, len(a), len(b)))
+		}
+		for i := range min(len(a), len(b)) {
+			compare(fmt.Sprintf("%s[%d]", path, i), a[i], b[i], diffs)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, fmt.Sprintf("%s: %v != %v", path, a, b))
+	}
+}
+
+// ignored reports whether a path matches one of Ignore, where [*] stands
+// for any index.
+func ignored(path string) bool {
+	for _, p := range Ignore {
+		prefix, rest, wildcard := strings.Cut(p, "[*]")
+		if !wildcard {
+			if p == path {
+				return true
+			}
+			continue
+		}
+		if !strings.HasPrefix(path, prefix+"[") {
+			continue
+		}
+		_, after, ok := strings.Cut(path[len(prefix):], "]")
+		if ok && after == rest {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareRows runs a query of the Django app on its database and the
+// matching query of the Go service on the service's, with the same
+// arguments, and returns the differences between the rows, compared in
+// order. Values are compared as text, times in UTC.
+func CompareRows(ctx context.Context, django, service *sql.DB, djangoQuery, serviceQuery string, args ...any) ([]string, error) {
+	want, err := rows(ctx, django, djangoQuery, args)
+	if err != nil {
+		return nil, fmt.Errorf("django: %w", err)
+	}
+	got, err := rows(ctx, service, serviceQuery, args)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w", err)
+	}
+	var diffs []string
+	if len(want) != len(got) {
+		diffs = append(diffs, fmt.Sprintf("%d rows != %d", len(want), len(got)))
+	}
+	for i := range min(len(want), len(got)) {
+		compare(fmt.Sprintf("$[%d]", i), want[i], got[i], &diffs)
+	}
+	return diffs, nil
+}
+
+// rows runs a query and returns its rows as maps from column to value as
+// text.
+func rows(ctx context.Context, db *sql.DB, query string, args []any) ([]any, error) {
+	rs, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []any
+	for rs.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rs.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := map[string]any{}
+		for i, c := range columns {
+			switch v := values[i].(type) {
+			case nil:
+				row[c] = nil
+			case []byte:
+				row[c] = string(v)
+			case time.Time:
+				row[c] = v.UTC().Format(time.RFC3339Nano)
+			default:
+				row[c] = fmt.Sprint(v)
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rs.Err()
+}
+`
+	files := map[string]string{}
+	if err := addGoFile(files, "shadow/shadow.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,59 @@
+// shadow_test.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateShadow builds the generated shadow package, which only needs
+// the standard library, and checks how CompareJSON reports differences.
+func TestGenerateShadow(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no go")
+	}
+	files, err := generateShadow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.22\n"
+	files["shadow/compare_test.go"] = `package shadow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareJSON(t *testing.T) {
+	Ignore = []string{"$.generated_at", "$.results[*].id"}
+	want := ` + "`" + `{"generated_at": 1, "count": 2, "results": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}` + "`" + `
+	got := ` + "`" + `{"generated_at": 2, "count": 2.0, "results": [{"id": 3, "name": "a"}], "extra": true}` + "`" + `
+	diffs := strings.Join(CompareJSON([]byte(want), []byte(got)), "; ")
+	if diffs != "$.count: 2 != 2.0; $.extra: only in the Go response; $.results: 2 items != 1" {
+		t.Error(diffs)
+	}
+	if d := CompareJSON([]byte("<p>"), []byte("<p>")); d != nil {
+		t.Error(d)
+	}
+}
+`
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "test", "./shadow")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, strings.TrimSpace(string(out)))
+	}
+}
@@ -0,0 +1,62 @@
+// slug.go
+package main
+
+// defaultSlugLength is Django's default SlugField max_length.
+const defaultSlugLength = 50
+
+// uniqueSlugs returns the model's SlugField(unique=True) fields, which get a
+// dedicated unique index and a GetBy query since looking rows up by slug is
+// one of the most common Django access patterns.
+func uniqueSlugs(m Model) []Field {
+	var slugs []Field
+	for _, f := range m.Fields {
+		if f.Type == "SlugField" && f.Unique {
+			slugs = append(slugs, f)
+		}
+	}
+	return slugs
+}
+
+// slugIndexName returns the name of the unique index on a slug field.
+func slugIndexName(m Model, f Field) string {
+	return m.table() + "_" + columnName(f) + "_uniq"
+}
+
+// slugIndexStatements returns the unique indexes for the model's slugs.
+func slugIndexStatements(m Model) []string {
+	var stmts []string
+	for _, f := range uniqueSlugs(m) {
+		stmts = append(stmts, "CREATE UNIQUE INDEX "+slugIndexName(m, f)+" ON "+m.table()+" ("+columnName(f)+");")
+	}
+	return stmts
+}
+
+// slugQueries generates a Get<Model>By<Slug> query for every unique slug,
+// unless one of the translated queries already has its SQL, as
+// objects.get(slug=slug) does.
+func slugQueries(models []Model, translated []TranslatedQuery) []TranslatedQuery {
+	existing := map[string]bool{}
+	for _, q := range translated {
+		existing[q.SQL] = true
+	}
+	var result []TranslatedQuery
+	for _, m := range models {
+		if m.isView() {
+			continue
+		}
+		for _, f := range uniqueSlugs(m) {
+			col := columnName(f)
+			sql := "SELECT * FROM " + m.table() + " WHERE " + col + " = sqlc.arg(" + col + ");"
+			if existing[sql] {
+				continue
+			}
+			result = append(result, TranslatedQuery{
+				Query: Query{App: m.App, Model: m.Name, Source: f.Name + " = SlugField(unique=True)"},
+				Name:  "Get" + m.Name + "By" + camel(f.Name),
+				Kind:  ":one",
+				SQL:   sql,
+			})
+		}
+	}
+	return result
+}
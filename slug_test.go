@@ -0,0 +1,63 @@
+// slug_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUniqueSlugs checks the unique index, MySQL column type and GetBy query
+// generated for a SlugField(unique=True), and that the query is not
+// generated again for a call site translated into it.
+func TestUniqueSlugs(t *testing.T) {
+	slugModels := func() []Model {
+		return []Model{{Name: "Article", App: "news", Managed: true, Fields: []Field{
+			{Name: "slug", Type: "SlugField", Unique: true},
+			{Name: "short", Type: "SlugField", Unique: true, MaxLength: 20},
+			{Name: "tag", Type: "SlugField"},
+		}}}
+	}
+
+	models := slugModels()
+	sql := generateSQL(models, Options{Dialect: "postgres"})
+	if strings.Contains(sql, "UNIQUE,") || !strings.Contains(sql, "CREATE UNIQUE INDEX article_slug_uniq ON article (slug);") {
+		t.Errorf("postgres slug index:\n%s", sql)
+	}
+	if strings.Contains(sql, "article_tag_uniq") {
+		t.Errorf("non-unique slug indexed:\n%s", sql)
+	}
+
+	models = slugModels()
+	if diags := applyDialectTypes(&Config{}, models, Options{Dialect: "mysql"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	sql = generateSQL(models, Options{Dialect: "mysql"})
+	for _, want := range []string{"slug VARCHAR(50) NOT NULL", "short VARCHAR(20) NOT NULL", "tag VARCHAR(50) NOT NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+
+	queries := slugQueries(models, nil)
+	if len(queries) != 2 {
+		t.Fatalf("got %d slug queries, want 2", len(queries))
+	}
+	if q := queries[0]; q.Name != "GetArticleBySlug" || q.Kind != ":one" ||
+		q.SQL != "SELECT * FROM article WHERE slug = sqlc.arg(slug);" {
+		t.Errorf("slug query = %s %s %q", q.Name, q.Kind, q.SQL)
+	}
+
+	// A call site translated into the same query gets no duplicate.
+	translated := translateQueries([]Query{
+		{Model: "Article", Chain: []Step{{Method: "get", Kwargs: []Arg{{Key: "slug", Value: "slug"}}}}},
+	}, models, "mysql")
+	queries = append(translated, slugQueries(models, translated)...)
+	uniqueNames(queries)
+	var names []string
+	for _, q := range queries {
+		names = append(names, q.Name)
+	}
+	if got := strings.Join(names, " "); got != "GetArticleBySlug GetArticleByShort" {
+		t.Errorf("queries = %s", got)
+	}
+}
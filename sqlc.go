@@ -0,0 +1,246 @@
+// sqlc.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// goTypes holds the sqlc go_type overrides for a column type, for NOT NULL
+// and nullable columns.
+type goTypes struct {
+	NotNull  string
+	Nullable string
+}
+
+// sqlcTypeOverrides maps column types, per dialect, to the Go types sqlc
+// should generate for them. Types sqlc already handles well are omitted.
+var sqlcTypeOverrides = map[string]map[string]goTypes{
+	"postgres": {
+		// Hstore is generated next to the queries (see generateHstore).
+		"hstore":    {"Hstore", "Hstore"},
+		"citext":    {"string", "database/sql.NullString"},
+		"int4range": {"string", "database/sql.NullString"},
+		"int8range": {"string", "database/sql.NullString"},
+		"numrange":  {"string", "database/sql.NullString"},
+		"daterange": {"string", "database/sql.NullString"},
+		"tstzrange": {"string", "database/sql.NullString"},
+		"numeric":   {"github.com/shopspring/decimal.Decimal", "github.com/shopspring/decimal.NullDecimal"},
+		"jsonb":     {"encoding/json.RawMessage", "github.com/sqlc-dev/pqtype.NullRawMessage"},
+		"bytea":     {"[]byte", "[]byte"},
+		"tsvector":  {"string", "database/sql.NullString"},
+		// PostGIS returns geometries as hex-encoded EWKB.
+		"geometry":  {"string", "database/sql.NullString"},
+		"geography": {"string", "database/sql.NullString"},
+	},
+	"mysql": {
+		"decimal":  {"github.com/shopspring/decimal.Decimal", "github.com/shopspring/decimal.NullDecimal"},
+		"json":     {"encoding/json.RawMessage", "[]byte"}, // NULL reads as nil; pqtype is PostgreSQL's
+		"longblob": {"[]byte", "[]byte"},
+	},
+}
+
+// sqlcTypeNames maps column types, per dialect, to the name sqlc knows them
+// by when it differs: MySQL reports NUMERIC columns as decimal.
+var sqlcTypeNames = map[string]map[string]string{
+	"mysql": {"numeric": "decimal"},
+}
+
+// sqlcEngines maps dialects to sqlc engine names.
+var sqlcEngines = map[string]string{
+	"postgres": "postgresql",
+	"mysql":    "mysql",
+}
+
+// generateSQLCConfig returns a sqlc.yaml configuration string reading the
+// schema files, such as the reference DDL for unmanaged models besides
+// schema.sql, and the query files, placed by the layout. Type overrides are
+// added for the column types used by the models and the fields of
+// type_overrides, and struct tags for their personal data.
+func generateSQLCConfig(models []Model, dialect string, layout Layout, schemas, queries []string, types map[string]TypeOverride, choices string) string {
+	schema, query := sqlcPaths(layout, schemas), sqlcPaths(layout, queries)
+	engine := sqlcEngines[dialect]
+	if engine == "" {
+		engine = dialect
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`# Edit as needed: hand edits are kept on regeneration.
+version: "2"
+sql:
+  - engine: %s
+    queries: %s
+    schema: %s
+    gen:
+      go:
+        package: "db"
+        out: %q
+`, engine, query, schema, layout.sqlcPath(layout.DB)))
+	sb.WriteString(goNames.sqlcOptions())
+	if overrides := append(sqlcOverrides(models, dialect), columnOverrides(models, types, choices)...); len(overrides) > 0 {
+		sb.WriteString("        overrides:\n")
+		for _, o := range overrides {
+			sb.WriteString(o)
+		}
+	}
+	return sb.String()
+}
+
+// mergeSQLCConfig merges a generated sqlc.yaml into the existing one so that
+// hand-tuned settings survive regeneration: the generated keys take their
+// new values, while the keys and the overrides only the existing file has
+// are kept. The generated config is returned as is when there are none.
+func mergeSQLCConfig(existing, generated string) (string, error) {
+	var old, cur yaml.Node
+	if err := yaml.Unmarshal([]byte(existing), &old); err != nil {
+		return "", err
+	}
+	if err := yaml.Unmarshal([]byte(generated), &cur); err != nil {
+		return "", err
+	}
+	if len(old.Content) == 0 || !mergeNode(cur.Content[0], old.Content[0]) {
+		return generated, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cur); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// mergeNode copies into a generated node what only the existing one has,
+// reporting whether there was anything. Mappings merge key by key and the
+// sql entries by position; overrides, identified by their column or
+// db_type, are kept unless generated.
+func mergeNode(gen, old *yaml.Node) bool {
+	if gen.Kind != old.Kind {
+		return false
+	}
+	kept := false
+	switch gen.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			key, value := old.Content[i], old.Content[i+1]
+			if v := mappingValue(gen, key.Value); v != nil {
+				kept = mergeNode(v, value) || kept
+				continue
+			}
+			gen.Content = append(gen.Content, key, value)
+			kept = true
+		}
+	case yaml.SequenceNode:
+		for i, item := range old.Content {
+			if id := overrideID(item); id != "" {
+				if !slices.ContainsFunc(gen.Content, func(n *yaml.Node) bool { return overrideID(n) == id }) {
+					gen.Content = append(gen.Content, item)
+					kept = true
+				}
+				continue
+			}
+			if i < len(gen.Content) {
+				kept = mergeNode(gen.Content[i], item) || kept
+			}
+		}
+	}
+	return kept
+}
+
+// mappingValue returns the value of a key of a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// overrideID identifies a sqlc override by the column or the column type
+// and nullability it applies to, or returns "" for other nodes.
+func overrideID(n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	if column := mappingValue(n, "column"); column != nil {
+		return "column " + column.Value
+	}
+	if dbType := mappingValue(n, "db_type"); dbType != nil {
+		nullable := "false"
+		if v := mappingValue(n, "nullable"); v != nil {
+			nullable = v.Value
+		}
+		return "db_type " + dbType.Value + " " + nullable
+	}
+	return ""
+}
+
+// sqlcPaths renders the paths of files as a sqlc.yaml value: the quoted
+// path of a single file, or a list of them.
+func sqlcPaths(layout Layout, files []string) string {
+	quoted := make([]string, len(files))
+	for i, f := range files {
+		quoted[i] = fmt.Sprintf("%q", layout.sqlcPath(f))
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// sqlcOverrides returns the rendered override entries for every column type
+// used by the models that has a Go type mapping.
+func sqlcOverrides(models []Model, dialect string) []string {
+	used := map[string]bool{}
+	for _, m := range models {
+		for _, f := range m.Fields {
+			for _, c := range fieldColumns(f) {
+				t := dbType(c.sqlType(dialect))
+				if name, ok := sqlcTypeNames[dialect][t]; ok {
+					t = name
+				}
+				used[t] = true
+			}
+		}
+	}
+	var types []string
+	for t := range used {
+		if _, ok := sqlcTypeOverrides[dialect][t]; ok {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	var entries []string
+	for _, t := range types {
+		gt := sqlcTypeOverrides[dialect][t]
+		entries = append(entries,
+			fmt.Sprintf("          - db_type: %q\n            go_type:%s\n", t, goTypeYAML(gt.NotNull)),
+			fmt.Sprintf("          - db_type: %q\n            go_type:%s\n            nullable: true\n", t, goTypeYAML(gt.Nullable)))
+	}
+	return entries
+}
+
+// goTypeYAML renders an override's go_type: a type of the package sqlc
+// generates, an exported name without an import path, takes the mapping
+// form.
+func goTypeYAML(t string) string {
+	if !token.IsExported(t) {
+		return fmt.Sprintf(" %q", t)
+	}
+	return fmt.Sprintf("\n              type: %q", t)
+}
+
+// dbType returns the lowercase base name of a column type as sqlc refers to
+// it, dropping any length or precision.
+func dbType(sqlType string) string {
+	if i := strings.IndexByte(sqlType, '('); i >= 0 {
+		sqlType = sqlType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(sqlType))
+}
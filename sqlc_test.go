@@ -0,0 +1,116 @@
+// sqlc_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPostgresFieldTypes checks the column types, extensions and sqlc
+// overrides for django.contrib.postgres fields.
+func TestPostgresFieldTypes(t *testing.T) {
+	models := []Model{{Name: "Profile", App: "people", Managed: true, Fields: []Field{
+		{Name: "email", Type: "CIEmailField"},
+		{Name: "attrs", Type: "HStoreField", Nullable: true},
+		{Name: "ages", Type: "IntegerRangeField"},
+	}}}
+
+	sql := generateSQL(models, Options{Dialect: "postgres"})
+	for _, want := range []string{
+		"CREATE EXTENSION IF NOT EXISTS citext;\nCREATE EXTENSION IF NOT EXISTS hstore;\n\n",
+		"email CITEXT NOT NULL",
+		"attrs HSTORE",
+		"ages INT4RANGE NOT NULL",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+	if mysql := generateSQL(models, Options{Dialect: "mysql"}); strings.Contains(mysql, "EXTENSION") || strings.Contains(mysql, "CITEXT") {
+		t.Errorf("postgres types leaked into mysql:\n%s", mysql)
+	}
+
+	cfg := generateSQLCConfig(models, "postgres", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "")
+	for _, want := range []string{
+		"engine: postgresql",
+		"overrides:\n          - db_type: \"citext\"\n            go_type: \"string\"\n",
+		"- db_type: \"hstore\"\n            go_type:\n              type: \"Hstore\"\n            nullable: true\n",
+		"- db_type: \"int4range\"\n            go_type: \"string\"\n",
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("missing %q in:\n%s", want, cfg)
+		}
+	}
+	if cfg := generateSQLCConfig(models, "mysql", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, ""); strings.Contains(cfg, "overrides") {
+		t.Errorf("mysql config has overrides:\n%s", cfg)
+	}
+}
+
+// TestJSONField checks the JSONField column type and Go type per dialect.
+func TestJSONField(t *testing.T) {
+	models := []Model{{Name: "Event", Fields: []Field{{Name: "payload", Type: "JSONField"}}}}
+	for dialect, want := range map[string]string{"postgres": "JSONB", "mysql": "JSON"} {
+		if got := sqlType("JSONField", dialect); got != want {
+			t.Errorf("%s: type %s, want %s", dialect, got, want)
+		}
+		cfg := generateSQLCConfig(models, dialect, defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "")
+		if !strings.Contains(cfg, `db_type: "`+strings.ToLower(want)+`"`) || !strings.Contains(cfg, "encoding/json.RawMessage") {
+			t.Errorf("%s sqlc.yaml:\n%s", dialect, cfg)
+		}
+	}
+}
+
+// TestBinaryField checks the BinaryField column type and its []byte
+// override per dialect.
+func TestBinaryField(t *testing.T) {
+	models := []Model{{Name: "Attachment", Fields: []Field{{Name: "data", Type: "BinaryField"}}}}
+	for dialect, want := range map[string]string{"postgres": "BYTEA", "mysql": "LONGBLOB"} {
+		if got := sqlType("BinaryField", dialect); got != want {
+			t.Errorf("%s: type %s, want %s", dialect, got, want)
+		}
+		cfg := generateSQLCConfig(models, dialect, defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "")
+		if !strings.Contains(cfg, `db_type: "`+strings.ToLower(want)+`"`) || !strings.Contains(cfg, `go_type: "[]byte"`) {
+			t.Errorf("%s sqlc.yaml:\n%s", dialect, cfg)
+		}
+	}
+	if f := dumpFieldType("longblob"); f.Type != "BinaryField" {
+		t.Errorf("dumped longblob = %s", f.Type)
+	}
+}
+
+// TestMergeSQLCConfig checks that hand-added settings and overrides of an
+// existing sqlc.yaml survive regeneration, and that generated ones win.
+func TestMergeSQLCConfig(t *testing.T) {
+	existing := `version: "2"
+sql:
+  - engine: postgresql
+    queries: old.sql
+    schema: schema.sql
+    gen:
+      go:
+        package: db
+        out: db
+        emit_json_tags: true
+        overrides:
+          - column: "post.meta"
+            go_type: "example.com/app/meta.Meta"
+          - db_type: "citext"
+            go_type: "example.com/app/text.CI"
+`
+	generated := generateSQLCConfig(nil, "postgres", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, nil, "")
+	merged, err := mergeSQLCConfig(existing, generated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"emit_json_tags: true", `column: "post.meta"`, `db_type: "citext"`, "query.sql"} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("merged config lacks %q:\n%s", want, merged)
+		}
+	}
+	if strings.Contains(merged, "old.sql") {
+		t.Errorf("merged config keeps the old queries:\n%s", merged)
+	}
+	if merged, err := mergeSQLCConfig(generated, generated); err != nil || merged != generated {
+		t.Errorf("unchanged config rewritten:\n%s", merged)
+	}
+}
@@ -0,0 +1,425 @@
+// sqldump.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dumpTypes maps column types found in pg_dump and mysqldump output to
+// Django field types, by type name without length or array suffix.
+var dumpTypes = map[string]string{
+	"smallint": "SmallIntegerField", "integer": "IntegerField", "int": "IntegerField", "bigint": "BigIntegerField",
+	"mediumint": "IntegerField", "serial": "IntegerField", "bigserial": "BigIntegerField", "smallserial": "SmallIntegerField",
+	"character varying": "CharField", "varchar": "CharField", "character": "CharField", "char": "CharField",
+	"text": "TextField", "mediumtext": "TextField", "longtext": "TextField",
+	"boolean": "BooleanField", "bool": "BooleanField",
+	"timestamp with time zone": "DateTimeField", "timestamp without time zone": "DateTimeField",
+	"timestamp": "DateTimeField", "datetime": "DateTimeField", "date": "DateField",
+	"time": "TimeField", "time without time zone": "TimeField",
+	"double precision": "FloatField", "double": "FloatField", "real": "FloatField", "float": "FloatField",
+	"numeric": "DecimalField", "decimal": "DecimalField",
+	"json": "JSONField", "jsonb": "JSONField", "uuid": "UUIDField", "char(32)": "UUIDField",
+	"bytea": "BinaryField", "blob": "BinaryField", "mediumblob": "BinaryField", "longblob": "BinaryField",
+	"inet": "GenericIPAddressField", "char(39)": "GenericIPAddressField",
+	"hstore": "HStoreField", "citext": "CITextField",
+	"int4range": "IntegerRangeField", "int8range": "BigIntegerRangeField", "numrange": "DecimalRangeField",
+	"daterange": "DateRangeField", "tstzrange": "DateTimeRangeField",
+}
+
+var (
+	dumpCreateTable = regexp.MustCompile(`(?is)^CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s*\((.*)\)[^)]*$`)
+	dumpAlterTable  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:ONLY\s+)?(\S+)\s+ADD\s+CONSTRAINT\s+(\S+)\s+(.*)$`)
+	dumpUniqueIndex = regexp.MustCompile(`(?is)^CREATE\s+UNIQUE\s+INDEX\s+(\S+)\s+ON\s+(?:ONLY\s+)?(\S+)\s+(?:USING\s+\w+\s*)?\((.*)\)$`)
+	dumpForeignKey  = regexp.MustCompile(`(?is)^FOREIGN\s+KEY\s*\(([^)]*)\)\s*REFERENCES\s+(\S+?)\s*\(([^)]*)\)`)
+	dumpKeyColumns  = regexp.MustCompile(`(?is)^(PRIMARY\s+KEY|UNIQUE(?:\s+(?:KEY|INDEX))?)\s*(\S*)\s*\(([^)]*)\)`)
+	dumpColumnType  = regexp.MustCompile(`(?is)^(.+?)(?:\s+(?:NOT|NULL|DEFAULT|PRIMARY|UNIQUE|REFERENCES|AUTO_INCREMENT|COLLATE|CHARACTER\s+SET|GENERATED|CHECK|COMMENT|CONSTRAINT)\b.*)?$`)
+	dumpLength      = regexp.MustCompile(`\((\d+)(?:,\s*(\d+))?\)`)
+	dumpDefault     = regexp.MustCompile(`(?i)\bDEFAULT\s+\(*('(?:[^']|'')*'|[\w.+-]+(?:\(\))?)`)
+)
+
+// dumpNumberTypes are the field types whose quoted defaults are numbers, as
+// mysqldump writes them.
+var dumpNumberTypes = wordSet(`SmallIntegerField IntegerField BigIntegerField PositiveSmallIntegerField
+	PositiveIntegerField PositiveBigIntegerField FloatField DecimalField`)
+
+// dumpTable accumulates what a dump says about one table.
+type dumpTable struct {
+	model   *Model
+	pk      []string
+	fks     map[string]string // column to referenced table
+	uniques [][]string
+	names   []string // unique constraint names, parallel to uniques
+}
+
+// parseSQLDump reverses the CREATE TABLE statements of a pg_dump or
+// mysqldump schema dump into models, with their column types and defaults, so that artifacts can be generated
+// from the real database schema. Table names are split into app and model
+// on the first underscore, as Django names them, and kept as db_table.
+func parseSQLDump(path string) (*Output, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]*dumpTable{}
+	var order []string
+	for _, stmt := range splitStatements(string(data)) {
+		sql := strings.TrimSpace(stmt.sql)
+		if m := dumpCreateTable.FindStringSubmatch(sql); m != nil {
+			name := unquoteIdent(m[1])
+			t := &dumpTable{fks: map[string]string{}}
+			app, model := dumpModelName(name)
+			t.model = &Model{Name: model, App: app, File: path, Line: stmt.line, Managed: true, DBTable: name}
+			for _, def := range splitTopLevel(m[2], ',') {
+				if err := t.addDefinition(strings.TrimSpace(def)); err != nil {
+					return nil, fmt.Errorf("%s:%d: %s: %w", path, stmt.line, name, err)
+				}
+			}
+			tables[name] = t
+			order = append(order, name)
+		} else if m := dumpAlterTable.FindStringSubmatch(sql); m != nil {
+			if t := tables[unquoteIdent(m[1])]; t != nil {
+				if err := t.addConstraint(unquoteIdent(m[2]), m[3]); err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", path, stmt.line, err)
+				}
+			}
+		} else if m := dumpUniqueIndex.FindStringSubmatch(sql); m != nil {
+			if t := tables[unquoteIdent(m[2])]; t != nil {
+				t.uniques = append(t.uniques, identList(m[3]))
+				t.names = append(t.names, unquoteIdent(m[1]))
+			}
+		}
+	}
+
+	out := &Output{}
+	for _, name := range order {
+		out.Models = append(out.Models, tables[name].build(tables))
+	}
+	return out, nil
+}
+
+// addDefinition handles one entry of a CREATE TABLE body.
+func (t *dumpTable) addDefinition(def string) error {
+	upper := strings.ToUpper(def)
+	switch {
+	case strings.HasPrefix(upper, "CONSTRAINT "):
+		parts := strings.SplitN(def, " ", 3)
+		if len(parts) < 3 {
+			return fmt.Errorf("malformed constraint %q", def)
+		}
+		return t.addConstraint(unquoteIdent(parts[1]), parts[2])
+	case strings.HasPrefix(upper, "PRIMARY KEY"), strings.HasPrefix(upper, "UNIQUE"), strings.HasPrefix(upper, "FOREIGN KEY"):
+		return t.addConstraint("", def)
+	case strings.HasPrefix(upper, "KEY "), strings.HasPrefix(upper, "INDEX "), strings.HasPrefix(upper, "FULLTEXT "),
+		strings.HasPrefix(upper, "CHECK "), strings.HasPrefix(upper, "EXCLUDE "):
+		return nil
+	}
+
+	name, rest, _ := strings.Cut(def, " ")
+	name = unquoteIdent(name)
+	ctype := strings.TrimSpace(dumpColumnType.FindStringSubmatch(rest)[1])
+	f := dumpFieldType(ctype)
+	f.Name, f.Nullable = name, !strings.Contains(strings.ToUpper(rest), "NOT NULL")
+	if m := dumpDefault.FindStringSubmatch(rest); m != nil {
+		f.Default, f.DefaultCallable = dumpDefaultValue(f, m[1])
+	}
+	upperRest := strings.ToUpper(rest)
+	if strings.Contains(upperRest, "PRIMARY KEY") {
+		t.pk = []string{name}
+		f.Nullable = false
+	}
+	if strings.Contains(upperRest, " UNIQUE") || strings.HasPrefix(upperRest, "UNIQUE") {
+		f.Unique = true
+	}
+	if i := strings.Index(upperRest, "REFERENCES "); i >= 0 {
+		ref := strings.Fields(rest[i+len("REFERENCES "):])
+		if len(ref) > 0 {
+			table, _, _ := strings.Cut(ref[0], "(")
+			t.fks[name] = unquoteIdent(table)
+		}
+	}
+	t.model.Fields = append(t.model.Fields, f)
+	return nil
+}
+
+// addConstraint handles a table constraint, inline or from ALTER TABLE.
+func (t *dumpTable) addConstraint(name, def string) error {
+	def = strings.TrimSpace(def)
+	if m := dumpForeignKey.FindStringSubmatch(def); m != nil {
+		cols := identList(m[1])
+		if len(cols) != 1 {
+			return fmt.Errorf("composite foreign key %s is not supported", name)
+		}
+		t.fks[cols[0]] = unquoteIdent(m[2])
+		return nil
+	}
+	m := dumpKeyColumns.FindStringSubmatch(def)
+	if m == nil {
+		return nil
+	}
+	cols := identList(m[3])
+	if strings.HasPrefix(strings.ToUpper(m[1]), "PRIMARY") {
+		t.pk = cols
+		return nil
+	}
+	if name == "" {
+		name = unquoteIdent(m[2])
+	}
+	t.uniques = append(t.uniques, cols)
+	t.names = append(t.names, name)
+	return nil
+}
+
+// build turns the table into a model: foreign key columns become relation
+// fields, a lone id primary key becomes Django's implicit one, and unique
+// keys become unique fields or constraints.
+func (t *dumpTable) build(tables map[string]*dumpTable) Model {
+	m := *t.model
+	fieldName := map[string]string{}
+	var fields []Field
+	for _, f := range m.Fields {
+		if len(t.pk) == 1 && t.pk[0] == "id" && f.Name == "id" {
+			for auto, integer := range autoTypes {
+				if f.Type == integer {
+					m.AutoField = auto
+				}
+			}
+			continue
+		}
+		if target, ok := t.fks[f.Name]; ok {
+			name := strings.TrimSuffix(f.Name, "_id")
+			related := target
+			if rt := tables[target]; rt != nil {
+				related = rt.model.Name
+			} else {
+				_, related = dumpModelName(target)
+			}
+			f = Field{Name: name, Type: "ForeignKey", Line: f.Line, Nullable: f.Nullable, Unique: f.Unique,
+				Relation: "foreignkey", RelatedTo: related}
+			if f.Unique {
+				f.Type, f.Relation, f.Unique = "OneToOneField", "one2one", false
+			}
+		}
+		fieldName[columnName(f)] = f.Name
+		fields = append(fields, f)
+	}
+	m.Fields = fields
+	if !(len(t.pk) == 1 && t.pk[0] == "id") {
+		for _, col := range t.pk {
+			m.PrimaryKey = append(m.PrimaryKey, fieldName[col])
+		}
+	}
+	for i, cols := range t.uniques {
+		if len(cols) == 1 {
+			for j := range m.Fields {
+				if columnName(m.Fields[j]) == cols[0] && m.Fields[j].Relation == "" {
+					m.Fields[j].Unique = true
+				}
+			}
+			continue
+		}
+		c := Constraint{Kind: "unique", Name: t.names[i]}
+		for _, col := range cols {
+			c.Fields = append(c.Fields, fieldName[col])
+		}
+		m.Constraints = append(m.Constraints, c)
+	}
+	return m
+}
+
+// dumpFieldType maps a dumped column type to a field of its Django type,
+// with the base type of arrays, the length of character types and the
+// precision and scale of decimals.
+func dumpFieldType(ctype string) Field {
+	ctype = strings.ToLower(strings.Join(strings.Fields(ctype), " "))
+	if strings.HasSuffix(ctype, "[]") {
+		return Field{Type: "ArrayField", BaseType: dumpFieldType(strings.TrimSuffix(ctype, "[]")).Type}
+	}
+	if ctype == "tinyint(1)" {
+		return Field{Type: "BooleanField"}
+	}
+	if t, ok := dumpTypes[ctype]; ok {
+		return Field{Type: t}
+	}
+	var size, scale int
+	if m := dumpLength.FindStringSubmatch(ctype); m != nil {
+		size, _ = strconv.Atoi(m[1])
+		scale, _ = strconv.Atoi(m[2])
+	}
+	name := strings.TrimSpace(dumpLength.ReplaceAllString(ctype, ""))
+	name, unsigned := strings.CutSuffix(name, " unsigned")
+	t, ok := dumpTypes[name]
+	if !ok {
+		return Field{Type: "TextField"}
+	}
+	if unsigned {
+		for positive, signed := range positiveTypes {
+			if signed == t {
+				t = positive
+			}
+		}
+	}
+	switch t {
+	case "CharField":
+		return Field{Type: t, MaxLength: size}
+	case "DecimalField":
+		return Field{Type: t, MaxDigits: size, DecimalPlaces: scale}
+	}
+	return Field{Type: t}
+}
+
+// dumpDefaultValue turns the DEFAULT of a dumped column into the literal
+// default or default callable of its field. Sequences and expressions
+// without a Django equivalent give neither.
+func dumpDefaultValue(f Field, def string) (any, string) {
+	if s, ok := strings.CutPrefix(def, "'"); ok {
+		s = strings.ReplaceAll(strings.TrimSuffix(s, "'"), "''", "'")
+		switch {
+		case f.Type == "BooleanField" && (s == "0" || s == "1" || s == "f" || s == "t"):
+			return s == "1" || s == "t", ""
+		case f.Type == "JSONField" && s == "{}":
+			return nil, "dict"
+		case f.Type == "JSONField" && s == "[]":
+			return nil, "list"
+		case dumpNumberTypes[f.Type]:
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n, ""
+			}
+		}
+		return s, ""
+	}
+	switch word := strings.ToLower(def); {
+	case word == "true" || word == "false":
+		return word == "true", ""
+	case word == "now()" || strings.HasPrefix(word, "current_"):
+		return nil, "timezone.now"
+	case word == "gen_random_uuid()" || word == "uuid()":
+		return nil, "uuid.uuid4"
+	}
+	if n, err := strconv.ParseFloat(def, 64); err == nil {
+		return n, ""
+	}
+	return nil, ""
+}
+
+// dumpModelName splits a Django table name such as blog_post into its app
+// label and a model name.
+func dumpModelName(table string) (app, model string) {
+	app, rest, ok := strings.Cut(table, "_")
+	if !ok {
+		return "", camel(table)
+	}
+	return app, camel(rest)
+}
+
+// unquoteIdent strips identifier quotes and a schema prefix.
+func unquoteIdent(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.Trim(s, "`\"")
+}
+
+// identList splits a parenthesized column list.
+func identList(s string) []string {
+	var cols []string
+	for _, c := range strings.Split(s, ",") {
+		// MySQL key parts may carry a prefix length, e.g. name(191).
+		c, _, _ = strings.Cut(strings.TrimSpace(c), "(")
+		cols = append(cols, unquoteIdent(strings.Fields(c + " ")[0]))
+	}
+	return cols
+}
+
+// dumpStatement is one SQL statement and the line it starts on.
+type dumpStatement struct {
+	sql  string
+	line int
+}
+
+// splitStatements splits a dump into statements, dropping comments and
+// respecting quoted strings and identifiers.
+func splitStatements(data string) []dumpStatement {
+	var stmts []dumpStatement
+	var sb strings.Builder
+	line, start := 1, 0
+	var quote byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if quote != 0 {
+			sb.WriteByte(c)
+			if c == '\n' {
+				line++
+			}
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '-' && strings.HasPrefix(data[i:], "--"), c == '#':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			i--
+			continue
+		case c == '/' && strings.HasPrefix(data[i:], "/*"):
+			end := strings.Index(data[i:], "*/")
+			if end < 0 {
+				end = len(data) - i
+			}
+			line += strings.Count(data[i:i+end], "\n")
+			i += end + 1
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ';':
+			if s := strings.TrimSpace(sb.String()); s != "" {
+				stmts = append(stmts, dumpStatement{s, start})
+			}
+			sb.Reset()
+			continue
+		case c == '\n':
+			line++
+		}
+		if sb.Len() == 0 {
+			if c <= ' ' {
+				continue
+			}
+			start = line
+		}
+		if c == '\n' || c == '\t' || c == '\r' {
+			c = ' '
+		}
+		sb.WriteByte(c)
+	}
+	return stmts
+}
+
+// splitTopLevel splits s on sep outside parentheses and quotes.
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, last := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			if end := strings.IndexByte(s[i+1:], s[i]); end >= 0 {
+				i += end + 1
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, s[last:])
+}
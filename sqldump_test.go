@@ -0,0 +1,184 @@
+// sqldump_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestParseSQLDump reads a pg_dump style schema and checks the models it
+// reverses: implicit ids, foreign keys, one-to-ones, composite keys and
+// unique constraints.
+func TestParseSQLDump(t *testing.T) {
+	dump := `--
+-- PostgreSQL database dump
+--
+
+CREATE TABLE public.blog_author (
+    id integer NOT NULL,
+    name character varying(100) NOT NULL,
+    email character varying(254) NOT NULL
+);
+
+CREATE TABLE public.blog_post (
+    id bigint NOT NULL,
+    title text NOT NULL,
+    author_id integer,
+    tags character varying(20)[] NOT NULL,
+    published timestamp with time zone NOT NULL
+);
+
+CREATE TABLE public.blog_profile (
+    id integer PRIMARY KEY,
+    author_id integer NOT NULL UNIQUE REFERENCES public.blog_author(id)
+);
+
+CREATE TABLE public.blog_vote (
+    post_id bigint NOT NULL,
+    author_id integer NOT NULL,
+    CONSTRAINT blog_vote_pkey PRIMARY KEY (post_id, author_id)
+);
+
+ALTER TABLE ONLY public.blog_author ADD CONSTRAINT blog_author_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.blog_post ADD CONSTRAINT blog_post_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.blog_post ADD CONSTRAINT blog_post_author_fk FOREIGN KEY (author_id) REFERENCES public.blog_author(id);
+CREATE UNIQUE INDEX blog_author_email_uniq ON public.blog_author USING btree (email);
+CREATE UNIQUE INDEX blog_post_title_published ON public.blog_post USING btree (title, published);
+`
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := parseSQLDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range out.Models {
+		var fields []string
+		for _, f := range m.Fields {
+			desc := f.Name + ":" + f.Type
+			if f.RelatedTo != "" {
+				desc += "->" + f.RelatedTo
+			}
+			if f.Unique {
+				desc += "!"
+			}
+			fields = append(fields, desc)
+		}
+		got = append(got, m.App+"."+m.Name+"("+strings.Join(fields, " ")+") pk="+strings.Join(m.PrimaryKey, ","))
+	}
+	want := []string{
+		"blog.Author(name:CharField email:CharField!) pk=",
+		"blog.Post(title:TextField author:ForeignKey->Author tags:ArrayField published:DateTimeField) pk=",
+		"blog.Profile(author:OneToOneField->Author) pk=",
+		"blog.Vote(post_id:BigIntegerField author_id:IntegerField) pk=post_id,author_id",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("models:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	post := out.Models[1]
+	if post.DBTable != "blog_post" || post.Line != 11 || post.Fields[2].BaseType != "CharField" {
+		t.Errorf("post = table %s line %d, tags base %s", post.DBTable, post.Line, post.Fields[2].BaseType)
+	}
+	if c := post.Constraints; len(c) != 1 || c[0].Name != "blog_post_title_published" || strings.Join(c[0].Fields, ",") != "title,published" {
+		t.Errorf("post constraints = %+v", c)
+	}
+	if out.Models[0].Fields[0].MaxLength != 100 {
+		t.Errorf("name max_length = %d", out.Models[0].Fields[0].MaxLength)
+	}
+}
+
+// TestParseMySQLDump checks mysqldump quoting, inline keys and tinyint(1)
+// booleans.
+func TestParseMySQLDump(t *testing.T) {
+	dump := "/*!40101 SET NAMES utf8mb4 */;\n" +
+		"CREATE TABLE `shop_item` (\n" +
+		"  `id` int NOT NULL AUTO_INCREMENT,\n" +
+		"  `sku` varchar(32) NOT NULL,\n" +
+		"  `active` tinyint(1) NOT NULL DEFAULT '1',\n" +
+		"  `note` longtext COMMENT 'it''s; fine',\n" +
+		"  PRIMARY KEY (`id`),\n" +
+		"  UNIQUE KEY `shop_item_sku_uniq` (`sku`),\n" +
+		"  KEY `shop_item_active` (`active`)\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := parseSQLDump(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Models) != 1 {
+		t.Fatalf("models = %+v", out.Models)
+	}
+	fields := out.Models[0].Fields
+	if len(fields) != 3 || !fields[0].Unique || fields[0].MaxLength != 32 || fields[1].Type != "BooleanField" ||
+		fields[2].Type != "TextField" || !fields[2].Nullable {
+		t.Errorf("fields = %+v", fields)
+	}
+}
+
+// TestSQLDumpRoundTrip checks that the schema read back from a dump of the
+// generated SQL keeps the precision and scale of decimals and the column
+// defaults, generating the same SQL again.
+func TestSQLDumpRoundTrip(t *testing.T) {
+	models := []Model{{Name: "Item", App: "shop", Managed: true, Fields: []Field{
+		{Name: "price", Type: "DecimalField", MaxDigits: 10, DecimalPlaces: 2, Default: 9.5},
+		{Name: "name", Type: "CharField", MaxLength: 20, Default: "a, 'b'"},
+		{Name: "stock", Type: "IntegerField", Default: float64(0)},
+		{Name: "active", Type: "BooleanField", Default: true},
+		{Name: "added", Type: "DateTimeField", DefaultCallable: "timezone.now"},
+	}}}
+	for _, dialect := range []string{"postgres", "mysql"} {
+		opts := Options{Dialect: dialect}
+		sql := generateSQL(prepareModels(models, opts), opts)
+		path := filepath.Join(t.TempDir(), "schema.sql")
+		if err := os.WriteFile(path, []byte(sql), 0644); err != nil {
+			t.Fatal(err)
+		}
+		out, err := parseSQLDump(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out.Models) != 1 {
+			t.Fatalf("%s: read %d models, want 1", dialect, len(out.Models))
+		}
+		price := out.Models[0].Fields[0]
+		if price.MaxDigits != 10 || price.DecimalPlaces != 2 || price.Default != 9.5 {
+			t.Errorf("%s: price = numeric(%d,%d) default %v, want numeric(10,2) default 9.5",
+				dialect, price.MaxDigits, price.DecimalPlaces, price.Default)
+		}
+		if got, want := sqlStatements(generateSQL(prepareModels(out.Models, opts), opts)), sqlStatements(sql); got != want {
+			t.Errorf("%s: regenerated\n%s\nwant\n%s", dialect, got, want)
+		}
+	}
+}
+
+// prepareModels names the tables of a copy of the models and sets their
+// column types for the dialect, as a run does before generating SQL.
+func prepareModels(models []Model, opts Options) []Model {
+	models = slices.Clone(models)
+	for i := range models {
+		models[i].Fields = slices.Clone(models[i].Fields)
+	}
+	applyTableNames(models, false)
+	applyDialectTypes(&Config{}, models, opts)
+	return models
+}
+
+// sqlStatements returns the SQL without its comments.
+func sqlStatements(sql string) string {
+	var lines []string
+	for _, line := range strings.Split(sql, "\n") {
+		code, _, _ := splitComment(line, 0)
+		if code = strings.TrimRight(code, " "); code != "" {
+			lines = append(lines, code)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,167 @@
+// sqlstyle.go
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SQLStyle is the layout of the generated SQL, set in the config's sql_style
+// section to match a team's conventions. The zero value is the generator's
+// own style: upper case keywords, four-space indents and trailing commas.
+// keyword_case: upper keeps the generator's keywords as they are; only lower
+// rewrites them, since upper casing could not tell keywords from names such
+// as a column called text.
+type SQLStyle struct {
+	KeywordCase string `yaml:"keyword_case"` // upper or lower
+	Indent      int    `yaml:"indent"`       // spaces per indent level
+	Commas      string `yaml:"commas"`       // trailing or leading
+}
+
+// generatorIndent is the indent width the generators write.
+const generatorIndent = 4
+
+// sqlKeywords are the keywords and type names the generators write in upper
+// case. Identifiers are always written in lower case, so changing the case
+// of these words never touches a name.
+var sqlKeywords = wordSet(`
+	ADD ALTER ALWAYS AND ANY AS ASC AUTO_INCREMENT BY CASE CHECK COLUMN COMMENT CONCAT CONFLICT
+	CONSTRAINT COUNT CREATE DEFAULT DEFERRABLE DEFERRED DELETE DESC DISTINCT
+	DO DROP DUPLICATE ELSE END ENUM EXCLUDE EXISTS EXTENSION FALSE FOREIGN FROM
+	GENERATED GROUP HAVING IF ILIKE IMMEDIATE IN INDEX INITIALLY INNER INSERT
+	INTO IS JOIN KEY LEFT LIKE LIMIT MODIFY NOT NOTHING NULL OFFSET ON OR
+	ORDER PRIMARY REFERENCES REGEXP RENAME RETURNING SELECT SET STORED TABLE THEN
+	TO TRUE TYPE UNIQUE UNSIGNED UPDATE USING VALUES VIEW WHEN WHERE WITH ZONE
+	BIGINT BLOB BOOLEAN BYTEA DATE DATETIME DOUBLE FLOAT INET INTEGER JSON JSONB
+	LONGBLOB NUMERIC PRECISION REAL SERIAL SMALLINT TEXT TIME TIMESTAMP UUID VARCHAR
+	BIGSERIAL SMALLSERIAL
+	BEFORE BEGIN CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP EACH EXECUTE FOR
+	FUNCTION LANGUAGE REPLACE RETURN RETURNS ROW TRIGGER
+	GEOMETRY GEOMETRYCOLLECTION LINESTRING MULTILINESTRING MULTIPOINT
+	MULTIPOLYGON POINT POLYGON SPATIAL SRID`)
+
+// wordSet returns the set of the space-separated words.
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// validate checks the style's settings.
+func (s SQLStyle) validate() error {
+	if s.KeywordCase != "" && s.KeywordCase != "upper" && s.KeywordCase != "lower" {
+		return fmt.Errorf("sql_style: keyword_case must be upper or lower, not %q", s.KeywordCase)
+	}
+	if s.Commas != "" && s.Commas != "trailing" && s.Commas != "leading" {
+		return fmt.Errorf("sql_style: commas must be trailing or leading, not %q", s.Commas)
+	}
+	if s.Indent < 0 {
+		return fmt.Errorf("sql_style: indent must not be negative")
+	}
+	return nil
+}
+
+// format lays out generated SQL in the style. Only keyword case, indent
+// width and comma placement change, never the number of lines, so line
+// numbers reported for the generated SQL stay valid.
+func (s SQLStyle) format(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		if s.KeywordCase == "lower" {
+			line = lowerKeywords(line)
+		}
+		if s.Indent > 0 && s.Indent != generatorIndent {
+			content := strings.TrimLeft(line, " ")
+			if n := len(line) - len(content); n%generatorIndent == 0 {
+				line = strings.Repeat(" ", n/generatorIndent*s.Indent) + content
+			}
+		}
+		lines[i] = line
+	}
+	if s.Commas == "leading" {
+		// quote is the string literal or quoted identifier left open by the
+		// lines so far, whose commas are not separators.
+		var quote byte
+		for i := 0; i+1 < len(lines); i++ {
+			// A trailing comment follows the comma.
+			code, note, open := splitComment(lines[i], quote)
+			quote = open
+			line := strings.TrimRight(code, " ")
+			next := lines[i+1]
+			content := strings.TrimLeft(next, " ")
+			if open != 0 || !strings.HasSuffix(line, ",") || content == "" {
+				continue
+			}
+			lines[i] = strings.TrimSuffix(line, ",") + code[len(line):] + note
+			lines[i+1] = next[:len(next)-len(content)] + ", " + content
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitComment splits a line into its code and its trailing comment, if any,
+// outside string literals and quoted identifiers. quote is the quote left
+// open by the previous lines, and open the one the line leaves open.
+func splitComment(line string, quote byte) (code, comment string, open byte) {
+	i := 0
+	if quote != 0 {
+		end := strings.IndexByte(line, quote)
+		if end < 0 {
+			return line, "", quote
+		}
+		i = end + 1
+	}
+	for ; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '-' && strings.HasPrefix(line[i:], "--"):
+			return line[:i], line[i:], 0
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(line[i+1:], c)
+			if end < 0 {
+				return line, "", c
+			}
+			i += end + 1
+		}
+	}
+	return line, "", 0
+}
+
+// lowerKeywords lower cases the keywords of a line outside string literals,
+// quoted identifiers and comments.
+func lowerKeywords(line string) string {
+	var sb strings.Builder
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == '-' && strings.HasPrefix(line[i:], "--"):
+			sb.WriteString(line[i:])
+			return sb.String()
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(line[i+1:], c)
+			if end < 0 {
+				sb.WriteString(line[i:])
+				return sb.String()
+			}
+			sb.WriteString(line[i : i+end+2])
+			i += end + 2
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(line) && (line[j] == '_' || line[j] == '.' || unicode.IsLetter(rune(line[j])) || unicode.IsDigit(rune(line[j]))) {
+				j++
+			}
+			if word := line[i:j]; sqlKeywords[word] {
+				sb.WriteString(strings.ToLower(word))
+			} else {
+				sb.WriteString(word)
+			}
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,66 @@
+// sqlstyle_test.go
+package main
+
+import "testing"
+
+// TestKeywordCase checks that upper keeps the generated SQL as it is and
+// lower re-cases only the keywords.
+func TestKeywordCase(t *testing.T) {
+	sql := "SELECT text FROM post WHERE title = 'NOT NULL'; -- SELECT"
+	if got := (SQLStyle{KeywordCase: "upper"}).format(sql); got != sql {
+		t.Errorf("upper = %q, want it unchanged", got)
+	}
+	want := "select text from post where title = 'NOT NULL'; -- SELECT"
+	if got := (SQLStyle{KeywordCase: "lower"}).format(sql); got != want {
+		t.Errorf("lower = %q, want %q", got, want)
+	}
+}
+
+// TestIndentAndCommas checks that the indent is rescaled per level and
+// that leading commas keep the number of lines and trailing comments.
+func TestIndentAndCommas(t *testing.T) {
+	sql := "CREATE TABLE post (\n" +
+		"    id SERIAL PRIMARY KEY, -- the key\n" +
+		"    title TEXT\n" +
+		");"
+	want := "CREATE TABLE post (\n" +
+		"  id SERIAL PRIMARY KEY -- the key\n" +
+		"  , title TEXT\n" +
+		");"
+	if got := (SQLStyle{Indent: 2, Commas: "leading"}).format(sql); got != want {
+		t.Errorf("format =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSQLStyleValidate(t *testing.T) {
+	for _, s := range []SQLStyle{{KeywordCase: "title"}, {Commas: "none"}, {Indent: -1}} {
+		if err := s.validate(); err == nil {
+			t.Errorf("%+v validated", s)
+		}
+	}
+	if err := (SQLStyle{KeywordCase: "lower", Indent: 2, Commas: "leading"}).validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestLeadingCommas checks that leading commas move the commas separating
+// items, keeping trailing comments, and leave those inside string literals.
+func TestLeadingCommas(t *testing.T) {
+	sql := "CREATE TABLE post (\n" +
+		"    id SERIAL PRIMARY KEY, -- the key\n" +
+		"    title TEXT DEFAULT 'a,b',\n" +
+		"    body TEXT DEFAULT 'first,\n" +
+		"second',\n" +
+		"    note TEXT\n" +
+		");"
+	want := "CREATE TABLE post (\n" +
+		"    id SERIAL PRIMARY KEY -- the key\n" +
+		"    , title TEXT DEFAULT 'a,b'\n" +
+		"    , body TEXT DEFAULT 'first,\n" +
+		"second'\n" +
+		"    , note TEXT\n" +
+		");"
+	if got := (SQLStyle{Commas: "leading"}).format(sql); got != want {
+		t.Errorf("format =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,42 @@
+// storage.go
+package main
+
+import "strings"
+
+// storageNote returns the note recording where a FileField or ImageField
+// stores its files, so the Go port can rebuild their URLs from the stored
+// paths, or "" when upload_to is not a literal prefix.
+func storageNote(f Field) string {
+	if f.UploadTo == "" || (f.Type != "FileField" && f.Type != "ImageField") {
+		return ""
+	}
+	return "upload_to: " + f.UploadTo
+}
+
+// columnNote returns the notes on a field's column: where its files are
+// stored and the labels of its choices.
+func columnNote(f Field) string {
+	var notes []string
+	for _, note := range []string{storageNote(f), choicesNote(f)} {
+		if note != "" {
+			notes = append(notes, note)
+		}
+	}
+	return strings.Join(notes, "; ")
+}
+
+// columnComments returns the COMMENT ON COLUMN statements carrying the
+// column notes of the model's fields on PostgreSQL, which sqlc copies to the
+// fields of the generated structs. MySQL columns carry them inline.
+func columnComments(m Model, dialect string) []string {
+	if dialect != "postgres" {
+		return nil
+	}
+	var stmts []string
+	for _, f := range m.Fields {
+		if note := columnNote(f); note != "" {
+			stmts = append(stmts, "COMMENT ON COLUMN "+m.table()+"."+columnName(f)+" IS "+value(Arg{Literal: true, Value: note}, "", nil)+";")
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,35 @@
+// storage_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestStorageNotes checks the VARCHAR column of file fields and where each
+// dialect records their upload_to.
+func TestStorageNotes(t *testing.T) {
+	schema := func(dialect string) string {
+		models := []Model{{Name: "Photo", App: "gallery", Managed: true, Fields: []Field{
+			{Name: "image", Type: "ImageField", UploadTo: "photos/"},
+			{Name: "raw", Type: "FileField"},
+		}}}
+		if diags := normalize(&Config{}, models, Options{Dialect: dialect, NoAppPrefix: true}); len(diags) != 0 {
+			t.Fatal(diags)
+		}
+		return generateSQL(models, Options{Dialect: dialect})
+	}
+	pg := schema("postgres")
+	for _, want := range []string{"image VARCHAR(100) NOT NULL", "raw VARCHAR(100) NOT NULL", "COMMENT ON COLUMN photo.image IS 'upload_to: photos/';"} {
+		if !strings.Contains(pg, want) {
+			t.Errorf("postgres missing %q:\n%s", want, pg)
+		}
+	}
+	if strings.Count(pg, "COMMENT ON") != 1 {
+		t.Errorf("want one column comment:\n%s", pg)
+	}
+	my := schema("mysql")
+	if !strings.Contains(my, "image VARCHAR(100) NOT NULL COMMENT 'upload_to: photos/'") || strings.Contains(my, "COMMENT ON") {
+		t.Errorf("mysql column comment:\n%s", my)
+	}
+}
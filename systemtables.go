@@ -0,0 +1,156 @@
+// systemtables.go
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Settings holds the Django settings the generator cares about, read from
+// settings modules.
+type Settings struct {
+	SessionEngine string   `json:"session_engine"`
+	InstalledApps []string `json:"installed_apps"`
+	// InstalledAppsUnresolved is set when INSTALLED_APPS is computed in a
+	// way the parser cannot follow, leaving InstalledApps incomplete.
+	InstalledAppsUnresolved bool `json:"installed_apps_unresolved"`
+	// CacheTables lists the LOCATION of every DatabaseCache backend.
+	CacheTables []string `json:"cache_tables"`
+	// SharedApps and TenantApps are django-tenants' split of the apps
+	// between the public schema and the schema of every tenant.
+	SharedApps []string `json:"shared_apps"`
+	TenantApps []string `json:"tenant_apps"`
+	// ConnMaxAge is the default database's CONN_MAX_AGE in seconds, -1
+	// for None (connections are never closed).
+	ConnMaxAge int `json:"conn_max_age"`
+	// UseTZ is USE_TZ, nil when the settings leave it to Django's default.
+	UseTZ *bool `json:"use_tz"`
+	// DjangoVersion is the Django version the project's requirements pin,
+	// or the one set in the config.
+	DjangoVersion string `json:"django_version"`
+}
+
+// django returns the project's Django version.
+func (s Settings) django() djangoVersion {
+	v, _ := parseDjangoVersion(s.DjangoVersion)
+	return v
+}
+
+// useTZ reports whether datetimes are stored aware, USE_TZ defaulting to
+// True since Django 5.0.
+func (s Settings) useTZ() bool {
+	if s.UseTZ == nil {
+		return !s.django().before(5, 0)
+	}
+	return *s.UseTZ
+}
+
+// dbSessions reports whether sessions are stored in the database, which is
+// Django's default when django.contrib.sessions is installed.
+func (s Settings) dbSessions() bool {
+	switch s.SessionEngine {
+	case "django.contrib.sessions.backends.db", "django.contrib.sessions.backends.cached_db":
+		return true
+	case "":
+		return slices.Contains(s.InstalledApps, "django.contrib.sessions")
+	}
+	return false
+}
+
+// systemTable is a key/value table owned by a Django subsystem rather than
+// a model: django_session, or a DatabaseCache table.
+type systemTable struct {
+	Table   string
+	Key     string
+	KeySize int
+	Value   string
+	Expires string
+	// Entity and Entities name the rows in the generated queries.
+	Entity   string
+	Entities string
+}
+
+// systemTables returns the subsystem tables requested with --sessions and
+// --cache, and warnings for subsystems the settings use but were not
+// requested, or requested but not configured.
+func systemTables(s Settings, sessions, cache bool) ([]systemTable, []Diagnostic) {
+	var tables []systemTable
+	var diags []Diagnostic
+	warn := func(code, format string, args ...any) {
+		diags = append(diags, Diagnostic{Code: code, Severity: severity(code), Message: fmt.Sprintf(format, args...)})
+	}
+	if sessions {
+		tables = append(tables, systemTable{
+			Table: "django_session", Key: "session_key", KeySize: 40, Value: "session_data", Expires: "expire_date",
+			Entity: "Session", Entities: "Sessions",
+		})
+	} else if s.dbSessions() {
+		warn(codeSessionTable, "sessions are stored in the database; pass --sessions to generate django_session")
+	}
+	if cache {
+		if len(s.CacheTables) == 0 {
+			warn(codeCacheTable, "--cache given but no DatabaseCache backend found in CACHES")
+		}
+		for _, t := range s.CacheTables {
+			entity := camel(toSnake(t))
+			tables = append(tables, systemTable{
+				Table: t, Key: "cache_key", KeySize: 255, Value: "value", Expires: "expires",
+				Entity: entity + "Entry", Entities: entity + "Entries",
+			})
+		}
+	} else if len(s.CacheTables) > 0 {
+		warn(codeCacheTable, "DatabaseCache uses %s; pass --cache to generate the cache tables", strings.Join(s.CacheTables, ", "))
+	}
+	return tables, diags
+}
+
+// systemTablesSQL renders the CREATE TABLE statements for the tables, as
+// Django's migrate and createcachetable commands would.
+func systemTablesSQL(tables []systemTable) string {
+	var sb strings.Builder
+	for _, t := range tables {
+		sb.WriteString(fmt.Sprintf("CREATE TABLE %s (\n    %s VARCHAR(%d) PRIMARY KEY,\n    %s TEXT NOT NULL,\n    %s TIMESTAMP NOT NULL\n);\n\n",
+			t.Table, t.Key, t.KeySize, t.Value, t.Expires))
+		sb.WriteString(fmt.Sprintf("CREATE INDEX %s_%s ON %s (%s);\n\n", t.Table, t.Expires, t.Table, t.Expires))
+	}
+	return sb.String()
+}
+
+// systemTablesDownSQL renders the DROP TABLE statements for the tables.
+func systemTablesDownSQL(tables []systemTable) string {
+	var sb strings.Builder
+	for _, t := range tables {
+		sb.WriteString("DROP TABLE IF EXISTS " + t.Table + ";\n")
+	}
+	return sb.String()
+}
+
+// systemQueries generates the get, set, delete and expiry queries the
+// subsystems need.
+func systemQueries(tables []systemTable, dialect string) []TranslatedQuery {
+	var result []TranslatedQuery
+	for _, t := range tables {
+		upsert := fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s, %s = EXCLUDED.%s",
+			t.Key, t.Value, t.Value, t.Expires, t.Expires)
+		if dialect == "mysql" {
+			upsert = fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = VALUES(%s), %s = VALUES(%s)",
+				t.Value, t.Value, t.Expires, t.Expires)
+		}
+		query := func(name, kind, sql string) TranslatedQuery {
+			return TranslatedQuery{
+				Query: Query{Source: t.Table + " table"},
+				Name:  name, Kind: kind, SQL: sql,
+			}
+		}
+		result = append(result,
+			query("Get"+t.Entity, ":one", fmt.Sprintf("SELECT %s, %s, %s FROM %s WHERE %s = sqlc.arg(%s) AND %s > NOW();",
+				t.Key, t.Value, t.Expires, t.Table, t.Key, t.Key, t.Expires)),
+			query("Set"+t.Entity, ":exec", fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (sqlc.arg(%s), sqlc.arg(%s), sqlc.arg(%s)) %s;",
+				t.Table, t.Key, t.Value, t.Expires, t.Key, t.Value, t.Expires, upsert)),
+			query("Delete"+t.Entity, ":exec", fmt.Sprintf("DELETE FROM %s WHERE %s = sqlc.arg(%s);", t.Table, t.Key, t.Key)),
+			query("DeleteExpired"+t.Entities, ":exec", fmt.Sprintf("DELETE FROM %s WHERE %s < NOW();", t.Table, t.Expires)),
+		)
+	}
+	return result
+}
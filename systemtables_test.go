@@ -0,0 +1,63 @@
+// systemtables_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSystemTables parses session and cache settings and checks the tables,
+// queries and warnings for --sessions and --cache.
+func TestSystemTables(t *testing.T) {
+	out := parseProject(t, map[string]string{"mysite/settings.py": `INSTALLED_APPS = ["django.contrib.sessions", "blog"]
+
+CACHES = {
+    "default": {
+        "BACKEND": "django.core.cache.backends.db.DatabaseCache",
+        "LOCATION": "site_cache",
+    },
+    "local": {"BACKEND": "django.core.cache.backends.locmem.LocMemCache"},
+}
+`})
+	if !out.Settings.dbSessions() || strings.Join(out.Settings.CacheTables, ",") != "site_cache" {
+		t.Fatalf("settings = %+v", out.Settings)
+	}
+
+	tables, diags := systemTables(out.Settings, false, false)
+	if len(tables) != 0 || len(diags) != 2 || diags[0].Code != codeSessionTable || diags[1].Code != codeCacheTable {
+		t.Errorf("without flags: tables %v, diagnostics %v", tables, diags)
+	}
+
+	tables, diags = systemTables(out.Settings, true, true)
+	if len(diags) != 0 {
+		t.Errorf("diagnostics = %v", diags)
+	}
+	sql := systemTablesSQL(tables)
+	for _, want := range []string{
+		"CREATE TABLE django_session (\n    session_key VARCHAR(40) PRIMARY KEY,",
+		"CREATE INDEX django_session_expire_date ON django_session (expire_date);",
+		"CREATE TABLE site_cache (\n    cache_key VARCHAR(255) PRIMARY KEY,",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("missing %q in:\n%s", want, sql)
+		}
+	}
+
+	var names []string
+	for _, q := range systemQueries(tables, "mysql") {
+		names = append(names, q.Name)
+		if q.Name == "SetSiteCacheEntry" && !strings.Contains(q.SQL, "ON DUPLICATE KEY UPDATE value = VALUES(value)") {
+			t.Errorf("mysql upsert = %q", q.SQL)
+		}
+	}
+	if got := strings.Join(names, ","); got != "GetSession,SetSession,DeleteSession,DeleteExpiredSessions,GetSiteCacheEntry,SetSiteCacheEntry,DeleteSiteCacheEntry,DeleteExpiredSiteCacheEntries" {
+		t.Errorf("queries = %s", got)
+	}
+}
+
+func TestCacheWithoutDatabaseCache(t *testing.T) {
+	_, diags := systemTables(Settings{SessionEngine: "django.contrib.sessions.backends.cache"}, false, true)
+	if len(diags) != 1 || diags[0].Code != codeCacheTable {
+		t.Errorf("diagnostics = %v", diags)
+	}
+}
@@ -0,0 +1,98 @@
+// table_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// dbTableModels parses a Post model, with the Meta.db_table given, and a
+// Comment model referencing it.
+func dbTableModels(t *testing.T, dbTable string) []Model {
+	t.Helper()
+	meta := ""
+	if dbTable != "" {
+		meta = "\n    class Meta:\n        db_table = \"" + dbTable + "\"\n"
+	}
+	out := parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    title = models.CharField(max_length=100)
+` + meta + `
+class Comment(models.Model):
+    post = models.ForeignKey(Post, on_delete=models.CASCADE)
+`,
+	})
+	if diags := normalize(&Config{}, out.Models, Options{Dialect: "postgres"}); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	return out.Models
+}
+
+// TestDBTable checks that tables and the foreign keys referencing them are
+// named after Meta.db_table, and that changing it renames the table.
+func TestDBTable(t *testing.T) {
+	opts := Options{Dialect: "postgres"}
+	models := dbTableModels(t, "blog_articles")
+	sql := generateSQL(models, opts)
+	for _, want := range []string{"CREATE TABLE blog_articles (", "REFERENCES blog_articles(id)"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "CREATE TABLE blog_post ") {
+		t.Errorf("table named after the model:\n%s", sql)
+	}
+
+	steps := diffModels(dbTableModels(t, ""), models, nil, opts)
+	if len(steps) == 0 || !strings.Contains(steps[0].up, "ALTER TABLE blog_post RENAME TO blog_articles;") || !strings.Contains(steps[0].up, "(db_table)") {
+		t.Errorf("steps = %+v", steps)
+	}
+}
+
+// TestAppTablePrefix checks that tables are named after the app label and
+// the lowercased model name like Django, after an AppConfig.label when the
+// app sets one, and after the model alone with --no-app-prefix.
+func TestAppTablePrefix(t *testing.T) {
+	files := map[string]string{
+		"blog/models.py": `from django.db import models
+
+class BlogPost(models.Model):
+    title = models.CharField(max_length=100)
+
+    class Meta:
+        constraints = [models.UniqueConstraint(fields=["title"], name="%(app_label)s_title")]
+`,
+		"shop/apps.py": `from django.apps import AppConfig
+
+class ShopConfig(AppConfig):
+    name = "shop"
+    label = "store"
+`,
+		"shop/models.py": `from django.db import models
+
+class OrderItem(models.Model):
+    post = models.ForeignKey("blog.BlogPost", on_delete=models.CASCADE)
+`,
+	}
+	for _, c := range []struct {
+		noAppPrefix bool
+		want        []string
+	}{
+		{false, []string{"CREATE TABLE blog_blogpost (", "CREATE TABLE store_orderitem (", "REFERENCES blog_blogpost(id)", "CONSTRAINT blog_title UNIQUE"}},
+		{true, []string{"CREATE TABLE blogpost (", "CREATE TABLE orderitem (", "REFERENCES blogpost(id)"}},
+	} {
+		out := parseProject(t, files)
+		opts := Options{Dialect: "postgres", NoAppPrefix: c.noAppPrefix}
+		if diags := normalize(&Config{}, out.Models, opts); len(diags) != 0 {
+			t.Fatal(diags)
+		}
+		sql := generateSQL(out.Models, opts)
+		for _, want := range c.want {
+			if !strings.Contains(sql, want) {
+				t.Errorf("no_app_prefix=%v: schema lacks %q:\n%s", c.noAppPrefix, want, sql)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// tableorder.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tablePlan is the order the tables of a set of models are created in, so
+// that every foreign key references a table that exists already.
+type tablePlan struct {
+	// models are the models with a table, each after the tables its
+	// foreign keys reference, and otherwise in their own order.
+	models []Model
+	// laterKeys are the foreign keys to tables created after their own, as
+	// in a cycle of foreign keys, added once all tables exist.
+	laterKeys []laterKey
+	// laterJoins holds the names of the join tables created last, those
+	// referencing a table created after their model's.
+	laterJoins map[string]bool
+}
+
+// laterKey is a foreign key added to its table with ALTER TABLE.
+type laterKey struct {
+	table string
+	field Field
+}
+
+// name returns the name of the constraint, the one PostgreSQL would give
+// it, so that the down migration can drop it.
+func (k laterKey) name() string {
+	return k.table + "_" + strings.ReplaceAll(columnList(fieldColumns(k.field)), ", ", "_") + "_fkey"
+}
+
+// addSQL renders the ALTER TABLE adding the foreign key.
+func (k laterKey) addSQL() string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s(%s);",
+		k.table, k.name(), columnList(fieldColumns(k.field)), k.field.relatedTable(), columnList(k.field.RelatedPK))
+}
+
+// dropSQL renders the ALTER TABLE dropping the foreign key.
+func (k laterKey) dropSQL(dialect string) string {
+	if dialect == "mysql" {
+		return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s;", k.table, k.name())
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", k.table, k.name())
+}
+
+// foreignKey reports whether the field is a foreign key column.
+func foreignKey(f Field) bool {
+	return f.Relation == "foreignkey" || f.Relation == "one2one"
+}
+
+// planTables orders the tables of the models for creation. Tables not among
+// the models, such as those of unmanaged models, are taken to exist.
+func planTables(models []Model) tablePlan {
+	plan := tablePlan{laterJoins: map[string]bool{}}
+	tables := map[string]bool{}
+	var remaining []Model
+	for _, m := range models {
+		if !m.isView() {
+			tables[m.table()] = true
+			remaining = append(remaining, m)
+		}
+	}
+	created := map[string]bool{}
+	ready := func(m Model) bool {
+		for _, f := range m.Fields {
+			if target := f.relatedTable(); foreignKey(f) && tables[target] && !created[target] && target != m.table() {
+				return false
+			}
+		}
+		return true
+	}
+	for len(remaining) > 0 {
+		// The first model whose references exist, or the first one left
+		// when they form a cycle.
+		next := 0
+		for i, m := range remaining {
+			if ready(m) {
+				next = i
+				break
+			}
+		}
+		m := remaining[next]
+		remaining = append(remaining[:next:next], remaining[next+1:]...)
+		created[m.table()] = true
+		for _, f := range m.Fields {
+			if target := f.relatedTable(); foreignKey(f) && tables[target] && !created[target] {
+				plan.laterKeys = append(plan.laterKeys, laterKey{table: m.table(), field: f})
+			}
+			if target := f.relatedTable(); f.Relation == "many2many" && tables[target] && !created[target] {
+				plan.laterJoins[joinTableName(m, f)] = true
+			}
+		}
+		plan.models = append(plan.models, m)
+	}
+	return plan
+}
+
+// deferred reports whether the foreign key of the field is added later.
+func (p tablePlan) deferred(m Model, f Field) bool {
+	for _, k := range p.laterKeys {
+		if k.table == m.table() && k.field.Name == f.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+// tableorder_test.go
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// forwardModels returns models declared before the tables they reference:
+// Book references Author and Tag, declared after it, and Author references
+// Book back.
+func forwardModels() []Model {
+	id := Field{Name: "id", Type: "BigAutoField", PrimaryKey: true}
+	models := []Model{
+		{Name: "Book", App: "books", Managed: true, Fields: []Field{
+			id,
+			{Name: "author", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Author"},
+			{Name: "tags", Type: "ManyToManyField", Relation: "many2many", RelatedTo: "Tag"},
+		}},
+		{Name: "Author", App: "books", Managed: true, Fields: []Field{
+			id,
+			{Name: "favourite", Type: "ForeignKey", Relation: "foreignkey", RelatedTo: "Book", Nullable: true},
+		}},
+		{Name: "Tag", App: "books", Managed: true, Fields: []Field{id}},
+	}
+	applyTableNames(models, false)
+	resolveRelations(models)
+	return models
+}
+
+var (
+	createdTable = regexp.MustCompile(`^CREATE TABLE (\w+)`)
+	alteredTable = regexp.MustCompile(`^ALTER TABLE (\w+)`)
+	references   = regexp.MustCompile(`REFERENCES (\w+)\(`)
+	droppedTable = regexp.MustCompile(`^DROP TABLE IF EXISTS (\w+)`)
+)
+
+// TestGenerateSQLForeignKeyOrder checks that every foreign key of the up
+// migration references a table created before it, and that the down
+// migration drops the foreign keys before the tables they reference.
+func TestGenerateSQLForeignKeyOrder(t *testing.T) {
+	models := forwardModels()
+	for _, dialect := range []string{"postgres", "mysql"} {
+		opts := Options{Dialect: dialect}
+		created := map[string]bool{}
+		// references of each table, by the table holding them.
+		refs := map[string][]string{}
+		table := ""
+		for _, line := range strings.Split(generateSQL(models, opts), "\n") {
+			line = strings.TrimSpace(line)
+			if m := createdTable.FindStringSubmatch(line); m != nil {
+				table = m[1]
+				created[table] = true
+			} else if m := alteredTable.FindStringSubmatch(line); m != nil {
+				table = m[1]
+			}
+			for _, m := range references.FindAllStringSubmatch(line, -1) {
+				if !created[m[1]] {
+					t.Errorf("%s: %s references %s before it is created", dialect, table, m[1])
+				}
+				refs[table] = append(refs[table], m[1])
+			}
+		}
+		for _, name := range []string{"books_book", "books_author", "books_tag", "books_book_tags"} {
+			if !created[name] {
+				t.Errorf("%s: %s is not created", dialect, name)
+			}
+		}
+
+		down := generateDownSQL(models, opts)
+		if !strings.Contains(down, "books_book_author_id_fkey") {
+			t.Errorf("%s: the down migration does not drop the added foreign key:\n%s", dialect, down)
+		}
+		dropped := map[string]bool{}
+		for _, line := range strings.Split(down, "\n") {
+			if strings.Contains(line, "_fkey") {
+				// Once the added foreign key is gone, Book no longer keeps
+				// Author from being dropped.
+				delete(refs, "books_book")
+				continue
+			}
+			m := droppedTable.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			for holder, targets := range refs {
+				for _, target := range targets {
+					if target == m[1] && holder != target && !dropped[holder] {
+						t.Errorf("%s: %s is dropped while %s references it", dialect, target, holder)
+					}
+				}
+			}
+			dropped[m[1]] = true
+		}
+	}
+}
+
+// TestPlanTablesKeepsOrder checks that models without forward references
+// keep their own order and need no foreign keys added later.
+func TestPlanTablesKeepsOrder(t *testing.T) {
+	models := forwardModels()
+	plan := planTables([]Model{models[2], models[0]})
+	var names []string
+	for _, m := range plan.models {
+		names = append(names, m.table())
+	}
+	if got := strings.Join(names, " "); got != "books_tag books_book" {
+		t.Errorf("order = %s, want books_tag books_book", got)
+	}
+	if len(plan.laterKeys) != 0 || len(plan.laterJoins) != 0 {
+		t.Errorf("plan defers %v and %v", plan.laterKeys, plan.laterJoins)
+	}
+}
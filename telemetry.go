@@ -0,0 +1,166 @@
+// telemetry.go
+package main
+
+import "fmt"
+
+// otelSystems maps dialects to the OpenTelemetry db.system.name values.
+var otelSystems = map[string]string{
+	"postgres": "postgresql",
+	"mysql":    "mysql",
+}
+
+// generateTelemetry renders the telemetry package. Its DB wraps the handle
+// given to the sqlc queries, starting a span per query named after the
+// "-- name:" comment sqlc keeps at the top of every statement, and Handler
+// wraps HTTP handlers in server spans.
+func generateTelemetry(module, dialect string) (map[string]string, error) {
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package telemetry traces the database queries and HTTP handlers with
+// OpenTelemetry, using the globally registered tracer provider.
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer(%[1]q)
+
+// DBTX is the database handle of the sqlc queries.
+type DBTX interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}
+
+// DB wraps the handle given to the sqlc queries, as in
+// db.New(telemetry.DB(conn)), so that every query runs in a span named
+// after it and recording its table. Exec spans also record the rows
+// affected; query spans end when the rows are returned.
+func DB(db DBTX) DBTX {
+	return tracedDB{db}
+}
+
+type tracedDB struct {
+	db DBTX
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	res, err := t.db.ExecContext(ctx, query, args...)
+	if err == nil {
+		if n, err := res.RowsAffected(); err == nil {
+			span.SetAttributes(attribute.Int64("db.response.rows_affected", n))
+		}
+	}
+	record(span, err)
+	return res, err
+}
+
+func (t tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	stmt, err := t.db.PrepareContext(ctx, query)
+	record(span, err)
+	return stmt, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	record(span, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	row := t.db.QueryRowContext(ctx, query, args...)
+	record(span, row.Err())
+	return row
+}
+
+var (
+	queryName  = regexp.MustCompile(%[3]s)
+	queryTable = regexp.MustCompile(%[4]s)
+)
+
+// startQuery starts the span of a query.
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	name := "query"
+	if m := queryName.FindStringSubmatch(query); m != nil {
+		name = m[1]
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system.name", %[2]q),
+		attribute.String("db.operation.name", name),
+	}
+	if m := queryTable.FindStringSubmatch(query); m != nil {
+		attrs = append(attrs, attribute.String("db.collection.name", m[1]))
+	}
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// record marks the span as failed on errors other than no rows.
+func record(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Handler wraps an HTTP handler in a server span named after its route,
+// continuing the trace of the caller and recording the status code.
+func Handler(route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", r.URL.Path),
+		))
+		defer span.End()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, r.WithContext(ctx))
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// statusWriter records the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+`, module+"/telemetry", otelSystems[dialect], "`^-- name: (\\w+)`", "`(?i)\\b(?:from|into|update|join)\\s+([a-z_][\\w.]*)`")
+	files := map[string]string{}
+	if err := addGoFile(files, "telemetry/telemetry.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,40 @@
+// telemetry_test.go
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestGenerateTelemetry checks the generated package and that its
+// patterns name the span and table of a sqlc query.
+func TestGenerateTelemetry(t *testing.T) {
+	files, err := generateTelemetry("example.com/app", "postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["telemetry/telemetry.go"]
+	for _, want := range []string{
+		`var tracer = otel.Tracer("example.com/app/telemetry")`,
+		`attribute.String("db.system.name", "postgresql")`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("telemetry.go lacks %q", want)
+		}
+	}
+	pattern := func(name string) *regexp.Regexp {
+		m := regexp.MustCompile(name + `\s*= regexp.MustCompile\(` + "`([^`]*)`").FindStringSubmatch(src)
+		if m == nil {
+			t.Fatalf("telemetry.go has no %s", name)
+		}
+		return regexp.MustCompile(m[1])
+	}
+	query := "-- name: ListPostByAuthor :many\nSELECT id, title FROM blog_post WHERE author_id = $1"
+	if m := pattern("queryName").FindStringSubmatch(query); m == nil || m[1] != "ListPostByAuthor" {
+		t.Errorf("query name = %v", m)
+	}
+	if m := pattern("queryTable").FindStringSubmatch(query); m == nil || m[1] != "blog_post" {
+		t.Errorf("query table = %v", m)
+	}
+}
@@ -0,0 +1,206 @@
+// tenants.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// tenantSchemaFile holds the DDL of the models living in every tenant's
+// schema with django-tenants.
+const tenantSchemaFile = "tenant_schema.sql"
+
+// tenants reports whether the settings configure django-tenants.
+func (s Settings) tenants() bool {
+	return len(s.TenantApps) > 0 &&
+		(slices.Contains(s.SharedApps, "django_tenants") || slices.Contains(s.InstalledApps, "django_tenants"))
+}
+
+// appLabel returns the label of an INSTALLED_APPS entry: "blog" for
+// "project.blog", "blog.apps.BlogConfig" or "project.apps.blog".
+func appLabel(entry string) string {
+	parts := strings.Split(entry, ".")
+	if n := len(parts); n >= 3 && parts[n-2] == "apps" && strings.HasSuffix(parts[n-1], "Config") {
+		parts = parts[:n-2]
+	}
+	return parts[len(parts)-1]
+}
+
+// listsApp reports whether an app list has the app.
+func listsApp(apps []string, app string) bool {
+	for _, entry := range apps {
+		if appLabel(entry) == app {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTenants splits the models between the shared public schema and the
+// schema of every tenant. Apps listed in both SHARED_APPS and TENANT_APPS
+// have their tables in both; apps in neither are kept in the public schema.
+func splitTenants(s Settings, models []Model) (shared, tenant []Model) {
+	for _, m := range models {
+		inTenant := listsApp(s.TenantApps, m.App)
+		if inTenant {
+			tenant = append(tenant, m)
+		}
+		if !inTenant || listsApp(s.SharedApps, m.App) {
+			shared = append(shared, m)
+		}
+	}
+	return shared, tenant
+}
+
+// tenantDiagnostics reports django-tenants projects generated for a dialect
+// without schemas; their tenant tables are then generated with the shared
+// ones.
+func tenantDiagnostics(s Settings, dialect string) []Diagnostic {
+	if !s.tenants() || dialect == "postgres" {
+		return nil
+	}
+	return []Diagnostic{{Code: codeTenantSchemas, Severity: severity(codeTenantSchemas),
+		Message: fmt.Sprintf("django-tenants needs PostgreSQL schemas; %s gets a single schema with the tenant tables", dialect)}}
+}
+
+// generateTenant renders the tenant package, which scopes database access
+// to a tenant's schema the way django-tenants' middleware does, and the
+// package embedding the tenant migrations.
+func generateTenant(module string, layout Layout) (map[string]string, error) {
+	files := map[string]string{}
+	embed := `// Code generated by django2go. DO NOT EDIT.
+
+// Package tenant embeds the migrations applied to every tenant schema.
+package tenant
+
+import "embed"
+
+// FS holds the up migrations, applied in file name order.
+//
+//go:embed *.up.sql
+var FS embed.FS
+`
+	if err := addGoFile(files, path.Join(filepath.ToSlash(layout.Migrations), "tenant", "embed.go"), embed); err != nil {
+		return nil, err
+	}
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package tenant scopes database access to the schema of a tenant, as
+// django-tenants does by setting the search_path for each request.
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	tenantmigrations %[1]q
+)
+
+// Open returns a connection of db whose search_path is the tenant schema
+// followed by public, where the shared tables live. Pass it to db.New to
+// run the queries in the tenant. Release it with Close, which resets the
+// search_path before returning the connection to the pool.
+func Open(ctx context.Context, db *sql.DB, schema string) (*Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "SET search_path TO "+quote(schema)+", public"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("tenant %%s: %%w", schema, err)
+	}
+	return &Conn{Conn: conn, Schema: schema}, nil
+}
+
+// Conn is a connection scoped to a tenant schema.
+type Conn struct {
+	*sql.Conn
+	Schema string
+}
+
+// Close resets the search_path and returns the connection to the pool, or
+// discards it when the reset fails so no other request uses the schema.
+func (c *Conn) Close() error {
+	if _, err := c.Conn.ExecContext(context.Background(), "RESET search_path"); err != nil {
+		c.Conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	return c.Conn.Close()
+}
+
+type contextKey struct{}
+
+// Middleware scopes every request to the tenant schema returned by resolve,
+// typically looked up from the Host header in the public domain table. The
+// connection is available to the handlers through FromContext.
+func Middleware(db *sql.DB, resolve func(*http.Request) (string, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			schema, err := resolve(r)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			conn, err := Open(r.Context(), db, schema)
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			defer conn.Close()
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, conn)))
+		})
+	}
+}
+
+// FromContext returns the tenant connection stored by Middleware, or nil.
+func FromContext(ctx context.Context) *Conn {
+	conn, _ := ctx.Value(contextKey{}).(*Conn)
+	return conn
+}
+
+// Create creates the schema of a new tenant and applies the tenant
+// migrations to it, as django-tenants does when a tenant is saved.
+func Create(ctx context.Context, db *sql.DB, schema string) error {
+	if _, err := db.ExecContext(ctx, "CREATE SCHEMA "+quote(schema)); err != nil {
+		return fmt.Errorf("tenant %%s: %%w", schema, err)
+	}
+	conn, err := Open(ctx, db, schema)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	names, err := fs.Glob(tenantmigrations.FS, "*.up.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stmts, err := fs.ReadFile(tenantmigrations.FS, name)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, string(stmts)); err != nil {
+			return fmt.Errorf("tenant %%s: %%s: %%w", schema, name, err)
+		}
+	}
+	return nil
+}
+
+// quote quotes a schema name as an SQL identifier.
+func quote(name string) string {
+	return %[2]s + strings.ReplaceAll(name, %[2]s, %[2]s+%[2]s) + %[2]s
+}
+`, layout.importPath(module, filepath.Join(layout.Migrations, "tenant")), "`\"`")
+	if err := addGoFile(files, "tenant/tenant.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,60 @@
+// tenants_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSplitTenants parses django-tenants settings and checks which schema
+// each app's models are generated in.
+func TestSplitTenants(t *testing.T) {
+	out := parseProject(t, map[string]string{"mysite/settings.py": `SHARED_APPS = ["django_tenants", "customers", "shop.apps.ShopConfig"]
+TENANT_APPS = ["blog", "shop"]
+INSTALLED_APPS = list(SHARED_APPS) + [app for app in TENANT_APPS if app not in SHARED_APPS]
+`})
+	if !out.Settings.tenants() {
+		t.Fatalf("settings = %+v", out.Settings)
+	}
+	models := []Model{{Name: "Client", App: "customers"}, {Name: "Post", App: "blog"}, {Name: "Product", App: "shop"}, {Name: "Log", App: "audit"}}
+	shared, tenant := splitTenants(out.Settings, models)
+	names := func(models []Model) string {
+		var s []string
+		for _, m := range models {
+			s = append(s, m.Name)
+		}
+		return strings.Join(s, ",")
+	}
+	if got := names(shared); got != "Client,Product,Log" {
+		t.Errorf("shared = %s", got)
+	}
+	if got := names(tenant); got != "Post,Product" {
+		t.Errorf("tenant = %s", got)
+	}
+	if diags := tenantDiagnostics(out.Settings, "mysql"); len(diags) != 1 || diags[0].Code != codeTenantSchemas {
+		t.Errorf("mysql diagnostics = %v", diags)
+	}
+	if diags := tenantDiagnostics(out.Settings, "postgres"); len(diags) != 0 {
+		t.Errorf("postgres diagnostics = %v", diags)
+	}
+}
+
+func TestGenerateTenant(t *testing.T) {
+	files, err := generateTenant("example.com/app", defaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := files["tenant/tenant.go"]
+	for _, want := range []string{
+		`tenantmigrations "example.com/app/migrations/tenant"`,
+		`"SET search_path TO "+quote(schema)+", public"`,
+		"return `\"` + strings.ReplaceAll(name, `\"`, `\"`+`\"`) + `\"`",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("tenant.go lacks %q:\n%s", want, src)
+		}
+	}
+	if !strings.Contains(files["migrations/tenant/embed.go"], "//go:embed *.up.sql") {
+		t.Errorf("embed.go:\n%s", files["migrations/tenant/embed.go"])
+	}
+}
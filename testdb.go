@@ -0,0 +1,131 @@
+// testdb.go
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// testContainers holds, per dialect, the testcontainers-go module, image,
+// extra run options and connection string options used by the generated
+// dbtest package.
+var testContainers = map[string]struct {
+	Module  string
+	Image   string
+	Run     string
+	Options string
+}{
+	"postgres": {"postgres", "postgres:16-alpine", "postgres.BasicWaitStrategies(),", `"sslmode=disable"`},
+	"mysql":    {"mysql", "mysql:8.0", "", `"multiStatements=true", "parseTime=true"`},
+}
+
+// generateTestDB renders the dbtest package, whose NewTestDB starts an
+// ephemeral database with testcontainers, applies the generated up
+// migrations and returns the sqlc queries, like Django's test database.
+// The migrations are embedded by a small package next to them.
+func generateTestDB(module string, layout Layout, dialect string) (map[string]string, error) {
+	files := map[string]string{}
+	embed := `// Code generated by django2go. DO NOT EDIT.
+
+// Package migrations embeds the generated migrations.
+package migrations
+
+import "embed"
+
+// FS holds the up migrations, applied in file name order.
+//
+//go:embed *.up.sql
+var FS embed.FS
+`
+	if err := addGoFile(files, path.Join(filepath.ToSlash(layout.Migrations), "embed.go"), embed); err != nil {
+		return nil, err
+	}
+
+	tc := testContainers[dialect]
+	driver := sqlDrivers[dialect]
+	src := fmt.Sprintf(`// Code generated by django2go. Edit as needed.
+
+// Package dbtest starts throwaway databases for tests.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"sort"
+	"testing"
+
+	_ %[1]q
+	"github.com/testcontainers/testcontainers-go/modules/%[2]s"
+
+	%[3]q
+	%[4]q
+)
+
+// NewTestDB starts an ephemeral %[2]s database, applies the migrations and
+// returns the queries. The database is removed when the test finishes.
+func NewTestDB(t testing.TB) *db.Queries {
+	t.Helper()
+	return db.New(NewTestConn(t))
+}
+
+// NewTestConn is NewTestDB returning the connection, for tests running
+// their own statements or transactions.
+func NewTestConn(t testing.TB) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	container, err := %[2]s.Run(ctx, %[5]q,
+		%[2]s.WithDatabase("test"),
+		%[2]s.WithUsername("test"),
+		%[2]s.WithPassword("test"),
+		%[8]s
+	)
+	if err != nil {
+		t.Fatalf("dbtest: start %[2]s: %%v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("dbtest: terminate %[2]s: %%v", err)
+		}
+	})
+	dsn, err := container.ConnectionString(ctx, %[6]s)
+	if err != nil {
+		t.Fatalf("dbtest: connection string: %%v", err)
+	}
+	conn, err := sql.Open(%[7]q, dsn)
+	if err != nil {
+		t.Fatalf("dbtest: open: %%v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := Migrate(ctx, conn); err != nil {
+		t.Fatalf("dbtest: %%v", err)
+	}
+	return conn
+}
+
+// Migrate applies the embedded up migrations in order.
+func Migrate(ctx context.Context, conn *sql.DB) error {
+	names, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stmts, err := fs.ReadFile(migrations.FS, name)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, string(stmts)); err != nil {
+			return fmt.Errorf("%%s: %%w", name, err)
+		}
+	}
+	return nil
+}
+`, driver[1], tc.Module, layout.importPath(module, layout.DB), layout.importPath(module, layout.Migrations), tc.Image, tc.Options, driver[0], tc.Run)
+	if err := addGoFile(files, "dbtest/dbtest.go", src); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,40 @@
+// testdb_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateTestDB checks the dbtest package and migrations embed for
+// each dialect.
+func TestGenerateTestDB(t *testing.T) {
+	for dialect, want := range map[string][]string{
+		"postgres": {
+			`"github.com/testcontainers/testcontainers-go/modules/postgres"`,
+			`postgres.Run(ctx, "postgres:16-alpine",`,
+			"postgres.BasicWaitStrategies(),",
+			`container.ConnectionString(ctx, "sslmode=disable")`,
+			`sql.Open("pgx", dsn)`,
+		},
+		"mysql": {
+			`_ "github.com/go-sql-driver/mysql"`,
+			`mysql.Run(ctx, "mysql:8.0",`,
+			`container.ConnectionString(ctx, "multiStatements=true", "parseTime=true")`,
+		},
+	} {
+		files, err := generateTestDB("example.com/app", defaultLayout, dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		if !strings.Contains(files["migrations/embed.go"], "//go:embed *.up.sql") {
+			t.Errorf("%s: embed.go:\n%s", dialect, files["migrations/embed.go"])
+		}
+		src := files["dbtest/dbtest.go"]
+		for _, w := range append(want, `"example.com/app/db"`, `"example.com/app/migrations"`, "func NewTestDB(t testing.TB) *db.Queries") {
+			if !strings.Contains(src, w) {
+				t.Errorf("%s: dbtest.go missing %q:\n%s", dialect, w, src)
+			}
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// triage.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Migration is a Django migration file with its schema operations.
+type Migration struct {
+	App          string        `json:"app"`
+	Name         string        `json:"name"`
+	File         string        `json:"file"`
+	Dependencies [][]string    `json:"dependencies"`
+	Replaces     [][]string    `json:"replaces"`
+	Operations   []MigrationOp `json:"operations"`
+}
+
+// MigrationOp is a schema operation of a migration. Model names the model
+// and, for field operations, Name the field.
+type MigrationOp struct {
+	Op      string  `json:"op"`
+	Line    int     `json:"line"`
+	Model   string  `json:"model"`
+	Name    string  `json:"name"`
+	OldName string  `json:"old_name"`
+	NewName string  `json:"new_name"`
+	Table   string  `json:"table"`
+	Managed bool    `json:"managed"`
+	Field   *Field  `json:"field"`
+	Fields  []Field `json:"fields"`
+}
+
+// orderMigrations sorts migrations so that every migration follows its
+// dependencies. Migrations replaced by a squashed one are left out.
+func orderMigrations(migs []Migration) []Migration {
+	byKey := map[string]Migration{}
+	replacedBy := map[string]string{}
+	var keys []string
+	for _, m := range migs {
+		key := m.App + "." + m.Name
+		byKey[key] = m
+		keys = append(keys, key)
+		for _, r := range m.Replaces {
+			replacedBy[r[0]+"."+r[1]] = key
+		}
+	}
+	sort.Strings(keys)
+	var ordered []Migration
+	visited := map[string]bool{}
+	var visit func(key string)
+	visit = func(key string) {
+		if r, ok := replacedBy[key]; ok {
+			key = r
+		}
+		m, ok := byKey[key]
+		if !ok || visited[key] {
+			return
+		}
+		visited[key] = true
+		for _, d := range m.Dependencies {
+			visit(d[0] + "." + d[1])
+		}
+		ordered = append(ordered, m)
+	}
+	for _, key := range keys {
+		if _, ok := replacedBy[key]; !ok {
+			visit(key)
+		}
+	}
+	return ordered
+}
+
+// replayMigrations applies the migrations' operations in order and returns
+// the models they leave behind, as Django's migration state would. Each
+// model is located on the last operation that changed it.
+func replayMigrations(migs []Migration) []Model {
+	state := map[string]*Model{}
+	var order []string
+	for _, mig := range orderMigrations(migs) {
+		for _, op := range mig.Operations {
+			key := mig.App + "." + strings.ToLower(op.Model)
+			switch op.Op {
+			case "CreateModel":
+				m := &Model{Name: op.Model, App: mig.App, Managed: op.Managed, DBTable: op.Table}
+				for _, f := range op.Fields {
+					f.Line = 0
+					m.Fields = append(m.Fields, f)
+				}
+				state[key] = m
+				order = append(order, key)
+			case "RenameModel":
+				key = mig.App + "." + strings.ToLower(op.OldName)
+				if m := state[key]; m != nil {
+					delete(state, key)
+					m.Name = op.NewName
+					key = mig.App + "." + strings.ToLower(op.NewName)
+					state[key] = m
+					order = append(order, key)
+				}
+			case "DeleteModel":
+				delete(state, key)
+			}
+			m := state[key]
+			if m == nil {
+				continue
+			}
+			m.File, m.Line = mig.File, op.Line
+			i := slices.IndexFunc(m.Fields, func(f Field) bool { return f.Name == op.Name || f.Name == op.OldName })
+			switch {
+			case op.Op == "AlterModelTable":
+				m.DBTable = op.Table
+			case op.Op == "AddField" && op.Field != nil:
+				f := *op.Field
+				f.Line = 0
+				m.Fields = append(m.Fields, f)
+			case i < 0:
+			case op.Op == "RemoveField":
+				m.Fields = slices.Delete(m.Fields, i, i+1)
+			case op.Op == "AlterField" && op.Field != nil:
+				m.Fields[i] = *op.Field
+				m.Fields[i].Line = 0
+			case op.Op == "RenameField":
+				m.Fields[i].Name = op.NewName
+			}
+		}
+	}
+	var models []Model
+	seen := map[string]bool{}
+	for _, key := range order {
+		if m := state[key]; m != nil && !seen[key] {
+			seen[key] = true
+			models = append(models, *m)
+		}
+	}
+	return models
+}
+
+// triageTable is a table as one source describes it.
+type triageTable struct {
+	loc     Diagnostic // file, line, app and model of the table
+	columns map[string]triageColumn
+}
+
+// triageColumn is a column as one source describes it.
+type triageColumn struct {
+	field    string
+	ftype    string
+	nullable bool
+	line     int
+}
+
+// triageSource is one of the schemas compared by triage.
+type triageSource struct {
+	name   string
+	tables map[string]triageTable
+}
+
+// modelTables returns the tables Django creates for managed models, by
+// their names in the Django database.
+func modelTables(models []Model) map[string]triageTable {
+	tables := map[string]triageTable{}
+	for _, m := range models {
+		if !m.Managed || m.isView() {
+			continue
+		}
+		// An explicit id primary key is the implicit one.
+		m.Fields = slices.DeleteFunc(slices.Clone(m.Fields), func(f Field) bool { return f.PrimaryKey && f.Name == "id" })
+		for _, f := range m.Fields {
+			if f.PrimaryKey {
+				m.PrimaryKey = []string{f.Name}
+			}
+		}
+		loc := Diagnostic{File: m.File, Line: m.Line, App: m.App, Model: m.Name}
+		t := triageTable{loc: loc, columns: map[string]triageColumn{}}
+		cols, fields := tableColumns(m)
+		for i, col := range cols {
+			f, _ := m.field(fields[i])
+			t.columns[col] = triageColumn{field: fields[i], ftype: f.Type, nullable: f.Nullable, line: f.Line}
+		}
+		tables[djangoTable(m)] = t
+		for _, f := range m.Fields {
+			if f.Relation != "many2many" {
+				continue
+			}
+			source, target := joinColumns(m, f)
+			join := triageTable{loc: loc, columns: map[string]triageColumn{}}
+			join.loc.Line, join.loc.Field = max(f.Line, m.Line), f.Name
+			for _, col := range []string{"id", source, target} {
+				join.columns[col] = triageColumn{}
+			}
+			tables[djangoTable(m)+"_"+toSnake(f.Name)] = join
+		}
+	}
+	return tables
+}
+
+// databaseTables introspects the tables of the connected database.
+func databaseTables(ctx context.Context, conn *sql.DB, dialect string) (map[string]triageTable, error) {
+	schema := "current_schema()"
+	if dialect == "mysql" {
+		schema = "DATABASE()"
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT table_name, column_name, is_nullable FROM information_schema.columns WHERE table_schema = "+schema)
+	if err != nil {
+		return nil, fmt.Errorf("introspect: %w", err)
+	}
+	defer rows.Close()
+	tables := map[string]triageTable{}
+	for rows.Next() {
+		var table, column, nullable string
+		if err := rows.Scan(&table, &column, &nullable); err != nil {
+			return nil, fmt.Errorf("introspect: %w", err)
+		}
+		t, ok := tables[table]
+		if !ok {
+			t = triageTable{columns: map[string]triageColumn{}}
+			tables[table] = t
+		}
+		t.columns[column] = triageColumn{nullable: nullable == "YES"}
+	}
+	return tables, rows.Err()
+}
+
+// triage compares the sources, ordered models, migrations and optionally
+// the database, table by table and column by column. Tables only found in
+// the database are ignored unless they belong to one of apps.
+func triage(sources []triageSource, apps map[string]bool) []Diagnostic {
+	var names []string
+	for _, s := range sources {
+		for name := range s.tables {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var diags []Diagnostic
+	for _, name := range names {
+		have := make([]bool, len(sources))
+		var loc Diagnostic
+		for i := len(sources) - 1; i >= 0; i-- {
+			if t, ok := sources[i].tables[name]; ok {
+				have[i], loc = true, t.loc
+			}
+		}
+		if app, _, _ := strings.Cut(name, "_"); loc.File == "" && !apps[app] {
+			continue
+		}
+		all := slices.Repeat([]bool{true}, len(sources))
+		if d, ok := triageMissing(sources, all, have, loc, "table "+name); ok {
+			diags = append(diags, d)
+		}
+
+		var cols []string
+		for i, s := range sources {
+			for col := range s.tables[name].columns {
+				if have[i] && !slices.Contains(cols, col) {
+					cols = append(cols, col)
+				}
+			}
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			in := make([]bool, len(sources))
+			found := make([]triageColumn, len(sources))
+			colLoc := loc
+			for i := len(sources) - 1; i >= 0; i-- {
+				c, ok := sources[i].tables[name].columns[col]
+				if !ok {
+					continue
+				}
+				in[i], found[i] = true, c
+				if t := sources[i].tables[name]; t.loc.File != "" {
+					colLoc = t.loc
+					colLoc.Field = c.field
+					if c.line > 0 {
+						colLoc.Line = c.line
+					}
+				}
+			}
+			what := "column " + name + "." + col
+			if d, ok := triageMissing(sources, have, in, colLoc, what); ok {
+				diags = append(diags, d)
+				continue
+			}
+			if d, ok := triageNullable(sources, in, found, colLoc, what); ok {
+				diags = append(diags, d)
+			}
+			if m, g := found[0].ftype, found[1].ftype; in[0] && in[1] && m != "" && g != "" && m != g {
+				d := colLoc
+				d.Code, d.Severity = codeModelsMigrations, severity(codeModelsMigrations)
+				d.Message = fmt.Sprintf("%s is a %s in models but a %s in migrations", what, m, g)
+				diags = append(diags, d)
+			}
+		}
+	}
+	return diags
+}
+
+// triageMissing reports a table or column that only some of the sources
+// having its table contain.
+func triageMissing(sources []triageSource, have, in []bool, loc Diagnostic, what string) (Diagnostic, bool) {
+	var present, missing []string
+	for i, s := range sources {
+		if in[i] {
+			present = append(present, s.name)
+		} else if have[i] {
+			missing = append(missing, s.name)
+		}
+	}
+	if len(missing) == 0 {
+		return Diagnostic{}, false
+	}
+	code := codeModelsMigrations
+	if (!have[0] || !have[1] || in[0] == in[1]) && len(sources) > 2 && have[2] {
+		code = codeMigrationUnapplied
+		if in[2] {
+			code = codeSchemaDrift
+		}
+	}
+	loc.Code, loc.Severity = code, severity(code)
+	loc.Message = fmt.Sprintf("%s is in %s but not in %s", what, sourceList(present, "and"), sourceList(missing, "or"))
+	return loc, true
+}
+
+// triageNullable reports a column whose nullability the sources having it
+// disagree on.
+func triageNullable(sources []triageSource, in []bool, found []triageColumn, loc Diagnostic, what string) (Diagnostic, bool) {
+	var null, notNull []string
+	for i, s := range sources {
+		if !in[i] {
+			continue
+		}
+		if found[i].nullable {
+			null = append(null, s.name)
+		} else {
+			notNull = append(notNull, s.name)
+		}
+	}
+	if len(null) == 0 || len(notNull) == 0 {
+		return Diagnostic{}, false
+	}
+	code := codeMigrationUnapplied
+	if in[0] && in[1] && found[0].nullable != found[1].nullable {
+		code = codeModelsMigrations
+	}
+	loc.Code, loc.Severity = code, severity(code)
+	loc.Message = fmt.Sprintf("%s is nullable in %s but NOT NULL in %s", what, sourceList(null, "and"), sourceList(notNull, "and"))
+	return loc, true
+}
+
+// sourceList joins source names as "a, b and c".
+func sourceList(names []string, conj string) string {
+	if len(names) <= 1 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conj + " " + names[len(names)-1]
+}
+
+// runTriage implements the triage subcommand: it compares the models, the
+// state left by replaying the Django migrations and the live database, and
+// reports where they disagree.
+func runTriage(args []string) {
+	fs := flag.NewFlagSet("triage", flag.ExitOnError)
+	input := fs.String("input", "", "Path to Django app (required)")
+	dialect := fs.String("dialect", "postgres", "SQL dialect: postgres or mysql")
+	databaseURL := fs.String("database-url", os.Getenv("DATABASE_URL"), "Connection string of the Django database (optional)")
+	maxWarnings := fs.Int("max-warnings", -1, "Fail when more inconsistencies are reported (-1 for no limit)")
+	errorOn := fs.String("error-on", "", "Comma-separated warning codes to treat as errors, e.g. W501")
+	parserScriptPath := fs.String("parser-script", "", "Python script to run instead of the built-in parser")
+	parserEnv := parserEnvFlags(fs)
+	fs.Parse(args)
+
+	if *input == "" {
+		fmt.Println("Error: triage requires --input")
+		fs.Usage()
+		os.Exit(exitUsage)
+	}
+	driver, ok := sqlDrivers[*dialect]
+	if !ok {
+		fail(exitUsage, "Error: --dialect must be one of %s", strings.Join(dialects, ", "))
+	}
+	errorCodes, err := parseCodes(*errorOn)
+	if err != nil {
+		fail(exitUsage, "Error: --error-on: %v", err)
+	}
+	out, err := runPythonParser(*input, *parserScriptPath, nil, parserEnv(), capModels, capMigrations)
+	if err != nil {
+		fail(exitParse, "Parser error: %v", err)
+	}
+
+	state := replayMigrations(out.Migrations)
+	apps := map[string]bool{}
+	for _, m := range append(slices.Clone(out.Models), state...) {
+		apps[m.App] = true
+	}
+	sources := []triageSource{
+		{"models", modelTables(out.Models)},
+		{"migrations", modelTables(state)},
+	}
+	if *databaseURL != "" {
+		conn, err := sql.Open(driver[0], *databaseURL)
+		if err != nil {
+			fail(exitIO, "Error: %v", err)
+		}
+		defer conn.Close()
+		tables, err := databaseTables(context.Background(), conn, *dialect)
+		if err != nil {
+			fail(exitIO, "Error: %v", err)
+		}
+		sources = append(sources, triageSource{"the database", tables})
+	} else {
+		fmt.Println("No --database-url given; comparing models and migrations only.")
+	}
+
+	diags := append(out.Diagnostics, triage(sources, apps)...)
+	checkDiagnostics(diags, DiagnosticPolicy{MaxWarnings: *maxWarnings, ErrorOn: errorCodes}, exitDrift)
+	if len(diags) == 0 {
+		fmt.Printf("✅ %s agree\n", sourceList(sourceNames(sources), "and"))
+	}
+}
+
+// sourceNames returns the names of the sources.
+func sourceNames(sources []triageSource) []string {
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.name
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+// triage_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// triageProject returns a project whose migrations lag behind the models:
+// the subtitle field has no migration.
+func triageProject(t *testing.T) *Output {
+	return parseProject(t, map[string]string{
+		"blog/models.py": `from django.db import models
+
+class Article(models.Model):
+    title = models.CharField(max_length=100)
+    subtitle = models.CharField(max_length=100)
+    body = models.TextField(null=True)
+`,
+		"blog/migrations/__init__.py": "",
+		"blog/migrations/0001_initial.py": `from django.db import migrations, models
+
+class Migration(migrations.Migration):
+    dependencies = []
+    operations = [
+        migrations.CreateModel(
+            name="Post",
+            fields=[
+                ("id", models.AutoField(primary_key=True)),
+                ("title", models.CharField(max_length=100)),
+                ("draft", models.BooleanField()),
+            ],
+        ),
+    ]
+`,
+		"blog/migrations/0002_rename.py": `from django.db import migrations, models
+
+class Migration(migrations.Migration):
+    dependencies = [("blog", "0001_initial")]
+    operations = [
+        migrations.RenameModel("Post", "Article"),
+        migrations.RemoveField(model_name="article", name="draft"),
+        migrations.AddField(model_name="article", name="body", field=models.TextField()),
+    ]
+`,
+	})
+}
+
+// TestReplayMigrations checks the model state left by the migrations.
+func TestReplayMigrations(t *testing.T) {
+	out := triageProject(t)
+	if len(out.Migrations) != 2 {
+		t.Fatalf("migrations = %+v", out.Migrations)
+	}
+	state := replayMigrations([]Migration{out.Migrations[1], out.Migrations[0]})
+	if len(state) != 1 {
+		t.Fatalf("state = %+v", state)
+	}
+	var fields []string
+	for _, f := range state[0].Fields {
+		fields = append(fields, f.Name)
+	}
+	if state[0].Name != "Article" || strings.Join(fields, ",") != "id,title,body" || state[0].File != "blog/migrations/0002_rename.py" {
+		t.Errorf("state = %s %v from %s", state[0].Name, fields, state[0].File)
+	}
+}
+
+// TestTriage checks the inconsistencies reported between the models, the
+// migrations and a database without the latest migration applied.
+func TestTriage(t *testing.T) {
+	out := triageProject(t)
+	database := map[string]triageTable{
+		"blog_post": {columns: map[string]triageColumn{"id": {}, "title": {}, "draft": {}}},
+		"blog_tmp":  {columns: map[string]triageColumn{"id": {}}},
+		"auth_user": {columns: map[string]triageColumn{"id": {}}},
+	}
+	sources := []triageSource{
+		{"models", modelTables(out.Models)},
+		{"migrations", modelTables(replayMigrations(out.Migrations))},
+		{"the database", database},
+	}
+	var got []string
+	for _, d := range triage(sources, map[string]bool{"blog": true}) {
+		got = append(got, d.Code+" "+d.Message)
+	}
+	want := []string{
+		"W502 table blog_article is in models and migrations but not in the database",
+		"W501 column blog_article.body is nullable in models but NOT NULL in migrations",
+		"W501 column blog_article.subtitle is in models but not in migrations",
+		"W503 table blog_post is in the database but not in models or migrations",
+		"W503 table blog_tmp is in the database but not in models or migrations",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestOrderMigrationsSquashed(t *testing.T) {
+	migs := []Migration{
+		{App: "blog", Name: "0002_more", Dependencies: [][]string{{"blog", "0001_initial"}}},
+		{App: "blog", Name: "0001_initial"},
+		{App: "blog", Name: "0001_squashed", Replaces: [][]string{{"blog", "0001_initial"}}},
+	}
+	var names []string
+	for _, m := range orderMigrations(migs) {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "0001_squashed,0002_more" {
+		t.Errorf("order = %s", got)
+	}
+}
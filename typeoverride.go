@@ -0,0 +1,45 @@
+// typeoverride.go
+package main
+
+// TypeOverride maps a Django field class, such as a third-party or custom
+// field, to its column type and the Go type sqlc generates for it.
+type TypeOverride struct {
+	// SQL is the column type on every dialect not listed in Dialects.
+	SQL      string            `yaml:"sql"`
+	Dialects map[string]string `yaml:"dialects"`
+	// GoType is the sqlc go_type of the columns, and NullableGoType that of
+	// nullable ones, defaulting to GoType.
+	GoType         string `yaml:"go_type"`
+	NullableGoType string `yaml:"nullable_go_type"`
+}
+
+// sqlType returns the column type for the dialect, or "" to keep the one
+// derived from the field.
+func (o TypeOverride) sqlType(dialect string) string {
+	if t := o.Dialects[dialect]; t != "" {
+		return t
+	}
+	return o.SQL
+}
+
+// goType returns the Go type of a column, or "" to leave it to sqlc.
+func (o TypeOverride) goType(nullable bool) string {
+	if nullable && o.NullableGoType != "" {
+		return o.NullableGoType
+	}
+	return o.GoType
+}
+
+// textColumnTypes lists the Django field types stored as TEXT by design.
+var textColumnTypes = map[string]bool{
+	"CharField": true, "TextField": true, "UUIDField": true, "DurationField": true, "FilePathField": true,
+	"NullBooleanField": true, "IPAddressField": true, "CommaSeparatedIntegerField": true,
+}
+
+// knownFieldType reports whether the field type is one of Django's, or of
+// the contrib and third-party packages mapped to a column type of their
+// own. Others fall back to TEXT.
+func knownFieldType(t string) bool {
+	_, varchar := varcharTypes[t]
+	return textColumnTypes[t] || varchar || geoTypes[t] != "" || t == "ArrayField" || sqlType(t, "postgres") != "TEXT"
+}
@@ -0,0 +1,51 @@
+// typeoverride_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTypeOverrides checks that type_overrides set the column type per
+// dialect and the Go type of the columns, and that unmapped field classes
+// are reported.
+func TestTypeOverrides(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"crm/models.py": `from django.db import models
+from phonenumber_field.modelfields import PhoneNumberField
+from colorfield.fields import ColorField
+
+class Contact(models.Model):
+    phone = PhoneNumberField()
+    mobile = PhoneNumberField(null=True)
+    color = ColorField()
+`,
+	})
+	cfg := &Config{TypeOverrides: map[string]TypeOverride{
+		"PhoneNumberField": {SQL: "VARCHAR(32)", Dialects: map[string]string{"sqlite": "TEXT"}, GoType: "string", NullableGoType: "*string"},
+	}}
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	diags := normalize(cfg, out.Models, opts)
+	if len(diags) != 1 || diags[0].Code != codeUnknownFieldType || !strings.Contains(diags[0].Message, "ColorField") {
+		t.Fatalf("diags = %+v", diags)
+	}
+	sql := generateSQL(out.Models, opts)
+	for _, want := range []string{"phone VARCHAR(32) NOT NULL", "mobile VARCHAR(32)", "color TEXT NOT NULL"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("schema lacks %q:\n%s", want, sql)
+		}
+	}
+	sqlc := generateSQLCConfig(out.Models, "postgres", defaultLayout, []string{"schema.sql"}, []string{"query.sql"}, cfg.TypeOverrides, "")
+	for _, want := range []string{
+		"- column: \"contact.phone\"\n            go_type: \"string\"\n",
+		"- column: \"contact.mobile\"\n            go_type: \"*string\"\n",
+	} {
+		if !strings.Contains(sqlc, want) {
+			t.Errorf("sqlc.yaml lacks %q:\n%s", want, sqlc)
+		}
+	}
+
+	if sqlType := (TypeOverride{SQL: "VARCHAR(32)", Dialects: map[string]string{"sqlite": "TEXT"}}).sqlType("sqlite"); sqlType != "TEXT" {
+		t.Errorf("sqlite type = %q", sqlType)
+	}
+}
@@ -0,0 +1,61 @@
+// view.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// isView reports whether the model is a read-only database view.
+func (m Model) isView() bool {
+	return m.View != ""
+}
+
+// loadViews reads the SELECT of every view model defined in a file.
+func loadViews(models []Model) error {
+	for i, m := range models {
+		if m.View != "" || m.ViewFile == "" {
+			continue
+		}
+		data, err := os.ReadFile(m.ViewFile)
+		if err != nil {
+			return fmt.Errorf("view for %s: %w", m.Name, err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			return fmt.Errorf("view for %s: %s is empty", m.Name, m.ViewFile)
+		}
+		models[i].View = string(data)
+	}
+	return nil
+}
+
+// generateViews generates CREATE VIEW statements for the view models.
+func generateViews(models []Model) string {
+	var sb strings.Builder
+	for _, m := range models {
+		if !m.isView() {
+			continue
+		}
+		sel := strings.TrimSuffix(strings.TrimSpace(m.View), ";")
+		sb.WriteString(fmt.Sprintf("CREATE VIEW %s AS\n%s;\n\n", m.table(), sel))
+	}
+	return sb.String()
+}
+
+// viewQueries generates the read-only list query for every view model.
+func viewQueries(models []Model) []TranslatedQuery {
+	var result []TranslatedQuery
+	for _, m := range models {
+		if !m.isView() {
+			continue
+		}
+		result = append(result, TranslatedQuery{
+			Query: Query{App: m.App, Model: m.Name, Source: "read-only view " + m.table()},
+			Name:  "List" + m.Name,
+			Kind:  ":many",
+			SQL:   "SELECT * FROM " + m.table() + ";",
+		})
+	}
+	return result
+}
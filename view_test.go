@@ -0,0 +1,63 @@
+// view_test.go
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestViewModels checks that an unmanaged model with a view hint becomes a
+// read-only view instead of a reference-only table.
+func TestViewModels(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"sales/models.py": `from django.db import models
+
+class Order(models.Model):
+    total = models.IntegerField()
+
+class OrderSummary(models.Model):  # django2go: view sql/order_summary.sql
+    orders = models.IntegerField()
+    revenue = models.IntegerField()
+
+    class Meta:
+        managed = False
+`,
+		"sales/sql/order_summary.sql": "SELECT COUNT(*) AS orders, SUM(total) AS revenue FROM order;\n",
+	})
+	if err := applyConfig(&Config{}, out.Models); err != nil {
+		t.Fatal(err)
+	}
+	managed, unmanaged := splitManaged(out.Models)
+	if len(managed) != 2 || len(unmanaged) != 0 {
+		t.Fatalf("managed %d, unmanaged %d, want the view managed", len(managed), len(unmanaged))
+	}
+	opts := Options{Dialect: "postgres"}
+	sql := generateSQL(managed, opts)
+	if strings.Contains(sql, "CREATE TABLE ordersummary") ||
+		!strings.Contains(sql, "CREATE VIEW ordersummary AS\nSELECT COUNT(*) AS orders, SUM(total) AS revenue FROM order;") {
+		t.Errorf("view SQL:\n%s", sql)
+	}
+	if down := generateDownSQL(managed, opts); !strings.HasPrefix(down, "DROP VIEW IF EXISTS ordersummary;") {
+		t.Errorf("down SQL:\n%s", down)
+	}
+
+	got := translateQueries([]Query{
+		{Model: "OrderSummary", Chain: []Step{{Method: "create", Kwargs: []Arg{{Key: "orders", Literal: true, Value: float64(1)}}}}},
+		{Model: "OrderSummary", Chain: []Step{{Method: "all"}, {Method: "delete"}}},
+	}, out.Models, "postgres")
+	for _, q := range got {
+		if q.Translated() || !strings.Contains(q.Reason, "read-only view") {
+			t.Errorf("write to view = %q, reason %q", q.SQL, q.Reason)
+		}
+	}
+	if q := viewQueries(out.Models); len(q) != 1 || q[0].Name != "ListOrderSummary" {
+		t.Errorf("view queries = %+v", q)
+	}
+}
+
+func TestViewFileErrors(t *testing.T) {
+	cfg := &Config{dir: t.TempDir(), Models: map[string]ModelConfig{"Report": {ViewFile: "missing.sql"}}}
+	if err := applyConfig(cfg, []Model{{Name: "Report"}}); err == nil {
+		t.Error("expected an error for a missing view file")
+	}
+}
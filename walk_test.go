@@ -0,0 +1,73 @@
+// walk_test.go
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// walkModels is a models file declaring one model, named name.
+func walkModels(name string) string {
+	return "from django.db import models\n\nclass " + name + "(models.Model):\n    title = models.CharField(max_length=10)\n"
+}
+
+// TestWalk checks that the parser skips symbolic links leaving the input,
+// reads linked directories inside it once, and stops at --max-depth.
+func TestWalk(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("no python3")
+	}
+	outside := writeProject(t, map[string]string{"vendor/models.py": walkModels("Vendored")})
+	dir := writeProject(t, map[string]string{
+		"blog/models.py":                  walkModels("Post"),
+		"apps/deep/nested/shop/models.py": walkModels("Item"),
+	})
+	for link, target := range map[string]string{
+		"vendor":     filepath.Join(outside, "vendor"),
+		"blog/again": filepath.Join(dir, "blog"),
+		"blog/loop":  dir,
+		"extra.py":   filepath.Join(outside, "vendor", "models.py"),
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models := func(out *Output) string {
+		var names []string
+		for _, m := range out.Models {
+			names = append(names, m.Name)
+		}
+		slices.Sort(names)
+		return strings.Join(names, ",")
+	}
+	out, err := runPythonParser(dir, "", nil, parserEnvironment("", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := models(out); got != "Item,Post" {
+		t.Errorf("models = %s", got)
+	}
+	var skipped []string
+	for _, d := range out.Diagnostics {
+		if d.Code == codeLinkOutside {
+			skipped = append(skipped, d.File)
+		}
+	}
+	slices.Sort(skipped)
+	if got := strings.Join(skipped, ","); got != "extra.py,vendor" {
+		t.Errorf("skipped links = %s", got)
+	}
+
+	out, err = runPythonParser(dir, "", []string{"--max-depth=2"}, parserEnvironment("", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := models(out); got != "Post" {
+		t.Errorf("models with --max-depth=2 = %s", got)
+	}
+}
@@ -0,0 +1,197 @@
+// workflow.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Workflow file kinds, set with emit.workflow.
+const (
+	workflowMake = "make"
+	workflowTask = "task"
+)
+
+// driftCodes are the triage warnings the drift-check target fails on.
+const driftCodes = "W501,W502,W503"
+
+// Workflow is the Makefile or Taskfile running the steps of the port from
+// the output directory: regenerating it, applying the migrations, running
+// sqlc and the tests, and checking the Django project for drift.
+type Workflow struct {
+	// Generate and Triage are the django2go arguments of the regeneration
+	// and of the drift check.
+	Generate []string
+	Triage   []string
+	// Trees lists the migrations directory and sqlc.yaml of every
+	// database, the default one first.
+	Trees []WorkflowTree
+}
+
+// WorkflowTree is the part of a database tree the workflow runs tools on.
+type WorkflowTree struct {
+	// URL is the environment variable holding the database's connection
+	// string.
+	URL        string
+	Migrations string
+	SQLC       string
+}
+
+// workflowArgs returns the arguments rerunning django2go with the flags of
+// the run from the output directory, and those of triage reading the same
+// project. Paths are made relative to the output directory, and the run
+// diffs against the snapshot it saved so that it only adds migrations for
+// the changes since.
+func workflowArgs(fs *flag.FlagSet, output string, layout Layout) (generate, triage []string, err error) {
+	root, err := filepath.Abs(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "output", "diff", "dry-run", "profile-output":
+			return
+		case "input", "config", "parser-script":
+			abs, absErr := filepath.Abs(value)
+			if absErr != nil {
+				err = absErr
+				return
+			}
+			if value, absErr = filepath.Rel(root, abs); absErr != nil {
+				value = abs
+			}
+			value = filepath.ToSlash(value)
+		}
+		arg := "--" + f.Name + "=" + value
+		generate = append(generate, arg)
+		switch f.Name {
+		case "input", "dialect", "parser-script", "settings", "env":
+			triage = append(triage, arg)
+		}
+	})
+	generate = append(generate, "--output=.", "--diff="+filepath.ToSlash(filepath.Join(layout.Migrations, snapshotFile)))
+	return generate, append(triage, "--error-on="+driftCodes), err
+}
+
+// workflowTrees returns the trees of the databases, relative to the output
+// directory.
+func workflowTrees(targets []target, output string, layout Layout) []WorkflowTree {
+	var trees []WorkflowTree
+	for _, t := range targets {
+		dir, err := filepath.Rel(output, t.dir)
+		if err != nil {
+			dir = t.name
+		}
+		url := "DATABASE_URL"
+		if t.name != defaultDatabase {
+			url = strings.ToUpper(t.name) + "_" + url
+		}
+		trees = append(trees, WorkflowTree{URL: url,
+			Migrations: filepath.ToSlash(filepath.Join(dir, layout.Migrations)),
+			SQLC:       filepath.ToSlash(filepath.Join(dir, layout.SQLC))})
+	}
+	return trees
+}
+
+// safeShellWord matches the words the shell reads as they are.
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes a word for the shell when it needs to be.
+func shellQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// workflowSyntax is how a workflow file refers to its variables and to
+// environment variables, and escapes the rest of a command.
+type workflowSyntax struct {
+	ref, env, escape func(string) string
+}
+
+// commands returns the shell commands of each target, the tools being
+// referred to by the variables DJANGO2GO, MIGRATE and SQLC.
+func (w Workflow) commands(syntax workflowSyntax) []workflowTarget {
+	words := func(args []string) string {
+		quoted := make([]string, len(args))
+		for i, a := range args {
+			quoted[i] = syntax.escape(shellQuote(a))
+		}
+		return strings.Join(quoted, " ")
+	}
+	var sqlc, up, down []string
+	for _, t := range w.Trees {
+		sqlc = append(sqlc, syntax.ref("SQLC")+" generate -f "+words([]string{t.SQLC}))
+		migrate := syntax.ref("MIGRATE") + " -path " + words([]string{t.Migrations}) + ` -database "` + syntax.env(t.URL) + `"`
+		up = append(up, migrate+" up")
+		down = append([]string{migrate + " down 1"}, down...)
+	}
+	return []workflowTarget{
+		{"regenerate", "Regenerate the schema, migrations and queries from the Django project, then the sqlc code", nil,
+			append([]string{syntax.ref("DJANGO2GO") + " " + words(w.Generate)}, sqlc...)},
+		{"sqlc", "Generate the Go code of the queries", nil, sqlc},
+		{"migrate-up", "Apply the pending migrations", nil, up},
+		{"migrate-down", "Roll back the last migration", nil, down},
+		{"test", "Run the Go tests against throwaway databases", []string{"sqlc"}, []string{"go test ./..."}},
+		{"drift-check", "Check that the models, migrations and database agree", nil,
+			[]string{syntax.ref("DJANGO2GO") + " triage " + words(w.Triage)}},
+	}
+}
+
+// workflowTarget is a Makefile target or Taskfile task.
+type workflowTarget struct {
+	name, desc string
+	deps, cmds []string
+}
+
+// generateWorkflow renders the Makefile or Taskfile.yaml of the workflow.
+// The tools default to the binaries on the PATH and can be overridden, as
+// in make regenerate DJANGO2GO=./django2go.
+func generateWorkflow(kind string, w Workflow) map[string]string {
+	var sb strings.Builder
+	if kind == workflowTask {
+		sb.WriteString("version: \"3\"\n\nvars:\n  DJANGO2GO: django2go\n  MIGRATE: migrate\n  SQLC: sqlc\n\ntasks:\n")
+		syntax := workflowSyntax{
+			ref:    func(v string) string { return "{{." + v + "}}" },
+			env:    func(v string) string { return "$" + v },
+			escape: func(s string) string { return s },
+		}
+		for _, t := range w.commands(syntax) {
+			fmt.Fprintf(&sb, "  %s:\n    desc: %s\n", t.name, t.desc)
+			if len(t.deps) > 0 {
+				fmt.Fprintf(&sb, "    deps: [%s]\n", strings.Join(t.deps, ", "))
+			}
+			sb.WriteString("    cmds:\n")
+			for _, c := range t.cmds {
+				fmt.Fprintf(&sb, "      - %q\n", c)
+			}
+		}
+		return map[string]string{"Taskfile.yaml": sb.String()}
+	}
+	targets := w.commands(workflowSyntax{
+		ref:    func(v string) string { return "$(" + v + ")" },
+		env:    func(v string) string { return "$$" + v },
+		escape: func(s string) string { return strings.ReplaceAll(s, "$", "$$") },
+	})
+	sb.WriteString("DJANGO2GO ?= django2go\nMIGRATE ?= migrate\nSQLC ?= sqlc\n\n.PHONY:")
+	for _, t := range targets {
+		sb.WriteString(" " + t.name)
+	}
+	sb.WriteString("\n")
+	for _, t := range targets {
+		fmt.Fprintf(&sb, "\n# %s.\n%s:", t.desc, t.name)
+		for _, d := range t.deps {
+			sb.WriteString(" " + d)
+		}
+		sb.WriteString("\n")
+		for _, c := range t.cmds {
+			sb.WriteString("\t" + c + "\n")
+		}
+	}
+	return map[string]string{"Makefile": sb.String()}
+}
@@ -0,0 +1,73 @@
+// workflow_test.go
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestWorkflowArgs checks that the rerun drops the output flags, makes paths
+// relative to the output directory and diffs against the saved snapshot.
+func TestWorkflowArgs(t *testing.T) {
+	dir := t.TempDir()
+	fs := flag.NewFlagSet("django2go", flag.ContinueOnError)
+	fs.String("input", "", "")
+	fs.String("output", "", "")
+	fs.String("dialect", "", "")
+	fs.Bool("dry-run", false, "")
+	fs.Bool("no-app-prefix", false, "")
+	fs.String("profile-output", "", "")
+	output := filepath.Join(dir, "out")
+	if err := fs.Parse([]string{"--input=" + filepath.Join(dir, "project"), "--output=" + output, "--dialect=mysql", "--dry-run", "--no-app-prefix", "--profile-output=prof"}); err != nil {
+		t.Fatal(err)
+	}
+	generate, triage, err := workflowArgs(fs, output, defaultLayout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"--dialect=mysql", "--input=../project", "--no-app-prefix=true", "--output=.", "--diff=migrations/" + snapshotFile}
+	if !slices.Equal(generate, want) {
+		t.Errorf("generate = %q, want %q", generate, want)
+	}
+	want = []string{"--dialect=mysql", "--input=../project", "--error-on=" + driftCodes}
+	if !slices.Equal(triage, want) {
+		t.Errorf("triage = %q, want %q", triage, want)
+	}
+}
+
+// TestGenerateWorkflow checks the Makefile and Taskfile targets of a port
+// with a second database.
+func TestGenerateWorkflow(t *testing.T) {
+	w := Workflow{
+		Generate: []string{"--input=../my project", "--output=."},
+		Triage:   []string{"--input=../my project"},
+		Trees:    workflowTrees([]target{{name: defaultDatabase, dir: "out"}, {name: "analytics", dir: filepath.Join("out", "analytics")}}, "out", defaultLayout),
+	}
+	makefile := generateWorkflow(workflowMake, w)["Makefile"]
+	for _, want := range []string{
+		"DJANGO2GO ?= django2go\n",
+		".PHONY: regenerate sqlc migrate-up migrate-down test drift-check\n",
+		"\t$(DJANGO2GO) '--input=../my project' --output=.\n",
+		"\t$(SQLC) generate -f analytics/sqlc.yaml\n",
+		"\t$(MIGRATE) -path migrations -database \"$$DATABASE_URL\" up\n",
+		"migrate-down:\n\t$(MIGRATE) -path analytics/migrations -database \"$$ANALYTICS_DATABASE_URL\" down 1\n\t$(MIGRATE) -path migrations",
+		"test: sqlc\n",
+	} {
+		if !strings.Contains(makefile, want) {
+			t.Errorf("Makefile lacks %q:\n%s", want, makefile)
+		}
+	}
+	taskfile := generateWorkflow(workflowTask, w)["Taskfile.yaml"]
+	for _, want := range []string{
+		"  DJANGO2GO: django2go\n",
+		"      - \"{{.MIGRATE}} -path migrations -database \\\"$DATABASE_URL\\\" up\"\n",
+		"    deps: [sqlc]\n",
+	} {
+		if !strings.Contains(taskfile, want) {
+			t.Errorf("Taskfile lacks %q:\n%s", want, taskfile)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// workspace.go
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// WorkspaceConfig converts a project into one output repository app by app.
+// Only the listed apps are generated; the models of the others stay in
+// Django until their app is added, so their tables are only referenced.
+type WorkspaceConfig struct {
+	// Apps lists the app labels converted so far.
+	Apps []string `yaml:"apps"`
+}
+
+// externalHeader is written at the top of external.sql.
+const externalHeader = `-- Reference only: these tables belong to apps not converted yet and are
+-- referenced by the converted ones. Only their keys are declared. They are
+-- read by sqlc but must never be applied as a migration.
+
+`
+
+// systemFragment names the schema fragment of the sessions and cache tables.
+const systemFragment = "django"
+
+// enabled reports whether the project is converted app by app.
+func (w WorkspaceConfig) enabled() bool {
+	return len(w.Apps) > 0
+}
+
+// checkWorkspace reports workspace apps the project does not have.
+func checkWorkspace(cfg *Config, models []Model) []Diagnostic {
+	apps := map[string]bool{}
+	for _, m := range models {
+		apps[m.App] = true
+	}
+	var diags []Diagnostic
+	for _, app := range cfg.Workspace.Apps {
+		if !apps[app] {
+			diags = append(diags, Diagnostic{Code: codeConfig, Severity: severity(codeConfig), File: cfg.path,
+				Message: fmt.Sprintf("workspace: unknown app %q", app)})
+		}
+	}
+	return diags
+}
+
+// applyWorkspace keeps the models, call sites and management commands of
+// the workspace apps, returning the models of the apps not converted yet.
+// The queries of every app go to their own file.
+func applyWorkspace(cfg *Config, out *Output) (pending []Model) {
+	if !cfg.Workspace.enabled() {
+		return nil
+	}
+	cfg.Layout.SplitQueries = true
+	converted := func(app string) bool { return slices.Contains(cfg.Workspace.Apps, app) }
+	var models []Model
+	for _, m := range out.Models {
+		if converted(m.App) {
+			models = append(models, m)
+		} else {
+			pending = append(pending, m)
+		}
+	}
+	out.Models = models
+	out.Queries = slices.DeleteFunc(out.Queries, func(q Query) bool { return !converted(q.App) })
+	out.Commands = slices.DeleteFunc(out.Commands, func(c Command) bool { return !converted(c.App) })
+	return pending
+}
+
+// markPending explains the call sites left untranslated because they query
+// a model of an app not converted yet.
+func markPending(queries []TranslatedQuery, pending []Model) {
+	for i, q := range queries {
+		if q.Translated() {
+			continue
+		}
+		for _, m := range pending {
+			if m.Name == q.Model {
+				queries[i].Reason = fmt.Sprintf("model %s belongs to app %s, not converted yet", m.Name, m.App)
+			}
+		}
+	}
+}
+
+// externalModels returns the stubs of the pending models the models relate
+// to, declaring only the primary key their foreign keys reference.
+func externalModels(models, pending []Model) []Model {
+	referenced := map[string]bool{}
+	for _, m := range models {
+		for _, f := range m.Fields {
+			if f.Relation != "" {
+				referenced[f.RelatedTo] = true
+			}
+		}
+	}
+	var stubs []Model
+	for _, m := range pending {
+		if !referenced[m.Name] || m.isView() {
+			continue
+		}
+		stub := Model{Name: m.Name, App: m.App, File: m.File, Line: m.Line, AutoField: m.AutoField, DBTable: m.DBTable, Table: m.Table}
+		for _, f := range m.Fields {
+			composite := slices.Contains(m.PrimaryKey, f.Name)
+			if !f.PrimaryKey && !composite {
+				continue
+			}
+			// A key that is itself a relation, such as the parent link of
+			// multi-table inheritance, keeps its columns but not the
+			// foreign key.
+			for _, c := range fieldColumns(f) {
+				stub.Fields = append(stub.Fields, Field{Name: c.Name, Type: c.Type, DBType: c.DBType, Line: f.Line,
+					PrimaryKey: f.PrimaryKey, MaxLength: f.MaxLength, MaxDigits: f.MaxDigits, DecimalPlaces: f.DecimalPlaces})
+				if composite {
+					stub.PrimaryKey = append(stub.PrimaryKey, c.Name)
+				}
+			}
+		}
+		stubs = append(stubs, stub)
+	}
+	return stubs
+}
+
+// schemaFragments splits the schema of the workspace into a file per app,
+// next to the schema under a directory named after it, so that each app
+// added to the workspace adds its own fragment. The sessions and cache
+// tables go to a fragment of their own.
+func schemaFragments(layout Layout, models []Model, opts Options, system string) (files []string, content map[string]string) {
+	dir := strings.TrimSuffix(layout.Schema, filepath.Ext(layout.Schema))
+	content = map[string]string{}
+	byApp := map[string][]Model{}
+	for _, m := range models {
+		file := filepath.Join(dir, m.App+".sql")
+		if _, ok := byApp[file]; !ok {
+			files = append(files, file)
+		}
+		byApp[file] = append(byApp[file], m)
+	}
+	for file, models := range byApp {
+		content[file] = generateSQL(models, opts)
+	}
+	if system != "" {
+		file := filepath.Join(dir, systemFragment+".sql")
+		files, content[file] = append(files, file), system
+	}
+	return files, content
+}
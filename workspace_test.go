@@ -0,0 +1,82 @@
+// workspace_test.go
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestWorkspace checks that a workspace converts only its apps, stubs the
+// tables they reference and splits the schema by app.
+func TestWorkspace(t *testing.T) {
+	out := parseProject(t, map[string]string{
+		"accounts/models.py": `from django.db import models
+
+class Member(models.Model):
+    email = models.CharField(max_length=100)
+`,
+		"blog/models.py": `from django.db import models
+
+class Post(models.Model):
+    author = models.ForeignKey("accounts.Member", on_delete=models.CASCADE)
+    title = models.CharField(max_length=100)
+`,
+		"blog/views.py": `from .models import Post
+from accounts.models import Member
+
+def posts():
+    return Post.objects.all()
+
+def members():
+    return Member.objects.all()
+`,
+	})
+	cfg := &Config{Layout: defaultLayout, Workspace: WorkspaceConfig{Apps: []string{"blog"}}}
+	opts := Options{Dialect: "postgres", NoAppPrefix: true}
+	if diags := normalize(cfg, out.Models, opts); len(diags) != 0 {
+		t.Fatal(diags)
+	}
+	pending := applyWorkspace(cfg, out)
+	if len(out.Models) != 1 || out.Models[0].Name != "Post" || len(pending) != 1 || pending[0].Name != "Member" {
+		t.Fatalf("models = %+v, pending = %+v", out.Models, pending)
+	}
+	if !cfg.Layout.SplitQueries {
+		t.Error("workspace does not split the queries by app")
+	}
+
+	queries := translateQueries(out.Queries, out.Models, opts.Dialect)
+	markPending(queries, pending)
+	var reasons []string
+	for _, q := range queries {
+		if !q.Translated() {
+			reasons = append(reasons, q.Reason)
+		}
+	}
+	if want := []string{"model Member belongs to app accounts, not converted yet"}; !slices.Equal(reasons, want) {
+		t.Errorf("reasons = %q, want %q", reasons, want)
+	}
+
+	external := externalModels(out.Models, pending)
+	if len(external) != 1 || len(external[0].Fields) != 0 {
+		t.Fatalf("external = %+v", external)
+	}
+	if sql := generateSQL(external, opts); !strings.Contains(sql, "CREATE TABLE member") || strings.Contains(sql, "email") {
+		t.Errorf("external schema declares more than the key:\n%s", sql)
+	}
+
+	files, content := schemaFragments(cfg.Layout, out.Models, opts, "CREATE TABLE django_session ();\n")
+	want := []string{filepath.Join("schema", "blog.sql"), filepath.Join("schema", "django.sql")}
+	if !slices.Equal(files, want) {
+		t.Fatalf("fragments = %q, want %q", files, want)
+	}
+	if !strings.Contains(content[want[0]], "CREATE TABLE post") || !strings.Contains(content[want[1]], "django_session") {
+		t.Errorf("fragments = %q", content)
+	}
+
+	cfg.Workspace.Apps = []string{"blog", "shop"}
+	if diags := checkWorkspace(cfg, pending); len(diags) != 2 || !strings.Contains(diags[1].Message, `"shop"`) {
+		t.Errorf("diags = %+v", diags)
+	}
+}